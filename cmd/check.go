@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+
+	"pc28-bot/internal/api"
+	"pc28-bot/internal/config"
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/telegram"
+)
+
+// runSelfTest 启动前自检：验证配置、数据库、上游API和Telegram token
+// 返回进程退出码（0=全部通过，1=存在失败项）
+func runSelfTest(configPath string) int {
+	fmt.Println("🔍 PC28预测机器人启动自检")
+	fmt.Println("")
+
+	failures := 0
+
+	// 1. 配置文件
+	cfg, err := config.LoadConfig(configPath)
+	printCheckResult("配置文件", err)
+	if err != nil {
+		// 后续检查都依赖配置，直接结束
+		printCheckSummary(1)
+		return 1
+	}
+
+	// 自检输出不需要业务日志干扰
+	logger.InitLogger("error")
+
+	// 2. 数据库连接
+	db, err := database.NewDB(&cfg.Database)
+	printCheckResult(fmt.Sprintf("数据库连接 (%s)", databaseDriverName(cfg)), err)
+	if err != nil {
+		failures++
+	} else {
+		db.Close()
+	}
+
+	// 3. 上游API
+	apiClient := api.NewClient(&cfg.API)
+	err = apiClient.HealthCheck()
+	printCheckResult("上游开奖API", err)
+	if err != nil {
+		failures++
+	}
+
+	// 4. Telegram token
+	if cfg.Telegram.Token == "" {
+		fmt.Println("⏭️  Telegram token: 未配置（无界面模式），跳过")
+	} else {
+		username, err := telegram.CheckToken(&cfg.Telegram)
+		if err == nil {
+			printCheckResult(fmt.Sprintf("Telegram token (@%s)", username), nil)
+		} else {
+			printCheckResult("Telegram token", err)
+			failures++
+		}
+	}
+
+	printCheckSummary(failures)
+	if failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printCheckResult 输出单项检查结果
+func printCheckResult(name string, err error) {
+	if err != nil {
+		fmt.Printf("❌ %s: %v\n", name, err)
+	} else {
+		fmt.Printf("✅ %s: 正常\n", name)
+	}
+}
+
+// printCheckSummary 输出自检汇总
+func printCheckSummary(failures int) {
+	fmt.Println("")
+	if failures > 0 {
+		fmt.Printf("❌ 自检失败: %d 项检查未通过\n", failures)
+	} else {
+		fmt.Println("✅ 自检通过: 所有检查项正常，可以启动")
+	}
+}
+
+// databaseDriverName 返回当前配置的存储驱动名
+func databaseDriverName(cfg *config.Config) string {
+	if cfg.Database.Driver == "" {
+		return "mysql"
+	}
+	return cfg.Database.Driver
+}
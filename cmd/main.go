@@ -1,43 +1,106 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"pc28-bot/internal/alert"
 	"pc28-bot/internal/api"
+	"pc28-bot/internal/broker"
 	"pc28-bot/internal/cache"
+	"pc28-bot/internal/chaos"
+	"pc28-bot/internal/clock"
+	"pc28-bot/internal/coldstore"
 	"pc28-bot/internal/config"
 	"pc28-bot/internal/database"
+	"pc28-bot/internal/diagnostics"
 	"pc28-bot/internal/logger"
+	"pc28-bot/internal/pipeline"
 	"pc28-bot/internal/predictor"
+	"pc28-bot/internal/report"
+	"pc28-bot/internal/schedule"
+	"pc28-bot/internal/sentry"
+	"pc28-bot/internal/supervisor"
 	"pc28-bot/internal/telegram"
+	"pc28-bot/internal/webhook"
 )
 
 // App 应用程序主结构
 type App struct {
-	config         *config.Config
-	mysql          *database.MySQLDB
-	cacheManager   *cache.CacheManager
-	apiClient      *api.Client
-	predictorMgr   *predictor.PredictorManager
-	validator      *predictor.Validator
-	statCalculator *predictor.StatisticsCalculator
-	telegramBot    *telegram.Bot
+	config          *config.Config
+	db              database.DB
+	cacheManager    *cache.CacheManager
+	apiClient       *api.Client
+	predictorMgr    *predictor.PredictorManager
+	validator       *predictor.Validator
+	statCalculator  *predictor.StatisticsCalculator
+	telegramBot     *telegram.Bot
+	supervisor      *supervisor.Supervisor
+	leaderElector   *database.LeaderElector
+	diagServer      *diagnostics.Server
+	alertManager    *alert.Manager
+	processor       *pipeline.RoundProcessor
+	sentryHook      *sentry.Hook
+	drawSchedule    *schedule.Schedule
+	replayServer    *api.ReplayServer    // 数据回放服务（未配置fixture时为nil）
+	compactor       *coldstore.Compactor // 冷存储归档器（未配置归档目录时为nil）
+	webhookNotifier *webhook.Notifier    // 对外webhook通知器（未配置端点时为nil）
+	brokerPublisher *broker.Publisher    // 消息代理发布器（未配置服务器时为nil）
+	cacheBus        *broker.CacheBus     // 跨副本缓存失效总线（未配置消息代理时为nil）
 
 	// 控制通道
-	stopChannel chan bool
-	wg          sync.WaitGroup
+	stopChannel    chan bool
+	pollingReload  chan time.Duration
+	restartChannel chan struct{}
+	restartOnce    sync.Once
 
-	// 错误状态跟踪（避免重复日志）
-	lastAPIError       string
-	lastDBError        string
+	// 在途处理跟踪（优雅停机时等待其完成）
+	inFlight sync.WaitGroup
+
+	// 配置文件路径（用于SIGHUP热重载）
+	configPath string
+
+	// 最近处理的期号
 	lastProcessedQihao string
+
+	// 最近触发定时报表的日期（避免同日重复推送）
+	lastReportDate string
+
+	// 最近执行夜间对账的日期（避免同日重复执行）
+	lastReconcileDate string
+
+	// 最近推送每日摘要的日期（避免同日重复推送）
+	lastSummaryDate string
+
+	// 最近执行算法交叉验证的日期（避免同日重复执行）
+	lastCrossValDate string
+
+	// 命中判定策略（验证与离线评估共用同一口径）
+	judgePolicy predictor.CorrectnessPolicy
+
+	// 时间源（默认真实时钟，测试/回放可通过SetClock注入模拟时钟）
+	clk clock.Clock
+
+	// 后台健康检查快照（HTTP端点直接返回缓存结果，不做实时探测）
+	healthMutex       sync.RWMutex
+	healthSnapshot    map[string]interface{}
+	apiHealthFailures int // API探测连续失败次数（达到阈值时整体降为unhealthy）
 }
 
+// 后台健康检查参数
+const (
+	healthCheckInterval      = 30 * time.Second // 后台探测间隔
+	healthDependencyTimeout  = 5 * time.Second  // 单个依赖的探测超时
+	healthUnhealthyThreshold = 3                // API连续失败达到该次数时状态从degraded升级为unhealthy
+)
+
 // NewApp 创建应用程序实例
 func NewApp(configPath string) (*App, error) {
 	// 加载配置
@@ -45,300 +108,591 @@ func NewApp(configPath string) (*App, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %v", err)
 	}
+	return NewAppWithConfig(cfg, configPath)
+}
+
+// NewAppWithConfig 用已加载的配置创建应用程序实例（多租户模式下传入租户派生配置）
+func NewAppWithConfig(cfg *config.Config, configPath string) (*App, error) {
+	var err error
 
 	// 初始化日志
-	logger.InitLogger(cfg.App.LogLevel)
-	fmt.Println("🚀 启动PC28预测机器人...")
+	logger.InitLoggerWithFormat(cfg.App.LogLevel, cfg.App.LogFormat)
+	logger.SetConsoleMode(cfg.App.ConsoleMode)
+	logger.Status("🚀 启动PC28预测机器人...")
+
+	// 可选启用Sentry错误上报
+	var sentryHook *sentry.Hook
+	if cfg.Sentry.DSN != "" {
+		hook, err := sentry.NewHook(cfg.Sentry.DSN, cfg.Sentry.Release, cfg.Sentry.Environment)
+		if err != nil {
+			logger.Warnf("Failed to initialize sentry hook: %v", err)
+		} else {
+			sentryHook = hook
+			logger.AddHook(hook)
+			logger.Status("✅ Sentry错误上报已启用")
+		}
+	}
 
-	// 初始化数据库
-	mysql, err := database.NewMySQLDB(&cfg.Database)
+	// 初始化存储后端（mysql或memory）
+	db, err := database.NewDB(&cfg.Database)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %v", err)
 	}
-	fmt.Println("✅ 数据库连接成功")
-	fmt.Println("✅ 数据库表结构初始化完成")
+	logger.Status("✅ 数据库连接成功")
+	logger.StatusVerbose("✅ 数据库表结构初始化完成")
+
+	// 启用故障注入模式（仅staging验证弹性能力，生产严禁开启）
+	var faultInjector *chaos.Injector
+	if cfg.Chaos.Enabled {
+		faultInjector = chaos.NewInjector(cfg.Chaos.FailRate, cfg.Chaos.DelayRate,
+			cfg.Chaos.MaxDelay, cfg.Chaos.Seed)
+		db = database.NewChaosDB(db, faultInjector)
+		logger.Status("⚠️  故障注入模式已启用 (fail=%.2f delay=%.2f)", cfg.Chaos.FailRate, cfg.Chaos.DelayRate)
+	}
 
 	// 初始化缓存管理器
-	cacheManager, err := cache.NewCacheManager(mysql, cfg.App.CacheTTL)
+	cacheManager, err := cache.NewCacheManager(db, cfg.App.CacheTTL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize cache manager: %v", err)
 	}
-	fmt.Println("✅ 缓存系统初始化完成")
+	logger.Status("✅ 缓存系统初始化完成")
+
+	// 配置了回放fixture时启动本地回放服务作为数据源
+	// （配合memory存储后端可离线跑通完整单期生命周期）
+	var replayServer *api.ReplayServer
+	if cfg.API.FixtureFile != "" {
+		replayServer, err = api.NewReplayServer(cfg.API.FixtureFile, cfg.App.DrawInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create replay server: %v", err)
+		}
+		url, err := replayServer.Start()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start replay server: %v", err)
+		}
+		cfg.API.URL = url
+		logger.Status("▶️  数据回放模式: %s", cfg.API.FixtureFile)
+	}
 
 	// 初始化API客户端
 	apiClient := api.NewClient(&cfg.API)
+	if faultInjector != nil {
+		apiClient.SetFaultInjector(faultInjector)
+	}
 
 	// 初始化预测器管理器
 	predictorMgr := predictor.NewPredictorManager()
+	if cfg.App.Algorithm != "" {
+		if err := predictorMgr.SetCurrentPredictor(cfg.App.Algorithm); err != nil {
+			logger.Warnf("Failed to set predictor %s: %v, using default", cfg.App.Algorithm, err)
+		}
+	}
 
-	// 初始化验证器和统计计算器
-	validator := predictor.NewValidator(mysql)
-	statCalculator := predictor.NewStatisticsCalculator(mysql)
+	// 为使用随机数的预测器注入随机源（种子非0时为确定性模式，回测可复现）
+	predictorMgr.SeedPredictors(cfg.App.PredictorSeed)
+	if cfg.App.PredictorSeed != 0 {
+		logger.StatusVerbose("🎲 确定性模式已启用 (seed=%d)", cfg.App.PredictorSeed)
+	}
 
-	// 初始化Telegram机器人
-	telegramBot, err := telegram.NewBot(&cfg.Telegram, cacheManager)
+	// 初始化验证器和统计计算器（命中判定策略由配置选择）
+	policy, err := predictor.NewCorrectnessPolicy(cfg.App.ValidationPolicy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize telegram bot: %v", err)
+		return nil, fmt.Errorf("failed to initialize correctness policy: %v", err)
+	}
+	validator := predictor.NewValidator(db, policy)
+	statCalculator := predictor.NewStatisticsCalculator(db)
+
+	// 初始化Telegram机器人（token为空时以无Telegram模式运行）
+	var telegramBot *telegram.Bot
+	if cfg.Telegram.Token != "" {
+		telegramBot, err = telegram.NewBot(&cfg.Telegram, cacheManager, db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize telegram bot: %v", err)
+		}
+		if faultInjector != nil {
+			telegramBot.SetFaultInjector(faultInjector)
+		}
+		logger.Status("✅ Telegram机器人连接成功")
+	} else {
+		logger.Status("ℹ️  未配置Telegram token，以无界面模式运行（仅采集数据和生成预测）")
 	}
-	fmt.Println("✅ Telegram机器人连接成功")
+
+	// 配置了冷存储目录时启用按月归档（超窗数据先落盘再从热存储移除）
+	var compactor *coldstore.Compactor
+	if cfg.App.ColdStorageDir != "" {
+		blobStore, err := coldstore.NewDirStore(cfg.App.ColdStorageDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize cold storage: %v", err)
+		}
+		compactor = coldstore.NewCompactor(db, blobStore)
+		if telegramBot != nil {
+			telegramBot.SetColdHistory(coldstore.NewReader(blobStore))
+		}
+		logger.Status("🗄️  冷存储归档已启用: %s", cfg.App.ColdStorageDir)
+	}
+
+	// 初始化协程监管器
+	sup := supervisor.NewSupervisor()
+	sup.SetAlertFunc(func(name string, crashes int, reason interface{}) {
+		logger.Errorf("ALERT: goroutine %s crashed %d times, last reason: %v", name, crashes, reason)
+		logger.Status("🚨 协程 %s 已连续崩溃 %d 次: %v", name, crashes, reason)
+	})
+
+	// 领导者选举由具备该能力的存储后端提供（MySQL咨询锁，包装层透传；
+	// 内存模式不具备，按单实例本地运行）
+	leaderElector := database.NewLeaderElectorFor(db, "pc28_bot_leader")
 
 	app := &App{
 		config:         cfg,
-		mysql:          mysql,
+		db:             db,
 		cacheManager:   cacheManager,
 		apiClient:      apiClient,
 		predictorMgr:   predictorMgr,
 		validator:      validator,
 		statCalculator: statCalculator,
 		telegramBot:    telegramBot,
+		supervisor:     sup,
+		leaderElector:  leaderElector,
+		alertManager:   alert.NewManager(&cfg.Alerting),
+		sentryHook:     sentryHook,
+		drawSchedule:   schedule.New(cfg.App.DrawInterval, cfg.App.MaintenanceWindows),
+		replayServer:   replayServer,
+		compactor:      compactor,
 		stopChannel:    make(chan bool),
+		restartChannel: make(chan struct{}),
+		judgePolicy:    policy,
+		clk:            clock.System(),
+		pollingReload:  make(chan time.Duration, 1),
+		configPath:     configPath,
 	}
 
-	fmt.Println("🎯 应用程序初始化完成")
-	return app, nil
-}
-
-// Start 启动应用程序
-func (a *App) Start() error {
-	fmt.Println("🔄 启动所有服务...")
-
-	// 初始化历史数据
-	if err := a.initializeHistoricalData(); err != nil {
-		logger.Warnf("Failed to initialize historical data: %v", err)
+	// 初始化单期处理器（拥有完整的开奖处理流程）
+	app.processor = pipeline.NewRoundProcessor(db, cacheManager, apiClient,
+		predictorMgr, validator, app.alertManager)
+	if telegramBot != nil {
+		app.processor.SetBroadcaster(telegramBot)
+		telegramBot.SetCorrector(app.processor)
+		telegramBot.SetStalenessChecker(app.processor)
+		telegramBot.SetSchedule(app.drawSchedule)
+		telegramBot.SetRestartFunc(app.RequestRestart)
+		telegramBot.SetPredictorManager(predictorMgr, policy)
+		telegramBot.SetOdds(cfg.App.OddsOddEven, cfg.App.OddsBigSmall)
+		telegramBot.SetCountdownInterval(cfg.App.CountdownEditInterval)
+	}
+	app.processor.SetSchedule(app.drawSchedule)
+	// 单次轮询的截止预算按开奖间隔设置：一期内完不成的工作放弃，避免ticker积压
+	app.processor.SetTickBudget(cfg.App.DrawInterval)
+	app.processor.SetBroadcastLead(cfg.App.BroadcastLeadTime)
+	app.processor.SetFreezeWindow(cfg.App.PredictionFreezeWindow)
+
+	// 配置了第二数据源时启用交叉核对（号码不一致的期号暂缓入库）
+	if cfg.API.SecondaryURL != "" {
+		secondaryCfg := cfg.API
+		secondaryCfg.URL = cfg.API.SecondaryURL
+		app.processor.SetCrossChecker(pipeline.NewCrossChecker(
+			api.NewClient(&secondaryCfg), app.alertManager))
+		logger.Status("🔁 第二数据源交叉核对已启用: %s", cfg.API.SecondaryURL)
 	}
 
-	// 启动Telegram机器人
-	a.telegramBot.Start()
+	// 初始化对外webhook通知器（领域事件推送给第三方系统）
+	if len(cfg.Webhooks) > 0 {
+		app.webhookNotifier = webhook.NewNotifier(cfg.Webhooks)
+		app.processor.AddEventPublisher(app.webhookNotifier)
+		logger.Status("🔗 已配置 %d 个对外webhook端点", len(cfg.Webhooks))
+	}
 
-	// 启动数据监控协程
-	a.wg.Add(1)
-	go a.dataMonitorLoop()
+	// 初始化消息代理发布器（领域事件发布到NATS主题）
+	if cfg.Broker.URL != "" {
+		app.brokerPublisher = broker.NewPublisher(cfg.Broker.URL, cfg.Broker.TopicPrefix)
+		app.processor.AddEventPublisher(app.brokerPublisher)
+		logger.Status("📡 消息代理发布已启用: %s", cfg.Broker.URL)
 
-	// 启动数据清理协程
-	a.wg.Add(1)
-	go a.dataCleanupLoop()
+		// 复用同一NATS服务器在多副本间广播缓存失效事件
+		app.cacheBus = broker.NewCacheBus(cfg.Broker.URL, cfg.Broker.TopicPrefix)
+		cacheManager.SetEventBus(app.cacheBus)
+	}
 
-	fmt.Println("✅ 所有服务启动完成")
-	fmt.Println("📡 开始监控PC28开奖数据...")
-	fmt.Printf("⏰ 轮询间隔: %v\n", a.config.App.PollingInterval)
-	fmt.Println("🔔 机器人仅在私聊中提供服务")
-	fmt.Println("💡 按 Ctrl+C 停止程序")
-	fmt.Println("")
-	return nil
-}
+	// 初始化趋势通知器（验证后检查移动平均方向变化）
+	trendNotifier := pipeline.NewTrendNotifier(statCalculator, cfg.App.TrendNotifyRounds)
+	if telegramBot != nil {
+		trendNotifier.SetBroadcaster(telegramBot)
+	}
+	app.processor.SetTrendNotifier(trendNotifier)
 
-// Stop 停止应用程序
-func (a *App) Stop() error {
-	fmt.Println("🛑 正在停止应用程序...")
+	// 初始化诊断服务（未配置监听地址则不启动）
+	if cfg.Debug.Listen != "" {
+		app.diagServer = diagnostics.NewServer(cfg.Debug.Listen, cfg.Debug.Token)
 
-	// 发送停止信号
-	close(a.stopChannel)
+		// 业务端点：星期×小时准确率热力图
+		app.diagServer.RegisterHandler("/debug/heatmap", func(w http.ResponseWriter, r *http.Request) {
+			heatmap, err := statCalculator.ComputeAccuracyHeatmap()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to compute heatmap: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(heatmap); err != nil {
+				http.Error(w, fmt.Sprintf("failed to encode heatmap: %v", err), http.StatusInternalServerError)
+			}
+		})
 
-	// 停止Telegram机器人
-	a.telegramBot.Stop()
+		// 业务端点：算法交叉验证榜单
+		app.diagServer.RegisterHandler("/debug/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+			entries, err := db.GetLeaderboard()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to get leaderboard: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(entries); err != nil {
+				http.Error(w, fmt.Sprintf("failed to encode leaderboard: %v", err), http.StatusInternalServerError)
+			}
+		})
 
-	// 等待所有协程结束
-	a.wg.Wait()
+		// 业务端点：健康快照（读后台探测缓存，立即返回）
+		app.diagServer.RegisterHandler("/debug/health", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(app.HealthCheck()); err != nil {
+				http.Error(w, fmt.Sprintf("failed to encode health snapshot: %v", err), http.StatusInternalServerError)
+			}
+		})
+
+		// 业务端点：各命令调用次数与处理耗时（用于发现慢处理）
+		if telegramBot != nil {
+			app.diagServer.RegisterHandler("/debug/commands", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if err := json.NewEncoder(w).Encode(telegramBot.CommandStats()); err != nil {
+					http.Error(w, fmt.Sprintf("failed to encode command stats: %v", err), http.StatusInternalServerError)
+				}
+			})
+		}
 
-	// 关闭缓存管理器
-	if err := a.cacheManager.Close(); err != nil {
-		logger.Errorf("Failed to close cache manager: %v", err)
-	}
+		// 业务端点：单期处理时间线（各阶段时间戳，排查推送慢）
+		app.diagServer.RegisterHandler("/debug/timeline", func(w http.ResponseWriter, r *http.Request) {
+			qihao := r.URL.Query().Get("qihao")
+			if qihao == "" {
+				http.Error(w, "missing qihao parameter", http.StatusBadRequest)
+				return
+			}
+			timeline, err := db.GetRoundTimeline(qihao)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to get round timeline: %v", err), http.StatusInternalServerError)
+				return
+			}
+			if timeline == nil {
+				http.Error(w, "no timeline recorded for this round", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(timeline); err != nil {
+				http.Error(w, fmt.Sprintf("failed to encode timeline: %v", err), http.StatusInternalServerError)
+			}
+		})
+
+		// 业务端点：各位置数字分布（窗口可调，供用户自行分析）
+		app.diagServer.RegisterHandler("/debug/positions", func(w http.ResponseWriter, r *http.Request) {
+			window := 100
+			if raw := r.URL.Query().Get("window"); raw != "" {
+				n, err := strconv.Atoi(raw)
+				if err != nil || n <= 0 || n > 1000 {
+					http.Error(w, "invalid window parameter (1-1000)", http.StatusBadRequest)
+					return
+				}
+				window = n
+			}
+			distribution, err := cacheManager.GetPositionDistribution(window)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to get position distribution: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(map[string]interface{}{
+				"window":       window,
+				"distribution": distribution,
+			}); err != nil {
+				http.Error(w, fmt.Sprintf("failed to encode position distribution: %v", err), http.StatusInternalServerError)
+			}
+		})
+
+		// Prometheus文本格式的新鲜度指标（标准告警规则可直接对
+		// seconds_since_*设阈值，不依赖日志采集；-1表示尚未发生）
+		app.diagServer.RegisterHandler("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			fmt.Fprintln(w, "# HELP pc28_seconds_since_last_round Seconds since the newest round's draw time (-1 if no round seen yet).")
+			fmt.Fprintln(w, "# TYPE pc28_seconds_since_last_round gauge")
+			fmt.Fprintf(w, "pc28_seconds_since_last_round %.0f\n", app.processor.SecondsSinceLastRound())
+			fmt.Fprintln(w, "# HELP pc28_seconds_since_last_prediction Seconds since the last prediction was generated (-1 if none yet).")
+			fmt.Fprintln(w, "# TYPE pc28_seconds_since_last_prediction gauge")
+			fmt.Fprintf(w, "pc28_seconds_since_last_prediction %.0f\n", app.processor.SecondsSinceLastPrediction())
+			fmt.Fprintln(w, "# HELP pc28_upstream_data_stale Whether the upstream source is considered stale (1) or fresh (0).")
+			fmt.Fprintln(w, "# TYPE pc28_upstream_data_stale gauge")
+			stale := 0
+			if app.processor.DataStale() {
+				stale = 1
+			}
+			fmt.Fprintf(w, "pc28_upstream_data_stale %d\n", stale)
+		})
 
-	// 关闭数据库连接
-	if err := a.mysql.Close(); err != nil {
-		logger.Errorf("Failed to close database: %v", err)
+		// 业务端点：开奖与预测验证订阅源（阅读器以token查询参数订阅）
+		feedBuilder := report.NewFeedBuilder(db)
+		app.diagServer.RegisterHandler("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+			body, err := feedBuilder.RenderRSS()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to render feed: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+			w.Write(body)
+		})
+		app.diagServer.RegisterHandler("/feed.json", func(w http.ResponseWriter, r *http.Request) {
+			body, err := feedBuilder.RenderJSONFeed()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to render feed: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+			w.Write(body)
+		})
 	}
 
-	fmt.Println("✅ 应用程序已安全停止")
-	return nil
+	logger.Status("🎯 应用程序初始化完成")
+	return app, nil
 }
 
-// initializeHistoricalData 初始化历史数据并同步预测验证
-func (a *App) initializeHistoricalData() error {
-	fmt.Println("📚 初始化历史开奖数据...")
+// Start 启动应用程序
+func (a *App) Start() error {
+	logger.Status("🔄 启动所有服务...")
 
-	// 获取更多的API历史数据以确保覆盖所有未验证的预测
-	historicalData, err := a.apiClient.GetHistoricalData(50) // 增加到50期
-	if err != nil {
-		return fmt.Errorf("failed to get historical data: %v", err)
-	}
-
-	// 保存到数据库（如果不存在的话）
-	savedCount := 0
-	for _, data := range historicalData {
-		// 检查是否已存在
-		existing, err := a.mysql.GetLotteryResultByQihao(data.Qihao)
-		if err != nil || existing == nil {
-			// 不存在，保存到数据库
-			if err := a.mysql.SaveLotteryResult(&data); err != nil {
-				logger.Warnf("Failed to save historical data %s: %v", data.Qihao, err)
-				continue
-			}
-			savedCount++
-		}
+	// 初始化历史数据
+	if err := a.processor.InitializeHistoricalData(); err != nil {
+		logger.Warnf("Failed to initialize historical data: %v", err)
 	}
 
-	if savedCount > 0 {
-		fmt.Printf("✅ 初始化了 %d 条历史数据\n", savedCount)
-	} else {
-		fmt.Println("✅ 历史数据已存在，无需初始化")
+	// 启动Telegram机器人（无界面模式下跳过）
+	if a.telegramBot != nil {
+		a.telegramBot.Start()
 	}
 
-	// 同步预测验证状态
-	fmt.Println("🔍 检查并更新预测验证状态...")
-	verifiedCount, err := a.syncPredictionVerifications(historicalData)
-	if err != nil {
-		logger.Warnf("Failed to sync prediction verifications: %v", err)
-	} else if verifiedCount > 0 {
-		fmt.Printf("✅ 更新了 %d 条预测验证结果\n", verifiedCount)
-	}
+	// 启动数据监控协程（受监管，panic后自动重启）
+	a.supervisor.Go("data-monitor", a.dataMonitorLoop)
 
-	// 清理过期的待开奖预测
-	fmt.Println("🧹 清理过期的待开奖预测...")
-	cleanedCount, err := a.cleanupExpiredPredictions(historicalData)
-	if err != nil {
-		logger.Warnf("Failed to cleanup expired predictions: %v", err)
-	} else if cleanedCount > 0 {
-		fmt.Printf("✅ 清理了 %d 条过期预测\n", cleanedCount)
-	}
+	// 启动数据清理协程（受监管，panic后自动重启）
+	a.supervisor.Go("data-cleanup", a.dataCleanupLoop)
 
-	// 更新缓存
-	if len(historicalData) > 0 {
-		if err := a.cacheManager.OnNewLotteryData(&historicalData[0]); err != nil {
-			logger.Warnf("Failed to update cache for historical data: %v", err)
-		}
-	}
+	// 启动每日统计快照协程（受监管，panic后自动重启）
+	a.supervisor.Go("stats-snapshot", a.statsSnapshotLoop)
+
+	// 启动后台健康检查协程（受监管，panic后自动重启）
+	a.supervisor.Go("health-check", a.healthCheckLoop)
 
-	// 检查是否需要生成最新预测
-	fmt.Println("🔍 检查是否需要生成最新预测...")
-	if err := a.ensureLatestPrediction(); err != nil {
-		logger.Warnf("Failed to ensure latest prediction: %v", err)
+	// 启动诊断服务
+	if a.diagServer != nil {
+		a.diagServer.Start()
+		logger.Status("🩺 诊断服务已启动: %s", a.config.Debug.Listen)
 	}
 
+	logger.Status("✅ 所有服务启动完成")
+	logger.Status("📡 开始监控PC28开奖数据...")
+	logger.Status("⏰ 轮询间隔: %v", a.config.App.PollingInterval)
+	logger.StatusVerbose("🔔 机器人仅在私聊中提供服务")
+	logger.StatusVerbose("💡 按 Ctrl+C 停止程序")
+	logger.Status("")
 	return nil
 }
 
-// syncPredictionVerifications 同步预测验证状态
-func (a *App) syncPredictionVerifications(historicalData []database.LotteryResult) (int, error) {
-	// 获取所有未验证的预测记录
-	unverifiedPredictions, err := a.mysql.GetUnverifiedPredictions()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get unverified predictions: %v", err)
+// SetClock 注入时间源（须在Start之前调用）
+// 模拟时钟会同步注入缓存TTL判定，使加速测试/回放下缓存过期行为一致
+func (a *App) SetClock(c clock.Clock) {
+	a.clk = c
+	a.cacheManager.SetClock(c)
+}
+
+// RequestRestart 请求排空重启（由管理员/restart命令触发）
+// 仅向main的等待循环发出信号，实际排空与停机复用Stop的优雅关闭流程
+func (a *App) RequestRestart() {
+	a.restartOnce.Do(func() {
+		close(a.restartChannel)
+	})
+}
+
+// Stop 停止应用程序
+func (a *App) Stop() error {
+	logger.Status("🛑 正在停止应用程序...")
+
+	// 发送停止信号（不再开始新一轮处理）
+	close(a.stopChannel)
+
+	// 等待在途的开奖处理完成（保存、验证、广播），超时则放弃
+	drainTimeout := a.config.App.ShutdownTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = 30 * time.Second
 	}
+	if a.drainInFlight(drainTimeout) {
+		logger.Status("✅ 在途处理已全部完成")
+	} else {
+		logger.Status("⚠️  等待在途处理超时（%v），强制停止", drainTimeout)
+	}
+
+	a.supervisor.Stop()
 
-	if len(unverifiedPredictions) == 0 {
-		return 0, nil
+	// 停止诊断服务
+	if a.diagServer != nil {
+		if err := a.diagServer.Stop(); err != nil {
+			logger.Errorf("Failed to stop diagnostics server: %v", err)
+		}
 	}
 
-	// 创建开奖数据的快速查找映射
-	lotteryMap := make(map[string]*database.LotteryResult)
-	for i := range historicalData {
-		lotteryMap[historicalData[i].Qihao] = &historicalData[i]
+	// 停止Telegram机器人
+	if a.telegramBot != nil {
+		a.telegramBot.Stop()
 	}
 
-	verifiedCount := 0
-	for _, prediction := range unverifiedPredictions {
-		// 查找对应的开奖结果
-		if lotteryResult, exists := lotteryMap[prediction.TargetQihao]; exists {
-			// 验证预测结果
-			_, err := a.validator.ValidatePrediction(prediction.TargetQihao, lotteryResult)
-			if err != nil {
-				logger.Warnf("Failed to validate prediction for %s: %v", prediction.TargetQihao, err)
-				continue
-			}
-			verifiedCount++
-			logger.Debugf("Verified prediction for %s", prediction.TargetQihao)
+	// 等待所有协程结束
+	a.supervisor.Wait()
+
+	// 释放领导权（让热备实例接管）
+	if a.leaderElector != nil {
+		if err := a.leaderElector.Release(); err != nil {
+			logger.Errorf("Failed to release leader lock: %v", err)
 		}
 	}
 
-	return verifiedCount, nil
-}
-
-// cleanupExpiredPredictions 清理过期的待开奖预测
-func (a *App) cleanupExpiredPredictions(historicalData []database.LotteryResult) (int, error) {
-	// 获取最新的期号
-	if len(historicalData) == 0 {
-		return 0, nil
+	// 关闭缓存管理器
+	if err := a.cacheManager.Close(); err != nil {
+		logger.Errorf("Failed to close cache manager: %v", err)
 	}
 
-	latestQihao := historicalData[0].Qihao
+	// 关闭数据库连接
+	if err := a.db.Close(); err != nil {
+		logger.Errorf("Failed to close database: %v", err)
+	}
 
-	// 删除目标期号小于最新期号且仍未验证的预测记录
-	cleanedCount, err := a.mysql.CleanupExpiredPredictions(latestQihao)
-	if err != nil {
-		return 0, fmt.Errorf("failed to cleanup expired predictions: %v", err)
+	// 停止数据回放服务
+	if a.replayServer != nil {
+		a.replayServer.Stop()
 	}
 
-	return cleanedCount, nil
-}
+	// 停止webhook通知器
+	if a.webhookNotifier != nil {
+		a.webhookNotifier.Stop()
+	}
 
-// ensureLatestPrediction 确保有最新的预测
-func (a *App) ensureLatestPrediction() error {
-	// 获取最新的开奖数据
-	latestLottery, err := a.cacheManager.GetLatestLotteryData()
-	if err != nil {
-		return fmt.Errorf("failed to get latest lottery data: %v", err)
+	// 停止消息代理发布器
+	if a.brokerPublisher != nil {
+		a.brokerPublisher.Stop()
 	}
 
-	// 获取最新的预测
-	latestPrediction, err := a.cacheManager.GetLatestPrediction()
-	if err != nil {
-		// 没有预测记录，生成一个
-		logger.Info("No prediction found, generating new prediction")
-		return a.generateNewPrediction()
+	// 停止缓存失效总线
+	if a.cacheBus != nil {
+		a.cacheBus.Stop()
 	}
 
-	// 检查预测的目标期号是否是下一期
-	expectedNextQihao := a.generateNextQihao(latestLottery.Qihao)
-	if latestPrediction.TargetQihao != expectedNextQihao {
-		// 预测的期号不是下一期，生成新预测
-		logger.Infof("Prediction target %s != expected %s, generating new prediction",
-			latestPrediction.TargetQihao, expectedNextQihao)
-		return a.generateNewPrediction()
+	// 停止Sentry上报（发送剩余已入队的事件）
+	if a.sentryHook != nil {
+		a.sentryHook.Stop()
 	}
 
-	logger.Info("Latest prediction is up to date")
+	logger.Status("✅ 应用程序已安全停止")
 	return nil
 }
 
-// generateNextQihao 生成下一期期号（辅助方法）
-func (a *App) generateNextQihao(latestQihao string) string {
-	// 尝试直接解析整个期号为数字
-	var qihaoNum int
-	if _, err := fmt.Sscanf(latestQihao, "%d", &qihaoNum); err == nil {
-		return fmt.Sprintf("%d", qihaoNum+1)
+// drainInFlight 等待在途处理完成，返回是否在超时前完成
+func (a *App) drainInFlight(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		a.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
 	}
-
-	// 如果解析失败，返回默认值
-	logger.Warnf("Failed to parse qihao: %s, using default", latestQihao)
-	return "3326999"
 }
 
 // dataMonitorLoop 数据监控循环
 func (a *App) dataMonitorLoop() {
-	defer a.wg.Done()
-
-	ticker := time.NewTicker(a.config.App.PollingInterval)
+	ticker := a.clk.NewTicker(a.config.App.PollingInterval)
 	defer ticker.Stop()
 
 	consecutiveErrors := 0
 	lastNewData := time.Time{}
 
+	wasLeader := false
+	inMaintenance := false
+
 	for {
 		select {
-		case <-ticker.C:
-			if err := a.processDataUpdate(); err != nil {
+		case <-ticker.C():
+			// 领导者选举：只有领导者执行轮询、预测和广播，备用实例保持热备
+			// （内存模式下无选举器，视为单实例领导者）
+			isLeader := true
+			if a.leaderElector != nil {
+				acquired, err := a.leaderElector.TryAcquire()
+				if err != nil {
+					logger.Warnf("Leader election check failed: %v", err)
+					continue
+				}
+				isLeader = acquired
+			}
+			if !isLeader {
+				if wasLeader {
+					logger.Status("⚠️  已失去主节点身份，进入热备状态")
+					wasLeader = false
+				}
+				continue
+			}
+			if !wasLeader {
+				logger.Status("👑 本实例已成为主节点，开始处理数据")
+				wasLeader = true
+			}
+
+			// 维护时段内暂停轮询、预测和失败告警，结束后广播恢复通知
+			if a.drawSchedule.InMaintenance(a.clk.Now()) {
+				if !inMaintenance {
+					logger.Status("⏸️  进入维护时段，暂停轮询和预测")
+					inMaintenance = true
+				}
+				continue
+			}
+			if inMaintenance {
+				inMaintenance = false
+				consecutiveErrors = 0
+				// 重置漏开奖计时，避免维护期间无开奖触发误报
+				a.alertManager.OnNewRound()
+				logger.Status("▶️  维护时段结束，恢复轮询")
+				if a.telegramBot != nil {
+					notice := "▶️ *Service Resuming*\n\nDaily maintenance has ended, predictions will resume shortly"
+					if err := a.telegramBot.BroadcastNotice(notice); err != nil {
+						logger.Warnf("Failed to broadcast resume notice: %v", err)
+					}
+				}
+			}
+
+			// 检查是否长时间未收到新开奖
+			a.alertManager.CheckMissedRounds()
+
+			// 滞留预测看门狗（超时未验证则强制回拉补验）
+			a.processor.CheckStalePredictions(a.config.Alerting.StalePredictionTimeout)
+
+			// 补投递崩溃或发送失败遗留的待投递广播事件
+			a.processor.DispatchPendingOutbox()
+
+			a.inFlight.Add(1)
+			err := a.processor.ProcessDataUpdate()
+			a.inFlight.Done()
+			if err != nil {
 				consecutiveErrors++
 				// 只在第一次错误和每30次错误时显示（减少刷屏）
 				if consecutiveErrors == 1 {
-					fmt.Printf("⚠️  数据获取失败: %v\n", err)
+					logger.Status("⚠️  数据获取失败: %v", err)
 				} else if consecutiveErrors%30 == 0 {
-					fmt.Printf("❌ 连续失败 %d 次，仍在重试...\n", consecutiveErrors)
+					logger.Status("❌ 连续失败 %d 次，仍在重试...", consecutiveErrors)
 				}
 			} else {
 				if consecutiveErrors > 0 {
-					fmt.Printf("✅ 数据连接已恢复（失败了 %d 次）\n", consecutiveErrors)
+					logger.Status("✅ 数据连接已恢复（失败了 %d 次）", consecutiveErrors)
 					consecutiveErrors = 0
 				}
 				// 检查是否有新数据处理
@@ -346,168 +700,354 @@ func (a *App) dataMonitorLoop() {
 					lastNewData = time.Now()
 				}
 			}
+		case newInterval := <-a.pollingReload:
+			ticker.Reset(newInterval)
+			logger.Status("⏰ 轮询间隔已更新: %v", newInterval)
 		case <-a.stopChannel:
 			return
 		}
 	}
 }
 
-// processDataUpdate 处理数据更新
-func (a *App) processDataUpdate() error {
-	// 获取最新数据
-	latestData, err := a.apiClient.FetchAndValidateLatestData()
+// ReloadConfig 重新加载配置（SIGHUP触发，不重启、不丢失缓存）
+func (a *App) ReloadConfig() error {
+	newCfg, err := config.LoadConfig(a.configPath)
 	if err != nil {
-		// 只在首次出错或错误类型变化时记录
-		if a.lastAPIError != err.Error() {
-			logger.Errorf("API fetch failed: %v", err)
-			a.lastAPIError = err.Error()
-		}
-		return fmt.Errorf("failed to fetch latest data: %v", err)
+		return fmt.Errorf("failed to reload config: %v", err)
 	}
-	a.lastAPIError = "" // 清除错误状态
 
-	// 检查是否是新数据
-	isNew, err := a.mysql.CheckNewQihao(latestData.Qihao)
-	if err != nil {
-		// 只在首次出错或错误类型变化时记录
-		if a.lastDBError != err.Error() {
-			logger.Errorf("Database check failed: %v", err)
-			a.lastDBError = err.Error()
-		}
-		return fmt.Errorf("failed to check new qihao: %v", err)
-	}
-	a.lastDBError = "" // 清除错误状态
+	oldCfg := a.config
 
-	if !isNew {
-		// 不是新数据，跳过处理（不记录日志避免重复）
-		return nil
+	// 应用日志级别
+	if newCfg.App.LogLevel != oldCfg.App.LogLevel {
+		logger.SetLevel(newCfg.App.LogLevel)
+		logger.Status("🔧 日志级别已更新: %s -> %s", oldCfg.App.LogLevel, newCfg.App.LogLevel)
 	}
 
-	fmt.Printf("🎯 发现新开奖: %s - %s (和值:%d)\n", latestData.Qihao, latestData.OpenNum, latestData.SumValue)
+	// 应用日志格式
+	if newCfg.App.LogFormat != oldCfg.App.LogFormat {
+		logger.SetFormat(newCfg.App.LogFormat)
+		logger.Status("🔧 日志格式已更新: %s", newCfg.App.LogFormat)
+	}
 
-	// 保存新数据到数据库
-	if err := a.mysql.SaveLotteryResult(latestData); err != nil {
-		return fmt.Errorf("failed to save lottery result: %v", err)
+	// 应用控制台状态输出级别
+	if newCfg.App.ConsoleMode != oldCfg.App.ConsoleMode {
+		logger.SetConsoleMode(newCfg.App.ConsoleMode)
 	}
 
-	// 更新缓存
-	if err := a.cacheManager.OnNewLotteryData(latestData); err != nil {
-		logger.Warnf("Failed to update cache for new data: %v", err)
+	// 应用缓存TTL（保留现有缓存内容）
+	if newCfg.App.CacheTTL != oldCfg.App.CacheTTL {
+		a.cacheManager.SetDefaultTTL(newCfg.App.CacheTTL)
 	}
 
-	// 验证之前的预测
-	if err := a.verifyPreviousPrediction(latestData); err != nil {
-		logger.Warnf("Failed to verify previous prediction: %v", err)
+	// 应用轮询间隔（通知监控协程重置定时器）
+	if newCfg.App.PollingInterval != oldCfg.App.PollingInterval {
+		select {
+		case a.pollingReload <- newCfg.App.PollingInterval:
+		default:
+		}
 	}
 
-	// 生成新预测
-	if err := a.generateNewPrediction(); err != nil {
-		logger.Errorf("Failed to generate new prediction: %v", err)
-		return err
+	// 应用算法选择
+	if newCfg.App.Algorithm != "" && newCfg.App.Algorithm != oldCfg.App.Algorithm {
+		if err := a.predictorMgr.SetCurrentPredictor(newCfg.App.Algorithm); err != nil {
+			logger.Warnf("Failed to switch predictor to %s: %v", newCfg.App.Algorithm, err)
+		} else {
+			logger.Status("🔮 预测算法已切换: %s", newCfg.App.Algorithm)
+			// 审计算法切换（actor为0表示配置重载触发）
+			if err := a.db.SaveAuditEntry(&database.AuditEntry{
+				Actor:  0,
+				Action: "algorithm_switch",
+				Params: newCfg.App.Algorithm,
+			}); err != nil {
+				logger.Warnf("Failed to record audit entry: %v", err)
+			}
+		}
 	}
 
-	fmt.Printf("✅ 新数据处理完成: %s\n", latestData.Qihao)
+	a.config = newCfg
+	logger.Status("✅ 配置重载完成")
 	return nil
 }
 
-// verifyPreviousPrediction 验证之前的预测
-func (a *App) verifyPreviousPrediction(actualResult *database.LotteryResult) error {
-	// 验证预测结果
-	validation, err := a.validator.ValidatePrediction(actualResult.Qihao, actualResult)
-	if err != nil {
-		return fmt.Errorf("validation failed: %v", err)
+// dataCleanupLoop 数据清理循环
+func (a *App) dataCleanupLoop() {
+	// 每小时执行一次清理
+	ticker := a.clk.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	retentionHours := a.config.App.DataRetentionHours
+	if retentionHours <= 0 {
+		retentionHours = 24
+	}
+
+	for {
+		select {
+		case <-ticker.C():
+			// 配置了冷存储时先归档，保证超窗数据在清理前已落盘
+			if a.compactor != nil {
+				cutoff := a.clk.Now().Add(-time.Duration(retentionHours) * time.Hour)
+				if archived, err := a.compactor.CompactBefore(cutoff); err != nil {
+					logger.Status("❌ 冷存储归档失败: %v", err)
+				} else if archived > 0 {
+					logger.StatusVerbose("🗄️  冷存储归档完成: %d 条", archived)
+				}
+			}
+			if err := a.db.CleanOldData(retentionHours); err != nil {
+				logger.Status("❌ 数据清理失败: %v", err)
+			} else {
+				logger.StatusVerbose("🧹 定期数据清理完成")
+			}
+		case <-a.stopChannel:
+			return
+		}
+	}
+}
+
+// statsSnapshotLoop 每日统计快照循环
+// 每小时覆盖更新当日快照，日终的最后一次写入即为当日最终数据
+func (a *App) statsSnapshotLoop() {
+	ticker := a.clk.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			a.snapshotDailyStats()
+			a.maybeSendScheduledReports()
+			a.maybeBroadcastDailySummary()
+			a.maybeReconcilePredictions()
+			a.maybeRunCrossValidation()
+		case <-a.stopChannel:
+			// 停机前写入最后一次快照
+			a.snapshotDailyStats()
+			return
+		}
 	}
+}
 
-	// 更新缓存
-	if err := a.cacheManager.OnPredictionVerified(actualResult.Qihao, validation.IsCorrect); err != nil {
-		logger.Warnf("Failed to update cache for prediction verification: %v", err)
+// maybeSendScheduledReports 在每周一推送周报、每月1日推送月报（每日至多触发一次）
+func (a *App) maybeSendScheduledReports() {
+	if a.telegramBot == nil {
+		return
 	}
 
-	logger.Infof("Prediction verified for %s: %s", actualResult.Qihao,
-		map[bool]string{true: "CORRECT", false: "INCORRECT"}[validation.IsCorrect])
+	now := a.clk.Now()
+	today := now.Format("2006-01-02")
+	if today == a.lastReportDate {
+		return
+	}
+	a.lastReportDate = today
 
-	return nil
+	pushSchedule := a.currentPushSchedule()
+	if now.Weekday() == pushSchedule.ReportWeekday() {
+		a.telegramBot.SendScheduledReport("week")
+	}
+	if now.Day() == pushSchedule.MonthlyReportDay {
+		a.telegramBot.SendScheduledReport("month")
+	}
 }
 
-// generateNewPrediction 生成新预测
-func (a *App) generateNewPrediction() error {
-	// 获取历史数据用于预测
-	historyData, err := a.cacheManager.GetLast3LotteryData()
+// currentPushSchedule 读取当前生效的推送计划
+// （管理员经Telegram上传后存储在app_state，每次检查时重新读取即时生效；
+// 未配置或读取失败时回退默认计划）
+func (a *App) currentPushSchedule() *schedule.PushSchedule {
+	stored, err := a.db.GetAppState(schedule.PushScheduleStateKey)
 	if err != nil {
-		return fmt.Errorf("failed to get history data for prediction: %v", err)
+		logger.Warnf("Failed to load push schedule, using defaults: %v", err)
+		return schedule.DefaultPushSchedule()
 	}
+	return schedule.LoadPushSchedule(stored)
+}
 
-	if len(historyData) < 3 {
-		return fmt.Errorf("insufficient history data for prediction: need 3, got %d", len(historyData))
+// maybeBroadcastDailySummary 向订阅用户推送前一日预测摘要
+// （推送计划指定的摘要小时内首个tick触发，默认零点）
+func (a *App) maybeBroadcastDailySummary() {
+	if a.telegramBot == nil {
+		return
 	}
 
-	// 生成预测
-	predictionResult, err := a.predictorMgr.Predict(historyData)
+	now := a.clk.Now()
+	today := now.Format("2006-01-02")
+	if now.Hour() != a.currentPushSchedule().SummaryHour() || today == a.lastSummaryDate {
+		return
+	}
+	a.lastSummaryDate = today
+
+	algorithm := ""
+	if current := a.predictorMgr.GetCurrentPredictor(); current != nil {
+		algorithm = current.GetName()
+	}
+
+	stats, err := a.statCalculator.ComputeDailySnapshot(now.AddDate(0, 0, -1), algorithm)
 	if err != nil {
-		return fmt.Errorf("prediction generation failed: %v", err)
+		logger.Warnf("Failed to compute daily summary: %v", err)
+		return
+	}
+	if stats.TotalPredictions == 0 {
+		// 前一日没有已验证的预测，无需推送
+		return
 	}
 
-	// 计算预测和值和单双
-	predictedNums, _ := database.ParseOpenNum(predictionResult.PredictedNum)
-	predictedSum := database.CalculateSum(predictedNums)
-	predictedOddEven := database.CalculateOddEven(predictedSum)
+	if err := a.telegramBot.BroadcastDailySummary(stats); err != nil {
+		logger.Warnf("Failed to broadcast daily summary: %v", err)
+	}
+}
 
-	// 保存预测到数据库
-	prediction := &database.Prediction{
-		TargetQihao:      predictionResult.TargetQihao,
-		PredictedNum:     predictionResult.PredictedNum,
-		PredictedSum:     predictedSum,
-		PredictedOddEven: predictedOddEven,
-		ConfidenceScore:  nil, // 不使用置信度
-		AlgorithmVersion: predictionResult.AlgorithmVersion,
-		PredictedAt:      predictionResult.Timestamp,
+// maybeReconcilePredictions 夜间对账（凌晨时段每日执行一次）
+// 对留存窗口内的验证结果做批量核对，修复is_correct与当前判定口径不一致的记录
+func (a *App) maybeReconcilePredictions() {
+	now := a.clk.Now()
+	today := now.Format("2006-01-02")
+	if now.Hour() != 4 || today == a.lastReconcileDate {
+		return
 	}
+	a.lastReconcileDate = today
 
-	if err := a.mysql.SavePrediction(prediction); err != nil {
-		return fmt.Errorf("failed to save prediction: %v", err)
+	// 取留存窗口内的全部开奖数据（24小时约288期）
+	results, err := a.db.GetLotteryHistory(300)
+	if err != nil {
+		logger.Warnf("Nightly reconciliation failed to get lottery history: %v", err)
+		return
 	}
 
-	// 更新缓存
-	if err := a.cacheManager.OnPredictionGenerated(prediction); err != nil {
-		logger.Warnf("Failed to update cache for new prediction: %v", err)
+	repaired, err := a.validator.ReconcileBatch(results)
+	if err != nil {
+		logger.Warnf("Nightly reconciliation failed: %v", err)
+		return
+	}
+	if repaired > 0 {
+		logger.Status("🔧 夜间对账修复了 %d 条验证结果", repaired)
+	} else {
+		logger.StatusVerbose("✅ 夜间对账完成，验证结果一致")
 	}
+}
 
-	// 广播新预测（如果有订阅用户）
-	latestResult, _ := a.cacheManager.GetLatestLotteryData()
-	if err := a.telegramBot.BroadcastNewPrediction(prediction, latestResult); err != nil {
-		logger.Warnf("Failed to broadcast new prediction: %v", err)
+// maybeRunCrossValidation 对全部已注册算法做滚动交叉验证（凌晨时段每日执行一次）
+// 在最近500期存量数据上滚动起点评估每个算法，结果写入榜单供/leaderboard和REST端点查询
+func (a *App) maybeRunCrossValidation() {
+	now := a.clk.Now()
+	today := now.Format("2006-01-02")
+	if now.Hour() != 5 || today == a.lastCrossValDate {
+		return
 	}
+	a.lastCrossValDate = today
 
-	fmt.Printf("🔮 生成预测: %s -> %s (固定算法)\n",
-		prediction.TargetQihao, prediction.PredictedNum)
+	const evalRounds = 500
 
-	return nil
+	names := a.predictorMgr.GetAvailablePredictors()
+	for _, name := range names {
+		p := a.predictorMgr.GetPredictor(name)
+		if p == nil {
+			continue
+		}
+
+		history, err := a.db.GetLatestLotteryResults(evalRounds + p.GetRequiredHistorySize())
+		if err != nil {
+			logger.Warnf("Cross-validation failed to load history: %v", err)
+			return
+		}
+
+		result, err := predictor.EvaluateOffline(p, a.judgePolicy, history, evalRounds)
+		if err != nil {
+			logger.Warnf("Cross-validation failed for algorithm %s: %v", name, err)
+			continue
+		}
+
+		entry := &database.LeaderboardEntry{
+			Algorithm:    result.Algorithm,
+			Version:      result.Version,
+			Rounds:       result.Rounds,
+			Correct:      result.Correct,
+			AccuracyRate: result.AccuracyRate,
+			BestStreak:   result.BestStreak,
+			WorstStreak:  result.WorstStreak,
+		}
+		if err := a.db.SaveLeaderboardEntry(entry); err != nil {
+			logger.Warnf("Failed to save leaderboard entry for %s: %v", name, err)
+			continue
+		}
+		logger.StatusVerbose("🏆 算法 %s 交叉验证完成: %.1f%% (%d期)", name, result.AccuracyRate, result.Rounds)
+	}
 }
 
-// dataCleanupLoop 数据清理循环
-func (a *App) dataCleanupLoop() {
-	defer a.wg.Done()
+// snapshotDailyStats 计算并写入当日统计快照
+func (a *App) snapshotDailyStats() {
+	algorithm := ""
+	if current := a.predictorMgr.GetCurrentPredictor(); current != nil {
+		algorithm = current.GetName()
+	}
 
-	// 每小时执行一次清理
-	ticker := time.NewTicker(1 * time.Hour)
+	stats, err := a.statCalculator.ComputeDailySnapshot(a.clk.Now(), algorithm)
+	if err != nil {
+		logger.Warnf("Failed to compute daily stats snapshot: %v", err)
+		return
+	}
+	if stats.TotalPredictions == 0 {
+		// 当日还没有已验证的预测，无需写入
+		return
+	}
+
+	if err := a.db.SaveDailyStats(stats); err != nil {
+		logger.Warnf("Failed to save daily stats snapshot: %v", err)
+		return
+	}
+	logger.StatusVerbose("📊 每日统计快照已更新: %s (%d期, %.1f%%)",
+		stats.StatDate.Format("2006-01-02"), stats.TotalPredictions, stats.AccuracyRate)
+}
+
+// healthCheckLoop 后台健康检查循环
+// 探测在后台按固定间隔执行并缓存结果，HealthCheck和HTTP端点只读缓存，
+// 避免实时探测外部依赖时挂住调用方
+func (a *App) healthCheckLoop() {
+	// 启动即探测一次，避免端点在首个间隔内返回空快照
+	a.refreshHealthSnapshot()
+
+	ticker := a.clk.NewTicker(healthCheckInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			if err := a.mysql.CleanOldData(); err != nil {
-				fmt.Printf("❌ 数据清理失败: %v\n", err)
-			} else {
-				fmt.Println("🧹 定期数据清理完成")
-			}
+		case <-ticker.C():
+			a.refreshHealthSnapshot()
 		case <-a.stopChannel:
 			return
 		}
 	}
 }
 
-// HealthCheck 健康检查
+// checkWithTimeout 带超时执行单个依赖探测
+func checkWithTimeout(timeout time.Duration, check func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("health check timed out after %v", timeout)
+	}
+}
+
+// HealthCheck 返回最近一次后台探测的健康快照（立即返回，不做实时探测）
 func (a *App) HealthCheck() map[string]interface{} {
+	a.healthMutex.RLock()
+	defer a.healthMutex.RUnlock()
+
+	if a.healthSnapshot == nil {
+		// 后台探测尚未完成首轮
+		return map[string]interface{}{
+			"timestamp": time.Now(),
+			"status":    "starting",
+		}
+	}
+	return a.healthSnapshot
+}
+
+// refreshHealthSnapshot 执行一轮依赖探测并更新健康快照
+func (a *App) refreshHealthSnapshot() {
 	health := map[string]interface{}{
 		"timestamp": time.Now(),
 		"status":    "ok",
@@ -516,14 +1056,21 @@ func (a *App) HealthCheck() map[string]interface{} {
 
 	services := health["services"].(map[string]interface{})
 
-	// 检查API健康状态
-	if err := a.apiClient.HealthCheck(); err != nil {
+	// 检查API健康状态（带超时；偶发失败为degraded，连续失败达阈值为unhealthy）
+	if err := checkWithTimeout(healthDependencyTimeout, a.apiClient.HealthCheck); err != nil {
+		a.apiHealthFailures++
 		services["api"] = map[string]interface{}{
-			"status": "error",
-			"error":  err.Error(),
+			"status":               "error",
+			"error":                err.Error(),
+			"consecutive_failures": a.apiHealthFailures,
+		}
+		if a.apiHealthFailures >= healthUnhealthyThreshold {
+			health["status"] = "unhealthy"
+		} else {
+			health["status"] = "degraded"
 		}
-		health["status"] = "degraded"
 	} else {
+		a.apiHealthFailures = 0
 		services["api"] = map[string]interface{}{
 			"status": "ok",
 		}
@@ -536,46 +1083,148 @@ func (a *App) HealthCheck() map[string]interface{} {
 		"stats":  cacheStats,
 	}
 
-	// 检查Telegram Bot状态
-	botInfo := a.telegramBot.GetBotInfo()
-	services["telegram"] = map[string]interface{}{
-		"status": "ok",
-		"info":   botInfo,
+	// 预测时延聚合（提前量与验证延迟）
+	health["latency"] = a.processor.LatencyStats()
+
+	// 检查Telegram Bot状态（无界面模式下标记为禁用）
+	if a.telegramBot != nil {
+		botInfo := a.telegramBot.GetBotInfo()
+		services["telegram"] = map[string]interface{}{
+			"status": "ok",
+			"info":   botInfo,
+		}
+		// 各命令的调用次数与处理耗时（用于发现慢处理）
+		health["commands"] = a.telegramBot.CommandStats()
+	} else {
+		services["telegram"] = map[string]interface{}{
+			"status": "disabled",
+		}
 	}
 
-	return health
+	a.healthMutex.Lock()
+	a.healthSnapshot = health
+	a.healthMutex.Unlock()
 }
 
+// restartExitCode 排空重启专用退出码，供进程守护（systemd/supervisor）
+// 区分"请求重启"与正常退出(0)/异常退出(1)，按此码拉起新实例
+const restartExitCode = 10
+
 func main() {
 	// 配置文件路径
 	configPath := "configs/config.yaml"
-	if len(os.Args) > 1 {
-		configPath = os.Args[1]
+	args := os.Args[1:]
+
+	// check子命令：启动前自检
+	if len(args) > 0 && args[0] == "check" {
+		if len(args) > 1 {
+			configPath = args[1]
+		}
+		os.Exit(runSelfTest(configPath))
+	}
+
+	// restore子命令：从归档文件恢复数据
+	if len(args) > 0 && args[0] == "restore" {
+		os.Exit(runRestore(args[1:]))
+	}
+
+	if len(args) > 0 {
+		configPath = args[0]
 	}
 
-	// 创建应用程序实例
-	app, err := NewApp(configPath)
+	// 加载配置（多租户模式下按租户派生配置创建多个实例）
+	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		fmt.Printf("❌ 应用初始化失败: %v\n", err)
+		fmt.Printf("❌ 配置加载失败: %v\n", err)
 		os.Exit(1)
 	}
 
-	// 启动应用程序
-	if err := app.Start(); err != nil {
-		fmt.Printf("❌ 应用启动失败: %v\n", err)
-		os.Exit(1)
+	// 创建应用程序实例（单租户时一个，多租户时每租户一个，数据完全隔离）
+	var apps []*App
+	if len(cfg.Tenants) > 0 {
+		for i := range cfg.Tenants {
+			tenant := &cfg.Tenants[i]
+			app, err := NewAppWithConfig(cfg.ForTenant(tenant), configPath)
+			if err != nil {
+				fmt.Printf("❌ 租户 %s 初始化失败: %v\n", tenant.Name, err)
+				os.Exit(1)
+			}
+			logger.Status("🏠 租户实例已创建: %s", tenant.Name)
+			apps = append(apps, app)
+		}
+	} else {
+		app, err := NewAppWithConfig(cfg, configPath)
+		if err != nil {
+			fmt.Printf("❌ 应用初始化失败: %v\n", err)
+			os.Exit(1)
+		}
+		apps = append(apps, app)
 	}
 
-	// 设置信号处理
+	// 启动全部实例
+	for _, app := range apps {
+		if err := app.Start(); err != nil {
+			fmt.Printf("❌ 应用启动失败: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// 合并各实例的重启请求（任一实例请求重启即整进程排空重启）
+	restartChan := make(chan struct{}, 1)
+	for _, app := range apps {
+		go func(a *App) {
+			<-a.restartChannel
+			select {
+			case restartChan <- struct{}{}:
+			default:
+			}
+		}(app)
+	}
+
+	// 设置信号处理（SIGHUP触发配置热重载）
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	// 等待停止信号
-	<-sigChan
+	// 等待停止信号或排空重启请求
+	restartRequested := false
+waitLoop:
+	for {
+		select {
+		case sig := <-sigChan:
+			if sig == syscall.SIGHUP {
+				if len(apps) > 1 {
+					// 多租户的派生配置无法从文件整体重载，避免覆盖租户专属部分
+					logger.Warn("Config hot reload is not supported in multi-tenant mode")
+					continue
+				}
+				logger.Status("🔄 收到SIGHUP信号，重新加载配置...")
+				if err := apps[0].ReloadConfig(); err != nil {
+					fmt.Printf("⚠️  配置重载失败: %v\n", err)
+				}
+				continue
+			}
+			break waitLoop
+		case <-restartChan:
+			logger.Status("🔁 收到重启请求，排空在途处理后退出...")
+			restartRequested = true
+			break waitLoop
+		}
+	}
 
-	// 优雅关闭
-	if err := app.Stop(); err != nil {
-		fmt.Printf("❌ 关闭时出错: %v\n", err)
+	// 优雅关闭全部实例
+	stopFailed := false
+	for _, app := range apps {
+		if err := app.Stop(); err != nil {
+			fmt.Printf("❌ 关闭时出错: %v\n", err)
+			stopFailed = true
+		}
+	}
+	if stopFailed {
 		os.Exit(1)
 	}
+
+	// 重启请求以专用退出码退出，由进程守护拉起新实例
+	if restartRequested {
+		os.Exit(restartExitCode)
+	}
 }
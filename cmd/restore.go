@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"pc28-bot/internal/coldstore"
+	"pc28-bot/internal/config"
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/qihao"
+)
+
+// restoreConflictSampleLimit 冲突明细最多展示的条数
+const restoreConflictSampleLimit = 5
+
+// runRestore 从归档文件恢复开奖数据
+// 先校验归档内容并报告将发生的变更，--dry-run时仅报告不写入；
+// 实际写入在单事务内完成，已存在的期号一律跳过不覆盖
+// 返回进程退出码（0=成功，1=校验或写入失败）
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	file := fs.String("file", "", "归档文件路径（冷存储月度CSV格式）")
+	dryRun := fs.Bool("dry-run", false, "仅校验并报告变更，不写入数据库")
+	configPath := fs.String("config", "configs/config.yaml", "配置文件路径")
+	fs.Parse(args)
+
+	if *file == "" {
+		fmt.Println("❌ 缺少归档文件参数: restore --file <archive.csv> [--dry-run]")
+		return 1
+	}
+
+	fmt.Println("♻️  PC28预测机器人数据恢复")
+	fmt.Println("")
+
+	// 1. 读取并解析归档
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		fmt.Printf("❌ 归档读取失败: %v\n", err)
+		return 1
+	}
+	results, err := coldstore.ParseArchive(data)
+	if err != nil {
+		fmt.Printf("❌ 归档解析失败: %v\n", err)
+		return 1
+	}
+	if len(results) == 0 {
+		fmt.Println("❌ 归档中没有可恢复的记录")
+		return 1
+	}
+
+	// 2. 逐条校验归档内容（期号格式、号码格式与和值口径）
+	for i := range results {
+		if err := validateArchiveResult(&results[i]); err != nil {
+			fmt.Printf("❌ 归档校验失败: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Printf("✅ 归档校验通过: %d 条记录\n", len(results))
+
+	// 3. 连接数据库（恢复输出不需要业务日志干扰）
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("❌ 配置加载失败: %v\n", err)
+		return 1
+	}
+	logger.InitLogger("error")
+	db, err := database.NewDB(&cfg.Database)
+	if err != nil {
+		fmt.Printf("❌ 数据库连接失败: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	// 4. 与现有数据比对，报告将发生的变更
+	var toInsert []database.LotteryResult
+	unchanged, conflicts := 0, 0
+	for i := range results {
+		existing, err := db.GetLotteryResultByQihao(results[i].Qihao)
+		if err != nil {
+			fmt.Printf("❌ 现有数据查询失败: %v\n", err)
+			return 1
+		}
+		switch {
+		case existing == nil:
+			toInsert = append(toInsert, results[i])
+		case existing.OpenNum == results[i].OpenNum:
+			unchanged++
+		default:
+			conflicts++
+			if conflicts <= restoreConflictSampleLimit {
+				fmt.Printf("⚠️  期号 %s 与现有数据冲突: 归档 %s / 现有 %s（保留现有）\n",
+					results[i].Qihao, results[i].OpenNum, existing.OpenNum)
+			}
+		}
+	}
+	fmt.Printf("📋 变更预览: 新增 %d 条，已存在相同 %d 条，冲突 %d 条\n",
+		len(toInsert), unchanged, conflicts)
+
+	if *dryRun {
+		fmt.Println("")
+		fmt.Println("✅ dry-run模式，未写入任何数据")
+		return 0
+	}
+	if len(toInsert) == 0 {
+		fmt.Println("")
+		fmt.Println("✅ 没有需要写入的记录")
+		return 0
+	}
+
+	// 5. 单事务写入（失败时整体回滚）
+	inserted, err := db.RestoreLotteryResults(toInsert)
+	if err != nil {
+		fmt.Printf("❌ 数据恢复失败（事务已回滚）: %v\n", err)
+		return 1
+	}
+	fmt.Println("")
+	fmt.Printf("✅ 数据恢复完成: 写入 %d 条\n", inserted)
+	return 0
+}
+
+// validateArchiveResult 校验单条归档记录
+func validateArchiveResult(result *database.LotteryResult) error {
+	if _, err := qihao.Parse(result.Qihao); err != nil {
+		return err
+	}
+	nums, err := database.ParseOpenNum(result.OpenNum)
+	if err != nil {
+		return fmt.Errorf("期号 %s: %v", result.Qihao, err)
+	}
+	if nums[0]+nums[1]+nums[2] != result.SumValue {
+		return fmt.Errorf("期号 %s: 和值 %d 与开奖号码 %s 不一致",
+			result.Qihao, result.SumValue, result.OpenNum)
+	}
+	if result.OpenTime.IsZero() {
+		return fmt.Errorf("期号 %s: 缺少开奖时间", result.Qihao)
+	}
+	return nil
+}
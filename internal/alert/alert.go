@@ -0,0 +1,295 @@
+package alert
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"pc28-bot/internal/config"
+	"pc28-bot/internal/logger"
+)
+
+// Alert 告警事件
+type Alert struct {
+	Type    string    `json:"type"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Sink 告警通道接口（webhook、SMTP等）
+type Sink interface {
+	// Name 通道名称
+	Name() string
+
+	// Send 发送告警
+	Send(alert Alert) error
+}
+
+// Manager 告警管理器
+// 跟踪持续性故障（API失败、数据库不可用、连败、漏开奖）并在达到
+// 配置阈值时向所有已注册通道发送告警，恢复后重置状态
+type Manager struct {
+	cfg   *config.Alerting
+	sinks []Sink
+	mutex sync.Mutex
+
+	// 故障状态跟踪
+	apiFailures     int
+	apiAlerted      bool
+	dbAlerted       bool
+	losingStreak    int
+	streakAlerted   bool
+	missedAlerted   bool
+	staleAlerted    bool
+	dataAlerted     bool
+	mismatchAlerted bool
+	lastRoundTime   time.Time
+
+	// 准确率异常检测（偏离50%超出正常方差，好坏两个方向都告警）
+	verifiedTotal   int
+	verifiedCorrect int
+	anomalyAlerted  bool
+}
+
+// NewManager 根据配置创建告警管理器并注册已启用的通道
+func NewManager(cfg *config.Alerting) *Manager {
+	m := &Manager{
+		cfg:           cfg,
+		lastRoundTime: time.Now(),
+	}
+
+	if cfg.WebhookURL != "" {
+		m.sinks = append(m.sinks, NewWebhookSink(cfg.WebhookURL))
+	}
+	if cfg.SMTP.Host != "" {
+		m.sinks = append(m.sinks, NewSMTPSink(&cfg.SMTP))
+	}
+
+	if len(m.sinks) > 0 {
+		logger.Infof("Alert manager initialized with %d sinks", len(m.sinks))
+	}
+	return m
+}
+
+// dispatch 向所有通道发送告警
+func (m *Manager) dispatch(alertType, message string) {
+	a := Alert{
+		Type:    alertType,
+		Message: message,
+		Time:    time.Now(),
+	}
+
+	for _, sink := range m.sinks {
+		if err := sink.Send(a); err != nil {
+			logger.Warnf("Failed to send alert via %s: %v", sink.Name(), err)
+		}
+	}
+	logger.Warnf("Alert dispatched [%s]: %s", alertType, message)
+}
+
+// OnAPIFailure API请求失败事件（达到阈值时触发告警）
+func (m *Manager) OnAPIFailure(err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.apiFailures++
+	threshold := m.cfg.APIFailureThreshold
+	if threshold <= 0 {
+		threshold = 30
+	}
+
+	if m.apiFailures >= threshold && !m.apiAlerted {
+		m.apiAlerted = true
+		m.dispatch("api_failure", fmt.Sprintf("上游API连续失败 %d 次: %v", m.apiFailures, err))
+	}
+}
+
+// OnAPIRecovered API恢复事件
+func (m *Manager) OnAPIRecovered() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.apiAlerted {
+		m.dispatch("api_recovered", fmt.Sprintf("上游API已恢复（此前失败 %d 次）", m.apiFailures))
+	}
+	m.apiFailures = 0
+	m.apiAlerted = false
+}
+
+// OnDBError 数据库错误事件
+func (m *Manager) OnDBError(err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.dbAlerted {
+		m.dbAlerted = true
+		m.dispatch("db_error", fmt.Sprintf("数据库不可用: %v", err))
+	}
+}
+
+// OnDBRecovered 数据库恢复事件
+func (m *Manager) OnDBRecovered() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.dbAlerted {
+		m.dispatch("db_recovered", "数据库已恢复")
+	}
+	m.dbAlerted = false
+}
+
+// OnPredictionVerified 预测验证事件（跟踪连败）
+func (m *Manager) OnPredictionVerified(isCorrect bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.verifiedTotal++
+	if isCorrect {
+		m.verifiedCorrect++
+	}
+	m.checkAccuracyAnomaly()
+
+	if isCorrect {
+		m.losingStreak = 0
+		m.streakAlerted = false
+		return
+	}
+
+	m.losingStreak++
+	threshold := m.cfg.LosingStreakThreshold
+	if threshold <= 0 {
+		threshold = 10
+	}
+
+	if m.losingStreak >= threshold && !m.streakAlerted {
+		m.streakAlerted = true
+		m.dispatch("losing_streak", fmt.Sprintf("预测已连续错误 %d 期", m.losingStreak))
+	}
+}
+
+// checkAccuracyAnomaly 检查实测准确率是否偏离50%超出正常方差
+// 显著偏高同样告警——可能是验证器bug伪装成优异表现（调用方需持有锁）
+func (m *Manager) checkAccuracyAnomaly() {
+	minSample := m.cfg.AnomalyMinSample
+	if minSample <= 0 {
+		minSample = 100
+	}
+	if m.verifiedTotal < minSample {
+		return
+	}
+
+	zThreshold := m.cfg.AnomalyZScore
+	if zThreshold <= 0 {
+		zThreshold = 3.0
+	}
+
+	// 单双预测的零假设为50%准确率的二项分布
+	n := float64(m.verifiedTotal)
+	zScore := (float64(m.verifiedCorrect) - 0.5*n) / (0.5 * math.Sqrt(n))
+
+	if math.Abs(zScore) >= zThreshold {
+		if !m.anomalyAlerted {
+			m.anomalyAlerted = true
+			accuracy := float64(m.verifiedCorrect) / n * 100
+			m.dispatch("accuracy_anomaly", fmt.Sprintf(
+				"实测准确率 %.1f%%（%d期）显著偏离50%%（z=%.2f），请检查验证逻辑是否异常",
+				accuracy, m.verifiedTotal, zScore))
+		}
+	} else {
+		m.anomalyAlerted = false
+	}
+}
+
+// OnStalePredictions 滞留预测事件（count为0表示已全部补验，重置告警状态）
+func (m *Manager) OnStalePredictions(count int, oldest time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if count == 0 {
+		if m.staleAlerted {
+			m.dispatch("stale_predictions_recovered", "滞留的未验证预测已全部补验")
+		}
+		m.staleAlerted = false
+		return
+	}
+
+	if !m.staleAlerted {
+		m.staleAlerted = true
+		m.dispatch("stale_predictions", fmt.Sprintf(
+			"强制回拉后仍有 %d 条预测超时未验证（最早滞留 %v），请检查上游数据源",
+			count, oldest.Round(time.Second)))
+	}
+}
+
+// OnDataStale 上游数据停更事件（翻转去重由本地状态完成）
+func (m *Manager) OnDataStale(age time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.dataAlerted {
+		m.dataAlerted = true
+		m.dispatch("data_stale", fmt.Sprintf(
+			"上游数据已停更 %v，新预测已暂停生成", age.Round(time.Second)))
+	}
+}
+
+// OnDataRecovered 上游数据恢复更新事件
+func (m *Manager) OnDataRecovered() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.dataAlerted {
+		m.dispatch("data_recovered", "上游数据已恢复更新，预测生成已恢复")
+	}
+	m.dataAlerted = false
+}
+
+// OnDataMismatch 两数据源开奖号码持续不一致事件（该期已暂缓入库）
+func (m *Manager) OnDataMismatch(qihao, primary, secondary string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.mismatchAlerted {
+		m.mismatchAlerted = true
+		m.dispatch("data_mismatch", fmt.Sprintf(
+			"期号 %s 两数据源开奖号码不一致（主源 %s / 副源 %s），该期已暂缓入库等待确认",
+			qihao, primary, secondary))
+	}
+}
+
+// OnDataMismatchResolved 数据源核对恢复一致事件
+func (m *Manager) OnDataMismatchResolved() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.mismatchAlerted {
+		m.dispatch("data_mismatch_recovered", "两数据源开奖号码已恢复一致，入库已恢复")
+	}
+	m.mismatchAlerted = false
+}
+
+// OnNewRound 新开奖事件（重置漏开奖计时）
+func (m *Manager) OnNewRound() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lastRoundTime = time.Now()
+	m.missedAlerted = false
+}
+
+// CheckMissedRounds 检查是否长时间没有新开奖（由监控循环周期性调用）
+func (m *Manager) CheckMissedRounds() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	timeout := m.cfg.MissedRoundTimeout
+	if timeout <= 0 {
+		timeout = 15 * time.Minute
+	}
+
+	if time.Since(m.lastRoundTime) > timeout && !m.missedAlerted {
+		m.missedAlerted = true
+		m.dispatch("missed_rounds", fmt.Sprintf("已超过 %v 未收到新开奖数据", timeout))
+	}
+}
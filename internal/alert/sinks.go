@@ -0,0 +1,88 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"pc28-bot/internal/config"
+)
+
+// WebhookSink 通用webhook告警通道（POST JSON）
+type WebhookSink struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookSink 创建webhook告警通道
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url: url,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Name 通道名称
+func (w *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Send 发送告警（POST JSON到配置的URL）
+func (w *WebhookSink) Send(alert Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %v", err)
+	}
+
+	resp, err := w.httpClient.Post(w.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPSink SMTP邮件告警通道
+type SMTPSink struct {
+	cfg *config.SMTP
+}
+
+// NewSMTPSink 创建SMTP告警通道
+func NewSMTPSink(cfg *config.SMTP) *SMTPSink {
+	return &SMTPSink{cfg: cfg}
+}
+
+// Name 通道名称
+func (s *SMTPSink) Name() string {
+	return "smtp"
+}
+
+// Send 发送告警邮件
+func (s *SMTPSink) Send(alert Alert) error {
+	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	subject := fmt.Sprintf("[PC28-Bot Alert] %s", alert.Type)
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n\r\nTime: %s\r\n",
+		s.cfg.From, strings.Join(s.cfg.To, ","), subject,
+		alert.Message, alert.Time.Format("2006-01-02 15:04:05"))
+
+	if err := smtp.SendMail(addr, auth, s.cfg.From, s.cfg.To, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send alert email: %v", err)
+	}
+	return nil
+}
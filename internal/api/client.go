@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,17 +10,28 @@ import (
 	"strings"
 	"time"
 
+	"pc28-bot/internal/chaos"
 	"pc28-bot/internal/config"
 	"pc28-bot/internal/database"
 	"pc28-bot/internal/logger"
 )
 
+// ErrUpstreamDown 上游API不可达（重试耗尽后仍失败）
+// 调用方用errors.Is区分"上游故障"与"上游正常但无数据"（database.ErrNoData）
+var ErrUpstreamDown = errors.New("upstream API unavailable")
+
 // Client API客户端
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
 	retryCount int
 	retryDelay time.Duration
+	chaos      *chaos.Injector // 故障注入器（未启用时为nil）
+}
+
+// SetFaultInjector 设置故障注入器（仅staging验证弹性能力时启用）
+func (c *Client) SetFaultInjector(injector *chaos.Injector) {
+	c.chaos = injector
 }
 
 // NewClient 创建新的API客户端
@@ -54,11 +66,15 @@ func (c *Client) FetchLotteryData(limit int) (*database.APIResponse, error) {
 		return resp, nil
 	}
 
-	return nil, fmt.Errorf("failed to fetch lottery data after %d attempts: %v", c.retryCount, lastErr)
+	return nil, fmt.Errorf("%w: failed to fetch lottery data after %d attempts: %v", ErrUpstreamDown, c.retryCount, lastErr)
 }
 
 // makeRequest 执行HTTP请求
 func (c *Client) makeRequest(url string) (*database.APIResponse, error) {
+	if err := c.chaos.Maybe("api.fetch"); err != nil {
+		return nil, err
+	}
+
 	logger.Debugf("Making API request to: %s", url)
 
 	resp, err := c.httpClient.Get(url)
@@ -173,7 +189,7 @@ func (c *Client) FetchAndValidateLatestData() (*database.LotteryResult, error) {
 	}
 
 	if len(apiResponse.Data) == 0 {
-		return nil, fmt.Errorf("no data returned from API")
+		return nil, fmt.Errorf("%w: no data returned from API", database.ErrNoData)
 	}
 
 	// 获取最新的一条数据
@@ -245,7 +261,7 @@ func (c *Client) GetHistoricalData(limit int) ([]database.LotteryResult, error)
 	}
 
 	if len(results) == 0 {
-		return nil, fmt.Errorf("no valid data could be converted")
+		return nil, fmt.Errorf("%w: no valid data could be converted", database.ErrNoData)
 	}
 
 	logger.Infof("Retrieved %d historical lottery results", len(results))
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+)
+
+// replayMinVisible 回放启动时立即可见的期数（满足预测所需的最少历史）
+const replayMinVisible = 10
+
+// ReplayServer 开奖数据回放服务
+// 将录制的上游API响应（fixture文件）以真实API的JSON协议在本地回放，
+// 按固定间隔逐期放出新开奖，模拟真实开奖节奏。配合memory存储后端
+// 可离线完整跑通 抓取->预测->验证->广播 的单期生命周期，是集成验证
+// 的数据源组件，也可用于算法回测的可复现输入
+type ReplayServer struct {
+	rounds   []database.APILotteryData // 旧到新
+	interval time.Duration
+	startAt  time.Time
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewReplayServer 从fixture文件创建回放服务
+// fixture为上游API响应的JSON录制（data按新到旧排列，与真实API一致），
+// interval为逐期放出新开奖的间隔
+func NewReplayServer(fixturePath string, interval time.Duration) (*ReplayServer, error) {
+	data, err := ioutil.ReadFile(fixturePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay fixture: %v", err)
+	}
+
+	var fixture database.APIResponse
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal replay fixture: %v", err)
+	}
+	if len(fixture.Data) == 0 {
+		return nil, fmt.Errorf("replay fixture contains no rounds")
+	}
+
+	// fixture按新到旧录制，内部翻转为旧到新便于逐期放出
+	rounds := make([]database.APILotteryData, len(fixture.Data))
+	for i, round := range fixture.Data {
+		rounds[len(fixture.Data)-1-i] = round
+	}
+
+	if interval <= 0 {
+		interval = 210 * time.Second // 与真实开奖间隔一致
+	}
+
+	return &ReplayServer{
+		rounds:   rounds,
+		interval: interval,
+	}, nil
+}
+
+// Start 在本地回环地址启动回放服务，返回可直接配置给API客户端的URL
+func (rs *ReplayServer) Start() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for replay server: %v", err)
+	}
+
+	rs.listener = listener
+	rs.startAt = time.Now()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", rs.handleFetch)
+	rs.server = &http.Server{Handler: mux}
+
+	go func() {
+		if err := rs.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Replay server error: %v", err)
+		}
+	}()
+
+	url := fmt.Sprintf("http://%s/", listener.Addr().String())
+	logger.Infof("Replay server started at %s: %d rounds, interval %v", url, len(rs.rounds), rs.interval)
+	return url, nil
+}
+
+// Stop 停止回放服务
+func (rs *ReplayServer) Stop() {
+	if rs.server != nil {
+		rs.server.Close()
+	}
+}
+
+// visibleRounds 按经过时间计算当前已放出的期数
+// 启动即放出replayMinVisible期历史，之后每个间隔再放出一期
+func (rs *ReplayServer) visibleRounds() int {
+	visible := replayMinVisible + int(time.Since(rs.startAt)/rs.interval)
+	if visible > len(rs.rounds) {
+		visible = len(rs.rounds)
+	}
+	return visible
+}
+
+// handleFetch 按真实API的协议返回当前已放出的开奖数据（新到旧）
+func (rs *ReplayServer) handleFetch(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	visible := rs.visibleRounds()
+	if limit > visible {
+		limit = visible
+	}
+
+	// 内部旧到新，输出新到旧
+	data := make([]database.APILotteryData, 0, limit)
+	for i := visible - 1; i >= visible-limit; i-- {
+		data = append(data, rs.rounds[i])
+	}
+
+	response := database.APIResponse{Data: data, Message: "success"}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&response); err != nil {
+		logger.Warnf("Failed to encode replay response: %v", err)
+	}
+}
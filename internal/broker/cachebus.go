@@ -0,0 +1,232 @@
+package broker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pc28-bot/internal/cache"
+	"pc28-bot/internal/logger"
+)
+
+// 缓存失效总线参数
+const (
+	cacheBusSubject = "cache.invalidation" // 主题后缀（完整主题为<prefix>.cache.invalidation）
+	cacheBusSID     = "1"                  // 订阅标识（单订阅固定值）
+	reconnectDelay  = 5 * time.Second      // 断线重连间隔
+)
+
+// CacheBus 基于NATS的跨副本缓存失效事件总线（实现cache.EventBus）
+// 与Publisher的fire-and-forget不同，失效事件要求订阅方持续在线，
+// 因此维护一条常驻连接，断开后自动重连并重建订阅
+type CacheBus struct {
+	addr  string
+	topic string
+
+	// 连接与写入器（发布方、心跳应答和重连协程并发访问，写入需串行）
+	mutex  sync.Mutex
+	conn   net.Conn
+	writer *bufio.Writer
+
+	handlerMutex sync.RWMutex
+	handlers     []func(event *cache.InvalidationEvent)
+
+	stopChannel chan struct{}
+	doneChannel chan struct{}
+}
+
+// NewCacheBus 创建缓存失效事件总线并启动常驻连接协程
+// url形如nats://host:4222（协议前缀可省略），topicPrefix为空时使用pc28
+func NewCacheBus(url, topicPrefix string) *CacheBus {
+	if topicPrefix == "" {
+		topicPrefix = "pc28"
+	}
+
+	addr := strings.TrimPrefix(url, "nats://")
+	if !strings.Contains(addr, ":") {
+		addr = addr + ":" + defaultPort
+	}
+
+	bus := &CacheBus{
+		addr:        addr,
+		topic:       topicPrefix + "." + cacheBusSubject,
+		stopChannel: make(chan struct{}),
+		doneChannel: make(chan struct{}),
+	}
+
+	go bus.run()
+	logger.Infof("Cache invalidation bus initialized, server: %s, topic: %s", addr, bus.topic)
+	return bus
+}
+
+// Publish 发布失效事件（实现cache.EventBus）
+// 未连接时直接返回错误，失效事件不补发：断线期间的副本重连后
+// 以本地TTL过期兜底，不值得为此维护发送队列
+func (cb *CacheBus) Publish(event *cache.InvalidationEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invalidation event: %v", err)
+	}
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if cb.writer == nil {
+		return fmt.Errorf("cache bus not connected")
+	}
+	if _, err = fmt.Fprintf(cb.writer, "PUB %s %d\r\n", cb.topic, len(body)); err == nil {
+		_, err = cb.writer.Write(body)
+	}
+	if err == nil {
+		_, err = cb.writer.WriteString("\r\n")
+	}
+	if err == nil {
+		err = cb.writer.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish invalidation event: %v", err)
+	}
+	return nil
+}
+
+// Subscribe 注册失效事件处理函数（实现cache.EventBus）
+func (cb *CacheBus) Subscribe(handler func(event *cache.InvalidationEvent)) {
+	cb.handlerMutex.Lock()
+	cb.handlers = append(cb.handlers, handler)
+	cb.handlerMutex.Unlock()
+}
+
+// Stop 停止总线并断开连接
+func (cb *CacheBus) Stop() {
+	close(cb.stopChannel)
+	cb.closeConn()
+	<-cb.doneChannel
+}
+
+// run 常驻连接协程：连接并订阅后持续读取分发，断开后延迟重连
+func (cb *CacheBus) run() {
+	defer close(cb.doneChannel)
+
+	for {
+		reader, err := cb.connect()
+		if err != nil {
+			logger.Warnf("Cache bus connection failed: %v", err)
+		} else {
+			cb.readLoop(reader)
+			cb.closeConn()
+		}
+
+		select {
+		case <-cb.stopChannel:
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// connect 建立连接并订阅失效主题（握手：读INFO行后发送CONNECT和SUB）
+func (cb *CacheBus) connect() (*bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", cb.addr, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS server: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	if _, err := reader.ReadString('\n'); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read NATS server info: %v", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	writer := bufio.NewWriter(conn)
+	_, err = writer.WriteString(`CONNECT {"verbose":false,"name":"pc28-bot-cachebus"}` + "\r\n")
+	if err == nil {
+		_, err = fmt.Fprintf(writer, "SUB %s %s\r\n", cb.topic, cacheBusSID)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe cache bus topic: %v", err)
+	}
+
+	cb.mutex.Lock()
+	cb.conn = conn
+	cb.writer = writer
+	cb.mutex.Unlock()
+
+	logger.Infof("Cache bus connected to NATS server %s", cb.addr)
+	return reader, nil
+}
+
+// readLoop 读取服务器帧：应答PING心跳、分发MSG（连接断开时返回）
+func (cb *CacheBus) readLoop(reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			cb.mutex.Lock()
+			if cb.writer != nil {
+				if _, err := cb.writer.WriteString("PONG\r\n"); err == nil {
+					cb.writer.Flush()
+				}
+			}
+			cb.mutex.Unlock()
+		case strings.HasPrefix(line, "MSG"):
+			cb.handleMsg(line, reader)
+		}
+	}
+}
+
+// handleMsg 解析MSG帧并分发失效事件
+// 帧格式：MSG <subject> <sid> [reply-to] <#bytes>\r\n<payload>\r\n
+func (cb *CacheBus) handleMsg(header string, reader *bufio.Reader) {
+	fields := strings.Fields(header)
+	if len(fields) < 4 {
+		return
+	}
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || size < 0 {
+		return
+	}
+
+	payload := make([]byte, size+2) // 含结尾\r\n
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return
+	}
+
+	var event cache.InvalidationEvent
+	if err := json.Unmarshal(payload[:size], &event); err != nil {
+		logger.Warnf("Cache bus received malformed event: %v", err)
+		return
+	}
+
+	cb.handlerMutex.RLock()
+	handlers := cb.handlers
+	cb.handlerMutex.RUnlock()
+	for _, handler := range handlers {
+		handler(&event)
+	}
+}
+
+// closeConn 关闭当前连接
+func (cb *CacheBus) closeConn() {
+	cb.mutex.Lock()
+	if cb.conn != nil {
+		cb.conn.Close()
+		cb.conn = nil
+		cb.writer = nil
+	}
+	cb.mutex.Unlock()
+}
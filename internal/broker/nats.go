@@ -0,0 +1,234 @@
+package broker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+)
+
+// 发布参数
+const (
+	queueSize   = 64              // 待发布消息缓冲（满时丢弃并告警，不阻塞开奖流程）
+	dialTimeout = 5 * time.Second // 连接建立超时
+	defaultPort = "4222"          // NATS默认端口
+)
+
+// Publisher NATS消息发布器
+// 使用NATS核心文本协议直连服务器发布领域事件（仅fire-and-forget的PUB，
+// 无需引入客户端依赖），下游消费方和看板订阅主题即可实时获得数据流，
+// 不用轮询REST接口；连接断开时在下一条消息发布前自动重连
+type Publisher struct {
+	addr        string
+	topicPrefix string
+
+	// 连接写入需串行：发布循环写PUB帧、心跳协程写PONG，
+	// 交错写入会破坏文本协议帧边界
+	writeMutex sync.Mutex
+	conn       net.Conn
+	writer     *bufio.Writer
+
+	queue       chan message
+	stopChannel chan struct{}
+	doneChannel chan struct{}
+}
+
+// message 待发布的领域事件
+type message struct {
+	eventType string
+	qihao     string
+	payload   string
+}
+
+// NewPublisher 创建NATS发布器并启动发布循环
+// url形如nats://host:4222（协议前缀可省略），topicPrefix为空时使用pc28
+func NewPublisher(url, topicPrefix string) *Publisher {
+	if topicPrefix == "" {
+		topicPrefix = "pc28"
+	}
+
+	addr := strings.TrimPrefix(url, "nats://")
+	if !strings.Contains(addr, ":") {
+		addr = addr + ":" + defaultPort
+	}
+
+	p := &Publisher{
+		addr:        addr,
+		topicPrefix: topicPrefix,
+		queue:       make(chan message, queueSize),
+		stopChannel: make(chan struct{}),
+		doneChannel: make(chan struct{}),
+	}
+
+	go p.publishLoop()
+	logger.Infof("NATS publisher initialized, server: %s, topic prefix: %s", addr, topicPrefix)
+	return p
+}
+
+// Publish 异步发布领域事件（实现pipeline.EventPublisher）
+// 队列已满时丢弃消息并告警，保证调用方不被不可达的服务器拖住
+func (p *Publisher) Publish(eventType, qihao, payload string) {
+	select {
+	case p.queue <- message{eventType: eventType, qihao: qihao, payload: payload}:
+	default:
+		logger.Warnf("NATS publish queue full, dropping event %s for %s", eventType, qihao)
+	}
+}
+
+// Stop 停止发布循环并断开连接（队列中未发布的消息丢弃）
+func (p *Publisher) Stop() {
+	close(p.stopChannel)
+	<-p.doneChannel
+}
+
+// topicFor 领域事件类型到主题的映射（未知类型返回空字符串表示不发布）
+func (p *Publisher) topicFor(eventType string) string {
+	switch eventType {
+	case database.OutboxNewRound:
+		return p.topicPrefix + ".round.new"
+	case database.OutboxPredictionCreated:
+		return p.topicPrefix + ".prediction.new"
+	case database.OutboxPredictionVerified:
+		return p.topicPrefix + ".prediction.verified"
+	default:
+		return ""
+	}
+}
+
+// publishLoop 发布循环（单协程串行发布，保持消息顺序）
+func (p *Publisher) publishLoop() {
+	defer close(p.doneChannel)
+	defer p.disconnect()
+
+	for {
+		select {
+		case msg := <-p.queue:
+			if err := p.publish(msg); err != nil {
+				logger.Warnf("NATS publish failed for %s: %v", msg.eventType, err)
+			}
+		case <-p.stopChannel:
+			return
+		}
+	}
+}
+
+// publish 发布单条消息（未连接时先建连，写入失败时断开留待下次重连）
+func (p *Publisher) publish(msg message) error {
+	topic := p.topicFor(msg.eventType)
+	if topic == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"type":      msg.eventType,
+		"qihao":     msg.qihao,
+		"payload":   json.RawMessage(rawOrNull(msg.payload)),
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal broker message: %v", err)
+	}
+
+	if err := p.ensureConnected(); err != nil {
+		return err
+	}
+
+	// NATS核心协议：PUB <subject> <#bytes>\r\n<payload>\r\n
+	p.writeMutex.Lock()
+	_, err = fmt.Fprintf(p.writer, "PUB %s %d\r\n", topic, len(body))
+	if err == nil {
+		_, err = p.writer.Write(body)
+	}
+	if err == nil {
+		_, err = p.writer.WriteString("\r\n")
+	}
+	if err == nil {
+		err = p.writer.Flush()
+	}
+	p.writeMutex.Unlock()
+
+	if err != nil {
+		p.disconnect()
+		return fmt.Errorf("failed to write to NATS server: %v", err)
+	}
+	return nil
+}
+
+// rawOrNull payload为空时以JSON null占位，保持消息结构稳定
+func rawOrNull(payload string) string {
+	if payload == "" {
+		return "null"
+	}
+	return payload
+}
+
+// ensureConnected 确保连接可用（握手：读INFO行后发送CONNECT）
+func (p *Publisher) ensureConnected() error {
+	if p.conn != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", p.addr, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS server: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	if _, err := reader.ReadString('\n'); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to read NATS server info: %v", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	writer := bufio.NewWriter(conn)
+	if _, err := writer.WriteString(`CONNECT {"verbose":false,"name":"pc28-bot"}` + "\r\n"); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send NATS connect: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send NATS connect: %v", err)
+	}
+
+	p.conn = conn
+	p.writer = writer
+	// 服务器心跳应答协程（不回PONG会被服务器断连）
+	go p.readLoop(conn, reader)
+	logger.Infof("Connected to NATS server %s", p.addr)
+	return nil
+}
+
+// readLoop 应答服务器心跳（连接断开时退出，下一条消息发布时重连）
+func (p *Publisher) readLoop(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if strings.HasPrefix(line, "PING") {
+			// 与发布循环共用写锁，避免PONG插入PUB帧中间
+			p.writeMutex.Lock()
+			_, err := conn.Write([]byte("PONG\r\n"))
+			p.writeMutex.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// disconnect 断开当前连接
+func (p *Publisher) disconnect() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+		p.writer = nil
+	}
+}
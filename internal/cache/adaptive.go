@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// 自适应TTL参数
+const (
+	adaptiveWindowRounds = 12               // 估算开奖间隔的采样轮数
+	adaptiveNearFraction = 0.2              // 临近开奖窗口占间隔的比例
+	adaptiveMinTTL       = 10 * time.Second // 临近开奖时的最短TTL
+	adaptiveLowHitRate   = 0.5              // 命中率低于此值时拉长TTL
+	adaptiveStretch      = 1.5              // 低命中率时的TTL放大倍数
+)
+
+// adaptiveTTL lottery:*键的自适应TTL控制器
+// 根据观测到的开奖间隔（数据变化频率）选择TTL：临近下一期开奖时用短TTL
+// 避免陈旧数据，两期之间数据不会变化则允许长TTL降低数据库回源压力；
+// 缓存命中率持续偏低时适当拉长TTL
+type adaptiveTTL struct {
+	mutex       sync.Mutex
+	roundTimes  []time.Time   // 最近各期开奖时间（旧到新）
+	avgInterval time.Duration // 估算的开奖间隔
+	lastTTL     time.Duration // 最近一次选定的TTL（供统计展示）
+	nearDraw    bool          // 最近一次选择时是否处于临近开奖窗口
+}
+
+// observeRound 记录一期开奖时间并更新间隔估算
+func (a *adaptiveTTL) observeRound(openTime time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.roundTimes = append(a.roundTimes, openTime)
+	if len(a.roundTimes) > adaptiveWindowRounds {
+		a.roundTimes = a.roundTimes[len(a.roundTimes)-adaptiveWindowRounds:]
+	}
+
+	// 用相邻两期的正常间隔求均值（跳过维护时段等异常跨度）
+	var total time.Duration
+	count := 0
+	for i := 1; i < len(a.roundTimes); i++ {
+		gap := a.roundTimes[i].Sub(a.roundTimes[i-1])
+		if gap > 0 && gap < time.Hour {
+			total += gap
+			count++
+		}
+	}
+	if count > 0 {
+		a.avgInterval = total / time.Duration(count)
+	}
+}
+
+// choose 根据当前时刻与命中率选择lottery:*键的TTL
+// 间隔样本不足时退回defaultTTL
+func (a *adaptiveTTL) choose(now time.Time, defaultTTL time.Duration, hitRate float64) time.Duration {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.avgInterval <= 0 || len(a.roundTimes) == 0 {
+		a.lastTTL = defaultTTL
+		a.nearDraw = false
+		return defaultTTL
+	}
+
+	nearWindow := time.Duration(float64(a.avgInterval) * adaptiveNearFraction)
+	remaining := a.roundTimes[len(a.roundTimes)-1].Add(a.avgInterval).Sub(now)
+
+	// 临近开奖（或已超期未采集到新数据）时用短TTL，尽快看到新开奖
+	if remaining <= nearWindow {
+		a.lastTTL = adaptiveMinTTL
+		a.nearDraw = true
+		return adaptiveMinTTL
+	}
+
+	// 两期之间：TTL覆盖到临近窗口为止，下限为默认TTL
+	ttl := remaining - nearWindow
+	if hitRate > 0 && hitRate < adaptiveLowHitRate {
+		ttl = time.Duration(float64(ttl) * adaptiveStretch)
+	}
+	if ttl < defaultTTL {
+		ttl = defaultTTL
+	}
+	if ttl > a.avgInterval {
+		ttl = a.avgInterval
+	}
+
+	a.lastTTL = ttl
+	a.nearDraw = false
+	return ttl
+}
+
+// snapshot 导出当前自适应状态（供缓存统计展示）
+func (a *adaptiveTTL) snapshot() map[string]interface{} {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return map[string]interface{}{
+		"avg_draw_interval": a.avgInterval.String(),
+		"lottery_ttl":       a.lastTTL.String(),
+		"near_draw":         a.nearDraw,
+		"samples":           len(a.roundTimes),
+	}
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"pc28-bot/internal/logger"
+)
+
+// 说明：请求的目标是通过pub/sub在多副本间广播缓存失效事件。
+// 代码树没有Redis后端，跨进程传输复用已有的NATS消息代理（见broker.CacheBus）：
+// CacheManager通过EventBus发布/订阅失效事件，副本收到非本机事件后立即失效L1缓存。
+
+// 失效事件类型
+const (
+	EventNewRound          = "new_round"          // 新一期开奖数据已写入
+	EventPredictionCreated = "prediction_created" // 新预测已生成
+)
+
+// InvalidationEvent 跨副本缓存失效事件
+type InvalidationEvent struct {
+	Type     string    `json:"type"`   // 事件类型（见上方常量）
+	Qihao    string    `json:"qihao"`  // 关联期号
+	Origin   string    `json:"origin"` // 发布方实例标识，用于忽略自身事件
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// EventBus 缓存失效事件总线
+// NATS传输实现见broker.CacheBus；Redis pub/sub等其他传输实现同一接口即可接入
+type EventBus interface {
+	// Publish 发布失效事件
+	Publish(event *InvalidationEvent) error
+	// Subscribe 注册事件处理函数
+	Subscribe(handler func(event *InvalidationEvent))
+}
+
+// instanceID 生成本实例标识（主机名+进程号）
+func instanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// SetEventBus 接入失效事件总线
+// 接入后本实例发布NewRound/PredictionCreated事件，并在收到其他实例的事件时失效L1缓存
+func (cm *CacheManager) SetEventBus(bus EventBus) {
+	cm.eventBus = bus
+	cm.instanceID = instanceID()
+
+	bus.Subscribe(func(event *InvalidationEvent) {
+		// 忽略自身发布的事件，本地缓存已在事件源头更新
+		if event.Origin == cm.instanceID {
+			return
+		}
+		cm.onRemoteInvalidation(event)
+	})
+
+	logger.Infof("Cache event bus attached, instance: %s", cm.instanceID)
+}
+
+// publishEvent 发布失效事件（未接入总线时为空操作）
+func (cm *CacheManager) publishEvent(eventType, qihao string) {
+	if cm.eventBus == nil {
+		return
+	}
+
+	event := &InvalidationEvent{
+		Type:     eventType,
+		Qihao:    qihao,
+		Origin:   cm.instanceID,
+		IssuedAt: time.Now(),
+	}
+	if err := cm.eventBus.Publish(event); err != nil {
+		logger.Warnf("Failed to publish cache invalidation event: %v", err)
+	}
+}
+
+// onRemoteInvalidation 处理其他实例发布的失效事件
+func (cm *CacheManager) onRemoteInvalidation(event *InvalidationEvent) {
+	switch event.Type {
+	case EventNewRound:
+		cm.InvalidatePattern("lottery:*")
+		cm.InvalidatePattern("prediction:*")
+	case EventPredictionCreated:
+		cm.InvalidatePattern("prediction:*")
+		cm.InvalidatePattern("stats:*")
+	default:
+		return
+	}
+
+	logger.Debugf("L1 cache invalidated by remote event: %s (qihao: %s, origin: %s)",
+		event.Type, event.Qihao, event.Origin)
+}
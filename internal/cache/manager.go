@@ -2,8 +2,11 @@ package cache
 
 import (
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"pc28-bot/internal/clock"
 	"pc28-bot/internal/database"
 	"pc28-bot/internal/logger"
 )
@@ -11,25 +14,43 @@ import (
 // CacheManager 内存缓存管理器
 type CacheManager struct {
 	memory     *MemoryCache
-	mysql      *database.MySQLDB
+	db         database.DB
 	defaultTTL time.Duration
+	eventBus   EventBus // 跨副本失效事件总线（可选，见events.go）
+	instanceID string   // 本实例标识，用于忽略自身事件
+
+	adaptive *adaptiveTTL // lottery:*键的自适应TTL控制器（见adaptive.go）
+	hits     int64        // 内存缓存命中计数
+	misses   int64        // 内存缓存未命中计数
 }
 
 // NewCacheManager 创建新的缓存管理器
-func NewCacheManager(mysql *database.MySQLDB, defaultTTL time.Duration) (*CacheManager, error) {
+func NewCacheManager(db database.DB, defaultTTL time.Duration) (*CacheManager, error) {
 	// 初始化内存缓存
 	memoryCache := NewMemoryCache(1000) // 最大1000项
 
 	manager := &CacheManager{
 		memory:     memoryCache,
-		mysql:      mysql,
+		db:         db,
 		defaultTTL: defaultTTL,
+		adaptive:   &adaptiveTTL{},
 	}
 
-	logger.Info("Cache manager initialized with Memory + MySQL")
+	logger.Info("Cache manager initialized with Memory + DB")
 	return manager, nil
 }
 
+// SetClock 注入时间源（转交内存缓存用于TTL过期判定，测试/回放可加速时间）
+func (cm *CacheManager) SetClock(c clock.Clock) {
+	cm.memory.SetClock(c)
+}
+
+// SetDefaultTTL 更新默认缓存TTL（配置重载时调用，不清空已有缓存）
+func (cm *CacheManager) SetDefaultTTL(ttl time.Duration) {
+	cm.defaultTTL = ttl
+	logger.Infof("Cache default TTL updated to %v", ttl)
+}
+
 // Close 关闭缓存管理器
 func (cm *CacheManager) Close() error {
 	logger.Info("Cache manager closed")
@@ -41,8 +62,10 @@ func (cm *CacheManager) Get(key string, dest interface{}) error {
 	// 尝试从内存缓存获取
 	err := cm.memory.Get(key, dest)
 	if err == nil {
+		atomic.AddInt64(&cm.hits, 1)
 		return nil
 	}
+	atomic.AddInt64(&cm.misses, 1)
 
 	// 从数据库获取（根据不同的缓存键类型）
 	data, err := cm.getFromDatabase(key)
@@ -50,8 +73,8 @@ func (cm *CacheManager) Get(key string, dest interface{}) error {
 		return fmt.Errorf("cache miss: %s", key)
 	}
 
-	// 回填到内存缓存
-	cm.memory.Set(key, data, cm.defaultTTL)
+	// 回填到内存缓存（lottery:*键按自适应TTL回填）
+	cm.memory.Set(key, data, cm.ttlForKey(key))
 
 	// 将数据复制到目标对象
 	return cm.copyData(data, dest)
@@ -94,22 +117,28 @@ func (cm *CacheManager) OnNewLotteryData(data *database.LotteryResult) error {
 	// 失效相关缓存
 	cm.InvalidatePattern("lottery:*")
 	cm.InvalidatePattern("prediction:*")
+	cm.InvalidatePattern("positions:*")
 
-	// 更新最新数据缓存
-	cm.Set("lottery:latest", data, cm.defaultTTL)
+	// 更新间隔估算并按自适应TTL缓存最新数据
+	cm.adaptive.observeRound(data.OpenTime)
+	lotteryTTL := cm.lotteryTTL()
+	cm.Set("lottery:latest", data, lotteryTTL)
 
 	// 获取并缓存最近3期数据
-	last3, err := cm.mysql.GetLatestLotteryResults(3)
+	last3, err := cm.db.GetLatestLotteryResults(3)
 	if err == nil {
-		cm.Set("lottery:last3", last3, cm.defaultTTL)
+		cm.Set("lottery:last3", last3, lotteryTTL)
 	}
 
 	// 获取并缓存最新10期数据
-	last10, err := cm.mysql.GetLatestLotteryResults(10)
+	last10, err := cm.db.GetLatestLotteryResults(10)
 	if err == nil {
-		cm.Set("lottery:last10", last10, cm.defaultTTL)
+		cm.Set("lottery:last10", last10, lotteryTTL)
 	}
 
+	// 通知其他副本失效L1缓存
+	cm.publishEvent(EventNewRound, data.Qihao)
+
 	logger.Infof("Cache updated for new lottery data: %s", data.Qihao)
 	return nil
 }
@@ -126,11 +155,14 @@ func (cm *CacheManager) OnPredictionGenerated(prediction *database.Prediction) e
 	cm.Set("prediction:latest", prediction, cm.defaultTTL)
 
 	// 获取并缓存最近10期预测记录
-	last10Predictions, err := cm.mysql.GetLatestPredictions(10)
+	last10Predictions, err := cm.db.GetLatestPredictions(10)
 	if err == nil {
 		cm.Set("prediction:history:10", last10Predictions, cm.defaultTTL)
 	}
 
+	// 通知其他副本失效L1缓存
+	cm.publishEvent(EventPredictionCreated, prediction.TargetQihao)
+
 	logger.Infof("Cache updated for new prediction: %s", prediction.TargetQihao)
 	return nil
 }
@@ -144,13 +176,13 @@ func (cm *CacheManager) OnPredictionVerified(qihao string, isCorrect bool) error
 	cm.InvalidatePattern("prediction:history:*")
 
 	// 获取并缓存更新的统计数据
-	stats, err := cm.mysql.GetPredictionStats()
+	stats, err := cm.db.GetPredictionStats()
 	if err == nil {
 		cm.Set("stats:accuracy", stats, cm.defaultTTL)
 	}
 
 	// 更新预测历史缓存
-	last10Predictions, err := cm.mysql.GetLatestPredictions(10)
+	last10Predictions, err := cm.db.GetLatestPredictions(10)
 	if err == nil {
 		cm.Set("prediction:history:10", last10Predictions, cm.defaultTTL)
 	}
@@ -165,12 +197,12 @@ func (cm *CacheManager) GetLatestLotteryData() (*database.LotteryResult, error)
 	err := cm.Get("lottery:latest", &result)
 	if err != nil {
 		// 从数据库获取
-		results, err := cm.mysql.GetLatestLotteryResults(1)
+		results, err := cm.db.GetLatestLotteryResults(1)
 		if err != nil || len(results) == 0 {
 			return nil, fmt.Errorf("no lottery data found")
 		}
 		result = results[0]
-		cm.Set("lottery:latest", result, cm.defaultTTL)
+		cm.Set("lottery:latest", result, cm.lotteryTTL())
 	}
 	return &result, nil
 }
@@ -181,11 +213,11 @@ func (cm *CacheManager) GetLast3LotteryData() ([]database.LotteryResult, error)
 	err := cm.Get("lottery:last3", &results)
 	if err != nil {
 		// 从数据库获取
-		results, err = cm.mysql.GetLatestLotteryResults(3)
+		results, err = cm.db.GetLatestLotteryResults(3)
 		if err != nil {
 			return nil, err
 		}
-		cm.Set("lottery:last3", results, cm.defaultTTL)
+		cm.Set("lottery:last3", results, cm.lotteryTTL())
 	}
 	return results, nil
 }
@@ -196,7 +228,7 @@ func (cm *CacheManager) GetLatestPrediction() (*database.Prediction, error) {
 	err := cm.Get("prediction:latest", &prediction)
 	if err != nil {
 		// 从数据库获取
-		predictions, err := cm.mysql.GetLatestPredictions(1)
+		predictions, err := cm.db.GetLatestPredictions(1)
 		if err != nil || len(predictions) == 0 {
 			return nil, fmt.Errorf("no prediction found")
 		}
@@ -213,17 +245,19 @@ func (cm *CacheManager) GetLotteryHistory(limit int) ([]database.LotteryResult,
 
 	// 尝试从内存缓存获取
 	if err := cm.memory.Get(cacheKey, &history); err == nil {
+		atomic.AddInt64(&cm.hits, 1)
 		return history, nil
 	}
+	atomic.AddInt64(&cm.misses, 1)
 
 	// 从数据库获取
-	history, err := cm.mysql.GetLotteryHistory(limit)
+	history, err := cm.db.GetLotteryHistory(limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get lottery history from database: %v", err)
 	}
 
-	// 保存到内存缓存
-	cm.memory.Set(cacheKey, history, 5*time.Minute)
+	// 保存到内存缓存（按自适应TTL）
+	cm.memory.Set(cacheKey, history, cm.lotteryTTL())
 
 	return history, nil
 }
@@ -236,7 +270,7 @@ func (cm *CacheManager) GetPredictionHistory(limit int) ([]database.Prediction,
 	err := cm.Get(cacheKey, &predictions)
 	if err != nil {
 		// 从数据库获取
-		predictions, err = cm.mysql.GetLatestPredictions(limit)
+		predictions, err = cm.db.GetLatestPredictions(limit)
 		if err != nil {
 			return nil, err
 		}
@@ -251,7 +285,7 @@ func (cm *CacheManager) GetPredictionStats() (*database.PredictionStats, error)
 	err := cm.Get("stats:accuracy", &stats)
 	if err != nil {
 		// 从数据库获取
-		statsPtr, err := cm.mysql.GetPredictionStats()
+		statsPtr, err := cm.db.GetPredictionStats()
 		if err != nil {
 			return nil, err
 		}
@@ -261,34 +295,71 @@ func (cm *CacheManager) GetPredictionStats() (*database.PredictionStats, error)
 	return &stats, nil
 }
 
+// GetPredictionStatsWindow 获取滚动窗口预测统计（缓存键按窗口参数化，window<=0为全量）
+func (cm *CacheManager) GetPredictionStatsWindow(window int) (*database.PredictionStats, error) {
+	if window <= 0 {
+		return cm.GetPredictionStats()
+	}
+
+	cacheKey := fmt.Sprintf("stats:accuracy:%d", window)
+	var stats database.PredictionStats
+	err := cm.Get(cacheKey, &stats)
+	if err != nil {
+		// 从数据库获取
+		statsPtr, err := cm.db.GetPredictionStatsWindow(window)
+		if err != nil {
+			return nil, err
+		}
+		stats = *statsPtr
+		cm.Set(cacheKey, stats, cm.defaultTTL)
+	}
+	return &stats, nil
+}
+
+// GetPositionDistribution 获取各位置数字分布（缓存键按窗口参数化）
+func (cm *CacheManager) GetPositionDistribution(limit int) (database.PositionDistribution, error) {
+	cacheKey := fmt.Sprintf("positions:dist:%d", limit)
+	var distribution database.PositionDistribution
+	err := cm.Get(cacheKey, &distribution)
+	if err != nil {
+		// 从数据库获取
+		distribution, err = cm.db.GetPositionDistribution(limit)
+		if err != nil {
+			return distribution, err
+		}
+		cm.Set(cacheKey, distribution, cm.defaultTTL)
+	}
+	return distribution, nil
+}
+
 // getFromDatabase 根据缓存键从数据库获取数据
 func (cm *CacheManager) getFromDatabase(key string) (interface{}, error) {
 	switch key {
 	case "lottery:latest":
-		results, err := cm.mysql.GetLatestLotteryResults(1)
+		results, err := cm.db.GetLatestLotteryResults(1)
 		if err != nil || len(results) == 0 {
 			return nil, fmt.Errorf("no latest lottery data")
 		}
 		return results[0], nil
 
 	case "lottery:last3":
-		return cm.mysql.GetLatestLotteryResults(3)
+		return cm.db.GetLatestLotteryResults(3)
 
 	case "lottery:last10":
-		return cm.mysql.GetLatestLotteryResults(10)
+		return cm.db.GetLatestLotteryResults(10)
 
 	case "prediction:latest":
-		predictions, err := cm.mysql.GetLatestPredictions(1)
+		predictions, err := cm.db.GetLatestPredictions(1)
 		if err != nil || len(predictions) == 0 {
 			return nil, fmt.Errorf("no latest prediction")
 		}
 		return predictions[0], nil
 
 	case "prediction:history:10":
-		return cm.mysql.GetLatestPredictions(10)
+		return cm.db.GetLatestPredictions(10)
 
 	case "stats:accuracy":
-		return cm.mysql.GetPredictionStats()
+		return cm.db.GetPredictionStats()
 
 	default:
 		return nil, fmt.Errorf("unknown cache key: %s", key)
@@ -324,10 +395,38 @@ func (cm *CacheManager) copyData(src, dest interface{}) error {
 			*ptr = v
 			return nil
 		}
+	case database.PositionDistribution:
+		if ptr, ok := dest.(*database.PositionDistribution); ok {
+			*ptr = v
+			return nil
+		}
 	}
 	return fmt.Errorf("unsupported type conversion")
 }
 
+// hitRate 计算内存缓存命中率（无访问记录时返回0）
+func (cm *CacheManager) hitRate() float64 {
+	hits := atomic.LoadInt64(&cm.hits)
+	misses := atomic.LoadInt64(&cm.misses)
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// lotteryTTL 获取lottery:*键的自适应TTL
+func (cm *CacheManager) lotteryTTL() time.Duration {
+	return cm.adaptive.choose(time.Now(), cm.defaultTTL, cm.hitRate())
+}
+
+// ttlForKey 根据缓存键选择TTL（lottery:*走自适应，其余用默认TTL）
+func (cm *CacheManager) ttlForKey(key string) time.Duration {
+	if strings.HasPrefix(key, "lottery:") {
+		return cm.lotteryTTL()
+	}
+	return cm.defaultTTL
+}
+
 // GetStats 获取缓存统计信息
 func (cm *CacheManager) GetStats() map[string]interface{} {
 	memStats := cm.memory.Stats()
@@ -335,5 +434,9 @@ func (cm *CacheManager) GetStats() map[string]interface{} {
 	return map[string]interface{}{
 		"memory_cache": memStats,
 		"cache_layers": 2, // Memory + MySQL
+		"hits":         atomic.LoadInt64(&cm.hits),
+		"misses":       atomic.LoadInt64(&cm.misses),
+		"hit_rate":     cm.hitRate(),
+		"adaptive_ttl": cm.adaptive.snapshot(),
 	}
 }
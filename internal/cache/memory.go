@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"pc28-bot/internal/clock"
 	"pc28-bot/internal/logger"
 )
 
@@ -27,6 +28,7 @@ type MemoryCache struct {
 	mutex   sync.RWMutex
 	maxSize int
 	size    int64
+	clock   clock.Clock
 }
 
 // NewMemoryCache 创建新的内存缓存
@@ -34,6 +36,7 @@ func NewMemoryCache(maxSize int) *MemoryCache {
 	cache := &MemoryCache{
 		maxSize: maxSize,
 		size:    0,
+		clock:   clock.System(),
 	}
 
 	// 启动清理协程
@@ -43,12 +46,23 @@ func NewMemoryCache(maxSize int) *MemoryCache {
 	return cache
 }
 
+// SetClock 注入时间源（模拟时钟下TTL过期按模拟时间判定）
+func (m *MemoryCache) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// isExpired 按当前时间源判断缓存项是否过期
+func (m *MemoryCache) isExpired(item *MemoryItem) bool {
+	return m.clock.Now().After(item.ExpiresAt)
+}
+
 // Set 设置缓存值
 func (m *MemoryCache) Set(key string, value interface{}, ttl time.Duration) error {
+	now := m.clock.Now()
 	item := &MemoryItem{
 		Value:     value,
-		ExpiresAt: time.Now().Add(ttl),
-		CreatedAt: time.Now(),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
 	}
 
 	// 检查缓存大小限制
@@ -76,7 +90,7 @@ func (m *MemoryCache) Get(key string, dest interface{}) error {
 	}
 
 	item := value.(*MemoryItem)
-	if item.IsExpired() {
+	if m.isExpired(item) {
 		m.items.Delete(key)
 		m.mutex.Lock()
 		m.size--
@@ -147,7 +161,7 @@ func (m *MemoryCache) Exists(key string) (bool, error) {
 	}
 
 	item := value.(*MemoryItem)
-	if item.IsExpired() {
+	if m.isExpired(item) {
 		m.items.Delete(key)
 		m.mutex.Lock()
 		m.size--
@@ -166,7 +180,7 @@ func (m *MemoryCache) SetTTL(key string, ttl time.Duration) error {
 	}
 
 	item := value.(*MemoryItem)
-	item.ExpiresAt = time.Now().Add(ttl)
+	item.ExpiresAt = m.clock.Now().Add(ttl)
 	m.items.Store(key, item)
 
 	logger.Debugf("Memory cache TTL set: %s, ttl: %v", key, ttl)
@@ -181,11 +195,11 @@ func (m *MemoryCache) GetTTL(key string) (time.Duration, error) {
 	}
 
 	item := value.(*MemoryItem)
-	if item.IsExpired() {
+	if m.isExpired(item) {
 		return 0, nil
 	}
 
-	return time.Until(item.ExpiresAt), nil
+	return item.ExpiresAt.Sub(m.clock.Now()), nil
 }
 
 // Clear 清空所有缓存
@@ -213,7 +227,7 @@ func (m *MemoryCache) Stats() map[string]interface{} {
 	var validItems, expiredItems int64
 	m.items.Range(func(key, value interface{}) bool {
 		item := value.(*MemoryItem)
-		if item.IsExpired() {
+		if m.isExpired(item) {
 			expiredItems++
 		} else {
 			validItems++
@@ -245,7 +259,7 @@ func (m *MemoryCache) cleanupExpired() {
 
 	m.items.Range(func(key, value interface{}) bool {
 		item := value.(*MemoryItem)
-		if item.IsExpired() {
+		if m.isExpired(item) {
 			expiredKeys = append(expiredKeys, key)
 		}
 		return true
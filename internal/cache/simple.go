@@ -12,7 +12,7 @@ import (
 // SimpleCache 简化的缓存实现（只使用内存缓存）
 type SimpleCache struct {
 	data   sync.Map
-	mysql  *database.MySQLDB
+	db     database.DB
 	ttl    time.Duration
 	mutex  sync.RWMutex
 }
@@ -29,10 +29,10 @@ func (item *CacheItem) IsExpired() bool {
 }
 
 // NewSimpleCache 创建简化缓存
-func NewSimpleCache(mysql *database.MySQLDB, ttl time.Duration) *SimpleCache {
+func NewSimpleCache(db database.DB, ttl time.Duration) *SimpleCache {
 	cache := &SimpleCache{
-		mysql: mysql,
-		ttl:   ttl,
+		db:  db,
+		ttl: ttl,
 	}
 	
 	// 启动清理协程
@@ -82,7 +82,7 @@ func (sc *SimpleCache) Clear() {
 func (sc *SimpleCache) loadFromDatabase(key string, dest interface{}) error {
 	switch key {
 	case "latest_lottery":
-		results, err := sc.mysql.GetLatestLotteryResults(1)
+		results, err := sc.db.GetLatestLotteryResults(1)
 		if err != nil || len(results) == 0 {
 			return fmt.Errorf("no latest lottery data")
 		}
@@ -90,7 +90,7 @@ func (sc *SimpleCache) loadFromDatabase(key string, dest interface{}) error {
 		return sc.copyValue(results[0], dest)
 
 	case "last3_lottery":
-		results, err := sc.mysql.GetLatestLotteryResults(3)
+		results, err := sc.db.GetLatestLotteryResults(3)
 		if err != nil {
 			return err
 		}
@@ -98,7 +98,7 @@ func (sc *SimpleCache) loadFromDatabase(key string, dest interface{}) error {
 		return sc.copyValue(results, dest)
 
 	case "latest_prediction":
-		predictions, err := sc.mysql.GetLatestPredictions(1)
+		predictions, err := sc.db.GetLatestPredictions(1)
 		if err != nil || len(predictions) == 0 {
 			return fmt.Errorf("no latest prediction")
 		}
@@ -106,7 +106,7 @@ func (sc *SimpleCache) loadFromDatabase(key string, dest interface{}) error {
 		return sc.copyValue(predictions[0], dest)
 
 	case "prediction_history":
-		predictions, err := sc.mysql.GetLatestPredictions(10)
+		predictions, err := sc.db.GetLatestPredictions(10)
 		if err != nil {
 			return err
 		}
@@ -114,7 +114,7 @@ func (sc *SimpleCache) loadFromDatabase(key string, dest interface{}) error {
 		return sc.copyValue(predictions, dest)
 
 	case "prediction_stats":
-		stats, err := sc.mysql.GetPredictionStats()
+		stats, err := sc.db.GetPredictionStats()
 		if err != nil {
 			return err
 		}
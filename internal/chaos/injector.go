@@ -0,0 +1,62 @@
+package chaos
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"pc28-bot/internal/logger"
+)
+
+// Injector 故障注入器
+// 按配置的概率随机延迟或失败关键调用（API抓取、数据库读写、Telegram发送），
+// 用于在staging环境验证熔断、重试和supervisor等弹性能力。
+// 仅应在非生产环境启用
+type Injector struct {
+	mutex     sync.Mutex
+	rng       *rand.Rand
+	failRate  float64       // 注入失败的概率（0-1）
+	delayRate float64       // 注入延迟的概率（0-1）
+	maxDelay  time.Duration // 单次注入延迟的上限
+}
+
+// NewInjector 创建故障注入器（seed为0时使用当前时间做种）
+func NewInjector(failRate, delayRate float64, maxDelay time.Duration, seed int64) *Injector {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if maxDelay <= 0 {
+		maxDelay = 2 * time.Second
+	}
+	return &Injector{
+		rng:       rand.New(rand.NewSource(seed)),
+		failRate:  failRate,
+		delayRate: delayRate,
+		maxDelay:  maxDelay,
+	}
+}
+
+// Maybe 按概率注入延迟和/或失败
+// 返回非nil错误表示本次调用应按失败处理，调用方在真实操作前调用
+func (i *Injector) Maybe(op string) error {
+	if i == nil {
+		return nil
+	}
+
+	i.mutex.Lock()
+	delayRoll := i.rng.Float64()
+	failRoll := i.rng.Float64()
+	delay := time.Duration(i.rng.Int63n(int64(i.maxDelay)))
+	i.mutex.Unlock()
+
+	if delayRoll < i.delayRate {
+		logger.Debugf("Chaos: delaying %s by %v", op, delay.Round(time.Millisecond))
+		time.Sleep(delay)
+	}
+	if failRoll < i.failRate {
+		logger.Debugf("Chaos: failing %s", op)
+		return fmt.Errorf("chaos: injected fault in %s", op)
+	}
+	return nil
+}
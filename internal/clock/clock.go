@@ -0,0 +1,64 @@
+package clock
+
+import (
+	"time"
+)
+
+// Clock 时间源抽象
+// 生产环境使用System（真实时钟），集成测试和数据回放可注入Simulated，
+// 通过Advance确定性地快进时间，而不必真实等待轮询/清理间隔
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+	// After 返回在时长d后收到当前时间的通道
+	After(d time.Duration) <-chan time.Time
+	// NewTicker 创建周期定时器
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker 周期定时器抽象（对齐time.Ticker的用法）
+type Ticker interface {
+	// C 返回定时触发通道
+	C() <-chan time.Time
+	// Reset 重置触发间隔
+	Reset(d time.Duration)
+	// Stop 停止定时器
+	Stop()
+}
+
+// systemClock 真实时钟实现
+type systemClock struct{}
+
+// System 返回真实时钟
+func System() Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return &systemTicker{ticker: time.NewTicker(d)}
+}
+
+// systemTicker 包装time.Ticker以满足Ticker接口
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *systemTicker) Reset(d time.Duration) {
+	t.ticker.Reset(d)
+}
+
+func (t *systemTicker) Stop() {
+	t.ticker.Stop()
+}
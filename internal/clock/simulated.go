@@ -0,0 +1,122 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Simulated 模拟时钟
+// 时间只在调用Advance时前进，定时器和After按模拟时间确定性触发，
+// 供集成测试和历史数据回放以任意速度推进时间
+type Simulated struct {
+	mutex   sync.Mutex
+	now     time.Time
+	timers  []*simTimer
+	tickers []*simTicker
+}
+
+// simTimer 一次性定时器（After创建）
+type simTimer struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// simTicker 周期定时器
+type simTicker struct {
+	clk      *Simulated
+	interval time.Duration
+	next     time.Time
+	ch       chan time.Time
+	stopped  bool
+}
+
+// NewSimulated 创建从start时刻开始的模拟时钟
+func NewSimulated(start time.Time) *Simulated {
+	return &Simulated{now: start}
+}
+
+// Now 返回当前模拟时间
+func (s *Simulated) Now() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.now
+}
+
+// After 返回在模拟时间前进d后触发的通道
+func (s *Simulated) After(d time.Duration) <-chan time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	timer := &simTimer{
+		deadline: s.now.Add(d),
+		ch:       make(chan time.Time, 1),
+	}
+	s.timers = append(s.timers, timer)
+	return timer.ch
+}
+
+// NewTicker 创建按模拟时间触发的周期定时器
+func (s *Simulated) NewTicker(d time.Duration) Ticker {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ticker := &simTicker{
+		clk:      s,
+		interval: d,
+		next:     s.now.Add(d),
+		ch:       make(chan time.Time, 1),
+	}
+	s.tickers = append(s.tickers, ticker)
+	return ticker
+}
+
+// Advance 将模拟时间前进d，触发期间到期的定时器
+func (s *Simulated) Advance(d time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	target := s.now.Add(d)
+	s.now = target
+
+	// 触发到期的一次性定时器
+	remaining := s.timers[:0]
+	for _, timer := range s.timers {
+		if !timer.deadline.After(target) {
+			timer.ch <- timer.deadline
+		} else {
+			remaining = append(remaining, timer)
+		}
+	}
+	s.timers = remaining
+
+	// 触发到期的周期定时器（接收方未消费时与time.Ticker一致，丢弃多余触发）
+	for _, ticker := range s.tickers {
+		if ticker.stopped {
+			continue
+		}
+		for !ticker.next.After(target) {
+			select {
+			case ticker.ch <- ticker.next:
+			default:
+			}
+			ticker.next = ticker.next.Add(ticker.interval)
+		}
+	}
+}
+
+func (t *simTicker) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *simTicker) Reset(d time.Duration) {
+	t.clk.mutex.Lock()
+	defer t.clk.mutex.Unlock()
+	t.interval = d
+	t.next = t.clk.now.Add(d)
+}
+
+func (t *simTicker) Stop() {
+	t.clk.mutex.Lock()
+	defer t.clk.mutex.Unlock()
+	t.stopped = true
+}
@@ -0,0 +1,72 @@
+// Package coldstore 历史开奖数据冷存储归档
+// 超出热存储保留窗口的开奖数据按月压缩为CSV归档文件后从数据库移除，
+// 归档读写通过BlobStore抽象完成（内置本地目录实现，可替换为S3等对象存储），
+// Reader在回测等场景下透明补足热存储之外的历史数据
+package coldstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BlobStore 归档文件存储抽象
+type BlobStore interface {
+	// Put 写入归档文件（同名覆盖）
+	Put(name string, data []byte) error
+
+	// Get 读取归档文件（不存在时返回nil数据且不报错）
+	Get(name string) ([]byte, error)
+
+	// List 列出全部归档文件名
+	List() ([]string, error)
+}
+
+// DirStore 本地目录归档存储
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore 创建本地目录归档存储（目录不存在时自动创建）
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cold storage dir: %v", err)
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+// Put 写入归档文件
+func (d *DirStore) Put(name string, data []byte) error {
+	if err := os.WriteFile(filepath.Join(d.dir, name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write archive %s: %v", name, err)
+	}
+	return nil
+}
+
+// Get 读取归档文件
+func (d *DirStore) Get(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(d.dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %v", name, err)
+	}
+	return data, nil
+}
+
+// List 列出全部归档文件名
+func (d *DirStore) List() ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archives: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
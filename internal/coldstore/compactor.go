@@ -0,0 +1,119 @@
+package coldstore
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"pc28-bot/internal/database"
+)
+
+// compactBatchSize 单批归档的开奖数据条数（分批处理避免一次性加载全部旧数据）
+const compactBatchSize = 500
+
+// csvHeader 月度归档文件的列定义（与LotteryResult字段一一对应）
+var csvHeader = []string{"qihao", "opentime", "opentime_string", "opennum", "sum_value", "odd_even", "big_small", "is_triple", "is_pair"}
+
+// Compactor 冷存储压缩归档器
+// 将早于保留窗口的开奖数据按开奖月份追加到对应归档文件，归档成功后从数据库删除；
+// 先归档后删除，删除失败时下次运行会重复归档同批数据，由Reader按期号去重兜底
+type Compactor struct {
+	db    database.DB
+	store BlobStore
+}
+
+// NewCompactor 创建冷存储压缩归档器
+func NewCompactor(db database.DB, store BlobStore) *Compactor {
+	return &Compactor{
+		db:    db,
+		store: store,
+	}
+}
+
+// CompactBefore 归档开奖时间早于cutoff的全部开奖数据，返回归档条数
+func (c *Compactor) CompactBefore(cutoff time.Time) (int, error) {
+	archived := 0
+	for {
+		results, err := c.db.GetLotteryResultsBefore(cutoff, compactBatchSize)
+		if err != nil {
+			return archived, fmt.Errorf("failed to load results for compaction: %v", err)
+		}
+		if len(results) == 0 {
+			return archived, nil
+		}
+
+		// 按开奖月份分组追加到月度归档文件
+		months := make(map[string][]database.LotteryResult)
+		for _, result := range results {
+			month := result.OpenTime.Format("2006-01")
+			months[month] = append(months[month], result)
+		}
+		for month, group := range months {
+			if err := c.appendMonth(month, group); err != nil {
+				return archived, err
+			}
+		}
+
+		qihaos := make([]string, 0, len(results))
+		for _, result := range results {
+			qihaos = append(qihaos, result.Qihao)
+		}
+		if _, err := c.db.DeleteLotteryResultsByQihao(qihaos); err != nil {
+			return archived, fmt.Errorf("failed to remove archived results: %v", err)
+		}
+
+		archived += len(results)
+		if len(results) < compactBatchSize {
+			return archived, nil
+		}
+	}
+}
+
+// appendMonth 将一批开奖数据追加到指定月份的归档文件
+func (c *Compactor) appendMonth(month string, results []database.LotteryResult) error {
+	name := archiveName(month)
+	existing, err := c.store.Get(name)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if len(existing) > 0 {
+		buffer.Write(existing)
+	} else {
+		if err := writer.Write(csvHeader); err != nil {
+			return fmt.Errorf("failed to write archive header: %v", err)
+		}
+	}
+
+	for _, result := range results {
+		record := []string{
+			result.Qihao,
+			result.OpenTime.Format(time.RFC3339),
+			result.OpenTimeString,
+			result.OpenNum,
+			strconv.Itoa(result.SumValue),
+			result.OddEven,
+			result.BigSmall,
+			strconv.FormatBool(result.IsTriple),
+			strconv.FormatBool(result.IsPair),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write archive record: %v", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to encode archive %s: %v", name, err)
+	}
+
+	return c.store.Put(name, buffer.Bytes())
+}
+
+// archiveName 月度归档文件名（month格式为2006-01）
+func archiveName(month string) string {
+	return fmt.Sprintf("lottery-%s.csv", month)
+}
@@ -0,0 +1,128 @@
+package coldstore
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/qihao"
+)
+
+// Reader 冷存储归档读取器
+// 回测时从月度归档文件补足热存储之外的历史数据，按期号去重
+// （容忍归档后删除失败导致的重复记录）
+type Reader struct {
+	store BlobStore
+}
+
+// NewReader 创建冷存储归档读取器
+func NewReader(store BlobStore) *Reader {
+	return &Reader{store: store}
+}
+
+// ReadBefore 读取期号早于beforeQihao的归档开奖数据（按期号降序，最多limit条）
+func (r *Reader) ReadBefore(beforeQihao string, limit int) ([]database.LotteryResult, error) {
+	names, err := r.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	// 月度文件名按字典序即时间序，从最新月份开始读直到凑够limit条
+	var months []string
+	for _, name := range names {
+		if strings.HasPrefix(name, "lottery-") && strings.HasSuffix(name, ".csv") {
+			months = append(months, name)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(months)))
+
+	seen := make(map[string]bool)
+	var results []database.LotteryResult
+	for _, name := range months {
+		records, err := r.readArchive(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range records {
+			if qihao.Compare(result.Qihao, beforeQihao) >= 0 || seen[result.Qihao] {
+				continue
+			}
+			seen[result.Qihao] = true
+			results = append(results, result)
+		}
+		if len(results) >= limit {
+			break
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return qihao.Compare(results[i].Qihao, results[j].Qihao) > 0
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// readArchive 解析单个月度归档文件
+func (r *Reader) readArchive(name string) ([]database.LotteryResult, error) {
+	data, err := r.store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := ParseArchive(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse archive %s: %v", name, err)
+	}
+	return results, nil
+}
+
+// ParseArchive 解析归档文件内容（Compactor写出的月度CSV格式）
+func ParseArchive(data []byte) ([]database.LotteryResult, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv content: %v", err)
+	}
+
+	var results []database.LotteryResult
+	for i, record := range records {
+		// 跳过表头行
+		if i == 0 || len(record) != len(csvHeader) {
+			continue
+		}
+
+		openTime, err := time.Parse(time.RFC3339, record[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid opentime: %v", err)
+		}
+		sumValue, err := strconv.Atoi(record[4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid sum_value: %v", err)
+		}
+		isTriple, _ := strconv.ParseBool(record[7])
+		isPair, _ := strconv.ParseBool(record[8])
+
+		results = append(results, database.LotteryResult{
+			Qihao:          record[0],
+			OpenTime:       openTime,
+			OpenTimeString: record[2],
+			OpenNum:        record[3],
+			SumValue:       sumValue,
+			OddEven:        record[5],
+			BigSmall:       record[6],
+			IsTriple:       isTriple,
+			IsPair:         isPair,
+		})
+	}
+	return results, nil
+}
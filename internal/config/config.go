@@ -3,21 +3,121 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
+	"strconv"
 	"time"
 
 	"gopkg.in/yaml.v2"
+
+	"pc28-bot/internal/schedule"
 )
 
 // Config 应用程序配置结构
 type Config struct {
-	Database Database `yaml:"database"`
-	Telegram Telegram `yaml:"telegram"`
-	API      API      `yaml:"api"`
-	App      App      `yaml:"app"`
+	Database Database          `yaml:"database"`
+	Telegram Telegram          `yaml:"telegram"`
+	API      API               `yaml:"api"`
+	App      App               `yaml:"app"`
+	Debug    Debug             `yaml:"debug"`
+	Alerting Alerting          `yaml:"alerting"`
+	Sentry   Sentry            `yaml:"sentry"`
+	Chaos    Chaos             `yaml:"chaos"`
+	Webhooks []WebhookEndpoint `yaml:"webhooks"` // 对外webhook订阅端点（为空则不启用）
+	Broker   Broker            `yaml:"broker"`
+	Tenants  []Tenant          `yaml:"tenants"` // 多租户实例列表（为空时按单机器人模式运行）
+}
+
+// Broker 消息代理发布配置
+// 领域事件发布到NATS主题（<prefix>.round.new等），下游无需轮询REST接口
+type Broker struct {
+	URL         string `yaml:"url"`          // NATS服务器地址（nats://host:4222），为空则不启用
+	TopicPrefix string `yaml:"topic_prefix"` // 主题前缀（默认pc28）
+}
+
+// WebhookEndpoint 对外webhook订阅端点
+// 领域事件（新开奖/新预测/验证结果）以JSON POST到第三方系统
+type WebhookEndpoint struct {
+	URL    string   `yaml:"url"`
+	Secret string   `yaml:"secret"` // HMAC-SHA256签名密钥，为空则不签名
+	Events []string `yaml:"events"` // 订阅的事件类型，为空表示全部事件
+}
+
+// Tenant 多租户部署中的单个机器人实例
+// 每个租户拥有独立的Telegram token、数据库、数据源和算法，数据完全隔离
+type Tenant struct {
+	Name      string   `yaml:"name"`
+	Telegram  Telegram `yaml:"telegram"`
+	Database  Database `yaml:"database"`
+	API       API      `yaml:"api"`       // url为空时沿用全局数据源
+	Algorithm string   `yaml:"algorithm"` // 为空时沿用全局算法配置
+}
+
+// ForTenant 以全局配置为基底生成指定租户的派生配置
+func (c *Config) ForTenant(t *Tenant) *Config {
+	derived := *c
+	derived.Tenants = nil
+	derived.Telegram = t.Telegram
+	derived.Database = t.Database
+	if t.API.URL != "" {
+		derived.API = t.API
+	}
+	if t.Algorithm != "" {
+		derived.App.Algorithm = t.Algorithm
+	}
+	// 诊断服务按进程仅监听一次，租户实例不重复启动
+	derived.Debug.Listen = ""
+	return &derived
+}
+
+// Sentry 错误上报配置
+type Sentry struct {
+	DSN         string `yaml:"dsn"`         // 为空则不启用上报
+	Release     string `yaml:"release"`     // 发布版本标记
+	Environment string `yaml:"environment"` // 运行环境标记（production/staging等）
+}
+
+// Alerting 外部告警配置
+type Alerting struct {
+	WebhookURL             string        `yaml:"webhook_url"`              // 为空则不启用webhook通道
+	SMTP                   SMTP          `yaml:"smtp"`                     // host为空则不启用邮件通道
+	APIFailureThreshold    int           `yaml:"api_failure_threshold"`    // API连续失败告警阈值
+	LosingStreakThreshold  int           `yaml:"losing_streak_threshold"`  // 预测连败告警阈值
+	MissedRoundTimeout     time.Duration `yaml:"missed_round_timeout"`     // 漏开奖告警超时
+	StalePredictionTimeout time.Duration `yaml:"stale_prediction_timeout"` // 预测未验证看门狗超时（默认10分钟）
+	AnomalyMinSample       int           `yaml:"anomaly_min_sample"`       // 准确率异常检测最小样本量（默认100）
+	AnomalyZScore          float64       `yaml:"anomaly_z_score"`          // 准确率异常z值阈值（默认3.0）
+}
+
+// SMTP 邮件告警配置
+type SMTP struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// Chaos 故障注入配置
+// 启用后按概率随机延迟或失败API抓取、数据库读写和Telegram发送，
+// 用于在staging验证熔断、重试和supervisor等弹性能力，严禁生产启用
+type Chaos struct {
+	Enabled   bool          `yaml:"enabled"`
+	FailRate  float64       `yaml:"fail_rate"`  // 注入失败的概率（0-1）
+	DelayRate float64       `yaml:"delay_rate"` // 注入延迟的概率（0-1）
+	MaxDelay  time.Duration `yaml:"max_delay"`  // 单次注入延迟上限（默认2秒）
+	Seed      int64         `yaml:"seed"`       // 随机种子（非0时可复现注入序列）
+}
+
+// Debug 运行时诊断配置
+type Debug struct {
+	Listen string `yaml:"listen"` // 诊断HTTP监听地址，为空则不启动
+	Token  string `yaml:"token"`  // 管理员访问token
 }
 
 // Database 数据库配置
 type Database struct {
+	Driver          string        `yaml:"driver"` // mysql（默认）或memory
 	Host            string        `yaml:"host"`
 	Port            int           `yaml:"port"`
 	Username        string        `yaml:"username"`
@@ -26,12 +126,18 @@ type Database struct {
 	MaxOpenConns    int           `yaml:"max_open_conns"`
 	MaxIdleConns    int           `yaml:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+
+	// WriteBehindFlush 写后缓冲刷写间隔，非0时开奖数据和预测写入先缓冲
+	// 再批量落库（多彩种模式下降低写入压力），为0时直接写入
+	WriteBehindFlush time.Duration `yaml:"write_behind_flush"`
 }
 
 // Telegram Bot配置
 type Telegram struct {
-	Token   string        `yaml:"token"`
-	Timeout time.Duration `yaml:"timeout"`
+	Token        string        `yaml:"token"`
+	Timeout      time.Duration `yaml:"timeout"`
+	AdminChatIDs []int64       `yaml:"admin_chat_ids"` // 接收错误日志转发的管理员会话
+	TemplateDir  string        `yaml:"template_dir"`   // 自定义消息模板目录（空则使用内置模板）
 }
 
 // API 外部API配置
@@ -40,14 +146,39 @@ type API struct {
 	Timeout    time.Duration `yaml:"timeout"`
 	RetryCount int           `yaml:"retry_count"`
 	RetryDelay time.Duration `yaml:"retry_delay"`
+
+	// FixtureFile 回放fixture文件路径，非空时在本地回放录制数据
+	// 作为数据源（替代url，配合memory后端离线验证完整流程）
+	FixtureFile string `yaml:"fixture_file"`
+
+	// SecondaryURL 第二数据源URL，非空时新开奖数据入库前与该源
+	// 交叉核对开奖号码，不一致的期号暂缓入库并告警
+	SecondaryURL string `yaml:"secondary_url"`
 }
 
 // App 应用程序配置
 type App struct {
-	PollingInterval    time.Duration `yaml:"polling_interval"`
-	DataRetentionHours int           `yaml:"data_retention_hours"`
-	LogLevel           string        `yaml:"log_level"`
-	CacheTTL           time.Duration `yaml:"cache_ttl"`
+	PollingInterval        time.Duration `yaml:"polling_interval"`
+	DrawInterval           time.Duration `yaml:"draw_interval"` // 开奖间隔（默认3.5分钟，用于倒计时推算）
+	DataRetentionHours     int           `yaml:"data_retention_hours"`
+	LogLevel               string        `yaml:"log_level"`
+	LogFormat              string        `yaml:"log_format"`   // text（默认）或json
+	ConsoleMode            string        `yaml:"console_mode"` // quiet/normal（默认）/verbose
+	CacheTTL               time.Duration `yaml:"cache_ttl"`
+	Algorithm              string        `yaml:"algorithm"`
+	ValidationPolicy       string        `yaml:"validation_policy"`        // 命中判定策略：odd_even（默认）/big_small/exact/positions
+	ShutdownTimeout        time.Duration `yaml:"shutdown_timeout"`         // 停机时等待在途处理的最长时间
+	TrendNotifyRounds      int           `yaml:"trend_notify_rounds"`      // 趋势方向变化需连续确认的轮数（默认3）
+	PredictorSeed          int64         `yaml:"predictor_seed"`           // 预测器随机种子（非0时开启确定性模式，回测可精确复现）
+	OddsOddEven            float64       `yaml:"odds_odd_even"`            // 单双玩法净赔率（用于假想盈亏回算，默认0.95）
+	OddsBigSmall           float64       `yaml:"odds_big_small"`           // 大小玩法净赔率（用于假想盈亏回算，默认0.95）
+	BroadcastLeadTime      time.Duration `yaml:"broadcast_lead_time"`      // 预测广播提前量（非0时推迟到开奖前该时长发送，为0立即广播）
+	CountdownEditInterval  time.Duration `yaml:"countdown_edit_interval"`  // 广播倒计时编辑间隔（非0时定期刷新广播消息的倒计时，钳制在30-60秒）
+	PredictionFreezeWindow time.Duration `yaml:"prediction_freeze_window"` // 预测冻结窗口（非0时距预计开奖小于该时长不再为本期生成预测，改为预测下一期）
+	ColdStorageDir         string        `yaml:"cold_storage_dir"`         // 冷存储归档目录（非空时旧数据先按月归档再清理，为空直接清理）
+
+	// MaintenanceWindows 每日维护时段，时段内暂停轮询、预测和失败告警
+	MaintenanceWindows []schedule.Window `yaml:"maintenance_windows"`
 }
 
 // LoadConfig 加载配置文件
@@ -57,14 +188,158 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %v", err)
 	}
 
+	// 展开YAML中的${VAR}环境变量引用
+	expanded := expandEnvVars(string(data))
+
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := yaml.Unmarshal([]byte(expanded), &config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %v", err)
 	}
 
+	// 应用PC28_*环境变量覆盖（优先级高于配置文件）
+	applyEnvOverrides(&config)
+
 	return &config, nil
 }
 
+// expandEnvVars 展开字符串中的${VAR}引用（未定义的变量保持原样）
+func expandEnvVars(s string) string {
+	return os.Expand(s, func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		// 未定义的变量保持原样，避免破坏包含$的配置值
+		return "${" + name + "}"
+	})
+}
+
+// applyEnvOverrides 应用PC28_*环境变量覆盖（用于安全注入密钥）
+func applyEnvOverrides(config *Config) {
+	// 数据库配置
+	overrideString("PC28_DATABASE_HOST", &config.Database.Host)
+	overrideInt("PC28_DATABASE_PORT", &config.Database.Port)
+	overrideString("PC28_DATABASE_USERNAME", &config.Database.Username)
+	overrideString("PC28_DATABASE_PASSWORD", &config.Database.Password)
+	overrideString("PC28_DATABASE_DATABASE", &config.Database.Database)
+	overrideInt("PC28_DATABASE_MAX_OPEN_CONNS", &config.Database.MaxOpenConns)
+	overrideInt("PC28_DATABASE_MAX_IDLE_CONNS", &config.Database.MaxIdleConns)
+	overrideDuration("PC28_DATABASE_CONN_MAX_LIFETIME", &config.Database.ConnMaxLifetime)
+	overrideDuration("PC28_DATABASE_WRITE_BEHIND_FLUSH", &config.Database.WriteBehindFlush)
+
+	// Telegram配置
+	overrideString("PC28_TELEGRAM_TOKEN", &config.Telegram.Token)
+	overrideDuration("PC28_TELEGRAM_TIMEOUT", &config.Telegram.Timeout)
+	overrideString("PC28_TELEGRAM_TEMPLATE_DIR", &config.Telegram.TemplateDir)
+
+	// API配置
+	overrideString("PC28_API_URL", &config.API.URL)
+	overrideDuration("PC28_API_TIMEOUT", &config.API.Timeout)
+	overrideInt("PC28_API_RETRY_COUNT", &config.API.RetryCount)
+	overrideDuration("PC28_API_RETRY_DELAY", &config.API.RetryDelay)
+	overrideString("PC28_API_FIXTURE_FILE", &config.API.FixtureFile)
+	overrideString("PC28_API_SECONDARY_URL", &config.API.SecondaryURL)
+
+	// 应用配置
+	overrideDuration("PC28_APP_POLLING_INTERVAL", &config.App.PollingInterval)
+	overrideDuration("PC28_APP_DRAW_INTERVAL", &config.App.DrawInterval)
+	overrideInt("PC28_APP_DATA_RETENTION_HOURS", &config.App.DataRetentionHours)
+	overrideString("PC28_APP_LOG_LEVEL", &config.App.LogLevel)
+	overrideString("PC28_APP_LOG_FORMAT", &config.App.LogFormat)
+	overrideString("PC28_APP_CONSOLE_MODE", &config.App.ConsoleMode)
+	overrideDuration("PC28_APP_CACHE_TTL", &config.App.CacheTTL)
+	overrideString("PC28_APP_ALGORITHM", &config.App.Algorithm)
+	overrideString("PC28_APP_VALIDATION_POLICY", &config.App.ValidationPolicy)
+	overrideDuration("PC28_APP_SHUTDOWN_TIMEOUT", &config.App.ShutdownTimeout)
+	overrideInt("PC28_APP_TREND_NOTIFY_ROUNDS", &config.App.TrendNotifyRounds)
+	overrideInt64("PC28_APP_PREDICTOR_SEED", &config.App.PredictorSeed)
+	overrideFloat("PC28_APP_ODDS_ODD_EVEN", &config.App.OddsOddEven)
+	overrideFloat("PC28_APP_ODDS_BIG_SMALL", &config.App.OddsBigSmall)
+	overrideDuration("PC28_APP_BROADCAST_LEAD_TIME", &config.App.BroadcastLeadTime)
+	overrideDuration("PC28_APP_COUNTDOWN_EDIT_INTERVAL", &config.App.CountdownEditInterval)
+	overrideDuration("PC28_APP_PREDICTION_FREEZE_WINDOW", &config.App.PredictionFreezeWindow)
+	overrideString("PC28_APP_COLD_STORAGE_DIR", &config.App.ColdStorageDir)
+
+	// 诊断配置
+	overrideString("PC28_DEBUG_LISTEN", &config.Debug.Listen)
+	overrideString("PC28_DEBUG_TOKEN", &config.Debug.Token)
+
+	// 告警配置
+	overrideString("PC28_ALERTING_WEBHOOK_URL", &config.Alerting.WebhookURL)
+	overrideString("PC28_ALERTING_SMTP_HOST", &config.Alerting.SMTP.Host)
+	overrideInt("PC28_ALERTING_SMTP_PORT", &config.Alerting.SMTP.Port)
+	overrideString("PC28_ALERTING_SMTP_USERNAME", &config.Alerting.SMTP.Username)
+	overrideString("PC28_ALERTING_SMTP_PASSWORD", &config.Alerting.SMTP.Password)
+	overrideInt("PC28_ALERTING_ANOMALY_MIN_SAMPLE", &config.Alerting.AnomalyMinSample)
+	overrideDuration("PC28_ALERTING_STALE_PREDICTION_TIMEOUT", &config.Alerting.StalePredictionTimeout)
+
+	// 故障注入配置
+	overrideBool("PC28_CHAOS_ENABLED", &config.Chaos.Enabled)
+	overrideFloat("PC28_CHAOS_FAIL_RATE", &config.Chaos.FailRate)
+	overrideFloat("PC28_CHAOS_DELAY_RATE", &config.Chaos.DelayRate)
+	overrideDuration("PC28_CHAOS_MAX_DELAY", &config.Chaos.MaxDelay)
+
+	// 消息代理配置
+	overrideString("PC28_BROKER_URL", &config.Broker.URL)
+	overrideString("PC28_BROKER_TOPIC_PREFIX", &config.Broker.TopicPrefix)
+
+	// Sentry配置
+	overrideString("PC28_SENTRY_DSN", &config.Sentry.DSN)
+	overrideString("PC28_SENTRY_RELEASE", &config.Sentry.Release)
+	overrideString("PC28_SENTRY_ENVIRONMENT", &config.Sentry.Environment)
+}
+
+// overrideString 用环境变量覆盖字符串配置项
+func overrideString(envKey string, dest *string) {
+	if value, ok := os.LookupEnv(envKey); ok {
+		*dest = value
+	}
+}
+
+// overrideInt 用环境变量覆盖整数配置项
+func overrideInt(envKey string, dest *int) {
+	if value, ok := os.LookupEnv(envKey); ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			*dest = n
+		}
+	}
+}
+
+// overrideBool 用环境变量覆盖布尔配置项
+func overrideBool(envKey string, dest *bool) {
+	if value, ok := os.LookupEnv(envKey); ok {
+		if v, err := strconv.ParseBool(value); err == nil {
+			*dest = v
+		}
+	}
+}
+
+// overrideInt64 用环境变量覆盖64位整数配置项
+func overrideInt64(envKey string, dest *int64) {
+	if value, ok := os.LookupEnv(envKey); ok {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			*dest = n
+		}
+	}
+}
+
+// overrideFloat 用环境变量覆盖浮点配置项
+func overrideFloat(envKey string, dest *float64) {
+	if value, ok := os.LookupEnv(envKey); ok {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			*dest = f
+		}
+	}
+}
+
+// overrideDuration 用环境变量覆盖时间配置项
+func overrideDuration(envKey string, dest *time.Duration) {
+	if value, ok := os.LookupEnv(envKey); ok {
+		if d, err := time.ParseDuration(value); err == nil {
+			*dest = d
+		}
+	}
+}
+
 // GetDSN 获取数据库连接字符串
 func (d *Database) GetDSN() string {
 	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
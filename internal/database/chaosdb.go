@@ -0,0 +1,89 @@
+package database
+
+import (
+	"pc28-bot/internal/chaos"
+)
+
+// ChaosDB 故障注入存储包装器
+// 在单期处理的关键数据库路径上按概率注入延迟或失败，
+// 用于验证pipeline的重试与降级逻辑。仅覆盖每期都会命中的方法，
+// 其余方法透传底层存储
+type ChaosDB struct {
+	DB
+
+	injector *chaos.Injector
+}
+
+// NewChaosDB 创建故障注入存储包装器
+func NewChaosDB(backend DB, injector *chaos.Injector) *ChaosDB {
+	return &ChaosDB{DB: backend, injector: injector}
+}
+
+// SaveLotteryResult 注入后保存开奖数据
+func (c *ChaosDB) SaveLotteryResult(result *LotteryResult) error {
+	if err := c.injector.Maybe("db.SaveLotteryResult"); err != nil {
+		return err
+	}
+	return c.DB.SaveLotteryResult(result)
+}
+
+// GetLatestLotteryResults 注入后查询最新开奖数据
+func (c *ChaosDB) GetLatestLotteryResults(limit int) ([]LotteryResult, error) {
+	if err := c.injector.Maybe("db.GetLatestLotteryResults"); err != nil {
+		return nil, err
+	}
+	return c.DB.GetLatestLotteryResults(limit)
+}
+
+// GetLotteryHistory 注入后查询历史开奖数据
+func (c *ChaosDB) GetLotteryHistory(limit int) ([]LotteryResult, error) {
+	if err := c.injector.Maybe("db.GetLotteryHistory"); err != nil {
+		return nil, err
+	}
+	return c.DB.GetLotteryHistory(limit)
+}
+
+// CheckNewQihao 注入后检查新期号
+func (c *ChaosDB) CheckNewQihao(qihao string) (bool, error) {
+	if err := c.injector.Maybe("db.CheckNewQihao"); err != nil {
+		return false, err
+	}
+	return c.DB.CheckNewQihao(qihao)
+}
+
+// SavePrediction 注入后保存预测记录
+func (c *ChaosDB) SavePrediction(prediction *Prediction) error {
+	if err := c.injector.Maybe("db.SavePrediction"); err != nil {
+		return err
+	}
+	return c.DB.SavePrediction(prediction)
+}
+
+// GetLatestPredictions 注入后查询预测记录
+func (c *ChaosDB) GetLatestPredictions(limit int) ([]Prediction, error) {
+	if err := c.injector.Maybe("db.GetLatestPredictions"); err != nil {
+		return nil, err
+	}
+	return c.DB.GetLatestPredictions(limit)
+}
+
+// UpdatePredictionResult 注入后更新预测结果
+func (c *ChaosDB) UpdatePredictionResult(qihao string, actualNum string, isCorrect bool) error {
+	if err := c.injector.Maybe("db.UpdatePredictionResult"); err != nil {
+		return err
+	}
+	return c.DB.UpdatePredictionResult(qihao, actualNum, isCorrect)
+}
+
+// GetPredictionStats 注入后查询预测统计
+func (c *ChaosDB) GetPredictionStats() (*PredictionStats, error) {
+	if err := c.injector.Maybe("db.GetPredictionStats"); err != nil {
+		return nil, err
+	}
+	return c.DB.GetPredictionStats()
+}
+
+// NewLeaderElector 转发底层后端的领导者选举能力（选举路径不注入故障）
+func (c *ChaosDB) NewLeaderElector(lockName string) *LeaderElector {
+	return NewLeaderElectorFor(c.DB, lockName)
+}
@@ -0,0 +1,14 @@
+package database
+
+import "errors"
+
+// 跨包共享的错误类别哨兵
+// api/database/predictor在包装错误时附带对应哨兵（%w），
+// 调用方（如pipeline的轮询处理）用errors.Is按错误类别分支，不再比较错误文本
+var (
+	// ErrNoData 查询/拉取成功但没有可用数据
+	ErrNoData = errors.New("no data available")
+
+	// ErrDuplicateRound 目标期已有不可覆盖的记录（如已验证的预测）
+	ErrDuplicateRound = errors.New("duplicate round")
+)
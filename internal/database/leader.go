@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"pc28-bot/internal/logger"
+)
+
+// LeaderElector 基于MySQL咨询锁(GET_LOCK)的领导者选举
+// 多实例部署时只有持有锁的实例（领导者）执行轮询、预测和广播，
+// 其余实例保持热备，领导者退出后锁自动释放实现故障切换
+type LeaderElector struct {
+	db       *sql.DB
+	lockName string
+
+	// 咨询锁与MySQL会话绑定，必须使用独占连接
+	conn     *sql.Conn
+	isLeader bool
+	mutex    sync.RWMutex
+}
+
+// LeaderElectorProvider 具备领导者选举能力的存储后端
+// （基于MySQL咨询锁实现，内存后端不具备；包装层转发底层能力）
+type LeaderElectorProvider interface {
+	NewLeaderElector(lockName string) *LeaderElector
+}
+
+// NewLeaderElectorFor 从存储后端提取领导者选举能力
+// 后端（含其包装的底层后端）不具备该能力时返回nil，
+// 调用方据此回退到单实例模式
+func NewLeaderElectorFor(db DB, lockName string) *LeaderElector {
+	if provider, ok := db.(LeaderElectorProvider); ok {
+		return provider.NewLeaderElector(lockName)
+	}
+	return nil
+}
+
+// NewLeaderElector 创建领导者选举器
+func (m *MySQLDB) NewLeaderElector(lockName string) *LeaderElector {
+	return &LeaderElector{
+		db:       m.db,
+		lockName: lockName,
+	}
+}
+
+// TryAcquire 尝试获取领导权（非阻塞）
+// 返回true表示本实例成为领导者
+// 已是领导者时每次都回查锁的持有会话：独占连接断开后服务器会
+// 释放咨询锁并可能被其他实例获取，不能只信任本地isLeader标记
+func (le *LeaderElector) TryAcquire() (bool, error) {
+	le.mutex.Lock()
+	defer le.mutex.Unlock()
+
+	if le.isLeader {
+		if le.verifyLock() {
+			return true, nil
+		}
+		// 连接断开或锁已易主，降级后走正常获取流程重新竞争
+		logger.Warnf("Leader lock lost: %s, demoting and re-contending", le.lockName)
+		le.isLeader = false
+		le.releaseConn()
+	}
+
+	// 申请独占连接保持锁的会话
+	if le.conn == nil {
+		conn, err := le.db.Conn(context.Background())
+		if err != nil {
+			return false, fmt.Errorf("failed to get connection for leader lock: %v", err)
+		}
+		le.conn = conn
+	}
+
+	var result sql.NullInt64
+	err := le.conn.QueryRowContext(context.Background(),
+		"SELECT GET_LOCK(?, 0)", le.lockName).Scan(&result)
+	if err != nil {
+		le.releaseConn()
+		return false, fmt.Errorf("failed to acquire leader lock: %v", err)
+	}
+
+	// GET_LOCK返回1表示获取成功，0表示锁被其他会话持有
+	if result.Valid && result.Int64 == 1 {
+		le.isLeader = true
+		logger.Infof("Acquired leader lock: %s", le.lockName)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// IsLeader 检查本实例是否为领导者
+func (le *LeaderElector) IsLeader() bool {
+	le.mutex.RLock()
+	defer le.mutex.RUnlock()
+	return le.isLeader
+}
+
+// Release 释放领导权
+func (le *LeaderElector) Release() error {
+	le.mutex.Lock()
+	defer le.mutex.Unlock()
+
+	if !le.isLeader {
+		le.releaseConn()
+		return nil
+	}
+
+	var result sql.NullInt64
+	err := le.conn.QueryRowContext(context.Background(),
+		"SELECT RELEASE_LOCK(?)", le.lockName).Scan(&result)
+	le.isLeader = false
+	le.releaseConn()
+
+	if err != nil {
+		return fmt.Errorf("failed to release leader lock: %v", err)
+	}
+
+	logger.Infof("Released leader lock: %s", le.lockName)
+	return nil
+}
+
+// verifyLock 校验咨询锁仍由本连接的会话持有（调用方需持有mutex）
+// IS_USED_LOCK返回持锁会话的连接ID，与本会话CONNECTION_ID()比对；
+// 查询失败（连接已断开）或已易主均视为失去领导权
+func (le *LeaderElector) verifyLock() bool {
+	if le.conn == nil {
+		return false
+	}
+
+	var held sql.NullBool
+	err := le.conn.QueryRowContext(context.Background(),
+		"SELECT IS_USED_LOCK(?) = CONNECTION_ID()", le.lockName).Scan(&held)
+	if err != nil {
+		return false
+	}
+	return held.Valid && held.Bool
+}
+
+// releaseConn 归还独占连接（调用方需持有mutex）
+func (le *LeaderElector) releaseConn() {
+	if le.conn != nil {
+		le.conn.Close()
+		le.conn = nil
+	}
+}
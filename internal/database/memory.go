@@ -0,0 +1,1354 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/qihao"
+)
+
+// MemoryDB 纯内存存储后端
+// 用于本地快速评估算法，数据不落盘，进程退出即丢失
+type MemoryDB struct {
+	mutex       sync.RWMutex
+	lotteries   map[string]*LotteryResult // 按期号索引
+	predictions []*Prediction
+	appState    map[string]string
+	auditLog    []*AuditEntry
+	feedbacks   []*Feedback
+	outbox      []*OutboxEvent
+	dailyStats  map[string]*DailyStats    // 按日期（2006-01-02）索引
+	users       map[int64]*BotUser        // 按用户chat ID索引
+	commandLog  map[string]map[string]int // 日期（2006-01-02）-> 命令 -> 使用次数
+	notifyPrefs map[int64]map[string]bool // 用户 -> 通知事件 -> 是否开启（只存显式设置）
+	follows     map[int64][]*UserFollow   // 用户 -> 跟单记录（按时间追加）
+	alertRules  []*AlertRule              // 用户自定义提醒规则（按创建顺序追加）
+	nextRuleID  int64
+	bans        map[int64]*BannedUser     // 用户 -> 封禁记录
+	features    map[string]*RoundFeatures // 期号 -> 滚动特征
+	leaderboard map[string]*LeaderboardEntry
+	gaps        map[string]time.Time      // 漏预测期号 -> 发现时间
+	timelines   map[string]*RoundTimeline // 期号 -> 各处理阶段时间戳
+	rechecks    map[int64]policyRecheck   // 预测ID -> 策略复判结果（平行存储）
+	nextID      int64
+}
+
+// policyRecheck 策略复判结果（与MySQL的recheck_*平行列对应）
+type policyRecheck struct {
+	policy    string
+	isCorrect bool
+}
+
+// NewMemoryDB 创建内存存储后端
+func NewMemoryDB() *MemoryDB {
+	logger.Info("Using in-memory storage backend (data will not persist)")
+	return &MemoryDB{
+		lotteries:   make(map[string]*LotteryResult),
+		appState:    make(map[string]string),
+		dailyStats:  make(map[string]*DailyStats),
+		users:       make(map[int64]*BotUser),
+		commandLog:  make(map[string]map[string]int),
+		notifyPrefs: make(map[int64]map[string]bool),
+		follows:     make(map[int64][]*UserFollow),
+		features:    make(map[string]*RoundFeatures),
+		leaderboard: make(map[string]*LeaderboardEntry),
+		gaps:        make(map[string]time.Time),
+		timelines:   make(map[string]*RoundTimeline),
+		rechecks:    make(map[int64]policyRecheck),
+		bans:        make(map[int64]*BannedUser),
+		nextID:      1,
+		nextRuleID:  1,
+	}
+}
+
+// Close 关闭存储后端（内存实现为空操作）
+func (m *MemoryDB) Close() error {
+	return nil
+}
+
+// SaveLotteryResult 保存开奖数据
+func (m *MemoryDB) SaveLotteryResult(result *LotteryResult) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	PopulateDerivedFields(result)
+
+	now := time.Now()
+	if existing, ok := m.lotteries[result.Qihao]; ok {
+		// 与MySQL的ON DUPLICATE KEY UPDATE语义保持一致
+		existing.OpenTime = result.OpenTime
+		existing.OpenTimeString = result.OpenTimeString
+		existing.OpenNum = result.OpenNum
+		existing.SumValue = result.SumValue
+		existing.OddEven = result.OddEven
+		existing.BigSmall = result.BigSmall
+		existing.IsTriple = result.IsTriple
+		existing.IsPair = result.IsPair
+		existing.UpdatedAt = now
+		return nil
+	}
+
+	saved := *result
+	saved.ID = m.nextID
+	saved.CreatedAt = now
+	saved.UpdatedAt = now
+	m.nextID++
+	m.lotteries[result.Qihao] = &saved
+
+	logger.Debugf("Saved lottery result: %s", result.Qihao)
+	return nil
+}
+
+// sortedLotteries 返回按期号降序排列的开奖数据副本（调用方需持有读锁）
+func (m *MemoryDB) sortedLotteries() []LotteryResult {
+	results := make([]LotteryResult, 0, len(m.lotteries))
+	for _, r := range m.lotteries {
+		results = append(results, *r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return qihao.Compare(results[i].Qihao, results[j].Qihao) > 0
+	})
+	return results
+}
+
+// GetLatestLotteryResults 获取最新的开奖数据
+func (m *MemoryDB) GetLatestLotteryResults(limit int) ([]LotteryResult, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	results := m.sortedLotteries()
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// GetLotteryResultByQihao 根据期号获取开奖数据
+func (m *MemoryDB) GetLotteryResultByQihao(qihao string) (*LotteryResult, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if result, ok := m.lotteries[qihao]; ok {
+		copied := *result
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+// GetLotteryHistory 获取历史开奖数据
+func (m *MemoryDB) GetLotteryHistory(limit int) ([]LotteryResult, error) {
+	return m.GetLatestLotteryResults(limit)
+}
+
+// SavePrediction 保存预测记录
+func (m *MemoryDB) SavePrediction(prediction *Prediction) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// 与MySQL实现一致：补算和值与单双
+	if prediction.PredictedSum == 0 {
+		if nums, err := ParseOpenNum(prediction.PredictedNum); err == nil {
+			prediction.PredictedSum = CalculateSum(nums)
+		}
+	}
+	if prediction.PredictedOddEven == "" {
+		prediction.PredictedOddEven = CalculateOddEven(prediction.PredictedSum)
+	}
+
+	// 与MySQL实现一致：已验证的预测不允许被重复保存覆盖
+	for _, pred := range m.predictions {
+		if pred.TargetQihao == prediction.TargetQihao && pred.VerifiedAt != nil {
+			return fmt.Errorf("%w: prediction for %s already verified", ErrDuplicateRound, prediction.TargetQihao)
+		}
+	}
+
+	now := time.Now()
+	saved := *prediction
+	saved.ID = m.nextID
+	saved.CreatedAt = now
+	saved.UpdatedAt = now
+	m.nextID++
+	m.predictions = append(m.predictions, &saved)
+
+	prediction.ID = saved.ID
+	logger.Debugf("Saved prediction for qihao: %s", prediction.TargetQihao)
+	return nil
+}
+
+// UpdatePredictionResult 更新预测结果
+func (m *MemoryDB) UpdatePredictionResult(qihao string, actualNum string, isCorrect bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	for _, pred := range m.predictions {
+		if pred.TargetQihao == qihao {
+			num := actualNum
+			correct := isCorrect
+			pred.ActualNum = &num
+			pred.IsCorrect = &correct
+			pred.VerifiedAt = &now
+			pred.UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+// sortedPredictions 返回按目标期号降序排列的预测副本（调用方需持有读锁）
+func (m *MemoryDB) sortedPredictions() []Prediction {
+	results := make([]Prediction, 0, len(m.predictions))
+	for _, p := range m.predictions {
+		results = append(results, *p)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return qihao.Compare(results[i].TargetQihao, results[j].TargetQihao) > 0
+	})
+	return results
+}
+
+// GetLatestPredictions 获取最新的预测记录
+func (m *MemoryDB) GetLatestPredictions(limit int) ([]Prediction, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	results := m.sortedPredictions()
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// GetPredictionByQihao 获取目标期号最新的预测记录（不存在时返回nil）
+func (m *MemoryDB) GetPredictionByQihao(qihao string) (*Prediction, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var target *Prediction
+	for _, pred := range m.predictions {
+		if pred.TargetQihao == qihao {
+			if target == nil || pred.PredictedAt.After(target.PredictedAt) {
+				target = pred
+			}
+		}
+	}
+	if target == nil {
+		return nil, nil
+	}
+
+	// 返回副本，避免调用方修改内部状态
+	clone := *target
+	return &clone, nil
+}
+
+// GetPredictionStats 获取预测统计信息
+func (m *MemoryDB) GetPredictionStats() (*PredictionStats, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stats := &PredictionStats{}
+	for _, pred := range m.predictions {
+		if pred.IsCorrect == nil {
+			continue
+		}
+		stats.TotalPredictions++
+		if *pred.IsCorrect {
+			stats.CorrectPredictions++
+		}
+		if stats.FirstPrediction.IsZero() || pred.PredictedAt.Before(stats.FirstPrediction) {
+			stats.FirstPrediction = pred.PredictedAt
+		}
+		if pred.PredictedAt.After(stats.LastPrediction) {
+			stats.LastPrediction = pred.PredictedAt
+		}
+	}
+
+	if stats.TotalPredictions > 0 {
+		stats.AccuracyRate = float64(stats.CorrectPredictions) / float64(stats.TotalPredictions) * 100
+	}
+
+	// 影子算法平均分歧度（仅统计计算过分歧度的记录）
+	disagreementSum, disagreementCount := 0.0, 0
+	for _, pred := range m.predictions {
+		if pred.IsCorrect == nil || pred.Disagreement == nil {
+			continue
+		}
+		disagreementSum += *pred.Disagreement
+		disagreementCount++
+	}
+	if disagreementCount > 0 {
+		avg := disagreementSum / float64(disagreementCount)
+		stats.AvgDisagreement = &avg
+	}
+
+	stats.CoverageGaps = len(m.gaps)
+	return stats, nil
+}
+
+// GetPredictionStatsWindow 获取最近window条已验证预测的滚动统计（window<=0为全量口径）
+func (m *MemoryDB) GetPredictionStatsWindow(window int) (*PredictionStats, error) {
+	if window <= 0 {
+		return m.GetPredictionStats()
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var verified []*Prediction
+	for _, pred := range m.predictions {
+		if pred.IsCorrect != nil {
+			verified = append(verified, pred)
+		}
+	}
+	if len(verified) > window {
+		verified = verified[len(verified)-window:]
+	}
+
+	stats := &PredictionStats{CoverageGaps: len(m.gaps)}
+	disagreementSum, disagreementCount := 0.0, 0
+	for _, pred := range verified {
+		stats.TotalPredictions++
+		if *pred.IsCorrect {
+			stats.CorrectPredictions++
+		}
+		if stats.FirstPrediction.IsZero() || pred.PredictedAt.Before(stats.FirstPrediction) {
+			stats.FirstPrediction = pred.PredictedAt
+		}
+		if pred.PredictedAt.After(stats.LastPrediction) {
+			stats.LastPrediction = pred.PredictedAt
+		}
+		if pred.Disagreement != nil {
+			disagreementSum += *pred.Disagreement
+			disagreementCount++
+		}
+	}
+
+	if stats.TotalPredictions > 0 {
+		stats.AccuracyRate = float64(stats.CorrectPredictions) / float64(stats.TotalPredictions) * 100
+	}
+	if disagreementCount > 0 {
+		avg := disagreementSum / float64(disagreementCount)
+		stats.AvgDisagreement = &avg
+	}
+
+	return stats, nil
+}
+
+// SaveVerificationResult 保存预测验证结果（命中判定由predictor层的策略完成）
+func (m *MemoryDB) SaveVerificationResult(qihao string, actualResult *LotteryResult, isCorrect bool, policy string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// 查找目标期号最新的预测记录
+	var target *Prediction
+	for _, pred := range m.predictions {
+		if pred.TargetQihao == qihao {
+			if target == nil || pred.PredictedAt.After(target.PredictedAt) {
+				target = pred
+			}
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("no prediction found for qihao: %s", qihao)
+	}
+
+	// 实际单双随验证结果一并记录（与MySQL实现保持一致）
+	actualOddEven := CalculateOddEven(actualResult.SumValue)
+
+	now := time.Now()
+	actualNum := actualResult.OpenNum
+	actualSum := actualResult.SumValue
+	target.ActualNum = &actualNum
+	target.ActualSum = &actualSum
+	target.ActualOddEven = &actualOddEven
+	target.IsCorrect = &isCorrect
+	target.ValidationPolicy = &policy
+	target.VerifiedAt = &now
+	target.UpdatedAt = now
+
+	return nil
+}
+
+// SavePolicyRecheck 写入策略复判结果（平行存储，不改动is_correct）
+func (m *MemoryDB) SavePolicyRecheck(id int64, policy string, isCorrect bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.rechecks[id] = policyRecheck{policy: policy, isCorrect: isCorrect}
+	return nil
+}
+
+// SavePositionHits 记录预测验证时按位置命中的数量
+func (m *MemoryDB) SavePositionHits(qihao string, hits int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var target *Prediction
+	for _, pred := range m.predictions {
+		if pred.TargetQihao == qihao {
+			if target == nil || pred.PredictedAt.After(target.PredictedAt) {
+				target = pred
+			}
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("no prediction found for qihao: %s", qihao)
+	}
+
+	target.PositionHits = &hits
+	target.UpdatedAt = time.Now()
+	return nil
+}
+
+// CleanOldData 清理超出保留窗口的旧数据
+func (m *MemoryDB) CleanOldData(retentionHours int) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if retentionHours <= 0 {
+		retentionHours = 24
+	}
+	cutoff := time.Now().Add(-time.Duration(retentionHours) * time.Hour)
+	for qihao, result := range m.lotteries {
+		if result.CreatedAt.Before(cutoff) {
+			delete(m.lotteries, qihao)
+		}
+	}
+
+	kept := m.predictions[:0]
+	for _, pred := range m.predictions {
+		if !pred.PredictedAt.Before(cutoff) {
+			kept = append(kept, pred)
+		}
+	}
+	m.predictions = kept
+	return nil
+}
+
+// GetLotteryResultsBefore 获取开奖时间早于cutoff的开奖数据（按开奖时间升序，最多limit条）
+func (m *MemoryDB) GetLotteryResultsBefore(cutoff time.Time, limit int) ([]LotteryResult, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var results []LotteryResult
+	for _, r := range m.lotteries {
+		if r.OpenTime.Before(cutoff) {
+			results = append(results, *r)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].OpenTime.Before(results[j].OpenTime)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// DeleteLotteryResultsByQihao 按期号批量删除开奖数据
+func (m *MemoryDB) DeleteLotteryResultsByQihao(qihaos []string) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	deleted := 0
+	for _, q := range qihaos {
+		if _, ok := m.lotteries[q]; ok {
+			delete(m.lotteries, q)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// RestoreLotteryResults 批量恢复开奖数据（已存在的期号跳过不覆盖）
+func (m *MemoryDB) RestoreLotteryResults(results []LotteryResult) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	inserted := 0
+	for i := range results {
+		if _, ok := m.lotteries[results[i].Qihao]; ok {
+			continue
+		}
+
+		saved := results[i]
+		PopulateDerivedFields(&saved)
+		saved.ID = m.nextID
+		saved.CreatedAt = now
+		saved.UpdatedAt = now
+		m.nextID++
+		m.lotteries[saved.Qihao] = &saved
+		inserted++
+	}
+	return inserted, nil
+}
+
+// CheckNewQihao 检查是否有新的期号
+func (m *MemoryDB) CheckNewQihao(qihao string) (bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	_, exists := m.lotteries[qihao]
+	return !exists, nil
+}
+
+// GetUnverifiedPredictions 获取所有未验证的预测记录
+func (m *MemoryDB) GetUnverifiedPredictions() ([]Prediction, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var results []Prediction
+	for _, pred := range m.predictions {
+		if pred.IsCorrect == nil && pred.ActualNum == nil {
+			results = append(results, *pred)
+		}
+	}
+	return results, nil
+}
+
+// GetPendingPredictionsBefore 获取目标期号不大于指定期号且未验证的预测（按期号升序）
+func (m *MemoryDB) GetPendingPredictionsBefore(beforeQihao string) ([]Prediction, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var results []Prediction
+	for _, pred := range m.predictions {
+		if pred.IsCorrect == nil && pred.ActualNum == nil && qihao.Compare(pred.TargetQihao, beforeQihao) <= 0 {
+			results = append(results, *pred)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return qihao.Compare(results[i].TargetQihao, results[j].TargetQihao) < 0
+	})
+	return results, nil
+}
+
+// CleanupExpiredPredictions 清理过期的待开奖预测记录
+func (m *MemoryDB) CleanupExpiredPredictions(latestQihao string) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cleaned := 0
+	kept := m.predictions[:0]
+	for _, pred := range m.predictions {
+		if qihao.Compare(pred.TargetQihao, latestQihao) < 0 && pred.IsCorrect == nil && pred.ActualNum == nil {
+			cleaned++
+			continue
+		}
+		kept = append(kept, pred)
+	}
+	m.predictions = kept
+	return cleaned, nil
+}
+
+// SaveAppState 保存应用状态项
+func (m *MemoryDB) SaveAppState(key, value string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.appState[key] = value
+	return nil
+}
+
+// GetSumDistribution 统计最近N期开奖和值的分布
+func (m *MemoryDB) GetSumDistribution(limit int) (map[int]int, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	results := m.sortedLotteries()
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	distribution := make(map[int]int)
+	for _, result := range results {
+		distribution[result.SumValue]++
+	}
+	return distribution, nil
+}
+
+// GetPositionDistribution 统计最近N期开奖各位置数字的分布
+func (m *MemoryDB) GetPositionDistribution(limit int) (PositionDistribution, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	results := m.sortedLotteries()
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	var distribution PositionDistribution
+	for _, result := range results {
+		nums, err := ParseOpenNum(result.OpenNum)
+		if err != nil || len(nums) != 3 {
+			continue
+		}
+		for position, digit := range nums {
+			if digit >= 0 && digit <= 9 {
+				distribution[position][digit]++
+			}
+		}
+	}
+	return distribution, nil
+}
+
+// SaveAuditEntry 保存管理员操作审计记录
+func (m *MemoryDB) SaveAuditEntry(entry *AuditEntry) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	saved := *entry
+	saved.ID = m.nextID
+	saved.CreatedAt = time.Now()
+	m.nextID++
+	m.auditLog = append(m.auditLog, &saved)
+
+	entry.ID = saved.ID
+	return nil
+}
+
+// SaveFeedback 保存用户反馈
+func (m *MemoryDB) SaveFeedback(feedback *Feedback) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	saved := *feedback
+	saved.ID = m.nextID
+	saved.CreatedAt = time.Now()
+	m.nextID++
+	m.feedbacks = append(m.feedbacks, &saved)
+
+	feedback.ID = saved.ID
+	return nil
+}
+
+// GetFeedbackEntries 获取最新的用户反馈
+func (m *MemoryDB) GetFeedbackEntries(limit int) ([]Feedback, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var entries []Feedback
+	for i := len(m.feedbacks) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, *m.feedbacks[i])
+	}
+	return entries, nil
+}
+
+// SaveOutboxEvent 写入领域事件outbox记录（写入后回填事件ID）
+func (m *MemoryDB) SaveOutboxEvent(event *OutboxEvent) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	saved := *event
+	saved.ID = m.nextID
+	saved.CreatedAt = time.Now()
+	m.nextID++
+	m.outbox = append(m.outbox, &saved)
+
+	event.ID = saved.ID
+	return nil
+}
+
+// GetPendingOutboxEvents 获取待投递的领域事件（按写入顺序）
+func (m *MemoryDB) GetPendingOutboxEvents(limit int) ([]OutboxEvent, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var events []OutboxEvent
+	for _, event := range m.outbox {
+		if event.DispatchedAt != nil {
+			continue
+		}
+		events = append(events, *event)
+		if len(events) >= limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// MarkOutboxDispatched 标记领域事件已投递
+func (m *MemoryDB) MarkOutboxDispatched(id int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, event := range m.outbox {
+		if event.ID == id {
+			now := time.Now()
+			event.DispatchedAt = &now
+			return nil
+		}
+	}
+	return nil
+}
+
+// GetAuditEntries 获取最新的审计记录
+func (m *MemoryDB) GetAuditEntries(limit int) ([]AuditEntry, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var entries []AuditEntry
+	for i := len(m.auditLog) - 1; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, *m.auditLog[i])
+	}
+	return entries, nil
+}
+
+// SaveDailyStats 保存每日统计快照（同日期覆盖更新）
+func (m *MemoryDB) SaveDailyStats(stats *DailyStats) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	dateKey := stats.StatDate.Format("2006-01-02")
+	if existing, ok := m.dailyStats[dateKey]; ok {
+		existing.TotalPredictions = stats.TotalPredictions
+		existing.CorrectPredictions = stats.CorrectPredictions
+		existing.AccuracyRate = stats.AccuracyRate
+		existing.AccuracyLow95 = stats.AccuracyLow95
+		existing.AccuracyHigh95 = stats.AccuracyHigh95
+		existing.BestStreak = stats.BestStreak
+		existing.WorstStreak = stats.WorstStreak
+		existing.Algorithm = stats.Algorithm
+		return nil
+	}
+
+	saved := *stats
+	saved.ID = m.nextID
+	saved.CreatedAt = time.Now()
+	m.nextID++
+	m.dailyStats[dateKey] = &saved
+	return nil
+}
+
+// GetDailyStats 获取最新的每日统计快照
+func (m *MemoryDB) GetDailyStats(limit int) ([]DailyStats, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	results := make([]DailyStats, 0, len(m.dailyStats))
+	for _, stats := range m.dailyStats {
+		results = append(results, *stats)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].StatDate.After(results[j].StatDate)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// RecordUserActivity 记录用户命令活跃（首次出现时登记为新用户）
+func (m *MemoryDB) RecordUserActivity(userID int64, command string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	if user, ok := m.users[userID]; ok {
+		user.LastSeenAt = now
+	} else {
+		m.users[userID] = &BotUser{UserID: userID, FirstSeenAt: now, LastSeenAt: now}
+	}
+
+	dateKey := now.Format("2006-01-02")
+	if m.commandLog[dateKey] == nil {
+		m.commandLog[dateKey] = make(map[string]int)
+	}
+	m.commandLog[dateKey][command]++
+	return nil
+}
+
+// GetEngagementStats 统计用户增长与命令使用情况（windowDays为流失判定和命令统计窗口）
+func (m *MemoryDB) GetEngagementStats(windowDays int) (*EngagementStats, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if windowDays <= 0 {
+		windowDays = 7
+	}
+
+	stats := &EngagementStats{
+		WindowDays:    windowDays,
+		CommandCounts: make(map[string]int),
+	}
+
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	churnBefore := now.AddDate(0, 0, -windowDays)
+
+	stats.TotalUsers = len(m.users)
+	for _, user := range m.users {
+		if user.LastSeenAt.Format("2006-01-02") == today {
+			stats.ActiveToday++
+		}
+		if user.FirstSeenAt.Format("2006-01-02") == today {
+			stats.NewToday++
+		}
+		if user.LastSeenAt.Before(churnBefore) {
+			stats.ChurnedUsers++
+		}
+	}
+
+	for dateKey, counts := range m.commandLog {
+		day, err := time.Parse("2006-01-02", dateKey)
+		if err != nil || day.Before(churnBefore) {
+			continue
+		}
+		for command, count := range counts {
+			stats.CommandCounts[command] += count
+		}
+	}
+	return stats, nil
+}
+
+// BanUser 封禁用户（同用户重复封禁为覆盖更新）
+func (m *MemoryDB) BanUser(ban *BannedUser) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	saved := *ban
+	saved.CreatedAt = time.Now()
+	m.bans[ban.UserID] = &saved
+	return nil
+}
+
+// UnbanUser 解除用户封禁，返回是否存在封禁记录
+func (m *MemoryDB) UnbanUser(userID int64) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.bans[userID]; !ok {
+		return false, nil
+	}
+	delete(m.bans, userID)
+	return true, nil
+}
+
+// GetBan 获取用户的有效封禁记录（无封禁或已过期时返回nil）
+func (m *MemoryDB) GetBan(userID int64) (*BannedUser, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	ban, ok := m.bans[userID]
+	if !ok {
+		return nil, nil
+	}
+	if ban.ExpiresAt != nil && time.Now().After(*ban.ExpiresAt) {
+		return nil, nil
+	}
+	result := *ban
+	return &result, nil
+}
+
+// SetNotifyPreference 设置用户对某类通知事件的开关
+func (m *MemoryDB) SetNotifyPreference(userID int64, event string, enabled bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.notifyPrefs[userID] == nil {
+		m.notifyPrefs[userID] = make(map[string]bool)
+	}
+	m.notifyPrefs[userID][event] = enabled
+	return nil
+}
+
+// GetNotifyPreferences 获取用户显式设置过的通知开关（未设置的事件默认开启）
+func (m *MemoryDB) GetNotifyPreferences(userID int64) (map[string]bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	prefs := make(map[string]bool, len(m.notifyPrefs[userID]))
+	for event, enabled := range m.notifyPrefs[userID] {
+		prefs[event] = enabled
+	}
+	return prefs, nil
+}
+
+// GetSubscribersFor 获取未关闭指定通知事件的全部用户
+func (m *MemoryDB) GetSubscribersFor(event string) ([]int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var users []int64
+	for userID := range m.users {
+		if prefs, ok := m.notifyPrefs[userID]; ok {
+			if enabled, set := prefs[event]; set && !enabled {
+				continue
+			}
+		}
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+// GetOptInSubscribersFor 获取显式开启指定通知事件的全部用户（opt-in事件默认关闭）
+func (m *MemoryDB) GetOptInSubscribersFor(event string) ([]int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var users []int64
+	for userID, prefs := range m.notifyPrefs {
+		if prefs[event] {
+			users = append(users, userID)
+		}
+	}
+	return users, nil
+}
+
+// SetUserLanguage 设置用户界面语言
+func (m *MemoryDB) SetUserLanguage(userID int64, language string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	if user, ok := m.users[userID]; ok {
+		user.Language = language
+		return nil
+	}
+	m.users[userID] = &BotUser{UserID: userID, Language: language, FirstSeenAt: now, LastSeenAt: now}
+	return nil
+}
+
+// SetUserTimezone 设置用户时区
+func (m *MemoryDB) SetUserTimezone(userID int64, timezone string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	if user, ok := m.users[userID]; ok {
+		user.Timezone = timezone
+		return nil
+	}
+	m.users[userID] = &BotUser{UserID: userID, Timezone: timezone, FirstSeenAt: now, LastSeenAt: now}
+	return nil
+}
+
+// GetUserTimezone 获取用户时区（未设置时返回空字符串）
+func (m *MemoryDB) GetUserTimezone(userID int64) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if user, ok := m.users[userID]; ok {
+		return user.Timezone, nil
+	}
+	return "", nil
+}
+
+// SetUserTier 设置用户层级
+func (m *MemoryDB) SetUserTier(userID int64, tier string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	if user, ok := m.users[userID]; ok {
+		user.Tier = tier
+		return nil
+	}
+	m.users[userID] = &BotUser{UserID: userID, Tier: tier, FirstSeenAt: now, LastSeenAt: now}
+	return nil
+}
+
+// GetBroadcastTargets 按受众选择器获取定向推送的目标用户
+// 选择器见store.go接口注释：层级、语言、活跃时段和个人跟单准确率分段
+func (m *MemoryDB) GetBroadcastTargets(audience string) ([]int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	// 准确率分段基于跟单记录而非用户表，单独处理
+	if strings.HasPrefix(audience, "accuracy_gte:") || strings.HasPrefix(audience, "accuracy_lt:") {
+		return m.accuracySegmentLocked(audience)
+	}
+
+	var users []int64
+	for userID, user := range m.users {
+		switch {
+		case audience == "" || audience == "all":
+		case audience == UserTierVIP:
+			if user.Tier != UserTierVIP {
+				continue
+			}
+		case strings.HasPrefix(audience, "lang:"):
+			if user.Language != strings.TrimPrefix(audience, "lang:") {
+				continue
+			}
+		case strings.HasPrefix(audience, "inactive:"):
+			days, err := parseSegmentValue(audience)
+			if err != nil {
+				return nil, err
+			}
+			if time.Since(user.LastSeenAt) < time.Duration(days)*24*time.Hour {
+				continue
+			}
+		case strings.HasPrefix(audience, "active:"):
+			days, err := parseSegmentValue(audience)
+			if err != nil {
+				return nil, err
+			}
+			if time.Since(user.LastSeenAt) >= time.Duration(days)*24*time.Hour {
+				continue
+			}
+		default:
+			return nil, fmt.Errorf("unknown broadcast audience: %s", audience)
+		}
+		users = append(users, userID)
+	}
+	return users, nil
+}
+
+// accuracySegmentLocked 按个人跟单准确率筛选用户（调用方需持有读锁）
+// 个人准确率 = 用户跟单中已验证预测的命中率，无已验证跟单的用户不入选
+func (m *MemoryDB) accuracySegmentLocked(audience string) ([]int64, error) {
+	threshold, err := parseSegmentValue(audience)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已验证预测的命中结果（按目标期号索引）
+	verified := make(map[string]bool)
+	for _, pred := range m.predictions {
+		if pred.IsCorrect != nil {
+			verified[pred.TargetQihao] = *pred.IsCorrect
+		}
+	}
+
+	var users []int64
+	for userID, follows := range m.follows {
+		correct, total := 0, 0
+		for _, follow := range follows {
+			isCorrect, ok := verified[follow.Qihao]
+			if !ok {
+				continue
+			}
+			total++
+			if isCorrect {
+				correct++
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		accuracy := float64(correct) / float64(total) * 100
+		if strings.HasPrefix(audience, "accuracy_gte:") && accuracy >= float64(threshold) {
+			users = append(users, userID)
+		} else if strings.HasPrefix(audience, "accuracy_lt:") && accuracy < float64(threshold) {
+			users = append(users, userID)
+		}
+	}
+	return users, nil
+}
+
+// GetUserLanguage 获取用户界面语言（未设置时返回空字符串）
+func (m *MemoryDB) GetUserLanguage(userID int64) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if user, ok := m.users[userID]; ok {
+		return user.Language, nil
+	}
+	return "", nil
+}
+
+// SaveCoverageGap 记录漏预测的期号（幂等）
+func (m *MemoryDB) SaveCoverageGap(qihao string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.gaps[qihao]; !exists {
+		m.gaps[qihao] = time.Now()
+	}
+	return nil
+}
+
+// SaveRoundStageTime 记录单期处理阶段的时间戳（stage见Stage*常量，幂等覆盖）
+func (m *MemoryDB) SaveRoundStageTime(qihao, stage string, at time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	timeline, ok := m.timelines[qihao]
+	if !ok {
+		timeline = &RoundTimeline{Qihao: qihao}
+		m.timelines[qihao] = timeline
+	}
+
+	stamp := at
+	switch stage {
+	case StageFetched:
+		timeline.FetchedAt = &stamp
+	case StageSaved:
+		timeline.SavedAt = &stamp
+	case StageVerified:
+		timeline.VerifiedAt = &stamp
+	case StagePredicted:
+		timeline.PredictedAt = &stamp
+	case StageBroadcast:
+		timeline.BroadcastAt = &stamp
+	default:
+		return fmt.Errorf("unknown round stage: %s", stage)
+	}
+	return nil
+}
+
+// GetRoundTimeline 获取单期各处理阶段的时间戳（不存在时返回nil）
+func (m *MemoryDB) GetRoundTimeline(qihao string) (*RoundTimeline, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	timeline, ok := m.timelines[qihao]
+	if !ok {
+		return nil, nil
+	}
+	copied := *timeline
+	return &copied, nil
+}
+
+// SaveLeaderboardEntry 保存算法榜单条目（同算法覆盖更新）
+func (m *MemoryDB) SaveLeaderboardEntry(entry *LeaderboardEntry) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stored := *entry
+	stored.EvaluatedAt = time.Now()
+	m.leaderboard[entry.Algorithm] = &stored
+	return nil
+}
+
+// GetLeaderboard 获取算法榜单（按准确率降序）
+func (m *MemoryDB) GetLeaderboard() ([]LeaderboardEntry, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var entries []LeaderboardEntry
+	for _, entry := range m.leaderboard {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].AccuracyRate != entries[j].AccuracyRate {
+			return entries[i].AccuracyRate > entries[j].AccuracyRate
+		}
+		return entries[i].Algorithm < entries[j].Algorithm
+	})
+	return entries, nil
+}
+
+// SaveUserFollow 保存用户跟单记录（重复跟单同一期为幂等操作）
+func (m *MemoryDB) SaveUserFollow(userID int64, qihao string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, follow := range m.follows[userID] {
+		if follow.Qihao == qihao {
+			return nil
+		}
+	}
+	m.follows[userID] = append(m.follows[userID], &UserFollow{
+		UserID:    userID,
+		Qihao:     qihao,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+// GetUserFollows 获取用户最近的跟单记录（按跟单时间降序）
+func (m *MemoryDB) GetUserFollows(userID int64, limit int) ([]UserFollow, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stored := m.follows[userID]
+	var follows []UserFollow
+	for i := len(stored) - 1; i >= 0 && len(follows) < limit; i-- {
+		follows = append(follows, *stored[i])
+	}
+	return follows, nil
+}
+
+// GetUserDataExport 汇总该用户存储的全部个人数据（/mydata导出用）
+func (m *MemoryDB) GetUserDataExport(userID int64) (*UserDataExport, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	export := &UserDataExport{
+		UserID:     userID,
+		ExportedAt: time.Now(),
+	}
+
+	if user, ok := m.users[userID]; ok {
+		copied := *user
+		export.User = &copied
+	}
+
+	if prefs, ok := m.notifyPrefs[userID]; ok && len(prefs) > 0 {
+		export.NotifyPrefs = make(map[string]bool, len(prefs))
+		for event, enabled := range prefs {
+			export.NotifyPrefs[event] = enabled
+		}
+	}
+
+	for _, rule := range m.alertRules {
+		if rule.UserID == userID {
+			export.AlertRules = append(export.AlertRules, *rule)
+		}
+	}
+	for i := len(m.follows[userID]) - 1; i >= 0; i-- {
+		export.Follows = append(export.Follows, *m.follows[userID][i])
+	}
+	for _, entry := range m.feedbacks {
+		if entry.UserID == userID {
+			export.Feedback = append(export.Feedback, *entry)
+		}
+	}
+	return export, nil
+}
+
+// DeleteUserData 删除该用户的全部个人数据，返回删除的记录总数
+// （封禁记录不删除，否则封禁可被"遗忘"规避）
+func (m *MemoryDB) DeleteUserData(userID int64) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	deleted := 0
+	if _, ok := m.users[userID]; ok {
+		delete(m.users, userID)
+		deleted++
+	}
+	deleted += len(m.notifyPrefs[userID])
+	delete(m.notifyPrefs, userID)
+	deleted += len(m.follows[userID])
+	delete(m.follows, userID)
+
+	keptRules := m.alertRules[:0]
+	for _, rule := range m.alertRules {
+		if rule.UserID == userID {
+			deleted++
+			continue
+		}
+		keptRules = append(keptRules, rule)
+	}
+	m.alertRules = keptRules
+
+	keptFeedback := m.feedbacks[:0]
+	for _, entry := range m.feedbacks {
+		if entry.UserID == userID {
+			deleted++
+			continue
+		}
+		keptFeedback = append(keptFeedback, entry)
+	}
+	m.feedbacks = keptFeedback
+
+	return deleted, nil
+}
+
+// SaveAlertRule 保存用户自定义提醒规则（同用户同规则同阈值为幂等操作）
+func (m *MemoryDB) SaveAlertRule(rule *AlertRule) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, existing := range m.alertRules {
+		if existing.UserID == rule.UserID && existing.RuleType == rule.RuleType && existing.Threshold == rule.Threshold {
+			return nil
+		}
+	}
+
+	saved := *rule
+	saved.ID = m.nextRuleID
+	saved.CreatedAt = time.Now()
+	m.nextRuleID++
+	m.alertRules = append(m.alertRules, &saved)
+	rule.ID = saved.ID
+	return nil
+}
+
+// GetAlertRules 获取用户的提醒规则（按创建时间升序）
+func (m *MemoryDB) GetAlertRules(userID int64) ([]AlertRule, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var rules []AlertRule
+	for _, rule := range m.alertRules {
+		if rule.UserID == userID {
+			rules = append(rules, *rule)
+		}
+	}
+	return rules, nil
+}
+
+// GetAllAlertRules 获取全部提醒规则（供pipeline逐期评估）
+func (m *MemoryDB) GetAllAlertRules() ([]AlertRule, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rules := make([]AlertRule, 0, len(m.alertRules))
+	for _, rule := range m.alertRules {
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+// DeleteAlertRules 删除用户的全部提醒规则，返回删除条数
+func (m *MemoryDB) DeleteAlertRules(userID int64) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	kept := m.alertRules[:0]
+	deleted := 0
+	for _, rule := range m.alertRules {
+		if rule.UserID == userID {
+			deleted++
+			continue
+		}
+		kept = append(kept, rule)
+	}
+	m.alertRules = kept
+	return deleted, nil
+}
+
+// SaveRoundFeatures 保存单期滚动特征（同期号覆盖更新）
+func (m *MemoryDB) SaveRoundFeatures(features *RoundFeatures) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stored := *features
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	m.features[features.Qihao] = &stored
+	return nil
+}
+
+// GetRoundFeatures 获取指定期号的滚动特征（不存在时返回nil）
+func (m *MemoryDB) GetRoundFeatures(qihao string) (*RoundFeatures, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	features, exists := m.features[qihao]
+	if !exists {
+		return nil, nil
+	}
+	result := *features
+	return &result, nil
+}
+
+// GetAppState 读取应用状态项（不存在时返回空字符串）
+func (m *MemoryDB) GetAppState(key string) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.appState[key], nil
+}
+
+// GetNextQihao 获取下一个期号
+func (m *MemoryDB) GetNextQihao() (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	results := m.sortedLotteries()
+	if len(results) == 0 {
+		return "3326001", nil // 默认起始期号
+	}
+
+	return qihao.Next(results[0].Qihao)
+}
@@ -12,10 +12,77 @@ type LotteryResult struct {
 	OpenTimeString string    `json:"opentime_string" db:"opentime_string"` // API原始时间字符串
 	OpenNum        string    `json:"opennum" db:"opennum"`
 	SumValue       int       `json:"sum_value" db:"sum_value"`
+	OddEven        string    `json:"odd_even" db:"odd_even"`   // 派生列：和值单双（保存时计算）
+	BigSmall       string    `json:"big_small" db:"big_small"` // 派生列：和值大小（>=14为大）
+	IsTriple       bool      `json:"is_triple" db:"is_triple"` // 派生列：豹子（三位数字全同）
+	IsPair         bool      `json:"is_pair" db:"is_pair"`     // 派生列：对子（恰好两位数字相同）
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// AuditEntry 管理员操作审计记录
+type AuditEntry struct {
+	ID        int64     `json:"id" db:"id"`
+	Actor     int64     `json:"actor" db:"actor"` // 操作者chat ID，0表示系统
+	Action    string    `json:"action" db:"action"`
+	Params    string    `json:"params" db:"params"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Feedback 用户反馈记录（/feedback多步会话收集）
+type Feedback struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Content   string    `json:"content" db:"content"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// UserDataExport 单个用户存储的全部个人数据汇总（/mydata导出用）
+// User为nil表示该用户从未登记（无任何个人数据）
+type UserDataExport struct {
+	UserID      int64           `json:"user_id"`
+	User        *BotUser        `json:"user,omitempty"`
+	NotifyPrefs map[string]bool `json:"notify_prefs,omitempty"`
+	AlertRules  []AlertRule     `json:"alert_rules,omitempty"`
+	Follows     []UserFollow    `json:"follows,omitempty"`
+	Feedback    []Feedback      `json:"feedback,omitempty"`
+	ExportedAt  time.Time       `json:"exported_at"`
+}
+
+// 领域事件类型（outbox模式，见OutboxEvent）
+const (
+	OutboxNewRound           = "new_round"           // 新一期开奖入库
+	OutboxPredictionCreated  = "prediction_created"  // 新预测生成
+	OutboxPredictionVerified = "prediction_verified" // 预测验证完成
+)
+
+// OutboxEvent 领域事件outbox记录
+// pipeline在数据落库的同时写入事件，广播成功后标记已投递；
+// 进程崩溃或发送失败时由补投循环重新投递，保证广播不丢失（至少一次）
+type OutboxEvent struct {
+	ID           int64      `json:"id" db:"id"`
+	EventType    string     `json:"event_type" db:"event_type"`
+	Qihao        string     `json:"qihao" db:"qihao"` // 事件关联的期号
+	Payload      string     `json:"payload" db:"payload"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	DispatchedAt *time.Time `json:"dispatched_at" db:"dispatched_at"` // nil表示待投递
+}
+
+// DailyStats 每日统计快照模型
+type DailyStats struct {
+	ID                 int64     `json:"id" db:"id"`
+	StatDate           time.Time `json:"stat_date" db:"stat_date"`
+	TotalPredictions   int       `json:"total_predictions" db:"total_predictions"`
+	CorrectPredictions int       `json:"correct_predictions" db:"correct_predictions"`
+	AccuracyRate       float64   `json:"accuracy_rate" db:"accuracy_rate"`
+	AccuracyLow95      float64   `json:"accuracy_low_95" db:"accuracy_low_95"`   // 准确率Wilson区间下界（95%置信水平）
+	AccuracyHigh95     float64   `json:"accuracy_high_95" db:"accuracy_high_95"` // 准确率Wilson区间上界
+	BestStreak         int       `json:"best_streak" db:"best_streak"`           // 当日最长连对
+	WorstStreak        int       `json:"worst_streak" db:"worst_streak"`         // 当日最长连错
+	Algorithm          string    `json:"algorithm" db:"algorithm"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+}
+
 // Prediction 预测记录模型
 type Prediction struct {
 	ID               int64      `json:"id" db:"id"`
@@ -27,7 +94,11 @@ type Prediction struct {
 	ActualSum        *int       `json:"actual_sum" db:"actual_sum"`
 	ActualOddEven    *string    `json:"actual_odd_even" db:"actual_odd_even"` // 实际单双：单/双
 	IsCorrect        *bool      `json:"is_correct" db:"is_correct"`
+	ValidationPolicy *string    `json:"validation_policy" db:"validation_policy"` // 验证时使用的命中判定策略
 	ConfidenceScore  *float64   `json:"confidence_score" db:"confidence_score"`
+	Disagreement     *float64   `json:"disagreement" db:"disagreement"`   // 影子算法分歧度（0-100，多算法注册时计算）
+	PositionNums     *string    `json:"position_nums" db:"position_nums"` // 位置级预测号码（逗号分隔，预测器不支持位置级预测时为NULL）
+	PositionHits     *int       `json:"position_hits" db:"position_hits"` // 验证时按位置命中的数量（0-3，验证器回填）
 	AlgorithmVersion string     `json:"algorithm_version" db:"algorithm_version"`
 	PredictedAt      time.Time  `json:"predicted_at" db:"predicted_at"`
 	VerifiedAt       *time.Time `json:"verified_at" db:"verified_at"`
@@ -35,6 +106,118 @@ type Prediction struct {
 	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// BotUser 机器人用户活跃记录（用于增长与流失分析）
+type BotUser struct {
+	UserID      int64     `json:"user_id" db:"user_id"`
+	Language    string    `json:"language" db:"language"` // 界面语言（空表示未完成引导）
+	Timezone    string    `json:"timezone" db:"timezone"` // IANA时区（空表示使用服务器时区）
+	Tier        string    `json:"tier" db:"tier"`         // 用户层级（空表示普通用户，见UserTierVIP）
+	FirstSeenAt time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// UserTierVIP VIP用户层级（定向推送中的VIP受众）
+const UserTierVIP = "vip"
+
+// BannedUser 用户封禁记录（ExpiresAt为nil表示永久封禁）
+type BannedUser struct {
+	UserID    int64      `json:"user_id" db:"user_id"`
+	Reason    string     `json:"reason" db:"reason"`
+	BannedBy  int64      `json:"banned_by" db:"banned_by"` // 操作者chat ID，0表示系统自动封禁
+	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// EngagementStats 用户增长与活跃度统计
+type EngagementStats struct {
+	TotalUsers    int            `json:"total_users"`    // 累计用户数
+	ActiveToday   int            `json:"active_today"`   // 今日活跃用户数
+	NewToday      int            `json:"new_today"`      // 今日新增用户数
+	ChurnedUsers  int            `json:"churned_users"`  // 超过窗口天数未活跃的用户数
+	WindowDays    int            `json:"window_days"`    // 统计窗口天数
+	CommandCounts map[string]int `json:"command_counts"` // 窗口内各命令使用次数
+}
+
+// UserFollow 用户跟单记录（用户对某期预测点击👍跟单）
+type UserFollow struct {
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Qihao     string    `json:"qihao" db:"qihao"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// 用户自定义提醒规则类型
+const (
+	AlertRuleStreakOdd   = "streak_odd"   // 连续N期单
+	AlertRuleStreakEven  = "streak_even"  // 连续N期双
+	AlertRuleStreakBig   = "streak_big"   // 连续N期大
+	AlertRuleStreakSmall = "streak_small" // 连续N期小
+	AlertRuleSumGte      = "sum_gte"      // 和值达到N
+)
+
+// AlertRule 用户自定义提醒规则（每期开奖后由pipeline评估，命中时通知规则所有者）
+type AlertRule struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	RuleType  string    `json:"rule_type" db:"rule_type"` // 见AlertRule*常量
+	Threshold int       `json:"threshold" db:"threshold"` // 连续期数或和值阈值
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LeaderboardEntry 算法交叉验证榜单条目（定时任务滚动评估产出，同算法覆盖更新）
+type LeaderboardEntry struct {
+	Algorithm    string    `json:"algorithm" db:"algorithm"`
+	Version      string    `json:"version" db:"version"`
+	Rounds       int       `json:"rounds" db:"rounds"`
+	Correct      int       `json:"correct" db:"correct"`
+	AccuracyRate float64   `json:"accuracy_rate" db:"accuracy_rate"`
+	BestStreak   int       `json:"best_streak" db:"best_streak"`
+	WorstStreak  int       `json:"worst_streak" db:"worst_streak"`
+	EvaluatedAt  time.Time `json:"evaluated_at" db:"evaluated_at"`
+}
+
+// RoundFeatures 单期滚动特征（由predictor层计算，按期号持久化，
+// 统计与ML预测器消费同一份特征输入以保证口径一致）
+type RoundFeatures struct {
+	Qihao          string    `json:"qihao" db:"qihao"`
+	OddEvenSeq     string    `json:"odd_even_seq" db:"odd_even_seq"`         // 最近K期单双序列（旧→新）
+	SumMA5         float64   `json:"sum_ma5" db:"sum_ma5"`                   // 和值5期移动平均
+	SumMA10        float64   `json:"sum_ma10" db:"sum_ma10"`                 // 和值10期移动平均
+	DigitParity    string    `json:"digit_parity" db:"digit_parity"`         // 本期三位开奖数字的奇偶（如"奇偶奇"）
+	OddEvenStreak  int       `json:"odd_even_streak" db:"odd_even_streak"`   // 当前单双同向连续期数
+	BigSmallStreak int       `json:"big_small_streak" db:"big_small_streak"` // 当前大小同向连续期数
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// 单期处理阶段（round_timelines表的列名，见SaveRoundStageTime）
+const (
+	StageFetched   = "fetched"   // 从数据源发现新开奖
+	StageSaved     = "saved"     // 开奖数据落库
+	StageVerified  = "verified"  // 上一期预测验证完成
+	StagePredicted = "predicted" // 新预测生成完成
+	StageBroadcast = "broadcast" // 预测广播发送成功
+)
+
+// RoundTimeline 单期各处理阶段的时间戳（用于排查"为什么这期推送慢"）
+// 未到达的阶段为nil；predicted/broadcast指本期开奖触发的下一期预测
+type RoundTimeline struct {
+	Qihao       string     `json:"qihao" db:"qihao"`
+	FetchedAt   *time.Time `json:"fetched_at" db:"fetched_at"`
+	SavedAt     *time.Time `json:"saved_at" db:"saved_at"`
+	VerifiedAt  *time.Time `json:"verified_at" db:"verified_at"`
+	PredictedAt *time.Time `json:"predicted_at" db:"predicted_at"`
+	BroadcastAt *time.Time `json:"broadcast_at" db:"broadcast_at"`
+}
+
+// PredictionCard 新预测推送卡片数据（由pipeline组装，telegram渲染）
+type PredictionCard struct {
+	Prediction  *Prediction    `json:"prediction"`   // 新预测
+	Latest      *LotteryResult `json:"latest"`       // 最新开奖
+	Verified    *Prediction    `json:"verified"`     // 上一期已验证预测（可为nil）
+	MiniHistory []Prediction   `json:"mini_history"` // 最近已验证预测（新到旧，最多5期）
+	Streak      int            `json:"streak"`       // 当前连对（正数）或连错（负数）轮数
+	NextDrawIn  time.Duration  `json:"next_draw_in"` // 距下一期开奖（未配置节奏模型时为0）
+}
+
 // CacheStatus 缓存状态模型
 type CacheStatus struct {
 	ID          int64     `json:"id" db:"id"`
@@ -51,8 +234,13 @@ type PredictionStats struct {
 	AccuracyRate       float64   `json:"accuracy_rate" db:"accuracy_rate"`
 	FirstPrediction    time.Time `json:"first_prediction" db:"first_prediction"`
 	LastPrediction     time.Time `json:"last_prediction" db:"last_prediction"`
+	AvgDisagreement    *float64  `json:"avg_disagreement" db:"avg_disagreement"` // 影子算法平均分歧度（无多算法数据时为nil）
+	CoverageGaps       int       `json:"coverage_gaps" db:"coverage_gaps"`       // 漏预测期数（开奖时从未存在对应预测的轮次）
 }
 
+// PositionDistribution 各位置数字分布（位置下标0-2 -> 数字0-9 -> 出现次数）
+type PositionDistribution [3][10]int
+
 // APIResponse API响应模型
 type APIResponse struct {
 	Data    []APILotteryData `json:"data"`
@@ -117,6 +305,33 @@ func CalculateOddEven(sum int) string {
 	return "单"
 }
 
+// CalculateBigSmall 计算大小（和值>=14为大）
+func CalculateBigSmall(sum int) string {
+	if sum >= 14 {
+		return "大"
+	}
+	return "小"
+}
+
+// PopulateDerivedFields 填充开奖记录的派生字段（保存时统一计算，
+// 避免单双/大小/对子/豹子在各处重复推导）
+func PopulateDerivedFields(result *LotteryResult) {
+	result.OddEven = CalculateOddEven(result.SumValue)
+	result.BigSmall = CalculateBigSmall(result.SumValue)
+	result.IsTriple = false
+	result.IsPair = false
+
+	nums, err := ParseOpenNum(result.OpenNum)
+	if err != nil {
+		return
+	}
+	if nums[0] == nums[1] && nums[1] == nums[2] {
+		result.IsTriple = true
+	} else if nums[0] == nums[1] || nums[1] == nums[2] || nums[0] == nums[2] {
+		result.IsPair = true
+	}
+}
+
 // ParseOddEven 解析单双字符串
 func ParseOddEven(oddEvenStr string) string {
 	if oddEvenStr == "双" || oddEvenStr == "偶" || oddEvenStr == "even" {
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"pc28-bot/internal/config"
 	"pc28-bot/internal/logger"
+	"pc28-bot/internal/qihao"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -49,18 +51,25 @@ func (m *MySQLDB) Close() error {
 	return m.db.Close()
 }
 
-// SaveLotteryResult 保存开奖数据
+// SaveLotteryResult 保存开奖数据（派生列在保存时统一计算）
 func (m *MySQLDB) SaveLotteryResult(result *LotteryResult) error {
-	query := `INSERT INTO lottery_results (qihao, opentime, opentime_string, opennum, sum_value) 
-			  VALUES (?, ?, ?, ?, ?) 
-			  ON DUPLICATE KEY UPDATE 
-			  opentime = VALUES(opentime), 
+	PopulateDerivedFields(result)
+
+	query := `INSERT INTO lottery_results (qihao, opentime, opentime_string, opennum, sum_value, odd_even, big_small, is_triple, is_pair)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			  ON DUPLICATE KEY UPDATE
+			  opentime = VALUES(opentime),
 			  opentime_string = VALUES(opentime_string),
-			  opennum = VALUES(opennum), 
+			  opennum = VALUES(opennum),
 			  sum_value = VALUES(sum_value),
+			  odd_even = VALUES(odd_even),
+			  big_small = VALUES(big_small),
+			  is_triple = VALUES(is_triple),
+			  is_pair = VALUES(is_pair),
 			  updated_at = CURRENT_TIMESTAMP`
 
-	_, err := m.db.Exec(query, result.Qihao, result.OpenTime, result.OpenTimeString, result.OpenNum, result.SumValue)
+	_, err := m.db.Exec(query, result.Qihao, result.OpenTime, result.OpenTimeString, result.OpenNum, result.SumValue,
+		result.OddEven, result.BigSmall, result.IsTriple, result.IsPair)
 	if err != nil {
 		return fmt.Errorf("failed to save lottery result: %v", err)
 	}
@@ -71,9 +80,9 @@ func (m *MySQLDB) SaveLotteryResult(result *LotteryResult) error {
 
 // GetLatestLotteryResults 获取最新的开奖数据
 func (m *MySQLDB) GetLatestLotteryResults(limit int) ([]LotteryResult, error) {
-	query := `SELECT id, qihao, opentime, opentime_string, opennum, sum_value, created_at, updated_at 
-			  FROM lottery_results 
-			  ORDER BY opentime DESC 
+	query := `SELECT id, qihao, opentime, opentime_string, opennum, sum_value, odd_even, big_small, is_triple, is_pair, created_at, updated_at
+			  FROM lottery_results
+			  ORDER BY opentime DESC
 			  LIMIT ?`
 
 	rows, err := m.db.Query(query, limit)
@@ -86,7 +95,8 @@ func (m *MySQLDB) GetLatestLotteryResults(limit int) ([]LotteryResult, error) {
 	for rows.Next() {
 		var result LotteryResult
 		err := rows.Scan(&result.ID, &result.Qihao, &result.OpenTime, &result.OpenTimeString,
-			&result.OpenNum, &result.SumValue, &result.CreatedAt, &result.UpdatedAt)
+			&result.OpenNum, &result.SumValue, &result.OddEven, &result.BigSmall,
+			&result.IsTriple, &result.IsPair, &result.CreatedAt, &result.UpdatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan lottery result: %v", err)
 		}
@@ -98,14 +108,15 @@ func (m *MySQLDB) GetLatestLotteryResults(limit int) ([]LotteryResult, error) {
 
 // GetLotteryResultByQihao 根据期号获取开奖数据
 func (m *MySQLDB) GetLotteryResultByQihao(qihao string) (*LotteryResult, error) {
-	query := `SELECT id, qihao, opentime, opentime_string, opennum, sum_value, created_at, updated_at 
-			  FROM lottery_results 
+	query := `SELECT id, qihao, opentime, opentime_string, opennum, sum_value, odd_even, big_small, is_triple, is_pair, created_at, updated_at
+			  FROM lottery_results
 			  WHERE qihao = ?`
 
 	var result LotteryResult
 	err := m.db.QueryRow(query, qihao).Scan(
 		&result.ID, &result.Qihao, &result.OpenTime, &result.OpenTimeString,
-		&result.OpenNum, &result.SumValue, &result.CreatedAt, &result.UpdatedAt,
+		&result.OpenNum, &result.SumValue, &result.OddEven, &result.BigSmall,
+		&result.IsTriple, &result.IsPair, &result.CreatedAt, &result.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -135,11 +146,16 @@ func (m *MySQLDB) SavePrediction(prediction *Prediction) error {
 		predictedOddEven = CalculateOddEven(predictedSum)
 	}
 
-	query := `INSERT INTO predictions (target_qihao, predicted_num, predicted_sum, predicted_odd_even, confidence_score, algorithm_version, predicted_at) 
-			  VALUES (?, ?, ?, ?, ?, ?, ?)`
+	// 已验证的预测不允许被重复保存覆盖
+	if existing, err := m.GetPredictionByQihao(prediction.TargetQihao); err == nil && existing != nil && existing.VerifiedAt != nil {
+		return fmt.Errorf("%w: prediction for %s already verified", ErrDuplicateRound, prediction.TargetQihao)
+	}
+
+	query := `INSERT INTO predictions (target_qihao, qihao_seq, predicted_num, predicted_sum, predicted_odd_even, confidence_score, disagreement, position_nums, algorithm_version, predicted_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := m.db.Exec(query, prediction.TargetQihao, prediction.PredictedNum, predictedSum, predictedOddEven,
-		prediction.ConfidenceScore, prediction.AlgorithmVersion, prediction.PredictedAt)
+	result, err := m.db.Exec(query, prediction.TargetQihao, qihaoSeq(prediction.TargetQihao), prediction.PredictedNum, predictedSum, predictedOddEven,
+		prediction.ConfidenceScore, prediction.Disagreement, prediction.PositionNums, prediction.AlgorithmVersion, prediction.PredictedAt)
 	if err != nil {
 		return fmt.Errorf("failed to save prediction: %v", err)
 	}
@@ -172,12 +188,12 @@ func (m *MySQLDB) UpdatePredictionResult(qihao string, actualNum string, isCorre
 
 // GetLatestPredictions 获取最新的预测记录
 func (m *MySQLDB) GetLatestPredictions(limit int) ([]Prediction, error) {
-	query := `SELECT id, target_qihao, predicted_num, predicted_sum, predicted_odd_even, 
-			  actual_num, actual_sum, actual_odd_even, is_correct, 
-			  confidence_score, algorithm_version, predicted_at, verified_at,
+	query := `SELECT id, target_qihao, predicted_num, predicted_sum, predicted_odd_even,
+			  actual_num, actual_sum, actual_odd_even, is_correct, validation_policy,
+			  confidence_score, disagreement, position_nums, position_hits, algorithm_version, predicted_at, verified_at,
 			  created_at, updated_at
-			  FROM predictions 
-			  ORDER BY CAST(target_qihao AS UNSIGNED) DESC 
+			  FROM predictions
+			  ORDER BY qihao_seq DESC
 			  LIMIT ?`
 
 	rows, err := m.db.Query(query, limit)
@@ -192,7 +208,8 @@ func (m *MySQLDB) GetLatestPredictions(limit int) ([]Prediction, error) {
 		err := rows.Scan(&prediction.ID, &prediction.TargetQihao, &prediction.PredictedNum,
 			&prediction.PredictedSum, &prediction.PredictedOddEven,
 			&prediction.ActualNum, &prediction.ActualSum, &prediction.ActualOddEven,
-			&prediction.IsCorrect, &prediction.ConfidenceScore,
+			&prediction.IsCorrect, &prediction.ValidationPolicy, &prediction.ConfidenceScore,
+			&prediction.Disagreement, &prediction.PositionNums, &prediction.PositionHits,
 			&prediction.AlgorithmVersion, &prediction.PredictedAt, &prediction.VerifiedAt,
 			&prediction.CreatedAt, &prediction.UpdatedAt)
 		if err != nil {
@@ -204,6 +221,35 @@ func (m *MySQLDB) GetLatestPredictions(limit int) ([]Prediction, error) {
 	return predictions, nil
 }
 
+// GetPredictionByQihao 获取目标期号最新的预测记录（不存在时返回nil）
+func (m *MySQLDB) GetPredictionByQihao(qihao string) (*Prediction, error) {
+	query := `SELECT id, target_qihao, predicted_num, predicted_sum, predicted_odd_even,
+			  actual_num, actual_sum, actual_odd_even, is_correct, validation_policy,
+			  confidence_score, disagreement, position_nums, position_hits, algorithm_version, predicted_at, verified_at,
+			  created_at, updated_at
+			  FROM predictions
+			  WHERE target_qihao = ?
+			  ORDER BY predicted_at DESC
+			  LIMIT 1`
+
+	var prediction Prediction
+	err := m.db.QueryRow(query, qihao).Scan(&prediction.ID, &prediction.TargetQihao, &prediction.PredictedNum,
+		&prediction.PredictedSum, &prediction.PredictedOddEven,
+		&prediction.ActualNum, &prediction.ActualSum, &prediction.ActualOddEven,
+		&prediction.IsCorrect, &prediction.ValidationPolicy, &prediction.ConfidenceScore,
+		&prediction.Disagreement, &prediction.PositionNums, &prediction.PositionHits,
+		&prediction.AlgorithmVersion, &prediction.PredictedAt, &prediction.VerifiedAt,
+		&prediction.CreatedAt, &prediction.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prediction by qihao: %v", err)
+	}
+
+	return &prediction, nil
+}
+
 // GetPredictionStats 获取预测统计信息
 func (m *MySQLDB) GetPredictionStats() (*PredictionStats, error) {
 	query := `SELECT 
@@ -213,14 +259,16 @@ func (m *MySQLDB) GetPredictionStats() (*PredictionStats, error) {
 			(SUM(CASE WHEN is_correct = 1 THEN 1 ELSE 0 END) * 100.0 / COUNT(*)), 2
 		) as accuracy_rate,
 		MIN(predicted_at) as first_prediction,
-		MAX(predicted_at) as last_prediction
-	FROM predictions 
+		MAX(predicted_at) as last_prediction,
+		AVG(disagreement) as avg_disagreement,
+		(SELECT COUNT(*) FROM coverage_gaps) as coverage_gaps
+	FROM predictions
 	WHERE is_correct IS NOT NULL`
 
 	var stats PredictionStats
 	err := m.db.QueryRow(query).Scan(
 		&stats.TotalPredictions, &stats.CorrectPredictions,
-		&stats.AccuracyRate, &stats.FirstPrediction, &stats.LastPrediction,
+		&stats.AccuracyRate, &stats.FirstPrediction, &stats.LastPrediction, &stats.AvgDisagreement, &stats.CoverageGaps,
 	)
 
 	if err == sql.ErrNoRows {
@@ -233,11 +281,51 @@ func (m *MySQLDB) GetPredictionStats() (*PredictionStats, error) {
 	return &stats, nil
 }
 
+// GetPredictionStatsWindow 获取最近window条已验证预测的滚动统计（window<=0为全量口径）
+func (m *MySQLDB) GetPredictionStatsWindow(window int) (*PredictionStats, error) {
+	if window <= 0 {
+		return m.GetPredictionStats()
+	}
+
+	query := `SELECT
+		COUNT(*) as total_predictions,
+		SUM(CASE WHEN is_correct = 1 THEN 1 ELSE 0 END) as correct_predictions,
+		ROUND(
+			(SUM(CASE WHEN is_correct = 1 THEN 1 ELSE 0 END) * 100.0 / COUNT(*)), 2
+		) as accuracy_rate,
+		MIN(predicted_at) as first_prediction,
+		MAX(predicted_at) as last_prediction,
+		AVG(disagreement) as avg_disagreement,
+		(SELECT COUNT(*) FROM coverage_gaps) as coverage_gaps
+	FROM (
+		SELECT is_correct, predicted_at, disagreement
+		FROM predictions
+		WHERE is_correct IS NOT NULL
+		ORDER BY id DESC
+		LIMIT ?
+	) recent`
+
+	var stats PredictionStats
+	err := m.db.QueryRow(query, window).Scan(
+		&stats.TotalPredictions, &stats.CorrectPredictions,
+		&stats.AccuracyRate, &stats.FirstPrediction, &stats.LastPrediction, &stats.AvgDisagreement, &stats.CoverageGaps,
+	)
+
+	if err == sql.ErrNoRows {
+		return &PredictionStats{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get windowed prediction stats: %v", err)
+	}
+
+	return &stats, nil
+}
+
 // GetLotteryHistory 获取历史开奖数据
 func (m *MySQLDB) GetLotteryHistory(limit int) ([]LotteryResult, error) {
-	query := `SELECT id, qihao, opentime, opentime_string, opennum, sum_value, created_at, updated_at 
-			   FROM lottery_results 
-			   ORDER BY qihao DESC 
+	query := `SELECT id, qihao, opentime, opentime_string, opennum, sum_value, odd_even, big_small, is_triple, is_pair, created_at, updated_at
+			   FROM lottery_results
+			   ORDER BY qihao DESC
 			   LIMIT ?`
 
 	rows, err := m.db.Query(query, limit)
@@ -256,6 +344,10 @@ func (m *MySQLDB) GetLotteryHistory(limit int) ([]LotteryResult, error) {
 			&result.OpenTimeString,
 			&result.OpenNum,
 			&result.SumValue,
+			&result.OddEven,
+			&result.BigSmall,
+			&result.IsTriple,
+			&result.IsPair,
 			&result.CreatedAt,
 			&result.UpdatedAt,
 		)
@@ -272,39 +364,44 @@ func (m *MySQLDB) GetLotteryHistory(limit int) ([]LotteryResult, error) {
 	return results, nil
 }
 
-// ValidatePrediction 验证预测结果（简化版）
-func (m *MySQLDB) ValidatePrediction(qihao string, actualResult *LotteryResult) (bool, error) {
-	// 获取对应的预测记录
-	query := `SELECT predicted_num, predicted_sum, predicted_odd_even FROM predictions WHERE target_qihao = ? ORDER BY predicted_at DESC LIMIT 1`
+// SaveVerificationResult 保存预测验证结果
+func (m *MySQLDB) SaveVerificationResult(qihao string, actualResult *LotteryResult, isCorrect bool, policy string) error {
+	// 计算实际单双（随验证结果一并记录，便于事后核查）
+	actualOddEven := CalculateOddEven(actualResult.SumValue)
 
-	var predictedNum string
-	var predictedSum int
-	var predictedOddEven string
-	err := m.db.QueryRow(query, qihao).Scan(&predictedNum, &predictedSum, &predictedOddEven)
-	if err == sql.ErrNoRows {
-		return false, fmt.Errorf("no prediction found for qihao: %s", qihao)
-	}
+	updateQuery := `UPDATE predictions
+					SET actual_num = ?, actual_sum = ?, actual_odd_even = ?, is_correct = ?, validation_policy = ?, verified_at = NOW()
+					WHERE target_qihao = ?`
+
+	result, err := m.db.Exec(updateQuery, actualResult.OpenNum, actualResult.SumValue, actualOddEven, isCorrect, policy, qihao)
 	if err != nil {
-		return false, fmt.Errorf("failed to get prediction: %v", err)
+		return fmt.Errorf("failed to update prediction result: %v", err)
 	}
 
-	// 计算实际单双
-	actualOddEven := CalculateOddEven(actualResult.SumValue)
-
-	// 单双预测验证（主要验证方式）
-	isCorrect := predictedOddEven == actualOddEven
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return fmt.Errorf("no prediction found for qihao: %s", qihao)
+	}
 
-	// 更新预测结果，包含实际和值和单双
-	updateQuery := `UPDATE predictions 
-					SET actual_num = ?, actual_sum = ?, actual_odd_even = ?, is_correct = ?, verified_at = NOW() 
-					WHERE target_qihao = ?`
+	return nil
+}
 
-	_, err = m.db.Exec(updateQuery, actualResult.OpenNum, actualResult.SumValue, actualOddEven, isCorrect, qihao)
-	if err != nil {
-		return false, fmt.Errorf("failed to update prediction result: %v", err)
+// SavePolicyRecheck 写入策略复判结果到平行列（不改动is_correct）
+func (m *MySQLDB) SavePolicyRecheck(id int64, policy string, isCorrect bool) error {
+	query := `UPDATE predictions SET recheck_is_correct = ?, recheck_policy = ? WHERE id = ?`
+	if _, err := m.db.Exec(query, isCorrect, policy, id); err != nil {
+		return fmt.Errorf("failed to save policy recheck: %v", err)
 	}
+	return nil
+}
 
-	return isCorrect, nil
+// SavePositionHits 记录预测验证时按位置命中的数量
+func (m *MySQLDB) SavePositionHits(qihao string, hits int) error {
+	query := `UPDATE predictions SET position_hits = ? WHERE target_qihao = ?`
+	if _, err := m.db.Exec(query, hits, qihao); err != nil {
+		return fmt.Errorf("failed to save position hits: %v", err)
+	}
+	return nil
 }
 
 // createTablesIfNotExists 自动创建表结构
@@ -325,16 +422,393 @@ func (m *MySQLDB) createTablesIfNotExists() error {
 			opentime_string VARCHAR(50) NOT NULL COMMENT 'API原始时间字符串',
 			opennum VARCHAR(20) NOT NULL COMMENT '开奖号码',
 			sum_value INT NOT NULL COMMENT '和值',
+			odd_even VARCHAR(4) NOT NULL DEFAULT '' COMMENT '派生列：和值单双',
+			big_small VARCHAR(4) NOT NULL DEFAULT '' COMMENT '派生列：和值大小',
+			is_triple BOOLEAN NOT NULL DEFAULT FALSE COMMENT '派生列：豹子',
+			is_pair BOOLEAN NOT NULL DEFAULT FALSE COMMENT '派生列：对子',
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '记录创建时间',
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '记录更新时间',
 			INDEX idx_qihao (qihao),
 			INDEX idx_opentime (opentime),
-			INDEX idx_created_at (created_at)
+			INDEX idx_created_at (created_at),
+			INDEX idx_odd_even (odd_even),
+			INDEX idx_big_small (big_small),
+			INDEX idx_special (is_triple, is_pair)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='开奖数据表'`
 
 		if _, err := m.db.Exec(createLotteryResultsTable); err != nil {
 			return fmt.Errorf("failed to create lottery_results table: %v", err)
 		}
+	} else {
+		// 已存在的开奖表补充派生列（旧行由后续保存逐步回填）
+		for column, definition := range map[string]string{
+			"odd_even":  "VARCHAR(4) NOT NULL DEFAULT '' COMMENT '派生列：和值单双'",
+			"big_small": "VARCHAR(4) NOT NULL DEFAULT '' COMMENT '派生列：和值大小'",
+			"is_triple": "BOOLEAN NOT NULL DEFAULT FALSE COMMENT '派生列：豹子'",
+			"is_pair":   "BOOLEAN NOT NULL DEFAULT FALSE COMMENT '派生列：对子'",
+		} {
+			if err := m.ensureColumn("lottery_results", column, definition); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 检查应用状态表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'app_state'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check app_state table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建应用状态表（崩溃恢复用的键值存储）
+		createAppStateTable := `CREATE TABLE app_state (
+			state_key VARCHAR(50) PRIMARY KEY COMMENT '状态键',
+			state_value VARCHAR(255) NOT NULL COMMENT '状态值',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='应用状态表'`
+
+		if _, err := m.db.Exec(createAppStateTable); err != nil {
+			return fmt.Errorf("failed to create app_state table: %v", err)
+		}
+	}
+
+	// 检查审计表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'audit_log'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check audit_log table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建审计表（管理员操作记录）
+		createAuditLogTable := `CREATE TABLE audit_log (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			actor BIGINT NOT NULL COMMENT '操作者chat ID，0表示系统',
+			action VARCHAR(50) NOT NULL COMMENT '操作类型',
+			params VARCHAR(255) NOT NULL DEFAULT '' COMMENT '操作参数',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '操作时间',
+			INDEX idx_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理员操作审计表'`
+
+		if _, err := m.db.Exec(createAuditLogTable); err != nil {
+			return fmt.Errorf("failed to create audit_log table: %v", err)
+		}
+	}
+
+	// 检查用户反馈表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'user_feedback'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check user_feedback table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建用户反馈表（/feedback会话收集）
+		createUserFeedbackTable := `CREATE TABLE user_feedback (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT NOT NULL COMMENT '反馈用户chat ID',
+			content TEXT NOT NULL COMMENT '反馈内容',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '提交时间',
+			INDEX idx_user_id (user_id),
+			INDEX idx_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户反馈表'`
+
+		if _, err := m.db.Exec(createUserFeedbackTable); err != nil {
+			return fmt.Errorf("failed to create user_feedback table: %v", err)
+		}
+	}
+
+	// 检查领域事件outbox表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'outbox_events'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check outbox_events table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建领域事件outbox表（广播补投递，见OutboxEvent）
+		createOutboxTable := `CREATE TABLE outbox_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			event_type VARCHAR(32) NOT NULL COMMENT '事件类型',
+			qihao VARCHAR(20) NOT NULL DEFAULT '' COMMENT '事件关联的期号',
+			payload TEXT COMMENT '事件内容',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '写入时间',
+			dispatched_at TIMESTAMP NULL DEFAULT NULL COMMENT '投递时间（NULL表示待投递）',
+			INDEX idx_dispatched_at (dispatched_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='领域事件outbox表'`
+
+		if _, err := m.db.Exec(createOutboxTable); err != nil {
+			return fmt.Errorf("failed to create outbox_events table: %v", err)
+		}
+	}
+
+	// 检查每日统计快照表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'stats_daily'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check stats_daily table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建每日统计快照表（长期报表不受原始数据保留策略影响）
+		createStatsDailyTable := `CREATE TABLE stats_daily (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			stat_date DATE UNIQUE NOT NULL COMMENT '统计日期',
+			total_predictions INT NOT NULL DEFAULT 0 COMMENT '当日预测总数',
+			correct_predictions INT NOT NULL DEFAULT 0 COMMENT '当日预测正确数',
+			accuracy_rate DECIMAL(5,2) NOT NULL DEFAULT 0 COMMENT '当日准确率',
+			accuracy_low_95 DECIMAL(5,2) NOT NULL DEFAULT 0 COMMENT '准确率Wilson区间下界（95%）',
+			accuracy_high_95 DECIMAL(5,2) NOT NULL DEFAULT 0 COMMENT '准确率Wilson区间上界（95%）',
+			best_streak INT NOT NULL DEFAULT 0 COMMENT '当日最长连对',
+			worst_streak INT NOT NULL DEFAULT 0 COMMENT '当日最长连错',
+			algorithm VARCHAR(50) NOT NULL DEFAULT '' COMMENT '预测算法',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='每日统计快照表'`
+
+		if _, err := m.db.Exec(createStatsDailyTable); err != nil {
+			return fmt.Errorf("failed to create stats_daily table: %v", err)
+		}
+	} else {
+		// 已存在的表补充置信区间列（历史快照区间为0，报表侧按无数据处理）
+		if err := m.ensureColumn("stats_daily", "accuracy_low_95",
+			"DECIMAL(5,2) NOT NULL DEFAULT 0 COMMENT '准确率Wilson区间下界（95%）'"); err != nil {
+			return err
+		}
+		if err := m.ensureColumn("stats_daily", "accuracy_high_95",
+			"DECIMAL(5,2) NOT NULL DEFAULT 0 COMMENT '准确率Wilson区间上界（95%）'"); err != nil {
+			return err
+		}
+	}
+
+	// 检查用户表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'bot_users'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check bot_users table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建用户表（记录首次/最近活跃时间，用于增长与流失分析）
+		createBotUsersTable := `CREATE TABLE bot_users (
+			user_id BIGINT PRIMARY KEY COMMENT '用户chat ID',
+			language VARCHAR(10) NOT NULL DEFAULT '' COMMENT '界面语言（空表示未完成引导）',
+			timezone VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'IANA时区（空表示使用服务器时区）',
+			tier VARCHAR(16) NOT NULL DEFAULT '' COMMENT '用户层级（空表示普通用户）',
+			first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '首次活跃时间',
+			last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '最近活跃时间',
+			INDEX idx_last_seen_at (last_seen_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='机器人用户表'`
+
+		if _, err := m.db.Exec(createBotUsersTable); err != nil {
+			return fmt.Errorf("failed to create bot_users table: %v", err)
+		}
+	} else {
+		// 已存在的用户表补充语言列和时区列
+		if err := m.ensureColumn("bot_users", "language",
+			"VARCHAR(10) NOT NULL DEFAULT '' COMMENT '界面语言（空表示未完成引导）'"); err != nil {
+			return err
+		}
+		if err := m.ensureColumn("bot_users", "timezone",
+			"VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'IANA时区（空表示使用服务器时区）'"); err != nil {
+			return err
+		}
+		if err := m.ensureColumn("bot_users", "tier",
+			"VARCHAR(16) NOT NULL DEFAULT '' COMMENT '用户层级（空表示普通用户）'"); err != nil {
+			return err
+		}
+	}
+
+	// 检查命令使用统计表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'command_usage'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check command_usage table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建命令使用统计表（按日×命令计数）
+		createCommandUsageTable := `CREATE TABLE command_usage (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			usage_date DATE NOT NULL COMMENT '统计日期',
+			command VARCHAR(32) NOT NULL COMMENT '命令名',
+			usage_count INT NOT NULL DEFAULT 0 COMMENT '使用次数',
+			UNIQUE KEY uk_date_command (usage_date, command)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='命令使用统计表'`
+
+		if _, err := m.db.Exec(createCommandUsageTable); err != nil {
+			return fmt.Errorf("failed to create command_usage table: %v", err)
+		}
+	}
+
+	// 检查通知开关表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'notify_prefs'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check notify_prefs table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建通知开关表（只存显式设置，未设置的事件默认开启）
+		createNotifyPrefsTable := `CREATE TABLE notify_prefs (
+			user_id BIGINT NOT NULL COMMENT '用户chat ID',
+			event VARCHAR(20) NOT NULL COMMENT '通知事件类型',
+			enabled BOOLEAN NOT NULL DEFAULT TRUE COMMENT '是否开启',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+			PRIMARY KEY (user_id, event)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户通知开关表'`
+
+		if _, err := m.db.Exec(createNotifyPrefsTable); err != nil {
+			return fmt.Errorf("failed to create notify_prefs table: %v", err)
+		}
+	}
+
+	// 检查跟单记录表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'user_follows'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check user_follows table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建跟单记录表（用户对某期预测点击👍跟单）
+		createUserFollowsTable := `CREATE TABLE user_follows (
+			user_id BIGINT NOT NULL COMMENT '用户chat ID',
+			qihao VARCHAR(20) NOT NULL COMMENT '跟单期号',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '跟单时间',
+			PRIMARY KEY (user_id, qihao),
+			INDEX idx_user_created (user_id, created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户跟单记录表'`
+
+		if _, err := m.db.Exec(createUserFollowsTable); err != nil {
+			return fmt.Errorf("failed to create user_follows table: %v", err)
+		}
+	}
+
+	// 检查滚动特征表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'features'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check features table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建滚动特征表（每期一行，供统计/ML预测器消费一致输入）
+		createFeaturesTable := `CREATE TABLE features (
+			qihao VARCHAR(20) PRIMARY KEY COMMENT '期号',
+			odd_even_seq VARCHAR(32) NOT NULL DEFAULT '' COMMENT '最近K期单双序列（旧→新）',
+			sum_ma5 DOUBLE NOT NULL DEFAULT 0 COMMENT '和值5期移动平均',
+			sum_ma10 DOUBLE NOT NULL DEFAULT 0 COMMENT '和值10期移动平均',
+			digit_parity VARCHAR(16) NOT NULL DEFAULT '' COMMENT '本期三位数字奇偶',
+			odd_even_streak INT NOT NULL DEFAULT 0 COMMENT '当前单双连续期数',
+			big_small_streak INT NOT NULL DEFAULT 0 COMMENT '当前大小连续期数',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='单期滚动特征表'`
+
+		if _, err := m.db.Exec(createFeaturesTable); err != nil {
+			return fmt.Errorf("failed to create features table: %v", err)
+		}
+	}
+
+	// 检查覆盖缺口表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'coverage_gaps'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check coverage_gaps table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建覆盖缺口表（漏预测期号，防止准确率统计掩盖漏报）
+		createCoverageGapsTable := `CREATE TABLE coverage_gaps (
+			qihao VARCHAR(20) PRIMARY KEY COMMENT '漏预测的期号',
+			detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '发现时间',
+			INDEX idx_detected_at (detected_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='预测覆盖缺口表'`
+
+		if _, err := m.db.Exec(createCoverageGapsTable); err != nil {
+			return fmt.Errorf("failed to create coverage_gaps table: %v", err)
+		}
+	}
+
+	// 检查单期处理时间线表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'round_timelines'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check round_timelines table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建单期处理时间线表（各处理阶段时间戳，排查推送慢）
+		createRoundTimelinesTable := `CREATE TABLE round_timelines (
+			qihao VARCHAR(20) PRIMARY KEY COMMENT '期号',
+			fetched_at TIMESTAMP NULL DEFAULT NULL COMMENT '发现新开奖时间',
+			saved_at TIMESTAMP NULL DEFAULT NULL COMMENT '开奖落库时间',
+			verified_at TIMESTAMP NULL DEFAULT NULL COMMENT '预测验证完成时间',
+			predicted_at TIMESTAMP NULL DEFAULT NULL COMMENT '新预测生成时间',
+			broadcast_at TIMESTAMP NULL DEFAULT NULL COMMENT '预测广播发送时间',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+			INDEX idx_created_at (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='单期处理时间线表'`
+
+		if _, err := m.db.Exec(createRoundTimelinesTable); err != nil {
+			return fmt.Errorf("failed to create round_timelines table: %v", err)
+		}
+	}
+
+	// 检查算法榜单表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'leaderboard'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check leaderboard table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建算法榜单表（定时交叉验证任务每算法一行覆盖更新）
+		createLeaderboardTable := `CREATE TABLE leaderboard (
+			algorithm VARCHAR(50) PRIMARY KEY COMMENT '算法名称',
+			version VARCHAR(50) NOT NULL DEFAULT '' COMMENT '算法版本',
+			rounds INT NOT NULL DEFAULT 0 COMMENT '评估期数',
+			correct INT NOT NULL DEFAULT 0 COMMENT '命中期数',
+			accuracy_rate DECIMAL(5,2) NOT NULL DEFAULT 0 COMMENT '准确率（%）',
+			best_streak INT NOT NULL DEFAULT 0 COMMENT '最长连对',
+			worst_streak INT NOT NULL DEFAULT 0 COMMENT '最长连错',
+			evaluated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '评估时间'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='算法交叉验证榜单表'`
+
+		if _, err := m.db.Exec(createLeaderboardTable); err != nil {
+			return fmt.Errorf("failed to create leaderboard table: %v", err)
+		}
+	}
+
+	// 检查提醒规则表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'alert_rules'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check alert_rules table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建提醒规则表（用户自定义，每期开奖后评估）
+		createAlertRulesTable := `CREATE TABLE alert_rules (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			user_id BIGINT NOT NULL COMMENT '规则所有者chat ID',
+			rule_type VARCHAR(32) NOT NULL COMMENT '规则类型',
+			threshold INT NOT NULL COMMENT '连续期数或和值阈值',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+			UNIQUE KEY uniq_user_rule (user_id, rule_type, threshold),
+			INDEX idx_user_id (user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户自定义提醒规则表'`
+
+		if _, err := m.db.Exec(createAlertRulesTable); err != nil {
+			return fmt.Errorf("failed to create alert_rules table: %v", err)
+		}
+	}
+
+	// 检查封禁表
+	err = m.db.QueryRow("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'banned_users'").Scan(&tableCount)
+	if err != nil {
+		return fmt.Errorf("failed to check banned_users table existence: %v", err)
+	}
+
+	if tableCount == 0 {
+		// 创建封禁表（expires_at为NULL表示永久封禁）
+		createBannedUsersTable := `CREATE TABLE banned_users (
+			user_id BIGINT PRIMARY KEY COMMENT '被封禁用户chat ID',
+			reason VARCHAR(255) NOT NULL DEFAULT '' COMMENT '封禁原因',
+			banned_by BIGINT NOT NULL DEFAULT 0 COMMENT '操作者chat ID，0表示系统自动封禁',
+			expires_at TIMESTAMP NULL DEFAULT NULL COMMENT '到期时间（NULL表示永久）',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '封禁时间'
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户封禁表'`
+
+		if _, err := m.db.Exec(createBannedUsersTable); err != nil {
+			return fmt.Errorf("failed to create banned_users table: %v", err)
+		}
 	}
 
 	// 检查预测表
@@ -348,14 +822,22 @@ func (m *MySQLDB) createTablesIfNotExists() error {
 		createPredictionsTable := `CREATE TABLE predictions (
 			id BIGINT AUTO_INCREMENT PRIMARY KEY,
 			target_qihao VARCHAR(20) NOT NULL COMMENT '目标期号',
+			qihao_seq BIGINT NOT NULL DEFAULT 0 COMMENT '目标期号的数值形式（排序用，写入时维护）',
 			predicted_num VARCHAR(20) NOT NULL COMMENT '预测号码',
 			actual_num VARCHAR(20) DEFAULT NULL COMMENT '实际开奖号码',
 			is_correct BOOLEAN DEFAULT NULL COMMENT '是否预测正确',
+			validation_policy VARCHAR(20) DEFAULT NULL COMMENT '验证时使用的命中判定策略',
+			recheck_is_correct BOOLEAN DEFAULT NULL COMMENT '策略复判结果（平行列，不参与统计）',
+			recheck_policy VARCHAR(20) DEFAULT NULL COMMENT '策略复判使用的判定策略',
 			confidence_score DECIMAL(5,2) DEFAULT NULL COMMENT '置信度评分',
+			disagreement DECIMAL(5,2) DEFAULT NULL COMMENT '影子算法分歧度（0-100）',
+			position_nums VARCHAR(20) DEFAULT NULL COMMENT '位置级预测号码（逗号分隔）',
+			position_hits TINYINT DEFAULT NULL COMMENT '验证时按位置命中的数量（0-3）',
 			algorithm_version VARCHAR(50) DEFAULT 'default' COMMENT '算法版本',
 			predicted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '预测时间',
 			verified_at TIMESTAMP NULL COMMENT '验证时间',
 			INDEX idx_target_qihao (target_qihao),
+			INDEX idx_qihao_seq (qihao_seq),
 			INDEX idx_predicted_at (predicted_at),
 			INDEX idx_is_correct (is_correct),
 			INDEX idx_verified_at (verified_at)
@@ -364,21 +846,106 @@ func (m *MySQLDB) createTablesIfNotExists() error {
 		if _, err := m.db.Exec(createPredictionsTable); err != nil {
 			return fmt.Errorf("failed to create predictions table: %v", err)
 		}
+	} else {
+		// 已存在的预测表补充策略列（记录历史验证口径）
+		if err := m.ensureColumn("predictions", "validation_policy",
+			"VARCHAR(20) DEFAULT NULL COMMENT '验证时使用的命中判定策略'"); err != nil {
+			return err
+		}
+		// 补充策略复判平行列（策略切换前的影响评估，不参与统计）
+		if err := m.ensureColumn("predictions", "recheck_is_correct",
+			"BOOLEAN DEFAULT NULL COMMENT '策略复判结果（平行列，不参与统计）'"); err != nil {
+			return err
+		}
+		if err := m.ensureColumn("predictions", "recheck_policy",
+			"VARCHAR(20) DEFAULT NULL COMMENT '策略复判使用的判定策略'"); err != nil {
+			return err
+		}
+		// 补充影子算法分歧度列
+		if err := m.ensureColumn("predictions", "disagreement",
+			"DECIMAL(5,2) DEFAULT NULL COMMENT '影子算法分歧度（0-100）'"); err != nil {
+			return err
+		}
+		// 补充位置级预测列（位置级预测与按位置命中评分）
+		if err := m.ensureColumn("predictions", "position_nums",
+			"VARCHAR(20) DEFAULT NULL COMMENT '位置级预测号码（逗号分隔）'"); err != nil {
+			return err
+		}
+		if err := m.ensureColumn("predictions", "position_hits",
+			"TINYINT DEFAULT NULL COMMENT '验证时按位置命中的数量（0-3）'"); err != nil {
+			return err
+		}
+		// 补充数值期号列并回填旧数据（CAST排序无法走索引，改为写入时维护的数值列）
+		if err := m.ensureColumn("predictions", "qihao_seq",
+			"BIGINT NOT NULL DEFAULT 0 COMMENT '目标期号的数值形式（排序用，写入时维护）'"); err != nil {
+			return err
+		}
+		if err := m.ensureIndex("predictions", "idx_qihao_seq", "(qihao_seq)"); err != nil {
+			return err
+		}
+		if _, err := m.db.Exec("UPDATE predictions SET qihao_seq = CAST(target_qihao AS UNSIGNED) WHERE qihao_seq = 0"); err != nil {
+			return fmt.Errorf("failed to backfill qihao_seq: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// qihaoSeq 将期号转换为数值形式（排序列写入用，解析失败返回0）
+func qihaoSeq(q string) int64 {
+	seq, err := qihao.Parse(q)
+	if err != nil {
+		return 0
+	}
+	return seq
+}
+
+// ensureIndex 确保指定表存在指定索引（幂等，用于旧表结构升级）
+func (m *MySQLDB) ensureIndex(table, index, definition string) error {
+	var indexCount int
+	err := m.db.QueryRow("SELECT COUNT(DISTINCT index_name) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?", table, index).Scan(&indexCount)
+	if err != nil {
+		return fmt.Errorf("failed to check %s index %s: %v", table, index, err)
+	}
+
+	if indexCount == 0 {
+		if _, err := m.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD INDEX %s %s", table, index, definition)); err != nil {
+			return fmt.Errorf("failed to add %s index %s: %v", table, index, err)
+		}
+	}
+	return nil
+}
+
+// ensureColumn 确保指定表存在指定列（幂等，用于旧表结构升级）
+func (m *MySQLDB) ensureColumn(table, column, definition string) error {
+	var columnCount int
+	err := m.db.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?", table, column).Scan(&columnCount)
+	if err != nil {
+		return fmt.Errorf("failed to check %s column %s: %v", table, column, err)
 	}
 
+	if columnCount == 0 {
+		if _, err := m.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+			return fmt.Errorf("failed to add %s column %s: %v", table, column, err)
+		}
+	}
 	return nil
 }
 
-// CleanOldData 清理旧数据
-func (m *MySQLDB) CleanOldData() error {
-	// 清理超过24小时的开奖数据
-	_, err := m.db.Exec("DELETE FROM lottery_results WHERE created_at < DATE_SUB(NOW(), INTERVAL 24 HOUR)")
+// CleanOldData 清理超出保留窗口的旧数据
+func (m *MySQLDB) CleanOldData(retentionHours int) error {
+	if retentionHours <= 0 {
+		retentionHours = 24
+	}
+
+	// 清理超出保留窗口的开奖数据
+	_, err := m.db.Exec("DELETE FROM lottery_results WHERE created_at < DATE_SUB(NOW(), INTERVAL ? HOUR)", retentionHours)
 	if err != nil {
 		return fmt.Errorf("failed to clean lottery results: %v", err)
 	}
 
-	// 清理超过24小时的预测记录
-	_, err = m.db.Exec("DELETE FROM predictions WHERE predicted_at < DATE_SUB(NOW(), INTERVAL 24 HOUR)")
+	// 清理超出保留窗口的预测记录
+	_, err = m.db.Exec("DELETE FROM predictions WHERE predicted_at < DATE_SUB(NOW(), INTERVAL ? HOUR)", retentionHours)
 	if err != nil {
 		return fmt.Errorf("failed to clean predictions: %v", err)
 	}
@@ -386,6 +953,92 @@ func (m *MySQLDB) CleanOldData() error {
 	return nil
 }
 
+// GetLotteryResultsBefore 获取开奖时间早于cutoff的开奖数据（按开奖时间升序，最多limit条）
+func (m *MySQLDB) GetLotteryResultsBefore(cutoff time.Time, limit int) ([]LotteryResult, error) {
+	query := `SELECT id, qihao, opentime, opentime_string, opennum, sum_value, odd_even, big_small, is_triple, is_pair, created_at, updated_at
+			  FROM lottery_results
+			  WHERE opentime < ?
+			  ORDER BY opentime ASC
+			  LIMIT ?`
+
+	rows, err := m.db.Query(query, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lottery results before cutoff: %v", err)
+	}
+	defer rows.Close()
+
+	var results []LotteryResult
+	for rows.Next() {
+		var result LotteryResult
+		err := rows.Scan(&result.ID, &result.Qihao, &result.OpenTime, &result.OpenTimeString,
+			&result.OpenNum, &result.SumValue, &result.OddEven, &result.BigSmall,
+			&result.IsTriple, &result.IsPair, &result.CreatedAt, &result.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lottery result: %v", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// DeleteLotteryResultsByQihao 按期号批量删除开奖数据
+func (m *MySQLDB) DeleteLotteryResultsByQihao(qihaos []string) (int, error) {
+	if len(qihaos) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.Repeat("?,", len(qihaos))
+	placeholders = placeholders[:len(placeholders)-1]
+	args := make([]interface{}, len(qihaos))
+	for i, q := range qihaos {
+		args[i] = q
+	}
+
+	result, err := m.db.Exec(fmt.Sprintf("DELETE FROM lottery_results WHERE qihao IN (%s)", placeholders), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete lottery results: %v", err)
+	}
+
+	deleted, _ := result.RowsAffected()
+	return int(deleted), nil
+}
+
+// RestoreLotteryResults 批量恢复开奖数据（单事务执行，已存在的期号跳过不覆盖）
+func (m *MySQLDB) RestoreLotteryResults(results []LotteryResult) (int, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin restore transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT IGNORE INTO lottery_results (qihao, opentime, opentime_string, opennum, sum_value, odd_even, big_small, is_triple, is_pair)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare restore statement: %v", err)
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for i := range results {
+		result := &results[i]
+		PopulateDerivedFields(result)
+
+		res, err := stmt.Exec(result.Qihao, result.OpenTime, result.OpenTimeString, result.OpenNum, result.SumValue,
+			result.OddEven, result.BigSmall, result.IsTriple, result.IsPair)
+		if err != nil {
+			return 0, fmt.Errorf("failed to restore lottery result %s: %v", result.Qihao, err)
+		}
+		affected, _ := res.RowsAffected()
+		inserted += int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit restore transaction: %v", err)
+	}
+	return inserted, nil
+}
+
 // CheckNewQihao 检查是否有新的期号
 func (m *MySQLDB) CheckNewQihao(qihao string) (bool, error) {
 	// 先测试表是否存在
@@ -411,11 +1064,11 @@ func (m *MySQLDB) CheckNewQihao(qihao string) (bool, error) {
 
 // GetUnverifiedPredictions 获取所有未验证的预测记录
 func (m *MySQLDB) GetUnverifiedPredictions() ([]Prediction, error) {
-	query := `SELECT id, target_qihao, predicted_num, predicted_sum, predicted_odd_even, 
-			  actual_num, actual_sum, actual_odd_even, is_correct, 
-			  confidence_score, algorithm_version, predicted_at, verified_at,
+	query := `SELECT id, target_qihao, predicted_num, predicted_sum, predicted_odd_even,
+			  actual_num, actual_sum, actual_odd_even, is_correct, validation_policy,
+			  confidence_score, disagreement, position_nums, position_hits, algorithm_version, predicted_at, verified_at,
 			  created_at, updated_at
-			  FROM predictions 
+			  FROM predictions
 			  WHERE is_correct IS NULL AND actual_num IS NULL
 			  ORDER BY predicted_at DESC`
 
@@ -431,7 +1084,8 @@ func (m *MySQLDB) GetUnverifiedPredictions() ([]Prediction, error) {
 		err := rows.Scan(&prediction.ID, &prediction.TargetQihao, &prediction.PredictedNum,
 			&prediction.PredictedSum, &prediction.PredictedOddEven,
 			&prediction.ActualNum, &prediction.ActualSum, &prediction.ActualOddEven,
-			&prediction.IsCorrect, &prediction.ConfidenceScore,
+			&prediction.IsCorrect, &prediction.ValidationPolicy, &prediction.ConfidenceScore,
+			&prediction.Disagreement, &prediction.PositionNums, &prediction.PositionHits,
 			&prediction.AlgorithmVersion, &prediction.PredictedAt, &prediction.VerifiedAt,
 			&prediction.CreatedAt, &prediction.UpdatedAt)
 		if err != nil {
@@ -443,18 +1097,53 @@ func (m *MySQLDB) GetUnverifiedPredictions() ([]Prediction, error) {
 	return predictions, nil
 }
 
-// CleanupExpiredPredictions 清理过期的待开奖预测记录
-func (m *MySQLDB) CleanupExpiredPredictions(latestQihao string) (int, error) {
-	// 删除目标期号小于最新期号且仍未验证的预测记录
-	query := `DELETE FROM predictions 
-			  WHERE target_qihao < ? AND is_correct IS NULL AND actual_num IS NULL`
+// GetPendingPredictionsBefore 获取目标期号不大于指定期号且未验证的预测（按期号升序）
+func (m *MySQLDB) GetPendingPredictionsBefore(qihao string) ([]Prediction, error) {
+	query := `SELECT id, target_qihao, predicted_num, predicted_sum, predicted_odd_even,
+			  actual_num, actual_sum, actual_odd_even, is_correct, validation_policy,
+			  confidence_score, disagreement, position_nums, position_hits, algorithm_version, predicted_at, verified_at,
+			  created_at, updated_at
+			  FROM predictions
+			  WHERE qihao_seq <= ? AND is_correct IS NULL AND actual_num IS NULL
+			  ORDER BY qihao_seq ASC`
 
-	result, err := m.db.Exec(query, latestQihao)
+	rows, err := m.db.Query(query, qihaoSeq(qihao))
 	if err != nil {
-		return 0, fmt.Errorf("failed to cleanup expired predictions: %v", err)
+		return nil, fmt.Errorf("failed to query pending predictions: %v", err)
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
+	var predictions []Prediction
+	for rows.Next() {
+		var prediction Prediction
+		err := rows.Scan(&prediction.ID, &prediction.TargetQihao, &prediction.PredictedNum,
+			&prediction.PredictedSum, &prediction.PredictedOddEven,
+			&prediction.ActualNum, &prediction.ActualSum, &prediction.ActualOddEven,
+			&prediction.IsCorrect, &prediction.ValidationPolicy, &prediction.ConfidenceScore,
+			&prediction.Disagreement, &prediction.PositionNums, &prediction.PositionHits,
+			&prediction.AlgorithmVersion, &prediction.PredictedAt, &prediction.VerifiedAt,
+			&prediction.CreatedAt, &prediction.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pending prediction: %v", err)
+		}
+		predictions = append(predictions, prediction)
+	}
+
+	return predictions, nil
+}
+
+// CleanupExpiredPredictions 清理过期的待开奖预测记录
+func (m *MySQLDB) CleanupExpiredPredictions(latestQihao string) (int, error) {
+	// 删除目标期号小于最新期号且仍未验证的预测记录
+	query := `DELETE FROM predictions 
+			  WHERE qihao_seq < ? AND is_correct IS NULL AND actual_num IS NULL`
+
+	result, err := m.db.Exec(query, qihaoSeq(latestQihao))
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup expired predictions: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get rows affected: %v", err)
 	}
@@ -475,18 +1164,877 @@ func (m *MySQLDB) GetNextQihao() (string, error) {
 		return "", fmt.Errorf("failed to get latest qihao: %v", err)
 	}
 
-	// 解析期号并增加1
-	if len(latestQihao) >= 7 {
-		prefix := latestQihao[:4]
-		numStr := latestQihao[4:]
-		num, err := strconv.Atoi(numStr)
+	return qihao.Next(latestQihao)
+}
+
+// SaveAppState 保存应用状态项
+func (m *MySQLDB) SaveAppState(key, value string) error {
+	query := `INSERT INTO app_state (state_key, state_value) VALUES (?, ?)
+			  ON DUPLICATE KEY UPDATE state_value = VALUES(state_value)`
+
+	_, err := m.db.Exec(query, key, value)
+	if err != nil {
+		return fmt.Errorf("failed to save app state %s: %v", key, err)
+	}
+	return nil
+}
+
+// GetSumDistribution 统计最近N期开奖和值的分布（在SQL中分组计算）
+func (m *MySQLDB) GetSumDistribution(limit int) (map[int]int, error) {
+	query := `SELECT sum_value, COUNT(*) FROM (
+				  SELECT sum_value FROM lottery_results ORDER BY qihao DESC LIMIT ?
+			  ) recent GROUP BY sum_value`
+
+	rows, err := m.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sum distribution: %v", err)
+	}
+	defer rows.Close()
+
+	distribution := make(map[int]int)
+	for rows.Next() {
+		var sumValue, count int
+		if err := rows.Scan(&sumValue, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan sum distribution: %v", err)
+		}
+		distribution[sumValue] = count
+	}
+	return distribution, rows.Err()
+}
+
+// GetPositionDistribution 统计最近N期开奖各位置数字的分布（在SQL中拆位）
+func (m *MySQLDB) GetPositionDistribution(limit int) (PositionDistribution, error) {
+	query := `SELECT
+				  CAST(SUBSTRING_INDEX(opennum, ',', 1) AS UNSIGNED),
+				  CAST(SUBSTRING_INDEX(SUBSTRING_INDEX(opennum, ',', 2), ',', -1) AS UNSIGNED),
+				  CAST(SUBSTRING_INDEX(opennum, ',', -1) AS UNSIGNED)
+			  FROM (
+				  SELECT opennum FROM lottery_results ORDER BY qihao DESC LIMIT ?
+			  ) recent`
+
+	var distribution PositionDistribution
+	rows, err := m.db.Query(query, limit)
+	if err != nil {
+		return distribution, fmt.Errorf("failed to query position distribution: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var digits [3]int
+		if err := rows.Scan(&digits[0], &digits[1], &digits[2]); err != nil {
+			return distribution, fmt.Errorf("failed to scan position distribution: %v", err)
+		}
+		for position, digit := range digits {
+			if digit >= 0 && digit <= 9 {
+				distribution[position][digit]++
+			}
+		}
+	}
+	return distribution, rows.Err()
+}
+
+// SaveOutboxEvent 写入领域事件outbox记录（写入后回填事件ID）
+func (m *MySQLDB) SaveOutboxEvent(event *OutboxEvent) error {
+	query := `INSERT INTO outbox_events (event_type, qihao, payload) VALUES (?, ?, ?)`
+
+	result, err := m.db.Exec(query, event.EventType, event.Qihao, event.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to save outbox event: %v", err)
+	}
+
+	if id, err := result.LastInsertId(); err == nil {
+		event.ID = id
+	}
+	return nil
+}
+
+// GetPendingOutboxEvents 获取待投递的领域事件（按写入顺序）
+func (m *MySQLDB) GetPendingOutboxEvents(limit int) ([]OutboxEvent, error) {
+	query := `SELECT id, event_type, qihao, payload, created_at, dispatched_at
+			  FROM outbox_events WHERE dispatched_at IS NULL
+			  ORDER BY id ASC LIMIT ?`
+	rows, err := m.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending outbox events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var event OutboxEvent
+		if err := rows.Scan(&event.ID, &event.EventType, &event.Qihao,
+			&event.Payload, &event.CreatedAt, &event.DispatchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// MarkOutboxDispatched 标记领域事件已投递
+func (m *MySQLDB) MarkOutboxDispatched(id int64) error {
+	query := `UPDATE outbox_events SET dispatched_at = NOW() WHERE id = ?`
+	if _, err := m.db.Exec(query, id); err != nil {
+		return fmt.Errorf("failed to mark outbox event dispatched: %v", err)
+	}
+	return nil
+}
+
+// SaveAuditEntry 保存管理员操作审计记录
+func (m *MySQLDB) SaveAuditEntry(entry *AuditEntry) error {
+	query := `INSERT INTO audit_log (actor, action, params) VALUES (?, ?, ?)`
+
+	result, err := m.db.Exec(query, entry.Actor, entry.Action, entry.Params)
+	if err != nil {
+		return fmt.Errorf("failed to save audit entry: %v", err)
+	}
+
+	if id, err := result.LastInsertId(); err == nil {
+		entry.ID = id
+	}
+	return nil
+}
+
+// GetAuditEntries 获取最新的审计记录
+func (m *MySQLDB) GetAuditEntries(limit int) ([]AuditEntry, error) {
+	query := `SELECT id, actor, action, params, created_at
+			  FROM audit_log ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	rows, err := m.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit entries: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Params, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// SaveFeedback 保存用户反馈
+func (m *MySQLDB) SaveFeedback(feedback *Feedback) error {
+	query := `INSERT INTO user_feedback (user_id, content) VALUES (?, ?)`
+
+	result, err := m.db.Exec(query, feedback.UserID, feedback.Content)
+	if err != nil {
+		return fmt.Errorf("failed to save feedback: %v", err)
+	}
+
+	if id, err := result.LastInsertId(); err == nil {
+		feedback.ID = id
+	}
+	return nil
+}
+
+// GetFeedbackEntries 获取最新的用户反馈
+func (m *MySQLDB) GetFeedbackEntries(limit int) ([]Feedback, error) {
+	query := `SELECT id, user_id, content, created_at
+			  FROM user_feedback ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	rows, err := m.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback entries: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []Feedback
+	for rows.Next() {
+		var entry Feedback
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Content, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// SaveDailyStats 保存每日统计快照（同日期覆盖更新）
+func (m *MySQLDB) SaveDailyStats(stats *DailyStats) error {
+	query := `INSERT INTO stats_daily (stat_date, total_predictions, correct_predictions,
+			  accuracy_rate, accuracy_low_95, accuracy_high_95, best_streak, worst_streak, algorithm)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			  ON DUPLICATE KEY UPDATE
+			  total_predictions = VALUES(total_predictions),
+			  correct_predictions = VALUES(correct_predictions),
+			  accuracy_rate = VALUES(accuracy_rate),
+			  accuracy_low_95 = VALUES(accuracy_low_95),
+			  accuracy_high_95 = VALUES(accuracy_high_95),
+			  best_streak = VALUES(best_streak),
+			  worst_streak = VALUES(worst_streak),
+			  algorithm = VALUES(algorithm)`
+
+	_, err := m.db.Exec(query, stats.StatDate.Format("2006-01-02"), stats.TotalPredictions,
+		stats.CorrectPredictions, stats.AccuracyRate, stats.AccuracyLow95, stats.AccuracyHigh95,
+		stats.BestStreak, stats.WorstStreak, stats.Algorithm)
+	if err != nil {
+		return fmt.Errorf("failed to save daily stats: %v", err)
+	}
+	return nil
+}
+
+// GetDailyStats 获取最新的每日统计快照
+func (m *MySQLDB) GetDailyStats(limit int) ([]DailyStats, error) {
+	query := `SELECT id, stat_date, total_predictions, correct_predictions,
+			  accuracy_rate, accuracy_low_95, accuracy_high_95, best_streak, worst_streak, algorithm, created_at
+			  FROM stats_daily ORDER BY stat_date DESC LIMIT ?`
+
+	rows, err := m.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily stats: %v", err)
+	}
+	defer rows.Close()
+
+	var results []DailyStats
+	for rows.Next() {
+		var stats DailyStats
+		if err := rows.Scan(&stats.ID, &stats.StatDate, &stats.TotalPredictions,
+			&stats.CorrectPredictions, &stats.AccuracyRate, &stats.AccuracyLow95,
+			&stats.AccuracyHigh95, &stats.BestStreak,
+			&stats.WorstStreak, &stats.Algorithm, &stats.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan daily stats: %v", err)
+		}
+		results = append(results, stats)
+	}
+	return results, rows.Err()
+}
+
+// RecordUserActivity 记录用户命令活跃（首次出现时登记为新用户）
+func (m *MySQLDB) RecordUserActivity(userID int64, command string) error {
+	userQuery := `INSERT INTO bot_users (user_id, first_seen_at, last_seen_at)
+			  VALUES (?, NOW(), NOW())
+			  ON DUPLICATE KEY UPDATE last_seen_at = NOW()`
+	if _, err := m.db.Exec(userQuery, userID); err != nil {
+		return fmt.Errorf("failed to record user activity: %v", err)
+	}
+
+	usageQuery := `INSERT INTO command_usage (usage_date, command, usage_count)
+			  VALUES (CURDATE(), ?, 1)
+			  ON DUPLICATE KEY UPDATE usage_count = usage_count + 1`
+	if _, err := m.db.Exec(usageQuery, command); err != nil {
+		return fmt.Errorf("failed to record command usage: %v", err)
+	}
+	return nil
+}
+
+// GetEngagementStats 统计用户增长与命令使用情况（windowDays为流失判定和命令统计窗口）
+func (m *MySQLDB) GetEngagementStats(windowDays int) (*EngagementStats, error) {
+	if windowDays <= 0 {
+		windowDays = 7
+	}
+
+	stats := &EngagementStats{
+		WindowDays:    windowDays,
+		CommandCounts: make(map[string]int),
+	}
+
+	userQuery := `SELECT COUNT(*),
+			  COALESCE(SUM(DATE(last_seen_at) = CURDATE()), 0),
+			  COALESCE(SUM(DATE(first_seen_at) = CURDATE()), 0),
+			  COALESCE(SUM(last_seen_at < DATE_SUB(NOW(), INTERVAL ? DAY)), 0)
+			  FROM bot_users`
+	err := m.db.QueryRow(userQuery, windowDays).Scan(&stats.TotalUsers,
+		&stats.ActiveToday, &stats.NewToday, &stats.ChurnedUsers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user stats: %v", err)
+	}
+
+	usageQuery := `SELECT command, SUM(usage_count) FROM command_usage
+			  WHERE usage_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+			  GROUP BY command`
+	rows, err := m.db.Query(usageQuery, windowDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query command usage: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var command string
+		var count int
+		if err := rows.Scan(&command, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan command usage: %v", err)
+		}
+		stats.CommandCounts[command] = count
+	}
+	return stats, rows.Err()
+}
+
+// BanUser 封禁用户（同用户重复封禁为覆盖更新）
+func (m *MySQLDB) BanUser(ban *BannedUser) error {
+	query := `INSERT INTO banned_users (user_id, reason, banned_by, expires_at) VALUES (?, ?, ?, ?)
+			  ON DUPLICATE KEY UPDATE
+			  reason = VALUES(reason),
+			  banned_by = VALUES(banned_by),
+			  expires_at = VALUES(expires_at)`
+	if _, err := m.db.Exec(query, ban.UserID, ban.Reason, ban.BannedBy, ban.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to ban user: %v", err)
+	}
+	return nil
+}
+
+// UnbanUser 解除用户封禁，返回是否存在封禁记录
+func (m *MySQLDB) UnbanUser(userID int64) (bool, error) {
+	result, err := m.db.Exec("DELETE FROM banned_users WHERE user_id = ?", userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unban user: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get unban result: %v", err)
+	}
+	return affected > 0, nil
+}
+
+// GetBan 获取用户的有效封禁记录（无封禁或已过期时返回nil，过期判定在Go侧以保持后端一致）
+func (m *MySQLDB) GetBan(userID int64) (*BannedUser, error) {
+	var ban BannedUser
+	err := m.db.QueryRow(`SELECT user_id, reason, banned_by, expires_at, created_at
+						  FROM banned_users WHERE user_id = ?`, userID).
+		Scan(&ban.UserID, &ban.Reason, &ban.BannedBy, &ban.ExpiresAt, &ban.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ban: %v", err)
+	}
+	if ban.ExpiresAt != nil && time.Now().After(*ban.ExpiresAt) {
+		return nil, nil
+	}
+	return &ban, nil
+}
+
+// SetNotifyPreference 设置用户对某类通知事件的开关
+func (m *MySQLDB) SetNotifyPreference(userID int64, event string, enabled bool) error {
+	query := `INSERT INTO notify_prefs (user_id, event, enabled) VALUES (?, ?, ?)
+			  ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)`
+	if _, err := m.db.Exec(query, userID, event, enabled); err != nil {
+		return fmt.Errorf("failed to set notify preference: %v", err)
+	}
+	return nil
+}
+
+// GetNotifyPreferences 获取用户显式设置过的通知开关（未设置的事件默认开启）
+func (m *MySQLDB) GetNotifyPreferences(userID int64) (map[string]bool, error) {
+	rows, err := m.db.Query("SELECT event, enabled FROM notify_prefs WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notify preferences: %v", err)
+	}
+	defer rows.Close()
+
+	prefs := make(map[string]bool)
+	for rows.Next() {
+		var event string
+		var enabled bool
+		if err := rows.Scan(&event, &enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notify preference: %v", err)
+		}
+		prefs[event] = enabled
+	}
+	return prefs, rows.Err()
+}
+
+// GetSubscribersFor 获取未关闭指定通知事件的全部用户
+func (m *MySQLDB) GetSubscribersFor(event string) ([]int64, error) {
+	query := `SELECT u.user_id FROM bot_users u
+			  LEFT JOIN notify_prefs p ON p.user_id = u.user_id AND p.event = ?
+			  WHERE COALESCE(p.enabled, TRUE)`
+	rows, err := m.db.Query(query, event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscribers: %v", err)
+	}
+	defer rows.Close()
+
+	var users []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan subscriber: %v", err)
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
+
+// GetOptInSubscribersFor 获取显式开启指定通知事件的全部用户（opt-in事件默认关闭）
+func (m *MySQLDB) GetOptInSubscribersFor(event string) ([]int64, error) {
+	rows, err := m.db.Query("SELECT user_id FROM notify_prefs WHERE event = ? AND enabled", event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query opt-in subscribers: %v", err)
+	}
+	defer rows.Close()
+
+	var users []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan opt-in subscriber: %v", err)
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
+
+// SetUserLanguage 设置用户界面语言
+func (m *MySQLDB) SetUserLanguage(userID int64, language string) error {
+	query := `INSERT INTO bot_users (user_id, language, first_seen_at, last_seen_at)
+			  VALUES (?, ?, NOW(), NOW())
+			  ON DUPLICATE KEY UPDATE language = VALUES(language)`
+	if _, err := m.db.Exec(query, userID, language); err != nil {
+		return fmt.Errorf("failed to set user language: %v", err)
+	}
+	return nil
+}
+
+// GetUserLanguage 获取用户界面语言（未设置时返回空字符串）
+func (m *MySQLDB) GetUserLanguage(userID int64) (string, error) {
+	var language string
+	err := m.db.QueryRow("SELECT language FROM bot_users WHERE user_id = ?", userID).Scan(&language)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user language: %v", err)
+	}
+	return language, nil
+}
+
+// SetUserTimezone 设置用户时区
+func (m *MySQLDB) SetUserTimezone(userID int64, timezone string) error {
+	query := `INSERT INTO bot_users (user_id, timezone, first_seen_at, last_seen_at)
+			  VALUES (?, ?, NOW(), NOW())
+			  ON DUPLICATE KEY UPDATE timezone = VALUES(timezone)`
+	if _, err := m.db.Exec(query, userID, timezone); err != nil {
+		return fmt.Errorf("failed to set user timezone: %v", err)
+	}
+	return nil
+}
+
+// GetUserTimezone 获取用户时区（未设置时返回空字符串）
+func (m *MySQLDB) GetUserTimezone(userID int64) (string, error) {
+	var timezone string
+	err := m.db.QueryRow("SELECT timezone FROM bot_users WHERE user_id = ?", userID).Scan(&timezone)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user timezone: %v", err)
+	}
+	return timezone, nil
+}
+
+// SetUserTier 设置用户层级
+func (m *MySQLDB) SetUserTier(userID int64, tier string) error {
+	query := `INSERT INTO bot_users (user_id, tier, first_seen_at, last_seen_at)
+			  VALUES (?, ?, NOW(), NOW())
+			  ON DUPLICATE KEY UPDATE tier = VALUES(tier)`
+	if _, err := m.db.Exec(query, userID, tier); err != nil {
+		return fmt.Errorf("failed to set user tier: %v", err)
+	}
+	return nil
+}
+
+// GetBroadcastTargets 按受众选择器获取定向推送的目标用户
+// 选择器见store.go接口注释：层级、语言、活跃时段和个人跟单准确率分段
+func (m *MySQLDB) GetBroadcastTargets(audience string) ([]int64, error) {
+	query := "SELECT user_id FROM bot_users"
+	var args []interface{}
+	switch {
+	case audience == "" || audience == "all":
+		// 全量用户
+	case audience == UserTierVIP:
+		query += " WHERE tier = ?"
+		args = append(args, UserTierVIP)
+	case strings.HasPrefix(audience, "lang:"):
+		query += " WHERE language = ?"
+		args = append(args, strings.TrimPrefix(audience, "lang:"))
+	case strings.HasPrefix(audience, "inactive:"):
+		days, err := parseSegmentValue(audience)
+		if err != nil {
+			return nil, err
+		}
+		query += " WHERE last_seen_at < DATE_SUB(NOW(), INTERVAL ? DAY)"
+		args = append(args, days)
+	case strings.HasPrefix(audience, "active:"):
+		days, err := parseSegmentValue(audience)
+		if err != nil {
+			return nil, err
+		}
+		query += " WHERE last_seen_at >= DATE_SUB(NOW(), INTERVAL ? DAY)"
+		args = append(args, days)
+	case strings.HasPrefix(audience, "accuracy_gte:"), strings.HasPrefix(audience, "accuracy_lt:"):
+		threshold, err := parseSegmentValue(audience)
+		if err != nil {
+			return nil, err
+		}
+		// 个人准确率 = 用户跟单中已验证预测的命中率
+		op := ">="
+		if strings.HasPrefix(audience, "accuracy_lt:") {
+			op = "<"
+		}
+		query = `SELECT uf.user_id FROM user_follows uf
+				 JOIN predictions p ON p.target_qihao = uf.qihao
+				 WHERE p.is_correct IS NOT NULL
+				 GROUP BY uf.user_id
+				 HAVING SUM(p.is_correct) / COUNT(*) * 100 ` + op + ` ?`
+		args = append(args, threshold)
+	default:
+		return nil, fmt.Errorf("unknown broadcast audience: %s", audience)
+	}
+
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get broadcast targets: %v", err)
+	}
+	defer rows.Close()
+
+	var users []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast target: %v", err)
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
+
+// parseSegmentValue 解析受众选择器冒号后的数值参数
+func parseSegmentValue(audience string) (int, error) {
+	parts := strings.SplitN(audience, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid broadcast audience: %s", audience)
+	}
+	value, err := strconv.Atoi(parts[1])
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid broadcast audience value: %s", audience)
+	}
+	return value, nil
+}
+
+// SaveCoverageGap 记录漏预测的期号（幂等）
+func (m *MySQLDB) SaveCoverageGap(qihao string) error {
+	query := `INSERT INTO coverage_gaps (qihao) VALUES (?)
+			  ON DUPLICATE KEY UPDATE qihao = qihao`
+	if _, err := m.db.Exec(query, qihao); err != nil {
+		return fmt.Errorf("failed to save coverage gap: %v", err)
+	}
+	return nil
+}
+
+// SaveLeaderboardEntry 保存算法榜单条目（同算法覆盖更新）
+func (m *MySQLDB) SaveLeaderboardEntry(entry *LeaderboardEntry) error {
+	query := `INSERT INTO leaderboard (algorithm, version, rounds, correct, accuracy_rate, best_streak, worst_streak)
+			  VALUES (?, ?, ?, ?, ?, ?, ?)
+			  ON DUPLICATE KEY UPDATE
+			  version = VALUES(version),
+			  rounds = VALUES(rounds),
+			  correct = VALUES(correct),
+			  accuracy_rate = VALUES(accuracy_rate),
+			  best_streak = VALUES(best_streak),
+			  worst_streak = VALUES(worst_streak)`
+	_, err := m.db.Exec(query, entry.Algorithm, entry.Version, entry.Rounds, entry.Correct,
+		entry.AccuracyRate, entry.BestStreak, entry.WorstStreak)
+	if err != nil {
+		return fmt.Errorf("failed to save leaderboard entry: %v", err)
+	}
+	return nil
+}
+
+// GetLeaderboard 获取算法榜单（按准确率降序）
+func (m *MySQLDB) GetLeaderboard() ([]LeaderboardEntry, error) {
+	query := `SELECT algorithm, version, rounds, correct, accuracy_rate, best_streak, worst_streak, evaluated_at
+			  FROM leaderboard ORDER BY accuracy_rate DESC, algorithm ASC`
+	rows, err := m.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query leaderboard: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []LeaderboardEntry
+	for rows.Next() {
+		var entry LeaderboardEntry
+		if err := rows.Scan(&entry.Algorithm, &entry.Version, &entry.Rounds, &entry.Correct,
+			&entry.AccuracyRate, &entry.BestStreak, &entry.WorstStreak, &entry.EvaluatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// SaveUserFollow 保存用户跟单记录（重复跟单同一期为幂等操作）
+func (m *MySQLDB) SaveUserFollow(userID int64, qihao string) error {
+	query := `INSERT INTO user_follows (user_id, qihao) VALUES (?, ?)
+			  ON DUPLICATE KEY UPDATE qihao = qihao`
+	if _, err := m.db.Exec(query, userID, qihao); err != nil {
+		return fmt.Errorf("failed to save user follow: %v", err)
+	}
+	return nil
+}
+
+// GetUserFollows 获取用户最近的跟单记录（按跟单时间降序）
+func (m *MySQLDB) GetUserFollows(userID int64, limit int) ([]UserFollow, error) {
+	query := `SELECT user_id, qihao, created_at FROM user_follows
+			  WHERE user_id = ? ORDER BY created_at DESC LIMIT ?`
+	rows, err := m.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user follows: %v", err)
+	}
+	defer rows.Close()
+
+	var follows []UserFollow
+	for rows.Next() {
+		var follow UserFollow
+		if err := rows.Scan(&follow.UserID, &follow.Qihao, &follow.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user follow: %v", err)
+		}
+		follows = append(follows, follow)
+	}
+	return follows, rows.Err()
+}
+
+// GetUserDataExport 汇总该用户存储的全部个人数据（/mydata导出用）
+func (m *MySQLDB) GetUserDataExport(userID int64) (*UserDataExport, error) {
+	export := &UserDataExport{
+		UserID:     userID,
+		ExportedAt: time.Now(),
+	}
+
+	var user BotUser
+	err := m.db.QueryRow(`SELECT user_id, COALESCE(language, ''), COALESCE(timezone, ''),
+			  COALESCE(tier, ''), first_seen_at, last_seen_at
+			  FROM bot_users WHERE user_id = ?`, userID).Scan(
+		&user.UserID, &user.Language, &user.Timezone, &user.Tier, &user.FirstSeenAt, &user.LastSeenAt)
+	switch {
+	case err == sql.ErrNoRows:
+		// 从未登记的用户：导出其余表以防存在孤儿记录
+	case err != nil:
+		return nil, fmt.Errorf("failed to query user record: %v", err)
+	default:
+		export.User = &user
+	}
+
+	prefs, err := m.GetNotifyPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(prefs) > 0 {
+		export.NotifyPrefs = prefs
+	}
+
+	if export.AlertRules, err = m.GetAlertRules(userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`SELECT user_id, qihao, created_at FROM user_follows
+			  WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user follows: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var follow UserFollow
+		if err := rows.Scan(&follow.UserID, &follow.Qihao, &follow.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user follow: %v", err)
+		}
+		export.Follows = append(export.Follows, follow)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	feedbackRows, err := m.db.Query(`SELECT id, user_id, content, created_at FROM user_feedback
+			  WHERE user_id = ? ORDER BY created_at ASC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user feedback: %v", err)
+	}
+	defer feedbackRows.Close()
+	for feedbackRows.Next() {
+		var entry Feedback
+		if err := feedbackRows.Scan(&entry.ID, &entry.UserID, &entry.Content, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback: %v", err)
+		}
+		export.Feedback = append(export.Feedback, entry)
+	}
+	return export, feedbackRows.Err()
+}
+
+// DeleteUserData 删除该用户的全部个人数据（单事务级联删除各用户表）
+// 封禁记录不删除，否则封禁可被"遗忘"规避
+func (m *MySQLDB) DeleteUserData(userID int64) (int, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// 先删子表再删登记表
+	tables := []string{"notify_prefs", "user_follows", "alert_rules", "user_feedback", "bot_users"}
+	deleted := 0
+	for _, table := range tables {
+		result, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE user_id = ?", table), userID)
 		if err != nil {
-			return "", fmt.Errorf("failed to parse qihao number: %v", err)
+			return 0, fmt.Errorf("failed to delete user data from %s: %v", table, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil {
+			deleted += int(affected)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit user data deletion: %v", err)
+	}
+	return deleted, nil
+}
+
+// SaveAlertRule 保存用户自定义提醒规则（同用户同规则同阈值为幂等操作）
+func (m *MySQLDB) SaveAlertRule(rule *AlertRule) error {
+	query := `INSERT INTO alert_rules (user_id, rule_type, threshold) VALUES (?, ?, ?)
+			  ON DUPLICATE KEY UPDATE threshold = threshold`
+	if _, err := m.db.Exec(query, rule.UserID, rule.RuleType, rule.Threshold); err != nil {
+		return fmt.Errorf("failed to save alert rule: %v", err)
+	}
+	return nil
+}
+
+// GetAlertRules 获取用户的提醒规则（按创建时间升序）
+func (m *MySQLDB) GetAlertRules(userID int64) ([]AlertRule, error) {
+	query := `SELECT id, user_id, rule_type, threshold, created_at FROM alert_rules
+			  WHERE user_id = ? ORDER BY created_at ASC`
+	return m.queryAlertRules(query, userID)
+}
+
+// GetAllAlertRules 获取全部提醒规则（供pipeline逐期评估）
+func (m *MySQLDB) GetAllAlertRules() ([]AlertRule, error) {
+	query := `SELECT id, user_id, rule_type, threshold, created_at FROM alert_rules
+			  ORDER BY user_id ASC, created_at ASC`
+	return m.queryAlertRules(query)
+}
+
+// queryAlertRules 执行提醒规则查询并扫描结果
+func (m *MySQLDB) queryAlertRules(query string, args ...interface{}) ([]AlertRule, error) {
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %v", err)
+	}
+	defer rows.Close()
+
+	var rules []AlertRule
+	for rows.Next() {
+		var rule AlertRule
+		if err := rows.Scan(&rule.ID, &rule.UserID, &rule.RuleType, &rule.Threshold, &rule.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %v", err)
 		}
-		return fmt.Sprintf("%s%03d", prefix, num+1), nil
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// DeleteAlertRules 删除用户的全部提醒规则，返回删除条数
+func (m *MySQLDB) DeleteAlertRules(userID int64) (int, error) {
+	result, err := m.db.Exec("DELETE FROM alert_rules WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete alert rules: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %v", err)
+	}
+	return int(affected), nil
+}
+
+// SaveRoundFeatures 保存单期滚动特征（同期号覆盖更新）
+func (m *MySQLDB) SaveRoundFeatures(features *RoundFeatures) error {
+	query := `INSERT INTO features (qihao, odd_even_seq, sum_ma5, sum_ma10, digit_parity, odd_even_streak, big_small_streak)
+			  VALUES (?, ?, ?, ?, ?, ?, ?)
+			  ON DUPLICATE KEY UPDATE
+			  odd_even_seq = VALUES(odd_even_seq),
+			  sum_ma5 = VALUES(sum_ma5),
+			  sum_ma10 = VALUES(sum_ma10),
+			  digit_parity = VALUES(digit_parity),
+			  odd_even_streak = VALUES(odd_even_streak),
+			  big_small_streak = VALUES(big_small_streak)`
+	_, err := m.db.Exec(query, features.Qihao, features.OddEvenSeq, features.SumMA5,
+		features.SumMA10, features.DigitParity, features.OddEvenStreak, features.BigSmallStreak)
+	if err != nil {
+		return fmt.Errorf("failed to save round features: %v", err)
+	}
+	return nil
+}
+
+// GetRoundFeatures 获取指定期号的滚动特征（不存在时返回nil）
+func (m *MySQLDB) GetRoundFeatures(qihao string) (*RoundFeatures, error) {
+	query := `SELECT qihao, odd_even_seq, sum_ma5, sum_ma10, digit_parity, odd_even_streak, big_small_streak, created_at
+			  FROM features WHERE qihao = ?`
+	var features RoundFeatures
+	err := m.db.QueryRow(query, qihao).Scan(&features.Qihao, &features.OddEvenSeq, &features.SumMA5,
+		&features.SumMA10, &features.DigitParity, &features.OddEvenStreak, &features.BigSmallStreak, &features.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query round features: %v", err)
+	}
+	return &features, nil
+}
+
+// SaveRoundStageTime 记录单期处理阶段的时间戳（stage见Stage*常量，幂等覆盖）
+func (m *MySQLDB) SaveRoundStageTime(qihao, stage string, at time.Time) error {
+	column, err := stageColumn(stage)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO round_timelines (qihao, %s) VALUES (?, ?)
+			  ON DUPLICATE KEY UPDATE %s = VALUES(%s)`, column, column, column)
+	if _, err := m.db.Exec(query, qihao, at); err != nil {
+		return fmt.Errorf("failed to save round stage time: %v", err)
+	}
+	return nil
+}
+
+// stageColumn 处理阶段到时间线列名的映射（白名单，阶段名不直接拼入SQL）
+func stageColumn(stage string) (string, error) {
+	switch stage {
+	case StageFetched, StageSaved, StageVerified, StagePredicted, StageBroadcast:
+		return stage + "_at", nil
+	default:
+		return "", fmt.Errorf("unknown round stage: %s", stage)
 	}
+}
 
-	return "", fmt.Errorf("invalid qihao format: %s", latestQihao)
+// GetRoundTimeline 获取单期各处理阶段的时间戳（不存在时返回nil）
+func (m *MySQLDB) GetRoundTimeline(qihao string) (*RoundTimeline, error) {
+	query := `SELECT qihao, fetched_at, saved_at, verified_at, predicted_at, broadcast_at
+			  FROM round_timelines WHERE qihao = ?`
+	var timeline RoundTimeline
+	err := m.db.QueryRow(query, qihao).Scan(&timeline.Qihao, &timeline.FetchedAt,
+		&timeline.SavedAt, &timeline.VerifiedAt, &timeline.PredictedAt, &timeline.BroadcastAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query round timeline: %v", err)
+	}
+	return &timeline, nil
+}
+
+// GetAppState 读取应用状态项（不存在时返回空字符串）
+func (m *MySQLDB) GetAppState(key string) (string, error) {
+	var value string
+	err := m.db.QueryRow("SELECT state_value FROM app_state WHERE state_key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get app state %s: %v", key, err)
+	}
+	return value, nil
 }
 
 // ParseOpenNum 解析开奖号码字符串
@@ -0,0 +1,244 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"pc28-bot/internal/config"
+)
+
+// DB 存储后端接口
+// MySQL为生产默认实现，memory实现用于本地快速评估算法
+type DB interface {
+	// Close 关闭存储后端
+	Close() error
+
+	// SaveLotteryResult 保存开奖数据
+	SaveLotteryResult(result *LotteryResult) error
+
+	// GetLatestLotteryResults 获取最新的开奖数据
+	GetLatestLotteryResults(limit int) ([]LotteryResult, error)
+
+	// GetLotteryResultByQihao 根据期号获取开奖数据
+	GetLotteryResultByQihao(qihao string) (*LotteryResult, error)
+
+	// GetLotteryHistory 获取历史开奖数据
+	GetLotteryHistory(limit int) ([]LotteryResult, error)
+
+	// SavePrediction 保存预测记录
+	SavePrediction(prediction *Prediction) error
+
+	// UpdatePredictionResult 更新预测结果
+	UpdatePredictionResult(qihao string, actualNum string, isCorrect bool) error
+
+	// GetLatestPredictions 获取最新的预测记录
+	GetLatestPredictions(limit int) ([]Prediction, error)
+
+	// GetPredictionByQihao 获取目标期号最新的预测记录（不存在时返回nil）
+	GetPredictionByQihao(qihao string) (*Prediction, error)
+
+	// GetPredictionStats 获取预测统计信息
+	GetPredictionStats() (*PredictionStats, error)
+
+	// GetPredictionStatsWindow 获取最近window条已验证预测的滚动统计
+	// （window<=0时等价于GetPredictionStats的全量口径）
+	GetPredictionStatsWindow(window int) (*PredictionStats, error)
+
+	// SaveVerificationResult 保存预测验证结果（命中判定由predictor层的策略完成，
+	// policy为判定时使用的策略名称，随记录保存以便历史统计可追溯口径）
+	SaveVerificationResult(qihao string, actualResult *LotteryResult, isCorrect bool, policy string) error
+
+	// SavePolicyRecheck 写入策略复判结果到平行列（不改动is_correct，
+	// 供切换判定策略前评估新口径对历史记录的影响）
+	SavePolicyRecheck(id int64, policy string, isCorrect bool) error
+
+	// SavePositionHits 记录预测验证时按位置命中的数量（0-3，验证器回填）
+	SavePositionHits(qihao string, hits int) error
+
+	// CleanOldData 清理超出保留窗口的旧数据（retentionHours<=0时按24小时处理）
+	CleanOldData(retentionHours int) error
+
+	// GetLotteryResultsBefore 获取开奖时间早于cutoff的开奖数据（按开奖时间升序，最多limit条）
+	// 供冷存储归档分批拉取待压缩的旧数据
+	GetLotteryResultsBefore(cutoff time.Time, limit int) ([]LotteryResult, error)
+
+	// DeleteLotteryResultsByQihao 按期号批量删除开奖数据（归档完成后移除热存储记录）
+	DeleteLotteryResultsByQihao(qihaos []string) (int, error)
+
+	// RestoreLotteryResults 批量恢复开奖数据（单事务执行，已存在的期号跳过不覆盖），
+	// 返回实际写入的条数
+	RestoreLotteryResults(results []LotteryResult) (int, error)
+
+	// CheckNewQihao 检查是否有新的期号
+	CheckNewQihao(qihao string) (bool, error)
+
+	// GetUnverifiedPredictions 获取所有未验证的预测记录
+	GetUnverifiedPredictions() ([]Prediction, error)
+
+	// GetPendingPredictionsBefore 获取目标期号不大于指定期号且未验证的预测（按期号升序）
+	// 相比全量扫描，可按开奖进度精确圈定待补验的记录
+	GetPendingPredictionsBefore(qihao string) ([]Prediction, error)
+
+	// CleanupExpiredPredictions 清理过期的待开奖预测记录
+	CleanupExpiredPredictions(latestQihao string) (int, error)
+
+	// GetNextQihao 获取下一个期号
+	GetNextQihao() (string, error)
+
+	// SaveAppState 保存应用状态项（崩溃恢复）
+	SaveAppState(key, value string) error
+
+	// GetAppState 读取应用状态项（不存在时返回空字符串）
+	GetAppState(key string) (string, error)
+
+	// SaveOutboxEvent 写入领域事件outbox记录（写入后回填事件ID）
+	SaveOutboxEvent(event *OutboxEvent) error
+
+	// GetPendingOutboxEvents 获取待投递的领域事件（按写入顺序，最多limit条）
+	GetPendingOutboxEvents(limit int) ([]OutboxEvent, error)
+
+	// MarkOutboxDispatched 标记领域事件已投递
+	MarkOutboxDispatched(id int64) error
+
+	// SaveAuditEntry 保存管理员操作审计记录
+	SaveAuditEntry(entry *AuditEntry) error
+
+	// GetAuditEntries 获取最新的审计记录
+	GetAuditEntries(limit int) ([]AuditEntry, error)
+
+	// SaveFeedback 保存用户反馈
+	SaveFeedback(feedback *Feedback) error
+
+	// GetFeedbackEntries 获取最新的用户反馈
+	GetFeedbackEntries(limit int) ([]Feedback, error)
+
+	// SaveDailyStats 保存每日统计快照（同日期覆盖更新）
+	SaveDailyStats(stats *DailyStats) error
+
+	// GetDailyStats 获取最新的每日统计快照
+	GetDailyStats(limit int) ([]DailyStats, error)
+
+	// GetSumDistribution 统计最近N期开奖和值的分布（和值 -> 出现次数）
+	GetSumDistribution(limit int) (map[int]int, error)
+
+	// GetPositionDistribution 统计最近N期开奖各位置数字的分布
+	GetPositionDistribution(limit int) (PositionDistribution, error)
+
+	// RecordUserActivity 记录用户命令活跃（首次出现时登记为新用户）
+	RecordUserActivity(userID int64, command string) error
+
+	// GetEngagementStats 统计用户增长与命令使用情况（windowDays为流失判定和命令统计窗口）
+	GetEngagementStats(windowDays int) (*EngagementStats, error)
+
+	// BanUser 封禁用户（同用户重复封禁为覆盖更新）
+	BanUser(ban *BannedUser) error
+
+	// UnbanUser 解除用户封禁，返回是否存在封禁记录
+	UnbanUser(userID int64) (bool, error)
+
+	// GetBan 获取用户的有效封禁记录（无封禁或已过期时返回nil）
+	GetBan(userID int64) (*BannedUser, error)
+
+	// SetNotifyPreference 设置用户对某类通知事件的开关
+	SetNotifyPreference(userID int64, event string, enabled bool) error
+
+	// GetNotifyPreferences 获取用户显式设置过的通知开关（未设置的事件默认开启）
+	GetNotifyPreferences(userID int64) (map[string]bool, error)
+
+	// GetSubscribersFor 获取未关闭指定通知事件的全部用户
+	GetSubscribersFor(event string) ([]int64, error)
+
+	// GetOptInSubscribersFor 获取显式开启指定通知事件的全部用户（opt-in事件默认关闭）
+	GetOptInSubscribersFor(event string) ([]int64, error)
+
+	// SetUserLanguage 设置用户界面语言（/start引导流程完成时写入）
+	SetUserLanguage(userID int64, language string) error
+
+	// GetUserLanguage 获取用户界面语言（未设置时返回空字符串）
+	GetUserLanguage(userID int64) (string, error)
+
+	// SetUserTimezone 设置用户时区（IANA名称，消息中的时间按此时区展示）
+	SetUserTimezone(userID int64, timezone string) error
+
+	// GetUserTimezone 获取用户时区（未设置时返回空字符串）
+	GetUserTimezone(userID int64) (string, error)
+
+	// SetUserTier 设置用户层级（空表示普通用户，见UserTierVIP）
+	SetUserTier(userID int64, tier string) error
+
+	// GetBroadcastTargets 按受众选择器获取定向推送的目标用户
+	// 受众选择器：all（全量）、vip（VIP层级）、lang:<code>（语言分段）、
+	// inactive:<天数>/active:<天数>（按最近活跃时间分段）、
+	// accuracy_gte:<百分比>/accuracy_lt:<百分比>（按个人跟单准确率分段）
+	GetBroadcastTargets(audience string) ([]int64, error)
+
+	// SaveRoundFeatures 保存单期滚动特征（同期号覆盖更新）
+	SaveRoundFeatures(features *RoundFeatures) error
+
+	// GetRoundFeatures 获取指定期号的滚动特征（不存在时返回nil）
+	GetRoundFeatures(qihao string) (*RoundFeatures, error)
+
+	// SaveCoverageGap 记录漏预测的期号（开奖时从未存在对应预测，幂等）
+	SaveCoverageGap(qihao string) error
+
+	// SaveRoundStageTime 记录单期处理阶段的时间戳（stage见Stage*常量，幂等覆盖）
+	SaveRoundStageTime(qihao, stage string, at time.Time) error
+
+	// GetRoundTimeline 获取单期各处理阶段的时间戳（不存在时返回nil）
+	GetRoundTimeline(qihao string) (*RoundTimeline, error)
+
+	// SaveLeaderboardEntry 保存算法榜单条目（同算法覆盖更新）
+	SaveLeaderboardEntry(entry *LeaderboardEntry) error
+
+	// GetLeaderboard 获取算法榜单（按准确率降序）
+	GetLeaderboard() ([]LeaderboardEntry, error)
+
+	// SaveAlertRule 保存用户自定义提醒规则（同用户同规则同阈值为幂等操作）
+	SaveAlertRule(rule *AlertRule) error
+
+	// GetAlertRules 获取用户的提醒规则（按创建时间升序）
+	GetAlertRules(userID int64) ([]AlertRule, error)
+
+	// GetAllAlertRules 获取全部提醒规则（供pipeline逐期评估）
+	GetAllAlertRules() ([]AlertRule, error)
+
+	// DeleteAlertRules 删除用户的全部提醒规则，返回删除条数
+	DeleteAlertRules(userID int64) (int, error)
+
+	// SaveUserFollow 保存用户跟单记录（重复跟单同一期为幂等操作）
+	SaveUserFollow(userID int64, qihao string) error
+
+	// GetUserFollows 获取用户最近的跟单记录（按跟单时间降序）
+	GetUserFollows(userID int64, limit int) ([]UserFollow, error)
+
+	// GetUserDataExport 汇总该用户存储的全部个人数据（/mydata导出用）
+	GetUserDataExport(userID int64) (*UserDataExport, error)
+
+	// DeleteUserData 删除该用户的全部个人数据（登记信息、通知偏好、
+	// 提醒规则、跟单记录、反馈），返回删除的记录总数；
+	// 封禁记录属于平台治理数据，不在删除范围内
+	DeleteUserData(userID int64) (int, error)
+}
+
+// NewDB 根据配置的driver创建存储后端
+// 配置了write_behind_flush时在后端外包一层写后缓冲
+func NewDB(cfg *config.Database) (DB, error) {
+	var backend DB
+	var err error
+	switch cfg.Driver {
+	case "", "mysql":
+		backend, err = NewMySQLDB(cfg)
+	case "memory":
+		backend = NewMemoryDB()
+	default:
+		return nil, fmt.Errorf("unknown database driver: %s", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.WriteBehindFlush > 0 {
+		return NewWriteBehindDB(backend, cfg.WriteBehindFlush), nil
+	}
+	return backend, nil
+}
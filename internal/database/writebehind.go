@@ -0,0 +1,252 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"pc28-bot/internal/logger"
+)
+
+// WriteBehindDB 写后缓冲存储包装器
+// 多彩种模式下写入频率成倍增长，本包装器将开奖数据和预测记录的写入
+// 先缓冲在内存中，按固定间隔批量刷入底层存储，降低写入压力。
+//
+// 持久性保证：
+//   - 所有读取缓冲内容可能覆盖的方法在查询前都会先刷写缓冲，
+//     保证读到自己刚写入的数据（read-your-writes）
+//   - 刷写失败时记录重新回到缓冲队列头部，下一轮刷写继续重试，不丢弃
+//   - Close在关闭底层存储前执行最终刷写，正常停机不丢数据
+//   - 进程崩溃时最多丢失一个刷写间隔内的缓冲写入，间隔应据此取值
+type WriteBehindDB struct {
+	DB
+
+	mutex              sync.Mutex
+	pendingResults     []*LotteryResult
+	pendingPredictions []*Prediction
+
+	flushInterval time.Duration
+	stopChannel   chan struct{}
+	doneChannel   chan struct{}
+}
+
+// NewWriteBehindDB 创建写后缓冲包装器并启动定时刷写循环
+func NewWriteBehindDB(backend DB, flushInterval time.Duration) *WriteBehindDB {
+	wb := &WriteBehindDB{
+		DB:            backend,
+		flushInterval: flushInterval,
+		stopChannel:   make(chan struct{}),
+		doneChannel:   make(chan struct{}),
+	}
+
+	go wb.flushLoop()
+	logger.Infof("Write-behind buffering enabled, flush interval: %v", flushInterval)
+	return wb
+}
+
+// flushLoop 定时刷写循环
+func (wb *WriteBehindDB) flushLoop() {
+	defer close(wb.doneChannel)
+
+	ticker := time.NewTicker(wb.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := wb.Flush(); err != nil {
+				logger.Warnf("Write-behind flush failed (will retry): %v", err)
+			}
+		case <-wb.stopChannel:
+			return
+		}
+	}
+}
+
+// SaveLotteryResult 缓冲开奖数据写入（定时批量刷入底层存储）
+func (wb *WriteBehindDB) SaveLotteryResult(result *LotteryResult) error {
+	wb.mutex.Lock()
+	defer wb.mutex.Unlock()
+
+	buffered := *result
+	wb.pendingResults = append(wb.pendingResults, &buffered)
+	return nil
+}
+
+// SavePrediction 缓冲预测记录写入（定时批量刷入底层存储）
+func (wb *WriteBehindDB) SavePrediction(prediction *Prediction) error {
+	wb.mutex.Lock()
+	defer wb.mutex.Unlock()
+
+	buffered := *prediction
+	wb.pendingPredictions = append(wb.pendingPredictions, &buffered)
+	return nil
+}
+
+// Flush 将缓冲中的全部写入刷入底层存储
+// 刷写失败的记录重新放回队列头部，保持原有顺序等待重试
+func (wb *WriteBehindDB) Flush() error {
+	wb.mutex.Lock()
+	results := wb.pendingResults
+	predictions := wb.pendingPredictions
+	wb.pendingResults = nil
+	wb.pendingPredictions = nil
+	wb.mutex.Unlock()
+
+	if len(results) == 0 && len(predictions) == 0 {
+		return nil
+	}
+
+	for i, result := range results {
+		if err := wb.DB.SaveLotteryResult(result); err != nil {
+			wb.requeue(results[i:], predictions)
+			return fmt.Errorf("failed to flush lottery result %s: %v", result.Qihao, err)
+		}
+	}
+	for i, prediction := range predictions {
+		if err := wb.DB.SavePrediction(prediction); err != nil {
+			wb.requeue(nil, predictions[i:])
+			return fmt.Errorf("failed to flush prediction %s: %v", prediction.TargetQihao, err)
+		}
+	}
+
+	logger.Debugf("Write-behind flushed %d results, %d predictions", len(results), len(predictions))
+	return nil
+}
+
+// requeue 将刷写失败的记录放回缓冲队列头部
+func (wb *WriteBehindDB) requeue(results []*LotteryResult, predictions []*Prediction) {
+	wb.mutex.Lock()
+	defer wb.mutex.Unlock()
+
+	wb.pendingResults = append(results, wb.pendingResults...)
+	wb.pendingPredictions = append(predictions, wb.pendingPredictions...)
+}
+
+// GetLatestLotteryResults 刷写缓冲后查询，保证读到缓冲中的开奖数据
+func (wb *WriteBehindDB) GetLatestLotteryResults(limit int) ([]LotteryResult, error) {
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return wb.DB.GetLatestLotteryResults(limit)
+}
+
+// GetLotteryResultByQihao 刷写缓冲后查询
+func (wb *WriteBehindDB) GetLotteryResultByQihao(qihao string) (*LotteryResult, error) {
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return wb.DB.GetLotteryResultByQihao(qihao)
+}
+
+// GetLotteryHistory 刷写缓冲后查询
+func (wb *WriteBehindDB) GetLotteryHistory(limit int) ([]LotteryResult, error) {
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return wb.DB.GetLotteryHistory(limit)
+}
+
+// CheckNewQihao 刷写缓冲后检查，避免将缓冲中已保存的期号误判为新期
+func (wb *WriteBehindDB) CheckNewQihao(qihao string) (bool, error) {
+	if err := wb.Flush(); err != nil {
+		return false, err
+	}
+	return wb.DB.CheckNewQihao(qihao)
+}
+
+// GetNextQihao 刷写缓冲后计算下一期号
+func (wb *WriteBehindDB) GetNextQihao() (string, error) {
+	if err := wb.Flush(); err != nil {
+		return "", err
+	}
+	return wb.DB.GetNextQihao()
+}
+
+// GetSumDistribution 刷写缓冲后统计和值分布
+func (wb *WriteBehindDB) GetSumDistribution(limit int) (map[int]int, error) {
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return wb.DB.GetSumDistribution(limit)
+}
+
+// GetLatestPredictions 刷写缓冲后查询，保证读到缓冲中的预测记录
+func (wb *WriteBehindDB) GetLatestPredictions(limit int) ([]Prediction, error) {
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return wb.DB.GetLatestPredictions(limit)
+}
+
+// GetPredictionByQihao 刷写缓冲后查询
+func (wb *WriteBehindDB) GetPredictionByQihao(qihao string) (*Prediction, error) {
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return wb.DB.GetPredictionByQihao(qihao)
+}
+
+// GetPredictionStats 刷写缓冲后统计
+func (wb *WriteBehindDB) GetPredictionStats() (*PredictionStats, error) {
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return wb.DB.GetPredictionStats()
+}
+
+// GetUnverifiedPredictions 刷写缓冲后查询
+func (wb *WriteBehindDB) GetUnverifiedPredictions() ([]Prediction, error) {
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return wb.DB.GetUnverifiedPredictions()
+}
+
+// GetPendingPredictionsBefore 刷写缓冲后查询
+func (wb *WriteBehindDB) GetPendingPredictionsBefore(qihao string) ([]Prediction, error) {
+	if err := wb.Flush(); err != nil {
+		return nil, err
+	}
+	return wb.DB.GetPendingPredictionsBefore(qihao)
+}
+
+// UpdatePredictionResult 刷写缓冲后更新，保证更新命中缓冲中尚未落库的记录
+func (wb *WriteBehindDB) UpdatePredictionResult(qihao string, actualNum string, isCorrect bool) error {
+	if err := wb.Flush(); err != nil {
+		return err
+	}
+	return wb.DB.UpdatePredictionResult(qihao, actualNum, isCorrect)
+}
+
+// SaveVerificationResult 刷写缓冲后保存验证结果
+func (wb *WriteBehindDB) SaveVerificationResult(qihao string, actualResult *LotteryResult, isCorrect bool, policy string) error {
+	if err := wb.Flush(); err != nil {
+		return err
+	}
+	return wb.DB.SaveVerificationResult(qihao, actualResult, isCorrect, policy)
+}
+
+// CleanupExpiredPredictions 刷写缓冲后清理，避免清理判定遗漏缓冲中的记录
+func (wb *WriteBehindDB) CleanupExpiredPredictions(latestQihao string) (int, error) {
+	if err := wb.Flush(); err != nil {
+		return 0, err
+	}
+	return wb.DB.CleanupExpiredPredictions(latestQihao)
+}
+
+// Close 停止刷写循环，执行最终刷写后关闭底层存储
+func (wb *WriteBehindDB) Close() error {
+	close(wb.stopChannel)
+	<-wb.doneChannel
+
+	if err := wb.Flush(); err != nil {
+		logger.Errorf("Final write-behind flush failed: %v", err)
+	}
+	return wb.DB.Close()
+}
+
+// NewLeaderElector 转发底层后端的领导者选举能力（底层不具备时返回nil）
+func (wb *WriteBehindDB) NewLeaderElector(lockName string) *LeaderElector {
+	return NewLeaderElectorFor(wb.DB, lockName)
+}
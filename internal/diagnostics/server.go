@@ -0,0 +1,125 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"pc28-bot/internal/logger"
+)
+
+// Server 运行时诊断HTTP服务
+// 暴露net/http/pprof和/debug/vars（协程数、内存、运行时长），
+// 用于定位生产环境的内存增长和协程泄漏问题
+type Server struct {
+	listenAddr string
+	authToken  string
+	startTime  time.Time
+	httpServer *http.Server
+	handlers   map[string]http.HandlerFunc // 额外注册的业务端点
+}
+
+// NewServer 创建诊断服务
+func NewServer(listenAddr, authToken string) *Server {
+	return &Server{
+		listenAddr: listenAddr,
+		authToken:  authToken,
+		startTime:  time.Now(),
+		handlers:   make(map[string]http.HandlerFunc),
+	}
+}
+
+// RegisterHandler 注册额外端点（在Start之前调用，同样经过token鉴权）
+func (s *Server) RegisterHandler(path string, handler http.HandlerFunc) {
+	s.handlers[path] = handler
+}
+
+// Start 启动诊断服务（后台协程）
+func (s *Server) Start() {
+	mux := http.NewServeMux()
+
+	// pprof端点
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// 运行时状态端点
+	mux.HandleFunc("/debug/vars", s.handleVars)
+
+	// 额外注册的业务端点
+	for path, handler := range s.handlers {
+		mux.HandleFunc(path, handler)
+	}
+
+	s.httpServer = &http.Server{
+		Addr:    s.listenAddr,
+		Handler: s.authMiddleware(mux),
+	}
+
+	go func() {
+		logger.Infof("Diagnostics server listening on %s", s.listenAddr)
+		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Diagnostics server error: %v", err)
+		}
+	}()
+}
+
+// Stop 停止诊断服务
+func (s *Server) Stop() error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Close()
+}
+
+// authMiddleware 管理员token鉴权（X-Debug-Token头或token查询参数）
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authToken == "" {
+			// 未配置token时拒绝访问，避免诊断信息意外暴露
+			http.Error(w, "diagnostics auth token not configured", http.StatusForbidden)
+			return
+		}
+
+		token := r.Header.Get("X-Debug-Token")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if token != s.authToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleVars 输出运行时状态（协程数、内存统计、运行时长）
+func (s *Server) handleVars(w http.ResponseWriter, r *http.Request) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	vars := map[string]interface{}{
+		"goroutines":     runtime.NumGoroutine(),
+		"uptime":         time.Since(s.startTime).String(),
+		"go_version":     runtime.Version(),
+		"heap_alloc":     memStats.HeapAlloc,
+		"heap_objects":   memStats.HeapObjects,
+		"heap_sys":       memStats.HeapSys,
+		"total_alloc":    memStats.TotalAlloc,
+		"num_gc":         memStats.NumGC,
+		"last_gc":        time.Unix(0, int64(memStats.LastGC)).Format(time.RFC3339),
+		"gc_pause_total": time.Duration(memStats.PauseTotalNs).String(),
+		"next_gc_target": memStats.NextGC,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(vars); err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode vars: %v", err), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,38 @@
+package logger
+
+import "fmt"
+
+// 控制台状态输出级别
+const (
+	ConsoleQuiet   = "quiet"   // 不输出状态信息（日志仍正常输出）
+	ConsoleNormal  = "normal"  // 输出关键状态（默认）
+	ConsoleVerbose = "verbose" // 输出全部状态细节
+)
+
+var consoleMode = ConsoleNormal
+
+// SetConsoleMode 设置控制台状态输出级别（无效值回退到normal）
+func SetConsoleMode(mode string) {
+	switch mode {
+	case ConsoleQuiet, ConsoleNormal, ConsoleVerbose:
+		consoleMode = mode
+	default:
+		consoleMode = ConsoleNormal
+	}
+}
+
+// Status 输出关键状态信息（quiet模式下不输出）
+func Status(format string, args ...interface{}) {
+	if consoleMode == ConsoleQuiet {
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
+
+// StatusVerbose 输出详细状态信息（仅verbose模式下输出）
+func StatusVerbose(format string, args ...interface{}) {
+	if consoleMode != ConsoleVerbose {
+		return
+	}
+	fmt.Printf(format+"\n", args...)
+}
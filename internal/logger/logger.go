@@ -1,27 +1,81 @@
 package logger
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 var Log *logrus.Logger
 
+// Fields 结构化日志字段（logrus.Fields的别名，避免各模块直接依赖logrus）
+type Fields = logrus.Fields
+
 // InitLogger 初始化日志器
 func InitLogger(level string) {
+	InitLoggerWithFormat(level, "")
+}
+
+// InitLoggerWithFormat 初始化日志器并指定输出格式（text或json）
+func InitLoggerWithFormat(level, format string) {
 	Log = logrus.New()
-	
+
 	// 设置输出格式
-	Log.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp:   true,
-		TimestampFormat: "2006-01-02 15:04:05",
-	})
-	
+	SetFormat(format)
+
 	// 设置输出到标准输出
 	Log.SetOutput(os.Stdout)
-	
+
 	// 设置日志级别
+	SetLevel(level)
+}
+
+// SetFormat 设置日志输出格式（json用于Loki/ELK等日志采集，默认text）
+func SetFormat(format string) {
+	switch format {
+	case "json":
+		Log.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: "2006-01-02 15:04:05",
+		})
+	default:
+		Log.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp:   true,
+			TimestampFormat: "2006-01-02 15:04:05",
+		})
+	}
+}
+
+// WithFields 创建带结构化字段的日志条目
+func WithFields(fields Fields) *logrus.Entry {
+	return Log.WithFields(fields)
+}
+
+// WithField 创建带单个结构化字段的日志条目
+func WithField(key string, value interface{}) *logrus.Entry {
+	return Log.WithField(key, value)
+}
+
+// AddHook 注册logrus钩子（如管理员错误转发）
+func AddHook(hook logrus.Hook) {
+	Log.AddHook(hook)
+}
+
+// NewCorrelationID 生成短关联ID，用于串联同一处理流程的日志
+func NewCorrelationID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		// 随机源不可用时退化为时间戳
+		return fmt.Sprintf("%08x", uint32(time.Now().UnixNano()))
+	}
+	return hex.EncodeToString(buf)
+}
+
+// SetLevel 动态设置日志级别（支持运行时调整）
+func SetLevel(level string) {
 	switch level {
 	case "debug":
 		Log.SetLevel(logrus.DebugLevel)
@@ -85,4 +139,3 @@ func Fatal(args ...interface{}) {
 func Fatalf(format string, args ...interface{}) {
 	Log.Fatalf(format, args...)
 }
-
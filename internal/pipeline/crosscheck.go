@@ -0,0 +1,113 @@
+package pipeline
+
+import (
+	"pc28-bot/internal/alert"
+	"pc28-bot/internal/api"
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/qihao"
+)
+
+// crossCheckMaxAttempts 交叉核对的最大重试轮数
+// 副源尚未发布该期时，超过该轮数后放行（副源滞后不应阻塞主流程）；
+// 号码不一致时，超过该轮数后触发告警并继续扣留该期
+const crossCheckMaxAttempts = 3
+
+// crossCheckLookback 在副源历史数据中查找目标期号的回看深度
+const crossCheckLookback = 10
+
+// CrossChecker 第二数据源交叉核对器
+// 新开奖数据入库前与副源核对开奖号码：一致才放行，不一致时该期
+// 暂缓入库并在后续轮询中重试，持续不一致则告警管理员而不落可疑数据。
+// 仅由轮询协程串行调用，无需加锁（与RoundProcessor的corrID约定一致）
+type CrossChecker struct {
+	secondary    *api.Client
+	alertManager *alert.Manager
+
+	// 待确认期号 → 已核对轮数（确认或放弃后删除，旧期号随进度清理）
+	pending map[string]int
+}
+
+// NewCrossChecker 创建第二数据源交叉核对器
+func NewCrossChecker(secondary *api.Client, alertManager *alert.Manager) *CrossChecker {
+	return &CrossChecker{
+		secondary:    secondary,
+		alertManager: alertManager,
+		pending:      make(map[string]int),
+	}
+}
+
+// Check 对新开奖数据做副源交叉核对
+// 返回true表示可以入库（两源一致；或副源不可达、持续缺失该期时放行），
+// 返回false表示本期暂缓入库，等待下一轮轮询重试
+func (c *CrossChecker) Check(result *database.LotteryResult) bool {
+	c.prune(result.Qihao)
+
+	other, err := c.lookup(result.Qihao)
+	if err != nil {
+		// 副源不可达时不阻塞主流程（核对是增强手段，不是单点依赖）
+		logger.Warnf("Cross-check skipped for %s: secondary source unavailable: %v", result.Qihao, err)
+		delete(c.pending, result.Qihao)
+		return true
+	}
+
+	if other == nil {
+		// 副源尚未发布该期：扣留重试，持续缺失视为副源滞后并放行
+		c.pending[result.Qihao]++
+		if c.pending[result.Qihao] >= crossCheckMaxAttempts {
+			logger.Warnf("Cross-check gave up for %s: round missing from secondary source after %d attempts",
+				result.Qihao, crossCheckMaxAttempts)
+			delete(c.pending, result.Qihao)
+			return true
+		}
+		logger.WithFields(logger.Fields{
+			"module":  "pipeline",
+			"qihao":   result.Qihao,
+			"attempt": c.pending[result.Qihao],
+		}).Info("Round not yet published by secondary source, holding for confirmation")
+		return false
+	}
+
+	if other.OpenNum != result.OpenNum {
+		// 号码不一致：扣留重试（任一源可能随后自我更正），
+		// 持续不一致则告警并继续扣留，绝不落可疑数据
+		c.pending[result.Qihao]++
+		logger.Warnf("Cross-check mismatch for %s: primary %s vs secondary %s (attempt %d/%d)",
+			result.Qihao, result.OpenNum, other.OpenNum, c.pending[result.Qihao], crossCheckMaxAttempts)
+		if c.pending[result.Qihao] >= crossCheckMaxAttempts {
+			c.alertManager.OnDataMismatch(result.Qihao, result.OpenNum, other.OpenNum)
+		}
+		return false
+	}
+
+	// 两源一致，放行入库
+	if c.pending[result.Qihao] > 0 {
+		logger.Status("✅ 期号 %s 交叉核对通过（第 %d 轮确认）", result.Qihao, c.pending[result.Qihao]+1)
+	}
+	delete(c.pending, result.Qihao)
+	c.alertManager.OnDataMismatchResolved()
+	return true
+}
+
+// lookup 在副源数据中查找目标期号，未发布时返回nil
+func (c *CrossChecker) lookup(target string) (*database.LotteryResult, error) {
+	rounds, err := c.secondary.GetHistoricalData(crossCheckLookback)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rounds {
+		if rounds[i].Qihao == target {
+			return &rounds[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// prune 清理早于当前期号的待确认记录（开奖进度越过后不再重试）
+func (c *CrossChecker) prune(current string) {
+	for q := range c.pending {
+		if q != current && qihao.Compare(q, current) < 0 {
+			delete(c.pending, q)
+		}
+	}
+}
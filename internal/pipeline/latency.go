@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"sync"
+	"time"
+)
+
+// durationStats 时延聚合（计数、总和、最小、最大）
+type durationStats struct {
+	count int64
+	sum   time.Duration
+	min   time.Duration
+	max   time.Duration
+}
+
+// observe 记录一次时延样本
+func (ds *durationStats) observe(d time.Duration) {
+	if ds.count == 0 || d < ds.min {
+		ds.min = d
+	}
+	if d > ds.max {
+		ds.max = d
+	}
+	ds.count++
+	ds.sum += d
+}
+
+// snapshot 导出聚合结果
+func (ds *durationStats) snapshot() map[string]interface{} {
+	if ds.count == 0 {
+		return map[string]interface{}{"count": 0}
+	}
+	return map[string]interface{}{
+		"count": ds.count,
+		"avg":   (ds.sum / time.Duration(ds.count)).String(),
+		"min":   ds.min.String(),
+		"max":   ds.max.String(),
+	}
+}
+
+// LatencyTracker 预测时延统计
+// lead记录预测生成时刻距开奖的提前量，verify记录开奖到验证完成的延迟，
+// 上游变慢时两项指标会先于用户反馈暴露问题
+type LatencyTracker struct {
+	mutex       sync.Mutex
+	leadStats   durationStats
+	verifyStats durationStats
+}
+
+// NewLatencyTracker 创建时延统计器
+func NewLatencyTracker() *LatencyTracker {
+	return &LatencyTracker{}
+}
+
+// ObserveRound 记录一轮的预测提前量与验证延迟
+func (lt *LatencyTracker) ObserveRound(lead, verifyLag time.Duration) {
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+
+	if lead > 0 {
+		lt.leadStats.observe(lead)
+	}
+	if verifyLag > 0 {
+		lt.verifyStats.observe(verifyLag)
+	}
+}
+
+// Snapshot 导出当前时延聚合
+func (lt *LatencyTracker) Snapshot() map[string]interface{} {
+	lt.mutex.Lock()
+	defer lt.mutex.Unlock()
+
+	return map[string]interface{}{
+		"prediction_lead": lt.leadStats.snapshot(),
+		"verify_lag":      lt.verifyStats.snapshot(),
+	}
+}
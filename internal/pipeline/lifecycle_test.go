@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pc28-bot/internal/alert"
+	"pc28-bot/internal/api"
+	"pc28-bot/internal/cache"
+	"pc28-bot/internal/config"
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/predictor"
+)
+
+// writeReplayFixture 生成回放fixture文件：rounds期连续开奖，
+// 期号递增、开奖时间贴近当前以通过新鲜度检查，data按新到旧排列（与真实API一致）
+func writeReplayFixture(t *testing.T, rounds int) string {
+	t.Helper()
+
+	nums := [][3]int{
+		{1, 2, 3}, {4, 5, 6}, {7, 8, 9}, {2, 3, 4}, {5, 6, 7},
+		{0, 1, 2}, {3, 4, 5}, {6, 7, 8}, {9, 0, 1}, {1, 3, 5},
+	}
+
+	now := time.Now().UTC()
+	data := make([]database.APILotteryData, 0, rounds)
+	for i := rounds - 1; i >= 0; i-- {
+		n := nums[i%len(nums)]
+		openTime := now.Add(-time.Duration(rounds-1-i) * 8 * time.Second)
+		data = append(data, database.APILotteryData{
+			Qihao:    fmt.Sprintf("%d", 3312001+i),
+			OpenTime: openTime.Format("01-02 15:04:05"),
+			OpenNum:  fmt.Sprintf("%d+%d+%d", n[0], n[1], n[2]),
+			Sum:      fmt.Sprintf("%d", n[0]+n[1]+n[2]),
+		})
+	}
+
+	body, err := json.Marshal(&database.APIResponse{Data: data, Message: "success"})
+	if err != nil {
+		t.Fatalf("failed to marshal fixture: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "replay.json")
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	return path
+}
+
+// TestRoundLifecycleWithReplay 以回放数据源和memory后端驱动完整单期生命周期：
+// 初始化历史数据 -> 生成待开奖预测 -> 回放放出新开奖 -> 验证预测结果
+func TestRoundLifecycleWithReplay(t *testing.T) {
+	logger.InitLogger("error")
+
+	replay, err := api.NewReplayServer(writeReplayFixture(t, 14), 150*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to create replay server: %v", err)
+	}
+	url, err := replay.Start()
+	if err != nil {
+		t.Fatalf("failed to start replay server: %v", err)
+	}
+	defer replay.Stop()
+
+	db := database.NewMemoryDB()
+	cacheManager, err := cache.NewCacheManager(db, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to create cache manager: %v", err)
+	}
+	defer cacheManager.Close()
+
+	apiClient := api.NewClient(&config.API{
+		URL:        url,
+		Timeout:    5 * time.Second,
+		RetryCount: 1,
+		RetryDelay: 10 * time.Millisecond,
+	})
+
+	processor := NewRoundProcessor(db, cacheManager, apiClient,
+		predictor.NewPredictorManager(), predictor.NewValidator(db, nil),
+		alert.NewManager(&config.Alerting{}))
+
+	// 初始化：回拉历史并为下一期生成预测
+	if err := processor.InitializeHistoricalData(); err != nil {
+		t.Fatalf("InitializeHistoricalData failed: %v", err)
+	}
+
+	pending, err := db.GetUnverifiedPredictions()
+	if err != nil {
+		t.Fatalf("failed to query unverified predictions: %v", err)
+	}
+	if len(pending) == 0 {
+		t.Fatal("expected a pending prediction after initialization, got none")
+	}
+
+	// 轮询直到回放放出新一期且对应预测完成验证
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := processor.ProcessDataUpdate(); err != nil {
+			t.Fatalf("ProcessDataUpdate failed: %v", err)
+		}
+
+		predictions, err := db.GetLatestPredictions(20)
+		if err != nil {
+			t.Fatalf("failed to query latest predictions: %v", err)
+		}
+		for _, p := range predictions {
+			if p.IsCorrect == nil {
+				continue
+			}
+			if p.ActualSum == nil {
+				t.Fatalf("prediction %s verified without actual sum", p.TargetQihao)
+			}
+			return // 完整走通 抓取->预测->验证
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatal("no prediction was verified before deadline")
+}
@@ -0,0 +1,161 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+)
+
+// outbox投递参数
+const (
+	outboxRedeliverGrace = 2 * time.Minute // 事件写入后留给正常流程投递的宽限期
+	outboxBatchSize      = 20              // 单次补投递的最大事件数
+)
+
+// recordOutboxEvent 写入领域事件outbox记录，返回事件ID
+// 事件与数据在同一处理步骤内落库，广播成功后标记已投递；
+// 写入失败只告警不阻断主流程（此时退化为无补投保障的原有行为）
+func (rp *RoundProcessor) recordOutboxEvent(eventType, qihao, payload string) int64 {
+	// 对外发布通道（webhook等）与outbox落库同点触发，发布为非阻塞操作
+	for _, pub := range rp.publishers {
+		pub.Publish(eventType, qihao, payload)
+	}
+
+	event := &database.OutboxEvent{
+		EventType: eventType,
+		Qihao:     qihao,
+		Payload:   payload,
+	}
+	if err := rp.db.SaveOutboxEvent(event); err != nil {
+		logger.Warnf("Failed to record outbox event %s for %s: %v", eventType, qihao, err)
+		return 0
+	}
+	return event.ID
+}
+
+// markOutboxDispatched 标记事件已投递（事件ID为0时跳过）
+func (rp *RoundProcessor) markOutboxDispatched(id int64) {
+	if id == 0 {
+		return
+	}
+	if err := rp.db.MarkOutboxDispatched(id); err != nil {
+		logger.Warnf("Failed to mark outbox event %d dispatched: %v", id, err)
+	}
+}
+
+// outboxRoundPayload 开奖事件内容
+func outboxRoundPayload(result *database.LotteryResult) string {
+	data, err := json.Marshal(map[string]interface{}{
+		"opennum": result.OpenNum,
+		"sum":     result.SumValue,
+	})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// outboxPredictionPayload 预测事件内容
+func outboxPredictionPayload(prediction *database.Prediction) string {
+	data, err := json.Marshal(map[string]interface{}{
+		"prednum":   prediction.PredictedNum,
+		"algorithm": prediction.AlgorithmVersion,
+	})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// DispatchPendingOutbox 补投递滞留的领域事件
+// 正常流程广播成功后事件立即标记已投递，本方法只处理崩溃或发送失败
+// 留下的待投递事件：宽限期后按事件类型重新投递（至少一次语义，
+// 极端情况下用户可能收到重复推送）
+func (rp *RoundProcessor) DispatchPendingOutbox() {
+	if rp.broadcaster == nil {
+		return
+	}
+
+	events, err := rp.db.GetPendingOutboxEvents(outboxBatchSize)
+	if err != nil {
+		logger.Warnf("Failed to load pending outbox events: %v", err)
+		return
+	}
+
+	redelivered := 0
+	for i := range events {
+		event := &events[i]
+		if time.Since(event.CreatedAt) < outboxRedeliverGrace {
+			continue
+		}
+		if rp.redeliverOutboxEvent(event) {
+			rp.markOutboxDispatched(event.ID)
+			redelivered++
+		}
+	}
+
+	if redelivered > 0 {
+		logger.Status("📤 补投递了 %d 条滞留的广播事件", redelivered)
+	}
+}
+
+// redeliverOutboxEvent 按事件类型重新投递，返回是否视为投递完成
+// 关联数据已清理或事件已过时（如目标期已开奖的预测）直接视为完成
+func (rp *RoundProcessor) redeliverOutboxEvent(event *database.OutboxEvent) bool {
+	switch event.EventType {
+	case database.OutboxNewRound:
+		result, err := rp.db.GetLotteryResultByQihao(event.Qihao)
+		if err != nil || result == nil {
+			return true
+		}
+		// 缓存更新幂等，崩溃恢复时一并补齐
+		if err := rp.cacheManager.OnNewLotteryData(result); err != nil {
+			logger.Warnf("Failed to refresh cache during outbox redelivery: %v", err)
+		}
+		if err := rp.broadcaster.BroadcastRawResult(result); err != nil {
+			logger.Warnf("Outbox redelivery failed for new round %s: %v", event.Qihao, err)
+			return false
+		}
+		return true
+
+	case database.OutboxPredictionCreated:
+		// 目标期已开奖则预测广播已无意义
+		if drawn, err := rp.db.GetLotteryResultByQihao(event.Qihao); err == nil && drawn != nil {
+			return true
+		}
+		prediction, err := rp.db.GetPredictionByQihao(event.Qihao)
+		if err != nil || prediction == nil {
+			return true
+		}
+		card := rp.assemblePredictionCard(prediction)
+		if err := rp.broadcaster.BroadcastNewPrediction(card); err != nil {
+			logger.Warnf("Outbox redelivery failed for prediction %s: %v", event.Qihao, err)
+			return false
+		}
+		return true
+
+	case database.OutboxPredictionVerified:
+		prediction, err := rp.db.GetPredictionByQihao(event.Qihao)
+		if err != nil || prediction == nil || prediction.IsCorrect == nil || prediction.ActualNum == nil {
+			return true
+		}
+		outcome := "❌ Incorrect"
+		if *prediction.IsCorrect {
+			outcome = "✅ Correct"
+		}
+		notice := fmt.Sprintf("🔁 *Verification Recap*\n\nRound `%s`: predicted `%s`, drew `%s` — %s",
+			event.Qihao, prediction.PredictedNum, *prediction.ActualNum, outcome)
+		if err := rp.broadcaster.BroadcastNotice(notice); err != nil {
+			logger.Warnf("Outbox redelivery failed for verification %s: %v", event.Qihao, err)
+			return false
+		}
+		return true
+
+	default:
+		logger.Warnf("Dropping outbox event %d with unknown type %s", event.ID, event.EventType)
+		return true
+	}
+}
@@ -0,0 +1,1059 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"pc28-bot/internal/alert"
+	"pc28-bot/internal/api"
+	"pc28-bot/internal/cache"
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/predictor"
+	"pc28-bot/internal/qihao"
+	"pc28-bot/internal/schedule"
+)
+
+// Broadcaster 预测广播接口（由Telegram机器人实现，无界面模式下为nil）
+// 卡片数据（含上一期验证结果、近期战绩、连对连错和倒计时）由pipeline组装
+type Broadcaster interface {
+	BroadcastNewPrediction(card *database.PredictionCard) error
+	BroadcastNotice(text string) error
+	BroadcastRawResult(result *database.LotteryResult) error
+	SendNotice(chatID int64, text string) error
+}
+
+// EventPublisher 领域事件对外发布接口（webhook等旁路通道实现）
+// 发布必须是非阻塞的：事件在记录到outbox的同时发出，慢消费方不得拖慢开奖流程
+type EventPublisher interface {
+	Publish(eventType, qihao, payload string)
+}
+
+// RoundProcessor 单期处理器
+// 拥有完整的开奖处理流程：历史同步、新数据入库、预测验证、
+// 过期清理、新预测生成与广播，可由轮询或其他数据源驱动
+type RoundProcessor struct {
+	db             database.DB
+	cacheManager   *cache.CacheManager
+	apiClient      *api.Client
+	predictorMgr   *predictor.PredictorManager
+	validator      *predictor.Validator
+	alertManager   *alert.Manager
+	broadcaster    Broadcaster
+	trendNotifier  *TrendNotifier
+	latencyTracker *LatencyTracker
+	drawSchedule   *schedule.Schedule
+	publishers     []EventPublisher
+	crossChecker   *CrossChecker
+
+	// 错误状态跟踪（按错误类别去重日志，见classifyError）
+	lastAPIError string
+	lastDBError  string
+
+	// 当前处理轮次的关联ID（单轮处理串行执行，无需加锁）
+	corrID string
+
+	// 当前处理轮次的期号（各阶段时间戳记到该期的时间线上）
+	timelineQihao string
+
+	// 本轮已验证的上一期预测（随下一期预测广播后清空）
+	lastVerified *database.Prediction
+
+	// 本轮验证事件的outbox记录ID（验证结果随下一期预测卡片投递，
+	// 由generateNewPrediction在承载广播成功后标记）
+	lastVerifiedEventID int64
+
+	// 滞留预测看门狗的上次检查时间（监控循环高频调用，内部按分钟节流）
+	lastStaleCheck time.Time
+
+	// 上游数据新鲜度状态（轮询协程写，telegram侧读横幅展示用）
+	dataStale atomic.Bool
+
+	// 最近一期开奖时间与最近一次预测生成时间的Unix秒
+	// （轮询协程写，诊断/metrics端点读，0表示尚未发生）
+	lastRoundUnix      atomic.Int64
+	lastPredictionUnix atomic.Int64
+
+	// 单次轮询的端到端截止预算（见retry.go，非正值时使用默认值）
+	tickBudget time.Duration
+
+	// 预测广播提前量：非0时预测不在生成后立即广播，而是推迟到
+	// 预计下一期开奖前该时长再发送（见scheduleDelayedBroadcast）
+	broadcastLead time.Duration
+
+	// 预测冻结窗口：非0时距目标期预计开奖小于该时长不再为该期
+	// 生成预测，迟到的数据改为预测再下一期（见generateNewPrediction）
+	freezeWindow time.Duration
+}
+
+// 应用状态键（持久化在app_state表，用于崩溃恢复）
+const (
+	stateLastProcessedQihao = "last_processed_qihao"
+	stateLastBroadcastQihao = "last_broadcast_qihao"
+)
+
+// NewRoundProcessor 创建单期处理器
+func NewRoundProcessor(db database.DB, cacheManager *cache.CacheManager, apiClient *api.Client,
+	predictorMgr *predictor.PredictorManager, validator *predictor.Validator,
+	alertManager *alert.Manager) *RoundProcessor {
+	return &RoundProcessor{
+		db:             db,
+		cacheManager:   cacheManager,
+		apiClient:      apiClient,
+		predictorMgr:   predictorMgr,
+		validator:      validator,
+		alertManager:   alertManager,
+		latencyTracker: NewLatencyTracker(),
+	}
+}
+
+// SetBroadcaster 设置预测广播器
+func (rp *RoundProcessor) SetBroadcaster(b Broadcaster) {
+	rp.broadcaster = b
+}
+
+// AddEventPublisher 注册领域事件对外发布通道（可注册多个）
+func (rp *RoundProcessor) AddEventPublisher(p EventPublisher) {
+	rp.publishers = append(rp.publishers, p)
+}
+
+// SetTrendNotifier 设置趋势变化通知器
+func (rp *RoundProcessor) SetTrendNotifier(tn *TrendNotifier) {
+	rp.trendNotifier = tn
+}
+
+// SetFreezeWindow 设置预测冻结窗口
+// 非0时临近开奖的期次不再生成预测，需配合开奖节奏模型使用
+func (rp *RoundProcessor) SetFreezeWindow(window time.Duration) {
+	rp.freezeWindow = window
+}
+
+// SetCrossChecker 设置第二数据源交叉核对器
+// 非nil时新开奖数据须通过副源核对后才入库（见CrossChecker.Check）
+func (rp *RoundProcessor) SetCrossChecker(c *CrossChecker) {
+	rp.crossChecker = c
+}
+
+// SetSchedule 设置开奖节奏模型（用于识别维护时段内生成的预测）
+func (rp *RoundProcessor) SetSchedule(s *schedule.Schedule) {
+	rp.drawSchedule = s
+}
+
+// SetBroadcastLead 设置预测广播提前量
+// 非0时预测广播推迟到预计开奖前该时长发送，为0时生成后立即广播
+func (rp *RoundProcessor) SetBroadcastLead(lead time.Duration) {
+	rp.broadcastLead = lead
+}
+
+// LatencyStats 导出预测时延聚合（供健康检查展示）
+func (rp *RoundProcessor) LatencyStats() map[string]interface{} {
+	return rp.latencyTracker.Snapshot()
+}
+
+// InitializeHistoricalData 初始化历史数据并同步预测验证
+// 上次运行的状态完整时直接从中断点恢复，避免每次启动都回拉50期
+func (rp *RoundProcessor) InitializeHistoricalData() error {
+	// 尝试从持久化状态快速恢复
+	if rp.tryResumeFromState() {
+		logger.Status("✅ 已从上次运行状态恢复，跳过历史数据回拉")
+		return nil
+	}
+
+	logger.Status("📚 初始化历史开奖数据...")
+
+	// 获取更多的API历史数据以确保覆盖所有未验证的预测
+	historicalData, err := rp.apiClient.GetHistoricalData(50) // 增加到50期
+	if err != nil {
+		return fmt.Errorf("failed to get historical data: %v", err)
+	}
+
+	// 保存到数据库（如果不存在的话）
+	savedCount := 0
+	for _, data := range historicalData {
+		// 检查是否已存在
+		existing, err := rp.db.GetLotteryResultByQihao(data.Qihao)
+		if err != nil || existing == nil {
+			// 不存在，保存到数据库
+			if err := rp.db.SaveLotteryResult(&data); err != nil {
+				logger.Warnf("Failed to save historical data %s: %v", data.Qihao, err)
+				continue
+			}
+			savedCount++
+		}
+	}
+
+	if savedCount > 0 {
+		logger.Status("✅ 初始化了 %d 条历史数据", savedCount)
+	} else {
+		logger.StatusVerbose("✅ 历史数据已存在，无需初始化")
+	}
+
+	// 同步预测验证状态
+	logger.StatusVerbose("🔍 检查并更新预测验证状态...")
+	verifiedCount, err := rp.syncPredictionVerifications(historicalData)
+	if err != nil {
+		logger.Warnf("Failed to sync prediction verifications: %v", err)
+	} else if verifiedCount > 0 {
+		logger.Status("✅ 更新了 %d 条预测验证结果", verifiedCount)
+	}
+
+	// 清理过期的待开奖预测
+	logger.StatusVerbose("🧹 清理过期的待开奖预测...")
+	cleanedCount, err := rp.cleanupExpiredPredictions(historicalData)
+	if err != nil {
+		logger.Warnf("Failed to cleanup expired predictions: %v", err)
+	} else if cleanedCount > 0 {
+		logger.Status("✅ 清理了 %d 条过期预测", cleanedCount)
+	}
+
+	// 更新缓存
+	if len(historicalData) > 0 {
+		if err := rp.cacheManager.OnNewLotteryData(&historicalData[0]); err != nil {
+			logger.Warnf("Failed to update cache for historical data: %v", err)
+		}
+	}
+
+	// 检查是否需要生成最新预测
+	logger.StatusVerbose("🔍 检查是否需要生成最新预测...")
+	if err := rp.ensureLatestPrediction(); err != nil {
+		logger.Warnf("Failed to ensure latest prediction: %v", err)
+	}
+
+	return nil
+}
+
+// tryResumeFromState 尝试从持久化状态恢复
+// 上次处理的期号仍是上游最新期号且没有待验证积压时，无需完整回拉
+func (rp *RoundProcessor) tryResumeFromState() bool {
+	lastProcessed, err := rp.db.GetAppState(stateLastProcessedQihao)
+	if err != nil || lastProcessed == "" {
+		return false
+	}
+
+	// 确认上游没有超出已处理进度
+	latest, err := rp.apiClient.FetchAndValidateLatestData()
+	if err != nil || latest.Qihao != lastProcessed {
+		return false
+	}
+
+	// 确认没有待验证的预测积压（有则走完整同步流程）
+	unverified, err := rp.db.GetUnverifiedPredictions()
+	if err != nil {
+		return false
+	}
+	for _, pred := range unverified {
+		if qihao.Compare(pred.TargetQihao, lastProcessed) <= 0 {
+			return false
+		}
+	}
+
+	// 恢复缓存并确保预测就绪
+	if err := rp.cacheManager.OnNewLotteryData(latest); err != nil {
+		logger.Warnf("Failed to warm cache during resume: %v", err)
+	}
+	if err := rp.ensureLatestPrediction(); err != nil {
+		logger.Warnf("Failed to ensure latest prediction during resume: %v", err)
+	}
+
+	logger.WithFields(logger.Fields{
+		"module": "pipeline",
+		"qihao":  lastProcessed,
+	}).Info("Resumed from persisted state")
+	return true
+}
+
+// syncPredictionVerifications 同步预测验证状态
+func (rp *RoundProcessor) syncPredictionVerifications(historicalData []database.LotteryResult) (int, error) {
+	if len(historicalData) == 0 {
+		return 0, nil
+	}
+
+	// 只圈定已开奖范围内的待验证预测（最新一期之后的待开奖预测无需处理）
+	unverifiedPredictions, err := rp.db.GetPendingPredictionsBefore(historicalData[0].Qihao)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending predictions: %v", err)
+	}
+
+	if len(unverifiedPredictions) == 0 {
+		return 0, nil
+	}
+
+	// 创建开奖数据的快速查找映射
+	lotteryMap := make(map[string]*database.LotteryResult)
+	for i := range historicalData {
+		lotteryMap[historicalData[i].Qihao] = &historicalData[i]
+	}
+
+	verifiedCount := 0
+	for _, prediction := range unverifiedPredictions {
+		// 查找对应的开奖结果
+		if lotteryResult, exists := lotteryMap[prediction.TargetQihao]; exists {
+			// 验证预测结果
+			_, err := rp.validator.ValidatePrediction(prediction.TargetQihao, lotteryResult)
+			if err != nil {
+				logger.Warnf("Failed to validate prediction for %s: %v", prediction.TargetQihao, err)
+				continue
+			}
+			verifiedCount++
+			logger.Debugf("Verified prediction for %s", prediction.TargetQihao)
+		}
+	}
+
+	return verifiedCount, nil
+}
+
+// cleanupExpiredPredictions 清理过期的待开奖预测
+func (rp *RoundProcessor) cleanupExpiredPredictions(historicalData []database.LotteryResult) (int, error) {
+	// 获取最新的期号
+	if len(historicalData) == 0 {
+		return 0, nil
+	}
+
+	latestQihao := historicalData[0].Qihao
+
+	// 删除目标期号小于最新期号且仍未验证的预测记录
+	cleanedCount, err := rp.db.CleanupExpiredPredictions(latestQihao)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup expired predictions: %v", err)
+	}
+
+	return cleanedCount, nil
+}
+
+// ensureLatestPrediction 确保有最新的预测
+func (rp *RoundProcessor) ensureLatestPrediction() error {
+	// 获取最新的开奖数据
+	latestLottery, err := rp.cacheManager.GetLatestLotteryData()
+	if err != nil {
+		return fmt.Errorf("failed to get latest lottery data: %v", err)
+	}
+
+	// 获取最新的预测
+	latestPrediction, err := rp.cacheManager.GetLatestPrediction()
+	if err != nil {
+		// 没有预测记录，生成一个
+		logger.Info("No prediction found, generating new prediction")
+		return rp.generateNewPrediction(rp.tickDeadline())
+	}
+
+	// 检查预测的目标期号是否是下一期
+	expectedNextQihao, err := qihao.Next(latestLottery.Qihao)
+	if err != nil {
+		logger.Warnf("Failed to compute next qihao from %s: %v", latestLottery.Qihao, err)
+		return rp.generateNewPrediction(rp.tickDeadline())
+	}
+	if latestPrediction.TargetQihao != expectedNextQihao {
+		// 预测的期号不是下一期，生成新预测
+		logger.Infof("Prediction target %s != expected %s, generating new prediction",
+			latestPrediction.TargetQihao, expectedNextQihao)
+		return rp.generateNewPrediction(rp.tickDeadline())
+	}
+
+	logger.Info("Latest prediction is up to date")
+	return nil
+}
+
+// CorrectRound 更正指定期号的开奖号码并重新验证对应预测
+// 用于上游事后修正开奖数据的场景：覆盖开奖记录、按当前策略重新判定
+// 命中并更新预测记录（统计口径随之自动修正），返回重新验证的结果
+func (rp *RoundProcessor) CorrectRound(qihao, openNum string) (*predictor.ValidationResult, error) {
+	nums, err := database.ParseOpenNum(openNum)
+	if err != nil {
+		return nil, fmt.Errorf("invalid open numbers %s: %v", openNum, err)
+	}
+
+	existing, err := rp.db.GetLotteryResultByQihao(qihao)
+	if err != nil || existing == nil {
+		return nil, fmt.Errorf("no lottery result found for qihao: %s", qihao)
+	}
+
+	// 覆盖开奖记录（SaveLotteryResult按期号upsert）
+	existing.OpenNum = openNum
+	existing.SumValue = database.CalculateSum(nums)
+	if err := rp.db.SaveLotteryResult(existing); err != nil {
+		return nil, fmt.Errorf("failed to save corrected lottery result: %v", err)
+	}
+
+	// 强制重新验证对应预测（覆盖原验证结果）
+	validation, err := rp.validator.RevalidatePrediction(qihao, existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-verify prediction: %v", err)
+	}
+
+	// 被更正的是最新一期时刷新缓存
+	if latest, err := rp.cacheManager.GetLatestLotteryData(); err == nil && latest.Qihao == qihao {
+		if err := rp.cacheManager.OnNewLotteryData(existing); err != nil {
+			logger.Warnf("Failed to refresh cache after correction: %v", err)
+		}
+	}
+
+	logger.WithFields(logger.Fields{
+		"module": "pipeline",
+		"qihao":  qihao,
+	}).Infof("Round corrected: %s, re-verified as %s", openNum,
+		map[bool]string{true: "CORRECT", false: "INCORRECT"}[validation.IsCorrect])
+
+	return validation, nil
+}
+
+// CheckStalePredictions 滞留预测看门狗
+// 预测超过timeout仍未验证（漏采或上游延迟）时强制回拉历史数据补验，
+// 补验后仍滞留的交由告警管理器通知管理员
+func (rp *RoundProcessor) CheckStalePredictions(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+
+	// 监控循环每次轮询都会调用，按分钟节流避免高频扫库
+	if time.Since(rp.lastStaleCheck) < time.Minute {
+		return
+	}
+	rp.lastStaleCheck = time.Now()
+
+	stale, oldest := rp.findStalePredictions(timeout)
+	if len(stale) == 0 {
+		rp.alertManager.OnStalePredictions(0, 0)
+		return
+	}
+
+	logger.Warnf("Found %d stale predictions (oldest %v), forcing backfill",
+		len(stale), oldest.Round(time.Second))
+
+	// 强制回拉历史数据并补验滞留的预测
+	if historicalData, err := rp.apiClient.GetHistoricalData(20); err != nil {
+		logger.Warnf("Forced backfill fetch failed: %v", err)
+	} else {
+		for _, data := range historicalData {
+			if existing, err := rp.db.GetLotteryResultByQihao(data.Qihao); err != nil || existing == nil {
+				if err := rp.db.SaveLotteryResult(&data); err != nil {
+					logger.Warnf("Failed to save backfilled data %s: %v", data.Qihao, err)
+				}
+			}
+		}
+		if verified, err := rp.syncPredictionVerifications(historicalData); err == nil && verified > 0 {
+			logger.Status("✅ 看门狗补验了 %d 条滞留预测", verified)
+		}
+	}
+
+	// 补验后仍滞留的才告警
+	stale, oldest = rp.findStalePredictions(timeout)
+	rp.alertManager.OnStalePredictions(len(stale), oldest)
+}
+
+// findStalePredictions 查找超时未验证的预测及最早滞留时长
+func (rp *RoundProcessor) findStalePredictions(timeout time.Duration) ([]database.Prediction, time.Duration) {
+	unverified, err := rp.db.GetUnverifiedPredictions()
+	if err != nil {
+		logger.Warnf("Stale prediction check failed: %v", err)
+		return nil, 0
+	}
+
+	var stale []database.Prediction
+	var oldest time.Duration
+	for _, pred := range unverified {
+		age := time.Since(pred.PredictedAt)
+		if age > timeout {
+			stale = append(stale, pred)
+			if age > oldest {
+				oldest = age
+			}
+		}
+	}
+	return stale, oldest
+}
+
+// classifyError 将错误归类为稳定的类别标识
+// 基于errors.Is匹配各包的错误哨兵，同类错误（即使文本含变化的参数）只记一次日志
+func classifyError(err error) string {
+	switch {
+	case errors.Is(err, api.ErrUpstreamDown):
+		return "upstream_down"
+	case errors.Is(err, database.ErrNoData):
+		return "no_data"
+	case errors.Is(err, database.ErrDuplicateRound):
+		return "duplicate_round"
+	case errors.Is(err, predictor.ErrStalePrediction):
+		return "stale_prediction"
+	default:
+		return err.Error()
+	}
+}
+
+// ProcessDataUpdate 处理数据更新（单次轮询）
+// 整个tick在截止预算内完成：各步骤按自己的策略在预算内重试，预算耗尽即放弃本轮
+func (rp *RoundProcessor) ProcessDataUpdate() error {
+	deadline := rp.tickDeadline()
+
+	// 获取最新数据（api客户端内部已有重试，这里不再叠加）
+	var latestData *database.LotteryResult
+	err := runStep(deadline, "fetch", fetchStepPolicy, func() error {
+		var fetchErr error
+		latestData, fetchErr = rp.apiClient.FetchAndValidateLatestData()
+		return fetchErr
+	})
+	if err != nil {
+		// 只在错误类别变化时记录
+		if kind := classifyError(err); rp.lastAPIError != kind {
+			logger.Errorf("API fetch failed (%s): %v", kind, err)
+			rp.lastAPIError = kind
+		}
+		rp.alertManager.OnAPIFailure(err)
+		return fmt.Errorf("failed to fetch latest data: %v", err)
+	}
+	rp.lastAPIError = "" // 清除错误状态
+	rp.alertManager.OnAPIRecovered()
+
+	// 跟踪上游数据新鲜度（fetch成功但开奖时间停滞同样视为数据延迟）
+	rp.updateFreshness(latestData)
+
+	// 检查是否是新数据
+	isNew, err := rp.db.CheckNewQihao(latestData.Qihao)
+	if err != nil {
+		// 只在错误类别变化时记录
+		if kind := classifyError(err); rp.lastDBError != kind {
+			logger.Errorf("Database check failed (%s): %v", kind, err)
+			rp.lastDBError = kind
+		}
+		rp.alertManager.OnDBError(err)
+		return fmt.Errorf("failed to check new qihao: %v", err)
+	}
+	rp.lastDBError = "" // 清除错误状态
+	rp.alertManager.OnDBRecovered()
+
+	if !isNew {
+		// 不是新数据，跳过处理（不记录日志避免重复）
+		return nil
+	}
+
+	// 配置了副源时先交叉核对：号码未确认一致前该期暂缓入库，
+	// 由后续轮询重试（重试与告警策略见CrossChecker.Check）
+	if rp.crossChecker != nil && !rp.crossChecker.Check(latestData) {
+		return nil
+	}
+
+	logger.Status("🎯 发现新开奖: %s - %s (和值:%d)", latestData.Qihao, latestData.OpenNum, latestData.SumValue)
+	rp.warnSkippedRounds(latestData.Qihao)
+	rp.alertManager.OnNewRound()
+	rp.corrID = logger.NewCorrelationID()
+	rp.timelineQihao = latestData.Qihao
+	rp.recordStage(database.StageFetched)
+	startTime := time.Now()
+
+	// 保存新数据到数据库（快速重试应对瞬态写入失败）
+	if err := runStep(deadline, "save", saveStepPolicy, func() error {
+		return rp.db.SaveLotteryResult(latestData)
+	}); err != nil {
+		return fmt.Errorf("failed to save lottery result: %v", err)
+	}
+	rp.recordStage(database.StageSaved)
+
+	// 开奖事件入outbox：与数据保存同步落库，广播成功后标记已投递，
+	// 崩溃或发送失败时由补投递循环兜底（见DispatchPendingOutbox）
+	newRoundEventID := rp.recordOutboxEvent(database.OutboxNewRound, latestData.Qihao, outboxRoundPayload(latestData))
+
+	// 更新缓存
+	if err := rp.cacheManager.OnNewLotteryData(latestData); err != nil {
+		logger.Warnf("Failed to update cache for new data: %v", err)
+	}
+
+	// 覆盖缺口检查：本期开奖时从未存在过对应预测，说明该期被漏掉，
+	// 单独记录以免准确率统计掩盖漏报
+	if pred, err := rp.db.GetPredictionByQihao(latestData.Qihao); err == nil && pred == nil {
+		logger.WithFields(logger.Fields{
+			"module":  "pipeline",
+			"qihao":   latestData.Qihao,
+			"corr_id": rp.corrID,
+		}).Warn("Coverage gap: round drawn without any prediction")
+		if err := rp.db.SaveCoverageGap(latestData.Qihao); err != nil {
+			logger.Warnf("Failed to record coverage gap: %v", err)
+		}
+	}
+
+	// 纯开奖结果推送（results订阅模式，不含预测内容）
+	if rp.broadcaster != nil {
+		if err := rp.broadcaster.BroadcastRawResult(latestData); err != nil {
+			logger.Warnf("Failed to broadcast raw result: %v", err)
+		} else {
+			rp.markOutboxDispatched(newRoundEventID)
+		}
+	} else {
+		// 无广播器时没有投递目标，直接标记完成
+		rp.markOutboxDispatched(newRoundEventID)
+	}
+
+	// 豹子提醒（罕见形态，向订阅用户推送）
+	if latestData.IsTriple && rp.broadcaster != nil {
+		notice := fmt.Sprintf("💎 *Triple Alert!*\n\nRound `%s` drew `%s` — all three digits match!",
+			latestData.Qihao, latestData.OpenNum)
+		if err := rp.broadcaster.BroadcastNotice(notice); err != nil {
+			logger.Warnf("Failed to broadcast triple alert: %v", err)
+		}
+	}
+
+	// 评估用户自定义提醒规则（失败不阻断流程）
+	rp.evaluateUserAlertRules(latestData)
+
+	// 更新本期滚动特征（供统计/ML预测器消费一致输入，失败不阻断流程）
+	rp.updateRoundFeatures()
+
+	// 验证之前的预测
+	if err := runStep(deadline, "verify", verifyStepPolicy, func() error {
+		return rp.verifyPreviousPrediction(latestData)
+	}); err != nil {
+		logger.Warnf("Failed to verify previous prediction: %v", err)
+	} else {
+		rp.recordStage(database.StageVerified)
+	}
+
+	// 生成新预测
+	if err := runStep(deadline, "predict", predictStepPolicy, func() error {
+		return rp.generateNewPrediction(deadline)
+	}); err != nil {
+		logger.Errorf("Failed to generate new prediction: %v", err)
+		return err
+	}
+
+	// 持久化处理进度（崩溃后可从此处恢复）
+	if err := rp.db.SaveAppState(stateLastProcessedQihao, latestData.Qihao); err != nil {
+		logger.Warnf("Failed to persist last processed qihao: %v", err)
+	}
+
+	logger.StatusVerbose("✅ 新数据处理完成: %s", latestData.Qihao)
+	logger.WithFields(logger.Fields{
+		"module":   "pipeline",
+		"qihao":    latestData.Qihao,
+		"corr_id":  rp.corrID,
+		"duration": time.Since(startTime).String(),
+	}).Info("Round processed")
+	return nil
+}
+
+// updateFreshness 跟踪上游数据新鲜度并在状态翻转时通知管理员
+// 数据延迟期间新预测被抑制（见generateNewPrediction），/latest附加延迟横幅
+func (rp *RoundProcessor) updateFreshness(latest *database.LotteryResult) {
+	rp.lastRoundUnix.Store(latest.OpenTime.Unix())
+
+	fresh := rp.apiClient.CheckDataFreshness(latest.OpenTime)
+	switch {
+	case !fresh && !rp.dataStale.Load():
+		rp.dataStale.Store(true)
+		age := time.Since(latest.OpenTime)
+		logger.Warnf("Upstream data is stale: round %s drawn %v ago, suppressing new predictions",
+			latest.Qihao, age.Round(time.Second))
+		rp.alertManager.OnDataStale(age)
+	case fresh && rp.dataStale.Load():
+		rp.dataStale.Store(false)
+		logger.Status("✅ 上游数据已恢复更新")
+		rp.alertManager.OnDataRecovered()
+	}
+}
+
+// warnSkippedRounds 检查新开奖期号相对上次处理进度是否存在跳期
+// （上游漏发的期数没有对应预测可验证，记录警告便于排查覆盖缺口）
+func (rp *RoundProcessor) warnSkippedRounds(current string) {
+	lastProcessed, err := rp.db.GetAppState(stateLastProcessedQihao)
+	if err != nil || lastProcessed == "" {
+		return
+	}
+	gap, err := qihao.Distance(lastProcessed, current)
+	if err != nil || gap <= 1 {
+		return
+	}
+	logger.Warnf("Upstream skipped %d round(s) between %s and %s", gap-1, lastProcessed, current)
+}
+
+// DataStale 上游数据是否处于延迟状态（telegram侧据此降级展示）
+func (rp *RoundProcessor) DataStale() bool {
+	return rp.dataStale.Load()
+}
+
+// SecondsSinceLastRound 最近一期开奖距今的秒数（尚未见到任何开奖时返回-1）
+func (rp *RoundProcessor) SecondsSinceLastRound() float64 {
+	return secondsSinceUnix(rp.lastRoundUnix.Load())
+}
+
+// SecondsSinceLastPrediction 最近一次预测生成距今的秒数（尚未生成过预测时返回-1）
+func (rp *RoundProcessor) SecondsSinceLastPrediction() float64 {
+	return secondsSinceUnix(rp.lastPredictionUnix.Load())
+}
+
+// secondsSinceUnix 计算Unix秒时间戳距今的秒数（0视为尚未发生）
+func secondsSinceUnix(unix int64) float64 {
+	if unix == 0 {
+		return -1
+	}
+	return time.Since(time.Unix(unix, 0)).Seconds()
+}
+
+// updateRoundFeatures 计算并持久化最新一期的滚动特征
+func (rp *RoundProcessor) updateRoundFeatures() {
+	history, err := rp.db.GetLatestLotteryResults(10)
+	if err != nil {
+		logger.Warnf("Failed to load history for round features: %v", err)
+		return
+	}
+	features := predictor.BuildRoundFeatures(history)
+	if features == nil {
+		return
+	}
+	if err := rp.db.SaveRoundFeatures(features); err != nil {
+		logger.Warnf("Failed to save round features: %v", err)
+	}
+}
+
+// verifyPreviousPrediction 验证之前的预测
+func (rp *RoundProcessor) verifyPreviousPrediction(actualResult *database.LotteryResult) error {
+	// 记录预测提前量（预测生成到开奖）用于时延统计，并捕获预测记录用于结果推送
+	// 按期号直查，避免翻最新N条漏掉更早的记录
+	var predictedAt time.Time
+	var prevPrediction *database.Prediction
+	if pred, err := rp.db.GetPredictionByQihao(actualResult.Qihao); err == nil && pred != nil {
+		predictedAt = pred.PredictedAt
+		prevPrediction = pred
+	}
+
+	// 验证预测结果
+	validation, err := rp.validator.ValidatePrediction(actualResult.Qihao, actualResult)
+	if err != nil {
+		return fmt.Errorf("validation failed: %v", err)
+	}
+
+	// 记录时延：预测提前量与开奖到验证的延迟
+	lead := time.Duration(0)
+	if !predictedAt.IsZero() {
+		lead = actualResult.OpenTime.Sub(predictedAt)
+	}
+	rp.latencyTracker.ObserveRound(lead, time.Since(actualResult.OpenTime))
+
+	// 暂存验证结果，随下一期预测一并广播
+	if prevPrediction != nil {
+		isCorrect := validation.IsCorrect
+		actualNum := actualResult.OpenNum
+		prevPrediction.IsCorrect = &isCorrect
+		prevPrediction.ActualNum = &actualNum
+		rp.lastVerified = prevPrediction
+		// 验证事件随下一期预测卡片一并投递，由generateNewPrediction标记
+		rp.lastVerifiedEventID = rp.recordOutboxEvent(database.OutboxPredictionVerified, actualResult.Qihao, "")
+	}
+
+	// 更新缓存
+	if err := rp.cacheManager.OnPredictionVerified(actualResult.Qihao, validation.IsCorrect); err != nil {
+		logger.Warnf("Failed to update cache for prediction verification: %v", err)
+	}
+
+	// 跟踪连败告警
+	rp.alertManager.OnPredictionVerified(validation.IsCorrect)
+
+	// 检查准确率趋势变化
+	if rp.trendNotifier != nil {
+		rp.trendNotifier.OnRoundVerified()
+	}
+
+	logger.WithFields(logger.Fields{
+		"module":  "pipeline",
+		"qihao":   actualResult.Qihao,
+		"corr_id": rp.corrID,
+	}).Infof("Prediction verified: %s",
+		map[bool]string{true: "CORRECT", false: "INCORRECT"}[validation.IsCorrect])
+
+	return nil
+}
+
+// assemblePredictionCard 组装新预测推送卡片数据
+// 近期战绩和连对连错取自已验证的预测记录，倒计时由开奖节奏模型推算
+func (rp *RoundProcessor) assemblePredictionCard(prediction *database.Prediction) *database.PredictionCard {
+	card := &database.PredictionCard{
+		Prediction: prediction,
+		Verified:   rp.lastVerified,
+	}
+
+	latestResult, _ := rp.cacheManager.GetLatestLotteryData()
+	card.Latest = latestResult
+
+	// 最近5期已验证预测（新到旧）+ 当前连对连错轮数
+	if recent, err := rp.db.GetLatestPredictions(20); err != nil {
+		logger.Warnf("Failed to load recent predictions for card: %v", err)
+	} else {
+		streakDone := false
+		for _, pred := range recent {
+			if pred.IsCorrect == nil {
+				continue
+			}
+			if len(card.MiniHistory) < 5 {
+				card.MiniHistory = append(card.MiniHistory, pred)
+			}
+			// 连对连错：从最新的已验证预测起计数，方向改变即定型
+			if !streakDone {
+				switch {
+				case card.Streak == 0:
+					if *pred.IsCorrect {
+						card.Streak = 1
+					} else {
+						card.Streak = -1
+					}
+				case card.Streak > 0 && *pred.IsCorrect:
+					card.Streak++
+				case card.Streak < 0 && !*pred.IsCorrect:
+					card.Streak--
+				default:
+					streakDone = true
+				}
+			}
+			if streakDone && len(card.MiniHistory) >= 5 {
+				break
+			}
+		}
+	}
+
+	if rp.drawSchedule != nil && latestResult != nil {
+		card.NextDrawIn = rp.drawSchedule.Countdown(latestResult.OpenTime, time.Now())
+	}
+	return card
+}
+
+// computeDisagreement 计算影子算法分歧度
+// 全部已注册算法对同一历史各自预测，统计单双结论与多数派不一致的比例（0-100）；
+// 注册算法不足2个时不计算，返回nil
+func (rp *RoundProcessor) computeDisagreement(history []database.LotteryResult) *float64 {
+	names := rp.predictorMgr.GetAvailablePredictors()
+	if len(names) < 2 {
+		return nil
+	}
+
+	oddCount, total := 0, 0
+	for _, name := range names {
+		p := rp.predictorMgr.GetPredictor(name)
+		if p == nil || len(history) < p.GetRequiredHistorySize() {
+			continue
+		}
+		result, err := p.Predict(history)
+		if err != nil {
+			logger.Warnf("Shadow prediction failed for algorithm %s: %v", name, err)
+			continue
+		}
+		nums, err := database.ParseOpenNum(result.PredictedNum)
+		if err != nil {
+			continue
+		}
+		if database.CalculateSum(nums)%2 == 1 {
+			oddCount++
+		}
+		total++
+	}
+	if total < 2 {
+		return nil
+	}
+
+	majority := oddCount
+	if total-oddCount > majority {
+		majority = total - oddCount
+	}
+	score := float64(total-majority) / float64(total) * 100
+	return &score
+}
+
+// generateNewPrediction 生成新预测
+func (rp *RoundProcessor) generateNewPrediction(deadline time.Time) error {
+	// 上游数据延迟时抑制新预测：基于停更数据的预测只会误导用户
+	if rp.dataStale.Load() {
+		logger.Warnf("Prediction suppressed: upstream data is stale")
+		return nil
+	}
+
+	// 获取历史数据用于预测
+	historyData, err := rp.cacheManager.GetLast3LotteryData()
+	if err != nil {
+		return fmt.Errorf("failed to get history data for prediction: %v", err)
+	}
+
+	if len(historyData) < 3 {
+		return fmt.Errorf("insufficient history data for prediction: need 3, got %d", len(historyData))
+	}
+
+	// 生成预测
+	predictionResult, err := rp.predictorMgr.Predict(historyData)
+	if err != nil {
+		return fmt.Errorf("prediction generation failed: %v", err)
+	}
+
+	// 冻结窗口检查：目标期临近开奖时不再为该期生成预测
+	// （用户来不及跟进，且预测易与开奖结果赛跑），迟到的数据改为预测再下一期
+	targetQihao := predictionResult.TargetQihao
+	if rp.freezeWindow > 0 && rp.drawSchedule != nil {
+		now := time.Now()
+		remaining := rp.drawSchedule.NextDrawTime(historyData[0].OpenTime, now).Sub(now)
+		if remaining < rp.freezeWindow {
+			following, err := qihao.Next(targetQihao)
+			if err != nil {
+				return fmt.Errorf("failed to advance frozen prediction target: %v", err)
+			}
+			logger.WithFields(logger.Fields{
+				"module":    "pipeline",
+				"qihao":     targetQihao,
+				"remaining": remaining.Round(time.Second).String(),
+				"corr_id":   rp.corrID,
+			}).Warnf("Target round inside freeze window, retargeting prediction to %s", following)
+			targetQihao = following
+		}
+	}
+
+	// 计算预测和值和单双
+	predictedNums, _ := database.ParseOpenNum(predictionResult.PredictedNum)
+	predictedSum := database.CalculateSum(predictedNums)
+	predictedOddEven := database.CalculateOddEven(predictedSum)
+
+	// 保存预测到数据库
+	prediction := &database.Prediction{
+		TargetQihao:      targetQihao,
+		PredictedNum:     predictionResult.PredictedNum,
+		PredictedSum:     predictedSum,
+		PredictedOddEven: predictedOddEven,
+		ConfidenceScore:  nil, // 不使用置信度
+		Disagreement:     rp.computeDisagreement(historyData),
+		AlgorithmVersion: predictionResult.AlgorithmVersion,
+		PredictedAt:      predictionResult.Timestamp,
+	}
+
+	// 位置级预测（预测器实现扩展接口时单独给出，验证侧按位置评分）
+	if positions, err := rp.predictorMgr.PredictPositions(historyData); err != nil {
+		logger.Warnf("Position-level prediction failed: %v", err)
+	} else if len(positions) == 3 {
+		joined := fmt.Sprintf("%d,%d,%d", positions[0], positions[1], positions[2])
+		prediction.PositionNums = &joined
+	}
+
+	if prediction.Disagreement != nil && *prediction.Disagreement >= 50 {
+		logger.WithFields(logger.Fields{
+			"module":       "pipeline",
+			"qihao":        prediction.TargetQihao,
+			"disagreement": *prediction.Disagreement,
+			"corr_id":      rp.corrID,
+		}).Warn("Low consensus between shadow algorithms")
+	}
+
+	if err := rp.db.SavePrediction(prediction); err != nil {
+		// 目标期已有已验证的预测（如更正流程重放），跳过而非报错
+		if errors.Is(err, database.ErrDuplicateRound) {
+			logger.Warnf("Skipping prediction for %s: %v", prediction.TargetQihao, err)
+			return nil
+		}
+		return fmt.Errorf("failed to save prediction: %v", err)
+	}
+
+	// 维护时段内生成的预测可能基于停更的数据，记录日志便于事后排查
+	if rp.drawSchedule != nil && rp.drawSchedule.InMaintenance(time.Now()) {
+		logger.WithFields(logger.Fields{
+			"module":  "pipeline",
+			"qihao":   prediction.TargetQihao,
+			"corr_id": rp.corrID,
+		}).Warn("Prediction generated during maintenance window")
+	}
+
+	// 更新缓存
+	if err := rp.cacheManager.OnPredictionGenerated(prediction); err != nil {
+		logger.Warnf("Failed to update cache for new prediction: %v", err)
+	}
+
+	rp.lastPredictionUnix.Store(time.Now().Unix())
+	rp.recordStage(database.StagePredicted)
+
+	// 预测事件入outbox（上一期验证事件随同一张卡片投递）
+	createdEventID := rp.recordOutboxEvent(database.OutboxPredictionCreated, prediction.TargetQihao, outboxPredictionPayload(prediction))
+	verifiedEventID := rp.lastVerifiedEventID
+	rp.lastVerifiedEventID = 0
+
+	// 广播新预测（如果有订阅用户且广播器已配置），附带上一期的验证结果
+	if rp.broadcaster != nil {
+		card := rp.assemblePredictionCard(prediction)
+		rp.lastVerified = nil
+		if delay := rp.broadcastDelay(card); delay > 0 {
+			rp.scheduleDelayedBroadcast(card, delay, createdEventID, verifiedEventID)
+		} else if err := runStep(deadline, "broadcast", broadcastStepPolicy, func() error {
+			return rp.broadcaster.BroadcastNewPrediction(card)
+		}); err != nil {
+			logger.Warnf("Failed to broadcast new prediction: %v", err)
+		} else {
+			rp.recordStage(database.StageBroadcast)
+			rp.markOutboxDispatched(createdEventID)
+			rp.markOutboxDispatched(verifiedEventID)
+			if err := rp.db.SaveAppState(stateLastBroadcastQihao, prediction.TargetQihao); err != nil {
+				logger.Warnf("Failed to persist last broadcast qihao: %v", err)
+			}
+		}
+	} else {
+		rp.markOutboxDispatched(createdEventID)
+		rp.markOutboxDispatched(verifiedEventID)
+	}
+
+	logger.Status("🔮 生成预测: %s -> %s (固定算法)",
+		prediction.TargetQihao, prediction.PredictedNum)
+	logger.WithFields(logger.Fields{
+		"module":  "pipeline",
+		"qihao":   prediction.TargetQihao,
+		"corr_id": rp.corrID,
+	}).Debug("Prediction generated")
+
+	return nil
+}
+
+// recordStage 记录当前处理期号的阶段时间戳（失败只告警，不阻断流程）
+func (rp *RoundProcessor) recordStage(stage string) {
+	rp.recordStageFor(rp.timelineQihao, stage)
+}
+
+// recordStageFor 记录指定期号的阶段时间戳（推迟广播在新一轮开始后落点用）
+func (rp *RoundProcessor) recordStageFor(qihao, stage string) {
+	if qihao == "" {
+		return
+	}
+	if err := rp.db.SaveRoundStageTime(qihao, stage, time.Now()); err != nil {
+		logger.Warnf("Failed to record %s stage for %s: %v", stage, qihao, err)
+	}
+}
+
+// broadcastDelay 计算预测广播的推迟时长
+// 配置了广播提前量时推迟到预计下一期开奖前该时长发送；
+// 未配置、缺少开奖节奏模型或已进入发送窗口时返回0（立即广播）
+func (rp *RoundProcessor) broadcastDelay(card *database.PredictionCard) time.Duration {
+	if rp.broadcastLead <= 0 || rp.drawSchedule == nil || card.Latest == nil {
+		return 0
+	}
+
+	nextDraw := rp.drawSchedule.NextDrawTime(card.Latest.OpenTime, time.Now())
+	return time.Until(nextDraw.Add(-rp.broadcastLead))
+}
+
+// scheduleDelayedBroadcast 定时器到期后发送推迟的预测广播
+// 发送前确认目标期仍未开奖（上游提前开奖时跳过，避免推送已过期的预测）；
+// 携带的outbox事件在广播成功后标记，跳过或失败时留待补投递循环处理
+func (rp *RoundProcessor) scheduleDelayedBroadcast(card *database.PredictionCard, delay time.Duration, eventIDs ...int64) {
+	corrID := rp.corrID
+	timelineQihao := rp.timelineQihao
+	logger.WithFields(logger.Fields{
+		"module":  "pipeline",
+		"qihao":   card.Prediction.TargetQihao,
+		"corr_id": corrID,
+	}).Infof("Prediction broadcast delayed by %v", delay.Round(time.Second))
+
+	time.AfterFunc(delay, func() {
+		// 目标期已开奖说明广播已无意义（节奏推算偏差或上游提前）
+		if drawn, err := rp.db.GetLotteryResultByQihao(card.Prediction.TargetQihao); err == nil && drawn != nil {
+			logger.Warnf("Skipping delayed broadcast for %s: round already drawn", card.Prediction.TargetQihao)
+			return
+		}
+
+		if err := rp.broadcaster.BroadcastNewPrediction(card); err != nil {
+			logger.Warnf("Failed to send delayed prediction broadcast: %v", err)
+			return
+		}
+		rp.recordStageFor(timelineQihao, database.StageBroadcast)
+		for _, id := range eventIDs {
+			rp.markOutboxDispatched(id)
+		}
+		if err := rp.db.SaveAppState(stateLastBroadcastQihao, card.Prediction.TargetQihao); err != nil {
+			logger.Warnf("Failed to persist last broadcast qihao: %v", err)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package pipeline
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"pc28-bot/internal/logger"
+)
+
+// 单次轮询的端到端截止预算
+// 每个tick从开始处理起只允许消耗有限时间，慢步骤重试到预算耗尽即放弃，
+// 避免单步卡住导致ticker积压后续轮次的工作
+const defaultTickBudget = 2 * time.Minute
+
+// stepPolicy 单步重试策略
+type stepPolicy struct {
+	attempts  int           // 最大尝试次数
+	baseDelay time.Duration // 退避基准时长（按尝试次数线性放大并加抖动）
+}
+
+// 各步骤的重试策略
+// fetch在api客户端内部已有重试，这里不再叠加；写库类步骤快速重试几次即可
+var (
+	fetchStepPolicy     = stepPolicy{attempts: 1}
+	saveStepPolicy      = stepPolicy{attempts: 3, baseDelay: 500 * time.Millisecond}
+	verifyStepPolicy    = stepPolicy{attempts: 2, baseDelay: time.Second}
+	predictStepPolicy   = stepPolicy{attempts: 2, baseDelay: time.Second}
+	broadcastStepPolicy = stepPolicy{attempts: 3, baseDelay: 2 * time.Second}
+)
+
+// SetTickBudget 设置单次轮询的端到端截止预算（非正值时使用默认2分钟）
+func (rp *RoundProcessor) SetTickBudget(budget time.Duration) {
+	rp.tickBudget = budget
+}
+
+// tickDeadline 计算本轮处理的截止时间
+func (rp *RoundProcessor) tickDeadline() time.Time {
+	budget := rp.tickBudget
+	if budget <= 0 {
+		budget = defaultTickBudget
+	}
+	return time.Now().Add(budget)
+}
+
+// runStep 在截止预算内按策略重试执行单个步骤
+// 重试间隔为baseDelay×尝试序号加±50%抖动，避免多实例同步重试；
+// 截止时间不足以等完下一次退避时直接放弃
+func runStep(deadline time.Time, name string, policy stepPolicy, fn func() error) error {
+	var lastErr error
+	for attempt := 1; attempt <= policy.attempts; attempt++ {
+		if err := fn(); err == nil {
+			return nil
+		} else {
+			lastErr = err
+		}
+
+		if attempt == policy.attempts {
+			break
+		}
+
+		delay := jitteredDelay(policy.baseDelay, attempt)
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("step %s: tick budget exhausted after %d attempt(s): %w", name, attempt, lastErr)
+		}
+
+		logger.Debugf("Step %s failed (attempt %d/%d), retrying in %v: %v",
+			name, attempt, policy.attempts, delay, lastErr)
+		time.Sleep(delay)
+	}
+	return fmt.Errorf("step %s failed after %d attempt(s): %w", name, policy.attempts, lastErr)
+}
+
+// jitteredDelay 计算带抖动的退避时长（baseDelay×尝试序号，±50%随机抖动）
+func jitteredDelay(baseDelay time.Duration, attempt int) time.Duration {
+	if baseDelay <= 0 {
+		return 0
+	}
+	delay := baseDelay * time.Duration(attempt)
+	jitter := time.Duration(rand.Int63n(int64(delay))) - delay/2
+	return delay + jitter
+}
@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/predictor"
+)
+
+// NoticeBroadcaster 文本通知广播接口（由Telegram机器人实现）
+type NoticeBroadcaster interface {
+	BroadcastNotice(text string) error
+}
+
+// TrendNotifier 准确率趋势变化通知器
+// 移动平均方向变化需连续确认若干轮才推送（滞后机制），避免方向抖动时刷屏
+type TrendNotifier struct {
+	statCalculator *predictor.StatisticsCalculator
+	broadcaster    NoticeBroadcaster
+	hysteresis     int // 连续确认轮数
+
+	lastNotified     string // 上次已通知的方向
+	pendingDirection string // 待确认的新方向
+	pendingCount     int
+}
+
+// NewTrendNotifier 创建趋势通知器
+func NewTrendNotifier(statCalculator *predictor.StatisticsCalculator, hysteresis int) *TrendNotifier {
+	if hysteresis <= 0 {
+		hysteresis = 3
+	}
+	return &TrendNotifier{
+		statCalculator: statCalculator,
+		hysteresis:     hysteresis,
+	}
+}
+
+// SetBroadcaster 设置通知广播器
+func (tn *TrendNotifier) SetBroadcaster(b NoticeBroadcaster) {
+	tn.broadcaster = b
+}
+
+// OnRoundVerified 每轮预测验证后检查趋势方向（与轮询处理同协程串行调用）
+func (tn *TrendNotifier) OnRoundVerified() {
+	analysis, err := tn.statCalculator.GetTrendAnalysis()
+	if err != nil {
+		logger.Warnf("Failed to get trend analysis: %v", err)
+		return
+	}
+
+	direction, _ := analysis["trend_direction"].(string)
+	if direction != "improving" && direction != "declining" {
+		// stable或数据不足，清除待确认状态
+		tn.pendingDirection = ""
+		tn.pendingCount = 0
+		return
+	}
+
+	if direction == tn.lastNotified {
+		// 方向未变，无需重复通知
+		tn.pendingDirection = ""
+		tn.pendingCount = 0
+		return
+	}
+
+	// 滞后确认：同一新方向连续出现足够轮数才通知
+	if direction == tn.pendingDirection {
+		tn.pendingCount++
+	} else {
+		tn.pendingDirection = direction
+		tn.pendingCount = 1
+	}
+	if tn.pendingCount < tn.hysteresis {
+		return
+	}
+
+	tn.notify(direction, analysis)
+	tn.lastNotified = direction
+	tn.pendingDirection = ""
+	tn.pendingCount = 0
+}
+
+// notify 推送趋势变化通知
+func (tn *TrendNotifier) notify(direction string, analysis map[string]interface{}) {
+	latestAverage := 0.0
+	if movingAverage, ok := analysis["moving_average"].([]float64); ok && len(movingAverage) > 0 {
+		latestAverage = movingAverage[len(movingAverage)-1]
+	}
+
+	var text string
+	if direction == "improving" {
+		text = fmt.Sprintf("📈 *Accuracy Trend Improving*\n\nRecent 10-round moving average: `%.1f%%`\nPredictions are for reference only, please be rational.", latestAverage)
+	} else {
+		text = fmt.Sprintf("📉 *Accuracy Trend Declining*\n\nRecent 10-round moving average: `%.1f%%`\nPredictions are for reference only, please be rational.", latestAverage)
+	}
+
+	logger.Infof("Accuracy trend changed to %s (moving average %.1f%%)", direction, latestAverage)
+
+	if tn.broadcaster != nil {
+		if err := tn.broadcaster.BroadcastNotice(text); err != nil {
+			logger.Warnf("Failed to broadcast trend notice: %v", err)
+		}
+	}
+}
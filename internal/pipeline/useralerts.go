@@ -0,0 +1,100 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+)
+
+// 用户提醒规则的连续形态统计窗口
+// 覆盖最大可配置阈值即可，无需完整历史
+const alertStreakWindow = 20
+
+// evaluateUserAlertRules 逐期评估用户自定义提醒规则并推送命中通知
+// 连续形态规则在连续次数恰好等于阈值时触发一次（之后继续延长不重复提醒），
+// 和值规则在每个满足条件的期触发；评估失败不阻断主流程
+func (rp *RoundProcessor) evaluateUserAlertRules(latest *database.LotteryResult) {
+	if rp.broadcaster == nil {
+		return
+	}
+
+	rules, err := rp.db.GetAllAlertRules()
+	if err != nil {
+		logger.Warnf("Failed to load alert rules: %v", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	history, err := rp.db.GetLatestLotteryResults(alertStreakWindow)
+	if err != nil {
+		logger.Warnf("Failed to load history for alert rules: %v", err)
+		return
+	}
+	oddEvenStreak := streakLength(history, func(r *database.LotteryResult) string { return r.OddEven })
+	bigSmallStreak := streakLength(history, func(r *database.LotteryResult) string { return r.BigSmall })
+
+	// 按用户聚合命中的规则，每人只发一条通知
+	hits := make(map[int64][]string)
+	for _, rule := range rules {
+		desc, matched := matchAlertRule(&rule, latest, oddEvenStreak, bigSmallStreak)
+		if matched {
+			hits[rule.UserID] = append(hits[rule.UserID], desc)
+		}
+	}
+
+	for userID, descs := range hits {
+		notice := fmt.Sprintf("🔔 *Alert Rule Triggered!*\n\nRound `%s` drew `%s` (sum %d):\n• %s",
+			latest.Qihao, latest.OpenNum, latest.SumValue, strings.Join(descs, "\n• "))
+		if err := rp.broadcaster.SendNotice(userID, notice); err != nil {
+			logger.Warnf("Failed to send alert rule notice to %d: %v", userID, err)
+		}
+	}
+}
+
+// matchAlertRule 判断单条规则是否被本期命中，返回命中描述
+func matchAlertRule(rule *database.AlertRule, latest *database.LotteryResult,
+	oddEvenStreak, bigSmallStreak int) (string, bool) {
+	switch rule.RuleType {
+	case database.AlertRuleStreakOdd:
+		if latest.OddEven == "单" && oddEvenStreak == rule.Threshold {
+			return fmt.Sprintf("%d odd results in a row", rule.Threshold), true
+		}
+	case database.AlertRuleStreakEven:
+		if latest.OddEven == "双" && oddEvenStreak == rule.Threshold {
+			return fmt.Sprintf("%d even results in a row", rule.Threshold), true
+		}
+	case database.AlertRuleStreakBig:
+		if latest.BigSmall == "大" && bigSmallStreak == rule.Threshold {
+			return fmt.Sprintf("%d big results in a row", rule.Threshold), true
+		}
+	case database.AlertRuleStreakSmall:
+		if latest.BigSmall == "小" && bigSmallStreak == rule.Threshold {
+			return fmt.Sprintf("%d small results in a row", rule.Threshold), true
+		}
+	case database.AlertRuleSumGte:
+		if latest.SumValue >= rule.Threshold {
+			return fmt.Sprintf("sum %d ≥ %d", latest.SumValue, rule.Threshold), true
+		}
+	}
+	return "", false
+}
+
+// streakLength 统计最新一期属性值的连续出现次数（history为新到旧）
+func streakLength(history []database.LotteryResult, attr func(*database.LotteryResult) string) int {
+	if len(history) == 0 {
+		return 0
+	}
+	current := attr(&history[0])
+	streak := 0
+	for i := range history {
+		if attr(&history[i]) != current {
+			break
+		}
+		streak++
+	}
+	return streak
+}
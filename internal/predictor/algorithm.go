@@ -2,11 +2,11 @@ package predictor
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 
 	"pc28-bot/internal/database"
 	"pc28-bot/internal/logger"
+	"pc28-bot/internal/qihao"
 )
 
 // DefaultPredictor 默认预测算法实现
@@ -79,7 +79,10 @@ func (dp *DefaultPredictor) Predict(history []database.LotteryResult) (*database
 	predictedNums := dp.analyzeAndPredict(recent3)
 
 	// 生成下一期期号
-	nextQihao := dp.generateNextQihao(recent3[0].Qihao)
+	nextQihao, err := qihao.Next(recent3[0].Qihao)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute next qihao: %v", err)
+	}
 
 	result := &database.PredictionResult{
 		TargetQihao:      nextQihao,
@@ -176,33 +179,6 @@ func (dp *DefaultPredictor) fixedOddEvenPrediction(allNums [][]int) []int {
 
 // 移除了所有置信度和智能预测相关函数
 
-// generateNextQihao 生成下一期期号
-func (dp *DefaultPredictor) generateNextQihao(latestQihao string) string {
-	// PC28期号格式通常是7位数字，如：3326106
-	if len(latestQihao) >= 7 {
-		// 尝试直接解析整个期号为数字
-		var qihaoNum int
-		if _, err := fmt.Sscanf(latestQihao, "%d", &qihaoNum); err == nil {
-			return fmt.Sprintf("%d", qihaoNum+1)
-		}
-
-		// 如果上述方法失败，尝试分割前缀和后缀
-		if len(latestQihao) == 7 {
-			prefix := latestQihao[:4] // 前4位
-			numStr := latestQihao[4:] // 后3位
-
-			var num int
-			if _, err := fmt.Sscanf(numStr, "%d", &num); err == nil {
-				return fmt.Sprintf("%s%03d", prefix, num+1)
-			}
-		}
-	}
-
-	// 如果解析失败，返回默认值
-	logger.Warnf("Failed to parse qihao: %s, using default", latestQihao)
-	return "3326999"
-}
-
 // GetPredictionSummary 获取预测摘要信息（简化版）
 func (dp *DefaultPredictor) GetPredictionSummary(history []database.LotteryResult) map[string]interface{} {
 	return map[string]interface{}{
@@ -215,8 +191,6 @@ func (dp *DefaultPredictor) GetPredictionSummary(history []database.LotteryResul
 	}
 }
 
-func init() {
-	// 初始化随机数种子
-	rand.Seed(time.Now().UnixNano())
-}
+// 移除了全局随机种子初始化：固定算法不使用随机数，
+// 需要随机性的算法改为通过SetRNG注入独立随机源（见rng.go）
 
@@ -0,0 +1,101 @@
+package predictor
+
+import (
+	"fmt"
+
+	"pc28-bot/internal/database"
+)
+
+// EvaluationResult 离线沙盒评估结果（不写入预测表）
+type EvaluationResult struct {
+	Algorithm    string  `json:"algorithm"`
+	Version      string  `json:"version"`
+	Rounds       int     `json:"rounds"`  // 实际评估的期数
+	Correct      int     `json:"correct"` // 命中期数
+	AccuracyRate float64 `json:"accuracy_rate"`
+	BestStreak   int     `json:"best_streak"`  // 最长连对
+	WorstStreak  int     `json:"worst_streak"` // 最长连错
+	Skipped      int     `json:"skipped"`      // 因历史不足或预测失败跳过的期数
+}
+
+// EvaluateOffline 在存量历史数据上离线评估预测器（滚动起点）
+// history按时间倒序排列（history[0]为最新一期），评估最近rounds期：
+// 对每个目标期仅用其之前的历史生成预测，由policy判定命中，
+// 全程不读写predictions表，可安全对线上运行的算法做对照实验
+func EvaluateOffline(p Predictor, policy CorrectnessPolicy, history []database.LotteryResult, rounds int) (*EvaluationResult, error) {
+	if policy == nil {
+		policy = &oddEvenPolicy{}
+	}
+	if rounds <= 0 {
+		return nil, fmt.Errorf("rounds must be positive")
+	}
+	if rounds > len(history) {
+		rounds = len(history)
+	}
+
+	result := &EvaluationResult{
+		Algorithm: p.GetName(),
+		Version:   p.GetVersion(),
+	}
+
+	required := p.GetRequiredHistorySize()
+	currentWin, currentLoss := 0, 0
+
+	// 按期号升序重放，连对/连错统计与每日快照口径一致
+	for i := rounds - 1; i >= 0; i-- {
+		prior := history[i+1:]
+		if len(prior) < required {
+			result.Skipped++
+			continue
+		}
+
+		predResult, err := p.Predict(prior)
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+
+		predictedNums, err := database.ParseOpenNum(predResult.PredictedNum)
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+		predictedSum := database.CalculateSum(predictedNums)
+
+		// 构造临时预测记录供策略判定，不持久化
+		prediction := &database.Prediction{
+			TargetQihao:      history[i].Qihao,
+			PredictedNum:     predResult.PredictedNum,
+			PredictedSum:     predictedSum,
+			PredictedOddEven: database.CalculateOddEven(predictedSum),
+		}
+
+		isCorrect, err := policy.Judge(prediction, &history[i])
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+
+		result.Rounds++
+		if isCorrect {
+			result.Correct++
+			currentWin++
+			currentLoss = 0
+			if currentWin > result.BestStreak {
+				result.BestStreak = currentWin
+			}
+		} else {
+			currentLoss++
+			currentWin = 0
+			if currentLoss > result.WorstStreak {
+				result.WorstStreak = currentLoss
+			}
+		}
+	}
+
+	if result.Rounds == 0 {
+		return nil, fmt.Errorf("no rounds could be evaluated (insufficient history)")
+	}
+	result.AccuracyRate = float64(result.Correct) / float64(result.Rounds) * 100
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package predictor
+
+import (
+	"strings"
+
+	"pc28-bot/internal/database"
+)
+
+// featureSeqLength 单双序列取最近多少期
+const featureSeqLength = 10
+
+// BuildRoundFeatures 根据历史开奖计算最新一期的滚动特征
+// history按时间倒序排列（history[0]为最新一期），至少需要1期数据
+func BuildRoundFeatures(history []database.LotteryResult) *database.RoundFeatures {
+	if len(history) == 0 {
+		return nil
+	}
+
+	latest := history[0]
+	features := &database.RoundFeatures{
+		Qihao:       latest.Qihao,
+		OddEvenSeq:  buildOddEvenSeq(history, featureSeqLength),
+		SumMA5:      sumMovingAverage(history, 5),
+		SumMA10:     sumMovingAverage(history, 10),
+		DigitParity: buildDigitParity(latest.OpenNum),
+	}
+	features.OddEvenStreak = streakLength(history, func(r *database.LotteryResult) bool {
+		return r.SumValue%2 == 1
+	})
+	features.BigSmallStreak = streakLength(history, func(r *database.LotteryResult) bool {
+		return r.SumValue >= 14
+	})
+	return features
+}
+
+// buildOddEvenSeq 构建最近k期的单双序列（旧→新）
+func buildOddEvenSeq(history []database.LotteryResult, k int) string {
+	if k > len(history) {
+		k = len(history)
+	}
+	var builder strings.Builder
+	for i := k - 1; i >= 0; i-- {
+		if history[i].SumValue%2 == 1 {
+			builder.WriteString("单")
+		} else {
+			builder.WriteString("双")
+		}
+	}
+	return builder.String()
+}
+
+// sumMovingAverage 计算最近k期和值的移动平均（历史不足k期时按实际期数计算）
+func sumMovingAverage(history []database.LotteryResult, k int) float64 {
+	if k > len(history) {
+		k = len(history)
+	}
+	if k == 0 {
+		return 0
+	}
+	total := 0
+	for i := 0; i < k; i++ {
+		total += history[i].SumValue
+	}
+	return float64(total) / float64(k)
+}
+
+// buildDigitParity 构建本期三位开奖数字的奇偶标记（如"奇偶奇"）
+func buildDigitParity(openNum string) string {
+	nums, err := database.ParseOpenNum(openNum)
+	if err != nil {
+		return ""
+	}
+	var builder strings.Builder
+	for _, num := range nums {
+		if num%2 == 1 {
+			builder.WriteString("奇")
+		} else {
+			builder.WriteString("偶")
+		}
+	}
+	return builder.String()
+}
+
+// streakLength 计算最新一期所在方向的连续期数（按classify的真/假分组）
+func streakLength(history []database.LotteryResult, classify func(*database.LotteryResult) bool) int {
+	direction := classify(&history[0])
+	streak := 0
+	for i := range history {
+		if classify(&history[i]) != direction {
+			break
+		}
+		streak++
+	}
+	return streak
+}
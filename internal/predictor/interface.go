@@ -63,6 +63,11 @@ func (pm *PredictorManager) GetCurrentPredictor() Predictor {
 	return pm.current
 }
 
+// GetPredictor 根据名称获取已注册的预测器（不存在时返回nil）
+func (pm *PredictorManager) GetPredictor(name string) Predictor {
+	return pm.predictors[name]
+}
+
 // GetAvailablePredictors 获取可用的预测器列表
 func (pm *PredictorManager) GetAvailablePredictors() []string {
 	var names []string
@@ -0,0 +1,36 @@
+package predictor
+
+// EVAnalysis 期望值与凯利公式分析结果
+// 将"预测仅供参考"的免责声明量化为可判断的数字
+type EVAnalysis struct {
+	Accuracy          float64 `json:"accuracy"`            // 实测准确率（0-1）
+	Odds              float64 `json:"odds"`                // 净赔率（押1赢odds）
+	BreakEvenAccuracy float64 `json:"break_even_accuracy"` // 盈亏平衡所需准确率（0-1）
+	ExpectedValue     float64 `json:"expected_value"`      // 每单位投注的期望收益
+	KellyFraction     float64 `json:"kelly_fraction"`      // 凯利建议仓位比例（0表示不应下注）
+}
+
+// ComputeEV 根据实测准确率与净赔率计算盈亏平衡点、期望值与凯利仓位
+func ComputeEV(accuracy, odds float64) *EVAnalysis {
+	analysis := &EVAnalysis{
+		Accuracy: accuracy,
+		Odds:     odds,
+	}
+	if odds <= 0 {
+		return analysis
+	}
+
+	// 盈亏平衡：accuracy*odds = (1-accuracy) 时期望为零
+	analysis.BreakEvenAccuracy = 1 / (1 + odds)
+
+	// 每单位投注期望收益
+	analysis.ExpectedValue = accuracy*odds - (1 - accuracy)
+
+	// 凯利公式：f = (p*(b+1) - 1) / b，期望为负时仓位取零
+	kelly := (accuracy*(odds+1) - 1) / odds
+	if kelly > 0 {
+		analysis.KellyFraction = kelly
+	}
+
+	return analysis
+}
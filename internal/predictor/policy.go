@@ -0,0 +1,115 @@
+package predictor
+
+import (
+	"fmt"
+
+	"pc28-bot/internal/database"
+)
+
+// CorrectnessPolicy 预测命中判定策略
+// 命中与否的判定此前分散在验证器和数据库层，两处口径不一致；
+// 统一由策略实现判定，数据库层只负责持久化判定结果
+type CorrectnessPolicy interface {
+	// Name 策略名称（用于配置选择和记录）
+	Name() string
+
+	// Judge 判定预测是否命中
+	Judge(prediction *database.Prediction, actual *database.LotteryResult) (bool, error)
+}
+
+// NewCorrectnessPolicy 根据配置的策略名称创建判定策略（空字符串为默认单双）
+func NewCorrectnessPolicy(name string) (CorrectnessPolicy, error) {
+	switch name {
+	case "", "odd_even":
+		return &oddEvenPolicy{}, nil
+	case "big_small":
+		return &bigSmallPolicy{}, nil
+	case "exact":
+		return &exactMatchPolicy{}, nil
+	case "positions":
+		return &positionsPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown correctness policy: %s", name)
+	}
+}
+
+// oddEvenPolicy 单双判定（默认策略，与历史行为一致）
+type oddEvenPolicy struct{}
+
+func (p *oddEvenPolicy) Name() string {
+	return "odd_even"
+}
+
+func (p *oddEvenPolicy) Judge(prediction *database.Prediction, actual *database.LotteryResult) (bool, error) {
+	actualOddEven := database.CalculateOddEven(actual.SumValue)
+	return prediction.PredictedOddEven == actualOddEven, nil
+}
+
+// bigSmallPolicy 大小判定（和值>=14为大）
+type bigSmallPolicy struct{}
+
+func (p *bigSmallPolicy) Name() string {
+	return "big_small"
+}
+
+func (p *bigSmallPolicy) Judge(prediction *database.Prediction, actual *database.LotteryResult) (bool, error) {
+	predictedBig := prediction.PredictedSum >= 14
+	actualBig := actual.SumValue >= 14
+	return predictedBig == actualBig, nil
+}
+
+// exactMatchPolicy 完全匹配判定（三个号码按位置全部一致）
+type exactMatchPolicy struct{}
+
+func (p *exactMatchPolicy) Name() string {
+	return "exact"
+}
+
+func (p *exactMatchPolicy) Judge(prediction *database.Prediction, actual *database.LotteryResult) (bool, error) {
+	matched, total, err := countMatchedPositions(prediction, actual)
+	if err != nil {
+		return false, err
+	}
+	return matched == total, nil
+}
+
+// positionsPolicy 位置匹配判定（至少2个位置号码一致）
+type positionsPolicy struct{}
+
+func (p *positionsPolicy) Name() string {
+	return "positions"
+}
+
+func (p *positionsPolicy) Judge(prediction *database.Prediction, actual *database.LotteryResult) (bool, error) {
+	matched, _, err := countMatchedPositions(prediction, actual)
+	if err != nil {
+		return false, err
+	}
+	return matched >= 2, nil
+}
+
+// countMatchedPositions 统计按位置匹配的号码数量
+func countMatchedPositions(prediction *database.Prediction, actual *database.LotteryResult) (int, int, error) {
+	predictedNums, err := database.ParseOpenNum(prediction.PredictedNum)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse predicted numbers: %v", err)
+	}
+
+	actualNums, err := database.ParseOpenNum(actual.OpenNum)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse actual numbers: %v", err)
+	}
+
+	minLen := len(predictedNums)
+	if len(actualNums) < minLen {
+		minLen = len(actualNums)
+	}
+
+	matched := 0
+	for i := 0; i < minLen; i++ {
+		if predictedNums[i] == actualNums[i] {
+			matched++
+		}
+	}
+	return matched, minLen, nil
+}
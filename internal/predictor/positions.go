@@ -0,0 +1,23 @@
+package predictor
+
+import (
+	"pc28-bot/internal/database"
+)
+
+// PositionPredictor 位置级预测扩展接口
+// 预测器可选实现：在整体号码之外单独给出三个位置的数字预测；
+// 未实现时验证侧回退为拆解整体号码得到的各位数字评分
+type PositionPredictor interface {
+	// PredictPositions 预测三个位置的数字（顺序与开奖号码位置对应）
+	PredictPositions(history []database.LotteryResult) ([]int, error)
+}
+
+// PredictPositions 取当前预测器的位置级预测
+// 当前预测器未实现扩展接口时返回nil（调用方回退用整体号码拆位）
+func (pm *PredictorManager) PredictPositions(history []database.LotteryResult) ([]int, error) {
+	pp, ok := pm.current.(PositionPredictor)
+	if !ok {
+		return nil, nil
+	}
+	return pp.PredictPositions(history)
+}
@@ -0,0 +1,93 @@
+package predictor
+
+import (
+	"fmt"
+)
+
+// defaultOdds 未配置赔率时的默认净赔率
+const defaultOdds = 0.95
+
+// profitRecentRounds 盈亏分析中保留的最近逐期明细条数
+const profitRecentRounds = 10
+
+// RoundProfit 单期假想盈亏（每期定额1单位投注）
+type RoundProfit struct {
+	Qihao      string  `json:"qihao"`
+	Win        bool    `json:"win"`
+	Profit     float64 `json:"profit"`     // 本期盈亏（命中+赔率，未中-1）
+	Cumulative float64 `json:"cumulative"` // 截至本期的累计净盈亏
+}
+
+// ProfitAnalysis 假想盈亏分析结果
+// 按"每期投注1单位、命中得净赔率、未中损失本金"的口径回算已验证预测
+type ProfitAnalysis struct {
+	Odds        float64       `json:"odds"`         // 使用的净赔率
+	Rounds      int           `json:"rounds"`       // 参与回算的已验证期数
+	Wins        int           `json:"wins"`         // 命中期数
+	Losses      int           `json:"losses"`       // 未中期数
+	NetProfit   float64       `json:"net_profit"`   // 累计净盈亏（单位注）
+	MaxDrawdown float64       `json:"max_drawdown"` // 最大回撤（单位注）
+	Recent      []RoundProfit `json:"recent"`       // 最近若干期逐期明细（旧→新）
+}
+
+// ComputeProfit 回算最近limit期已验证预测的假想盈亏
+// odds为净赔率（非正值时使用默认0.95）
+func (sc *StatisticsCalculator) ComputeProfit(odds float64, limit int) (*ProfitAnalysis, error) {
+	if odds <= 0 {
+		odds = defaultOdds
+	}
+
+	predictions, err := sc.db.GetLatestPredictions(limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get predictions for profit analysis: %v", err)
+	}
+
+	analysis := &ProfitAnalysis{Odds: odds}
+	cumulative := 0.0
+	peak := 0.0
+	var rounds []RoundProfit
+
+	// 预测记录为最新在前，按时间正序回算
+	for i := len(predictions) - 1; i >= 0; i-- {
+		pred := predictions[i]
+		if pred.IsCorrect == nil {
+			continue
+		}
+
+		profit := -1.0
+		win := *pred.IsCorrect
+		if win {
+			profit = odds
+			analysis.Wins++
+		} else {
+			analysis.Losses++
+		}
+
+		cumulative += profit
+		if cumulative > peak {
+			peak = cumulative
+		}
+		if drawdown := peak - cumulative; drawdown > analysis.MaxDrawdown {
+			analysis.MaxDrawdown = drawdown
+		}
+		rounds = append(rounds, RoundProfit{
+			Qihao:      pred.TargetQihao,
+			Win:        win,
+			Profit:     profit,
+			Cumulative: cumulative,
+		})
+	}
+
+	analysis.Rounds = analysis.Wins + analysis.Losses
+	if analysis.Rounds == 0 {
+		return nil, fmt.Errorf("no verified predictions available for profit analysis")
+	}
+	analysis.NetProfit = cumulative
+
+	if len(rounds) > profitRecentRounds {
+		rounds = rounds[len(rounds)-profitRecentRounds:]
+	}
+	analysis.Recent = rounds
+
+	return analysis, nil
+}
@@ -0,0 +1,99 @@
+package predictor
+
+import (
+	"fmt"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+)
+
+// recheckScanLimit 策略复判扫描的预测记录上限
+// PC28每天约288期，原始数据保留24小时左右，取一周的量足以覆盖全部留存记录
+const recheckScanLimit = 2016
+
+// flippedSampleLimit 差异报告中展示的翻转样本期号上限
+const flippedSampleLimit = 10
+
+// PolicyDiff 策略复判差异报告
+// 原口径指各记录验证时存档的is_correct（validation_policy可能不止一种），
+// 新口径为本次复判使用的策略；复判结果写入recheck_*平行列，不改写历史
+type PolicyDiff struct {
+	NewPolicy       string         `json:"new_policy"`
+	Total           int            `json:"total"`            // 参与复判的已验证预测数
+	StoredCorrect   int            `json:"stored_correct"`   // 原口径命中数
+	RecheckCorrect  int            `json:"recheck_correct"`  // 新口径命中数
+	Flipped         int            `json:"flipped"`          // 判定翻转的记录数
+	FlippedSample   []string       `json:"flipped_sample"`   // 翻转样本期号（最多10个）
+	StoredPolicies  map[string]int `json:"stored_policies"`  // 原判定策略分布
+	StoredAccuracy  float64        `json:"stored_accuracy"`  // 原口径准确率（%）
+	RecheckAccuracy float64        `json:"recheck_accuracy"` // 新口径准确率（%）
+}
+
+// RecomputeUnderPolicy 用指定策略复判留存的已验证预测
+// 复判结果写入平行列（SavePolicyRecheck），历史is_correct保持不变，
+// 返回新旧口径的差异报告供管理员在切换策略前评估影响
+func (v *Validator) RecomputeUnderPolicy(newPolicy CorrectnessPolicy) (*PolicyDiff, error) {
+	predictions, err := v.db.GetLatestPredictions(recheckScanLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get predictions for policy recheck: %v", err)
+	}
+
+	diff := &PolicyDiff{
+		NewPolicy:      newPolicy.Name(),
+		StoredPolicies: make(map[string]int),
+	}
+
+	for i := range predictions {
+		pred := &predictions[i]
+		if pred.IsCorrect == nil || pred.ActualNum == nil || pred.ActualSum == nil {
+			continue
+		}
+
+		// 用验证时存档的实际号码和和值重建判定所需的开奖数据
+		actual := &database.LotteryResult{
+			Qihao:    pred.TargetQihao,
+			OpenNum:  *pred.ActualNum,
+			SumValue: *pred.ActualSum,
+		}
+
+		recheckCorrect, err := newPolicy.Judge(pred, actual)
+		if err != nil {
+			logger.Warnf("Failed to rejudge prediction %s under policy %s: %v",
+				pred.TargetQihao, newPolicy.Name(), err)
+			continue
+		}
+
+		if err := v.db.SavePolicyRecheck(pred.ID, newPolicy.Name(), recheckCorrect); err != nil {
+			logger.Warnf("Failed to save policy recheck for %s: %v", pred.TargetQihao, err)
+		}
+
+		diff.Total++
+		if *pred.IsCorrect {
+			diff.StoredCorrect++
+		}
+		if recheckCorrect {
+			diff.RecheckCorrect++
+		}
+		if recheckCorrect != *pred.IsCorrect {
+			diff.Flipped++
+			if len(diff.FlippedSample) < flippedSampleLimit {
+				diff.FlippedSample = append(diff.FlippedSample, pred.TargetQihao)
+			}
+		}
+
+		storedPolicy := "unknown"
+		if pred.ValidationPolicy != nil {
+			storedPolicy = *pred.ValidationPolicy
+		}
+		diff.StoredPolicies[storedPolicy]++
+	}
+
+	if diff.Total > 0 {
+		diff.StoredAccuracy = float64(diff.StoredCorrect) / float64(diff.Total) * 100
+		diff.RecheckAccuracy = float64(diff.RecheckCorrect) / float64(diff.Total) * 100
+	}
+
+	logger.Infof("Policy recheck under %s completed: %d predictions, %d flipped",
+		newPolicy.Name(), diff.Total, diff.Flipped)
+	return diff, nil
+}
@@ -0,0 +1,51 @@
+package predictor
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"pc28-bot/internal/logger"
+)
+
+// Seedable 可注入随机源的预测器（可选实现）
+// 使用随机数的算法实现此接口后，随机序列由配置的种子决定，
+// 回测和对照实验可精确复现；固定算法无需实现
+type Seedable interface {
+	// SetRNG 注入独立随机源
+	SetRNG(rng *rand.Rand)
+}
+
+// NewRNG 根据种子创建独立随机源（seed为0时使用时间种子，即非确定性模式）
+func NewRNG(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// SeedPredictors 为全部实现Seedable的已注册预测器注入随机源
+// 每个预测器按名称顺序派生独立种子（seed+序号），既可复现又互不干扰；
+// seed非0时开启确定性模式
+func (pm *PredictorManager) SeedPredictors(seed int64) {
+	names := pm.GetAvailablePredictors()
+	sort.Strings(names)
+
+	seeded := 0
+	for i, name := range names {
+		seedable, ok := pm.predictors[name].(Seedable)
+		if !ok {
+			continue
+		}
+		if seed == 0 {
+			seedable.SetRNG(NewRNG(0))
+		} else {
+			seedable.SetRNG(NewRNG(seed + int64(i)))
+		}
+		seeded++
+	}
+
+	if seed != 0 && seeded > 0 {
+		logger.Infof("Deterministic mode enabled for %d predictor(s) (seed=%d)", seeded, seed)
+	}
+}
@@ -1,6 +1,7 @@
 package predictor
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -8,6 +9,13 @@ import (
 	"pc28-bot/internal/logger"
 )
 
+// ErrStalePrediction 预测记录滞留过久（数据保留24小时，对应开奖可能已被清理），
+// 常规验证不再处理，调用方用errors.Is识别后走对账/告警分支
+var ErrStalePrediction = errors.New("stale prediction")
+
+// staleValidationWindow 常规验证接受的预测最大滞留时长（与数据保留周期一致）
+const staleValidationWindow = 24 * time.Hour
+
 // ValidationResult 验证结果
 type ValidationResult struct {
 	IsCorrect        bool      `json:"is_correct"`
@@ -21,37 +29,59 @@ type ValidationResult struct {
 }
 
 // Validator 预测验证器
+// 命中判定统一由CorrectnessPolicy完成，是全系统唯一的判定入口
 type Validator struct {
-	mysql *database.MySQLDB
+	db     database.DB
+	policy CorrectnessPolicy
 }
 
-// NewValidator 创建新的验证器
-func NewValidator(mysql *database.MySQLDB) *Validator {
+// NewValidator 创建新的验证器（policy为nil时使用默认单双策略）
+func NewValidator(db database.DB, policy CorrectnessPolicy) *Validator {
+	if policy == nil {
+		policy = &oddEvenPolicy{}
+	}
 	return &Validator{
-		mysql: mysql,
+		db:     db,
+		policy: policy,
 	}
 }
 
 // ValidatePrediction 验证预测结果
+// 已验证过的预测直接返回库中结果（幂等，避免重复验证覆盖verified_at）
 func (v *Validator) ValidatePrediction(qihao string, actualResult *database.LotteryResult) (*ValidationResult, error) {
+	return v.validate(qihao, actualResult, false)
+}
+
+// RevalidatePrediction 强制重新验证（用于开奖数据更正后修正判定结果）
+func (v *Validator) RevalidatePrediction(qihao string, actualResult *database.LotteryResult) (*ValidationResult, error) {
+	return v.validate(qihao, actualResult, true)
+}
+
+// validate 验证预测结果（force为false时跳过已验证的记录）
+func (v *Validator) validate(qihao string, actualResult *database.LotteryResult, force bool) (*ValidationResult, error) {
 	logger.Infof("Validating prediction for qihao: %s", qihao)
 
 	// 从数据库获取对应的预测记录
-	predictions, err := v.mysql.GetLatestPredictions(20) // 获取最近20条预测记录
+	targetPrediction, err := v.db.GetPredictionByQihao(qihao)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get predictions: %v", err)
+		return nil, fmt.Errorf("failed to get prediction: %v", err)
+	}
+	if targetPrediction == nil {
+		return nil, fmt.Errorf("%w: no prediction found for qihao %s", database.ErrNoData, qihao)
 	}
 
-	var targetPrediction *database.Prediction
-	for _, pred := range predictions {
-		if pred.TargetQihao == qihao {
-			targetPrediction = &pred
-			break
-		}
+	// 滞留过久的未验证预测不做常规验证（强制重验/对账不受此限制）
+	if !force && targetPrediction.VerifiedAt == nil &&
+		time.Since(targetPrediction.PredictedAt) > staleValidationWindow {
+		return nil, fmt.Errorf("%w: prediction for %s predicted at %s",
+			ErrStalePrediction, qihao, targetPrediction.PredictedAt.Format("2006-01-02 15:04:05"))
 	}
 
-	if targetPrediction == nil {
-		return nil, fmt.Errorf("no prediction found for qihao: %s", qihao)
+	// 已验证过且非强制时直接返回库中结果
+	if !force && targetPrediction.VerifiedAt != nil && targetPrediction.IsCorrect != nil {
+		logger.Debugf("Prediction for %s already verified at %s, skipping",
+			qihao, targetPrediction.VerifiedAt.Format("2006-01-02 15:04:05"))
+		return v.resultFromStored(targetPrediction, actualResult)
 	}
 
 	// 解析预测号码和实际号码
@@ -65,16 +95,32 @@ func (v *Validator) ValidatePrediction(qihao string, actualResult *database.Lott
 		return nil, fmt.Errorf("failed to parse actual numbers: %v", err)
 	}
 
-	// 进行详细验证
+	// 统计匹配详情（用于报表展示，不参与命中判定）
 	result := v.performDetailedValidation(predictedNums, actualNums)
 	result.ValidationTime = time.Now()
 
-	// 使用数据库的ValidatePrediction方法来更新记录
-	isCorrect, err := v.mysql.ValidatePrediction(qihao, actualResult)
+	// 由配置的策略统一判定命中与否
+	isCorrect, err := v.policy.Judge(targetPrediction, actualResult)
 	if err != nil {
-		logger.Errorf("Failed to validate prediction in database: %v", err)
-	} else {
-		result.IsCorrect = isCorrect
+		return nil, fmt.Errorf("failed to judge prediction: %v", err)
+	}
+	result.IsCorrect = isCorrect
+
+	// 持久化验证结果（随记录保存判定策略名称）
+	if err := v.db.SaveVerificationResult(qihao, actualResult, isCorrect, v.policy.Name()); err != nil {
+		logger.Errorf("Failed to save verification result: %v", err)
+	}
+
+	// 位置级命中评分：优先用位置级预测，未提供时回退为整体号码拆位
+	positionNums := predictedNums
+	if targetPrediction.PositionNums != nil {
+		if nums, err := database.ParseOpenNum(*targetPrediction.PositionNums); err == nil {
+			positionNums = nums
+		}
+	}
+	positionHits := len(v.getMatchedPositions(positionNums, actualNums))
+	if err := v.db.SavePositionHits(qihao, positionHits); err != nil {
+		logger.Warnf("Failed to save position hits: %v", err)
 	}
 
 	logger.Infof("Prediction validation completed for %s: %s (match: %s)",
@@ -83,7 +129,29 @@ func (v *Validator) ValidatePrediction(qihao string, actualResult *database.Lott
 	return result, nil
 }
 
-// performDetailedValidation 执行详细验证
+// resultFromStored 根据库中已验证的预测记录构造验证结果
+func (v *Validator) resultFromStored(pred *database.Prediction, actualResult *database.LotteryResult) (*ValidationResult, error) {
+	predictedNums, err := database.ParseOpenNum(pred.PredictedNum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse predicted numbers: %v", err)
+	}
+
+	actualNum := actualResult.OpenNum
+	if pred.ActualNum != nil {
+		actualNum = *pred.ActualNum
+	}
+	actualNums, err := database.ParseOpenNum(actualNum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse actual numbers: %v", err)
+	}
+
+	result := v.performDetailedValidation(predictedNums, actualNums)
+	result.IsCorrect = *pred.IsCorrect
+	result.ValidationTime = *pred.VerifiedAt
+	return result, nil
+}
+
+// performDetailedValidation 统计匹配详情（命中判定由CorrectnessPolicy负责）
 func (v *Validator) performDetailedValidation(predicted, actual []int) *ValidationResult {
 	result := &ValidationResult{
 		PredictedNumbers: predicted,
@@ -94,7 +162,6 @@ func (v *Validator) performDetailedValidation(predicted, actual []int) *Validati
 
 	// 检查完全匹配
 	if v.isExactMatch(predicted, actual) {
-		result.IsCorrect = true
 		result.MatchType = "exact"
 		result.MatchedPositions = []int{0, 1, 2}
 		return result
@@ -105,11 +172,8 @@ func (v *Validator) performDetailedValidation(predicted, actual []int) *Validati
 	if len(matchedPositions) > 0 {
 		result.MatchType = "partial"
 		result.MatchedPositions = matchedPositions
-		// 部分匹配也可以根据策略定义为正确或错误
-		result.IsCorrect = len(matchedPositions) >= 2 // 至少匹配2个位置算正确
 	} else {
 		result.MatchType = "none"
-		result.IsCorrect = false
 	}
 
 	return result
@@ -165,28 +229,72 @@ func (v *Validator) ValidateBatch(results []database.LotteryResult) ([]Validatio
 	return validationResults, nil
 }
 
+// ReconcileBatch 批量对账验证结果（夜间任务调用）
+// 先补验有开奖数据但尚未验证的预测，再用当前策略核对已验证记录的
+// is_correct，修复口径不一致的行（历史上验证逻辑分叉遗留的脏数据），
+// 返回修复的记录数量
+func (v *Validator) ReconcileBatch(results []database.LotteryResult) (int, error) {
+	// 补验缺失的验证（幂等，已验证的记录直接跳过）
+	if _, err := v.ValidateBatch(results); err != nil {
+		logger.Warnf("Reconciliation backfill failed: %v", err)
+	}
+
+	repaired := 0
+	for i := range results {
+		actual := &results[i]
+
+		pred, err := v.db.GetPredictionByQihao(actual.Qihao)
+		if err != nil || pred == nil || pred.IsCorrect == nil {
+			continue
+		}
+
+		expected, err := v.policy.Judge(pred, actual)
+		if err != nil {
+			logger.Warnf("Failed to judge prediction %s during reconciliation: %v", actual.Qihao, err)
+			continue
+		}
+
+		if expected != *pred.IsCorrect {
+			if _, err := v.RevalidatePrediction(actual.Qihao, actual); err != nil {
+				logger.Warnf("Failed to repair prediction %s: %v", actual.Qihao, err)
+				continue
+			}
+			repaired++
+		}
+	}
+
+	if repaired > 0 {
+		logger.Infof("Reconciliation repaired %d inconsistent predictions", repaired)
+	}
+	return repaired, nil
+}
+
 // Statistics 统计信息
 type Statistics struct {
-	TotalPredictions     int       `json:"total_predictions"`
-	CorrectPredictions   int       `json:"correct_predictions"`
-	IncorrectPredictions int       `json:"incorrect_predictions"`
-	AccuracyRate         float64   `json:"accuracy_rate"`
-	ExactMatches         int       `json:"exact_matches"`
-	PartialMatches       int       `json:"partial_matches"`
-	NoMatches            int       `json:"no_matches"`
-	AverageConfidence    float64   `json:"average_confidence"`
-	LastUpdateTime       time.Time `json:"last_update_time"`
+	TotalPredictions     int        `json:"total_predictions"`
+	CorrectPredictions   int        `json:"correct_predictions"`
+	IncorrectPredictions int        `json:"incorrect_predictions"`
+	AccuracyRate         float64    `json:"accuracy_rate"`
+	AccuracyLow95        float64    `json:"accuracy_low_95"`  // 准确率Wilson区间下界（95%置信水平）
+	AccuracyHigh95       float64    `json:"accuracy_high_95"` // 准确率Wilson区间上界
+	ExactMatches         int        `json:"exact_matches"`
+	PartialMatches       int        `json:"partial_matches"`
+	NoMatches            int        `json:"no_matches"`
+	PositionHitRates     [3]float64 `json:"position_hit_rates"` // 各位置数字命中率（%，位置级评分过的记录）
+	PositionSamples      int        `json:"position_samples"`   // 参与位置级评分的记录数
+	AverageConfidence    float64    `json:"average_confidence"`
+	LastUpdateTime       time.Time  `json:"last_update_time"`
 }
 
 // StatisticsCalculator 统计计算器
 type StatisticsCalculator struct {
-	mysql *database.MySQLDB
+	db database.DB
 }
 
 // NewStatisticsCalculator 创建统计计算器
-func NewStatisticsCalculator(mysql *database.MySQLDB) *StatisticsCalculator {
+func NewStatisticsCalculator(db database.DB) *StatisticsCalculator {
 	return &StatisticsCalculator{
-		mysql: mysql,
+		db: db,
 	}
 }
 
@@ -195,13 +303,13 @@ func (sc *StatisticsCalculator) CalculateStatistics() (*Statistics, error) {
 	logger.Debug("Calculating prediction statistics")
 
 	// 从数据库获取统计信息
-	dbStats, err := sc.mysql.GetPredictionStats()
+	dbStats, err := sc.db.GetPredictionStats()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get prediction stats from database: %v", err)
 	}
 
 	// 获取详细的预测记录进行深度分析
-	predictions, err := sc.mysql.GetLatestPredictions(100) // 分析最近100条记录
+	predictions, err := sc.db.GetLatestPredictions(100) // 分析最近100条记录
 	if err != nil {
 		return nil, fmt.Errorf("failed to get prediction details: %v", err)
 	}
@@ -213,6 +321,7 @@ func (sc *StatisticsCalculator) CalculateStatistics() (*Statistics, error) {
 		AccuracyRate:         dbStats.AccuracyRate,
 		LastUpdateTime:       time.Now(),
 	}
+	stats.AccuracyLow95, stats.AccuracyHigh95 = WilsonInterval(dbStats.CorrectPredictions, dbStats.TotalPredictions)
 
 	// 计算详细统计
 	sc.calculateDetailedStats(predictions, stats)
@@ -227,6 +336,7 @@ func (sc *StatisticsCalculator) CalculateStatistics() (*Statistics, error) {
 func (sc *StatisticsCalculator) calculateDetailedStats(predictions []database.Prediction, stats *Statistics) {
 	var confidenceSum float64
 	validConfidenceCount := 0
+	var positionHits [3]int
 
 	for _, pred := range predictions {
 		// 计算平均置信度
@@ -240,6 +350,7 @@ func (sc *StatisticsCalculator) calculateDetailedStats(predictions []database.Pr
 			if err := sc.categorizeMatch(&pred, stats); err != nil {
 				logger.Warnf("Failed to categorize match for prediction %d: %v", pred.ID, err)
 			}
+			sc.tallyPositionHits(&pred, &positionHits, stats)
 		}
 	}
 
@@ -247,6 +358,40 @@ func (sc *StatisticsCalculator) calculateDetailedStats(predictions []database.Pr
 	if validConfidenceCount > 0 {
 		stats.AverageConfidence = confidenceSum / float64(validConfidenceCount)
 	}
+
+	// 各位置数字命中率
+	if stats.PositionSamples > 0 {
+		for i := range positionHits {
+			stats.PositionHitRates[i] = float64(positionHits[i]) / float64(stats.PositionSamples) * 100
+		}
+	}
+}
+
+// tallyPositionHits 统计各位置数字命中（优先位置级预测，未提供时回退整体号码拆位）
+func (sc *StatisticsCalculator) tallyPositionHits(pred *database.Prediction, hits *[3]int, stats *Statistics) {
+	predictedStr := pred.PredictedNum
+	if pred.PositionNums != nil {
+		predictedStr = *pred.PositionNums
+	}
+
+	predictedNums, err := database.ParseOpenNum(predictedStr)
+	if err != nil {
+		return
+	}
+	actualNums, err := database.ParseOpenNum(*pred.ActualNum)
+	if err != nil {
+		return
+	}
+	if len(predictedNums) != 3 || len(actualNums) != 3 {
+		return
+	}
+
+	stats.PositionSamples++
+	for i := 0; i < 3; i++ {
+		if predictedNums[i] == actualNums[i] {
+			hits[i]++
+		}
+	}
 }
 
 // categorizeMatch 分类匹配类型
@@ -293,7 +438,7 @@ func (sc *StatisticsCalculator) categorizeMatch(pred *database.Prediction, stats
 // GetPerformanceReport 获取性能报告
 func (sc *StatisticsCalculator) GetPerformanceReport(days int) (map[string]interface{}, error) {
 	// 获取指定天数的预测记录
-	predictions, err := sc.mysql.GetLatestPredictions(days * 288) // PC28每天约288期
+	predictions, err := sc.db.GetLatestPredictions(days * 288) // PC28每天约288期
 	if err != nil {
 		return nil, fmt.Errorf("failed to get predictions for performance report: %v", err)
 	}
@@ -333,9 +478,104 @@ func (sc *StatisticsCalculator) GetPerformanceReport(days int) (map[string]inter
 	}, nil
 }
 
+// HeatmapData 24×7准确率热力图数据
+type HeatmapData struct {
+	Accuracy    [7][24]float64 `json:"accuracy"` // 星期（周日=0）×小时的准确率（%）
+	Samples     [7][24]int     `json:"samples"`  // 各单元格样本数
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// ComputeAccuracyHeatmap 从留存的预测记录计算星期×小时准确率热力图
+func (sc *StatisticsCalculator) ComputeAccuracyHeatmap() (*HeatmapData, error) {
+	// 取一周的量（PC28每天约288期），受原始数据保留策略限制
+	predictions, err := sc.db.GetLatestPredictions(7 * 288)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get predictions for heatmap: %v", err)
+	}
+
+	heatmap := &HeatmapData{GeneratedAt: time.Now()}
+	var corrects [7][24]int
+
+	for _, pred := range predictions {
+		if pred.IsCorrect == nil || pred.VerifiedAt == nil {
+			continue
+		}
+		weekday := int(pred.VerifiedAt.Weekday())
+		hour := pred.VerifiedAt.Hour()
+		heatmap.Samples[weekday][hour]++
+		if *pred.IsCorrect {
+			corrects[weekday][hour]++
+		}
+	}
+
+	for weekday := 0; weekday < 7; weekday++ {
+		for hour := 0; hour < 24; hour++ {
+			if heatmap.Samples[weekday][hour] > 0 {
+				heatmap.Accuracy[weekday][hour] =
+					float64(corrects[weekday][hour]) / float64(heatmap.Samples[weekday][hour]) * 100
+			}
+		}
+	}
+	return heatmap, nil
+}
+
+// ComputeDailySnapshot 计算指定日期的统计快照（按验证时间归属当日）
+func (sc *StatisticsCalculator) ComputeDailySnapshot(date time.Time, algorithm string) (*database.DailyStats, error) {
+	// PC28每天约288期，多取一天余量覆盖跨日验证
+	predictions, err := sc.db.GetLatestPredictions(576)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get predictions for daily snapshot: %v", err)
+	}
+
+	dateKey := date.Format("2006-01-02")
+
+	// 按期号升序重放当日验证序列，统计连对/连错
+	var dayResults []bool
+	for i := len(predictions) - 1; i >= 0; i-- {
+		pred := predictions[i]
+		if pred.IsCorrect == nil || pred.VerifiedAt == nil {
+			continue
+		}
+		if pred.VerifiedAt.Format("2006-01-02") != dateKey {
+			continue
+		}
+		dayResults = append(dayResults, *pred.IsCorrect)
+	}
+
+	stats := &database.DailyStats{
+		StatDate:  date,
+		Algorithm: algorithm,
+	}
+
+	currentWin, currentLoss := 0, 0
+	for _, correct := range dayResults {
+		stats.TotalPredictions++
+		if correct {
+			stats.CorrectPredictions++
+			currentWin++
+			currentLoss = 0
+			if currentWin > stats.BestStreak {
+				stats.BestStreak = currentWin
+			}
+		} else {
+			currentLoss++
+			currentWin = 0
+			if currentLoss > stats.WorstStreak {
+				stats.WorstStreak = currentLoss
+			}
+		}
+	}
+
+	if stats.TotalPredictions > 0 {
+		stats.AccuracyRate = float64(stats.CorrectPredictions) / float64(stats.TotalPredictions) * 100
+	}
+	stats.AccuracyLow95, stats.AccuracyHigh95 = WilsonInterval(stats.CorrectPredictions, stats.TotalPredictions)
+	return stats, nil
+}
+
 // GetTrendAnalysis 获取趋势分析
 func (sc *StatisticsCalculator) GetTrendAnalysis() (map[string]interface{}, error) {
-	predictions, err := sc.mysql.GetLatestPredictions(50) // 分析最近50期
+	predictions, err := sc.db.GetLatestPredictions(50) // 分析最近50期
 	if err != nil {
 		return nil, fmt.Errorf("failed to get predictions for trend analysis: %v", err)
 	}
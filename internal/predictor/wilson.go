@@ -0,0 +1,34 @@
+package predictor
+
+import "math"
+
+// wilsonZ95 95%置信水平对应的正态分位数
+const wilsonZ95 = 1.96
+
+// WilsonInterval 计算命中率的Wilson得分置信区间（95%水平，返回百分比）
+// 小样本下的原始准确率有误导性（3/4命中显示75%但几乎没有统计意义），
+// Wilson区间在样本少时自动变宽，向消费方如实传达不确定性；total为0时返回[0,100]
+func WilsonInterval(correct, total int) (low, high float64) {
+	if total <= 0 {
+		return 0, 100
+	}
+
+	n := float64(total)
+	p := float64(correct) / n
+	z := wilsonZ95
+	z2 := z * z
+
+	denom := 1 + z2/n
+	center := (p + z2/(2*n)) / denom
+	margin := z * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / denom
+
+	low = (center - margin) * 100
+	high = (center + margin) * 100
+	if low < 0 {
+		low = 0
+	}
+	if high > 100 {
+		high = 100
+	}
+	return low, high
+}
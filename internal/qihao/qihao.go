@@ -0,0 +1,163 @@
+// Package qihao 期号解析与推进
+// 此前下一期期号的计算逻辑散落在多处且回退默认值不一致，
+// 统一收敛到本包，按彩种方案处理递增与日切进位
+package qihao
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scheme 期号方案（按彩种配置解析与递增规则）
+// PrefixLen为0时整个期号视为一个连续递增的数字（PC28采用此方案）；
+// 前缀方案下期号由前缀和定长序号组成，序号溢出时进位前缀并从1重新编号
+// （用于按日重新编号的彩种）
+type Scheme struct {
+	PrefixLen int // 前缀位数（0表示整体连续数字方案）
+	SerialLen int // 序号位数（仅前缀方案使用，递增时按位补零）
+}
+
+// Default PC28默认方案：期号整体为连续递增的数字（如3326106）
+var Default = Scheme{}
+
+// Parse 解析期号为数字（用于比较和递增）
+func Parse(q string) (int64, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(q), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid qihao: %s", q)
+	}
+	return n, nil
+}
+
+// Validate 校验期号格式（可解析且为正数）
+func Validate(q string) error {
+	n, err := Parse(q)
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return fmt.Errorf("invalid qihao: %s", q)
+	}
+	return nil
+}
+
+// Next 按默认方案计算下一期期号
+func Next(q string) (string, error) {
+	return Default.Next(q)
+}
+
+// Prev 按默认方案计算上一期期号
+func Prev(q string) (string, error) {
+	return Default.Prev(q)
+}
+
+// Distance 计算从a到b的期数差（b晚于a时为正）
+// 仅默认连续数字方案有意义，任一期号不可解析时返回错误
+func Distance(a, b string) (int64, error) {
+	na, err := Parse(a)
+	if err != nil {
+		return 0, err
+	}
+	nb, err := Parse(b)
+	if err != nil {
+		return 0, err
+	}
+	return nb - na, nil
+}
+
+// Compare 比较两个期号的先后（a早于b返回负数，相同返回0）
+// 均可解析为数字时按数值比较，否则退化为字符串比较
+func Compare(a, b string) int {
+	na, errA := Parse(a)
+	nb, errB := Parse(b)
+	if errA == nil && errB == nil {
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		default:
+			return 0
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// Next 计算下一期期号
+func (s Scheme) Next(q string) (string, error) {
+	if s.PrefixLen <= 0 {
+		n, err := Parse(q)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(n+1, 10), nil
+	}
+
+	if len(q) != s.PrefixLen+s.SerialLen {
+		return "", fmt.Errorf("qihao %s does not match scheme length %d", q, s.PrefixLen+s.SerialLen)
+	}
+
+	prefix, err := strconv.Atoi(q[:s.PrefixLen])
+	if err != nil {
+		return "", fmt.Errorf("invalid qihao prefix: %s", q)
+	}
+	serial, err := strconv.Atoi(q[s.PrefixLen:])
+	if err != nil {
+		return "", fmt.Errorf("invalid qihao serial: %s", q)
+	}
+
+	// 序号溢出时日切进位：前缀加1、序号从1重新编号
+	maxSerial := 1
+	for i := 0; i < s.SerialLen; i++ {
+		maxSerial *= 10
+	}
+	maxSerial--
+
+	if serial >= maxSerial {
+		return fmt.Sprintf("%0*d%0*d", s.PrefixLen, prefix+1, s.SerialLen, 1), nil
+	}
+	return fmt.Sprintf("%0*d%0*d", s.PrefixLen, prefix, s.SerialLen, serial+1), nil
+}
+
+// Prev 计算上一期期号
+// 前缀方案下序号1的上一期为前一前缀的最后一期（日切退位）
+func (s Scheme) Prev(q string) (string, error) {
+	if s.PrefixLen <= 0 {
+		n, err := Parse(q)
+		if err != nil {
+			return "", err
+		}
+		if n <= 1 {
+			return "", fmt.Errorf("qihao %s has no predecessor", q)
+		}
+		return strconv.FormatInt(n-1, 10), nil
+	}
+
+	if len(q) != s.PrefixLen+s.SerialLen {
+		return "", fmt.Errorf("qihao %s does not match scheme length %d", q, s.PrefixLen+s.SerialLen)
+	}
+
+	prefix, err := strconv.Atoi(q[:s.PrefixLen])
+	if err != nil {
+		return "", fmt.Errorf("invalid qihao prefix: %s", q)
+	}
+	serial, err := strconv.Atoi(q[s.PrefixLen:])
+	if err != nil {
+		return "", fmt.Errorf("invalid qihao serial: %s", q)
+	}
+
+	maxSerial := 1
+	for i := 0; i < s.SerialLen; i++ {
+		maxSerial *= 10
+	}
+	maxSerial--
+
+	if serial <= 1 {
+		if prefix <= 0 {
+			return "", fmt.Errorf("qihao %s has no predecessor", q)
+		}
+		return fmt.Sprintf("%0*d%0*d", s.PrefixLen, prefix-1, s.SerialLen, maxSerial), nil
+	}
+	return fmt.Sprintf("%0*d%0*d", s.PrefixLen, prefix, s.SerialLen, serial-1), nil
+}
@@ -0,0 +1,171 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"time"
+
+	"pc28-bot/internal/database"
+)
+
+// feedItemLimit 订阅源保留的最新条目数（开奖与验证结果各取该数量后合并）
+const feedItemLimit = 20
+
+// FeedBuilder 开奖结果与预测验证的订阅源生成器
+// 从数据库取最近开奖和已验证预测，渲染RSS 2.0与JSON Feed两种格式，
+// 用户在订阅阅读器中即可跟进结果，无需打开Telegram
+type FeedBuilder struct {
+	db database.DB
+}
+
+// NewFeedBuilder 创建订阅源生成器
+func NewFeedBuilder(db database.DB) *FeedBuilder {
+	return &FeedBuilder{db: db}
+}
+
+// feedItem 订阅源单条目（与具体输出格式无关的中间表示）
+type feedItem struct {
+	title       string
+	description string
+	guid        string
+	published   time.Time
+}
+
+// buildItems 汇总最近开奖与已验证预测，按时间降序合并
+func (f *FeedBuilder) buildItems() ([]feedItem, error) {
+	results, err := f.db.GetLatestLotteryResults(feedItemLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lottery results for feed: %v", err)
+	}
+
+	var items []feedItem
+	for i := range results {
+		r := &results[i]
+		items = append(items, feedItem{
+			title: fmt.Sprintf("Round %s: %s = %d (%s/%s)",
+				r.Qihao, r.OpenNum, r.SumValue, r.BigSmall, r.OddEven),
+			description: fmt.Sprintf("Round %s drew %s with sum %d at %s.",
+				r.Qihao, r.OpenNum, r.SumValue, r.OpenTime.Format("2006-01-02 15:04:05")),
+			guid:      "round-" + r.Qihao,
+			published: r.OpenTime,
+		})
+	}
+
+	predictions, err := f.db.GetLatestPredictions(feedItemLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load predictions for feed: %v", err)
+	}
+	for i := range predictions {
+		p := &predictions[i]
+		if p.IsCorrect == nil || p.ActualNum == nil {
+			continue
+		}
+		outcome := "Incorrect"
+		if *p.IsCorrect {
+			outcome = "Correct"
+		}
+		published := p.PredictedAt
+		if p.VerifiedAt != nil {
+			published = *p.VerifiedAt
+		}
+		items = append(items, feedItem{
+			title: fmt.Sprintf("Prediction %s: %s — %s",
+				p.TargetQihao, p.PredictedNum, outcome),
+			description: fmt.Sprintf("Predicted %s for round %s, actual draw was %s. Result: %s.",
+				p.PredictedNum, p.TargetQihao, *p.ActualNum, outcome),
+			guid:      "prediction-" + p.TargetQihao,
+			published: published,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].published.After(items[j].published)
+	})
+	if len(items) > feedItemLimit*2 {
+		items = items[:feedItemLimit*2]
+	}
+	return items, nil
+}
+
+// rssFeed RSS 2.0输出结构
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Description string `xml:"description"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+}
+
+// RenderRSS 渲染RSS 2.0格式订阅源
+func (f *FeedBuilder) RenderRSS() ([]byte, error) {
+	items, err := f.buildItems()
+	if err != nil {
+		return nil, err
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       "PC28 Results & Predictions",
+			Link:        "https://t.me/",
+			Description: "Recent PC28 lottery rounds and verified predictions",
+		},
+	}
+	for _, item := range items {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       item.title,
+			Description: item.description,
+			GUID:        item.guid,
+			PubDate:     item.published.Format(time.RFC1123Z),
+		})
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal RSS feed: %v", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// RenderJSONFeed 渲染JSON Feed 1.1格式订阅源
+func (f *FeedBuilder) RenderJSONFeed() ([]byte, error) {
+	items, err := f.buildItems()
+	if err != nil {
+		return nil, err
+	}
+
+	jsonItems := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		jsonItems = append(jsonItems, map[string]interface{}{
+			"id":             item.guid,
+			"title":          item.title,
+			"content_text":   item.description,
+			"date_published": item.published.Format(time.RFC3339),
+		})
+	}
+
+	feed := map[string]interface{}{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title":   "PC28 Results & Predictions",
+		"items":   jsonItems,
+	}
+	body, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON feed: %v", err)
+	}
+	return body, nil
+}
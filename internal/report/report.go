@@ -0,0 +1,176 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pc28-bot/internal/database"
+)
+
+// Report 周期统计报表
+type Report struct {
+	Period             string // week或month
+	StartDate          time.Time
+	EndDate            time.Time
+	TotalPredictions   int
+	CorrectPredictions int
+	AccuracyRate       float64
+	BestStreak         int
+	WorstStreak        int
+	DailyRows          []database.DailyStats
+	HourlyAccuracy     map[int]float64 // 小时 -> 准确率（样本不足的小时不出现）
+	GeneratedAt        time.Time
+}
+
+// Generator 报表生成器
+// 汇总基于stats_daily快照表，因此不受原始数据24小时保留策略影响
+type Generator struct {
+	db database.DB
+}
+
+// NewGenerator 创建报表生成器
+func NewGenerator(db database.DB) *Generator {
+	return &Generator{db: db}
+}
+
+// Generate 生成指定周期的报表（week=最近7天，month=最近30天）
+func (g *Generator) Generate(period string) (*Report, error) {
+	days := 7
+	if period == "month" {
+		days = 30
+	} else {
+		period = "week"
+	}
+
+	dailyStats, err := g.db.GetDailyStats(days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily stats for report: %v", err)
+	}
+
+	now := time.Now()
+	report := &Report{
+		Period:      period,
+		StartDate:   now.AddDate(0, 0, -days+1),
+		EndDate:     now,
+		DailyRows:   dailyStats,
+		GeneratedAt: now,
+	}
+
+	for _, day := range dailyStats {
+		report.TotalPredictions += day.TotalPredictions
+		report.CorrectPredictions += day.CorrectPredictions
+		if day.BestStreak > report.BestStreak {
+			report.BestStreak = day.BestStreak
+		}
+		if day.WorstStreak > report.WorstStreak {
+			report.WorstStreak = day.WorstStreak
+		}
+	}
+	if report.TotalPredictions > 0 {
+		report.AccuracyRate = float64(report.CorrectPredictions) / float64(report.TotalPredictions) * 100
+	}
+
+	report.HourlyAccuracy = g.computeHourlyAccuracy(days)
+	return report, nil
+}
+
+// computeHourlyAccuracy 按验证时间的小时分桶计算准确率
+// 原始预测仅保留24小时，更早的窗口只能覆盖留存数据
+func (g *Generator) computeHourlyAccuracy(days int) map[int]float64 {
+	predictions, err := g.db.GetLatestPredictions(days * 288)
+	if err != nil {
+		return nil
+	}
+
+	totals := make(map[int]int)
+	corrects := make(map[int]int)
+	for _, pred := range predictions {
+		if pred.IsCorrect == nil || pred.VerifiedAt == nil {
+			continue
+		}
+		hour := pred.VerifiedAt.Hour()
+		totals[hour]++
+		if *pred.IsCorrect {
+			corrects[hour]++
+		}
+	}
+
+	accuracy := make(map[int]float64)
+	for hour, total := range totals {
+		if total > 0 {
+			accuracy[hour] = float64(corrects[hour]) / float64(total) * 100
+		}
+	}
+	return accuracy
+}
+
+// FormatTelegram 格式化为Telegram消息
+func (g *Generator) FormatTelegram(report *Report) string {
+	var builder strings.Builder
+
+	title := "Weekly"
+	if report.Period == "month" {
+		title = "Monthly"
+	}
+	builder.WriteString(fmt.Sprintf("📊 *%s Report*\n", title))
+	builder.WriteString(fmt.Sprintf("`%s` ~ `%s`\n\n",
+		report.StartDate.Format("2006-01-02"), report.EndDate.Format("2006-01-02")))
+
+	builder.WriteString(fmt.Sprintf("Total Predictions: `%d`\n", report.TotalPredictions))
+	builder.WriteString(fmt.Sprintf("Correct: `%d`\n", report.CorrectPredictions))
+	builder.WriteString(fmt.Sprintf("Accuracy: `%.2f%%`\n", report.AccuracyRate))
+	builder.WriteString(fmt.Sprintf("Best Streak: `%d`\n", report.BestStreak))
+	builder.WriteString(fmt.Sprintf("Worst Streak: `%d`\n\n", report.WorstStreak))
+
+	if len(report.HourlyAccuracy) > 0 {
+		builder.WriteString("*Hourly Accuracy (retained data)*\n")
+		for hour := 0; hour < 24; hour++ {
+			if accuracy, ok := report.HourlyAccuracy[hour]; ok {
+				builder.WriteString(fmt.Sprintf("`%02d:00` %.1f%%\n", hour, accuracy))
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+// WriteCSV 将报表写为CSV文件，返回文件路径（调用方负责清理）
+func (g *Generator) WriteCSV(report *Report) (string, error) {
+	path := filepath.Join(os.TempDir(),
+		fmt.Sprintf("pc28_report_%s_%s.csv", report.Period, report.GeneratedAt.Format("20060102_150405")))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create report file: %v", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"date", "total", "correct", "accuracy", "best_streak", "worst_streak", "algorithm"}
+	if err := writer.Write(header); err != nil {
+		return "", fmt.Errorf("failed to write report header: %v", err)
+	}
+
+	for _, day := range report.DailyRows {
+		row := []string{
+			day.StatDate.Format("2006-01-02"),
+			fmt.Sprintf("%d", day.TotalPredictions),
+			fmt.Sprintf("%d", day.CorrectPredictions),
+			fmt.Sprintf("%.2f", day.AccuracyRate),
+			fmt.Sprintf("%d", day.BestStreak),
+			fmt.Sprintf("%d", day.WorstStreak),
+			day.Algorithm,
+		}
+		if err := writer.Write(row); err != nil {
+			return "", fmt.Errorf("failed to write report row: %v", err)
+		}
+	}
+
+	return path, nil
+}
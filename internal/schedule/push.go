@@ -0,0 +1,105 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PushScheduleStateKey 推送计划在app_state表中的键
+// （管理员经Telegram上传计划文件后存储于此，推送循环每次检查时读取）
+const PushScheduleStateKey = "push_schedule"
+
+// PushSchedule 定时推送计划（每日摘要时间、周报日、月报日）
+// 未配置时使用默认值：零点摘要、周一周报、每月1日月报
+type PushSchedule struct {
+	DailySummaryTime string `yaml:"daily_summary_time" json:"daily_summary_time"` // HH:MM格式（检查按小时粒度命中）
+	WeeklyReportDay  string `yaml:"weekly_report_day" json:"weekly_report_day"`   // 星期英文名（monday~sunday）
+	MonthlyReportDay int    `yaml:"monthly_report_day" json:"monthly_report_day"` // 每月几号（1-28，保证每月都存在）
+}
+
+// weekdayNames 星期英文名到time.Weekday的映射
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// DefaultPushSchedule 默认推送计划（与历史硬编码行为一致）
+func DefaultPushSchedule() *PushSchedule {
+	return &PushSchedule{
+		DailySummaryTime: "00:00",
+		WeeklyReportDay:  "monday",
+		MonthlyReportDay: 1,
+	}
+}
+
+// ParsePushSchedule 解析推送计划文件内容（YAML或JSON）并校验
+func ParsePushSchedule(data []byte) (*PushSchedule, error) {
+	schedule := DefaultPushSchedule()
+	if err := yaml.Unmarshal(data, schedule); err != nil {
+		return nil, fmt.Errorf("invalid schedule file: %v", err)
+	}
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// LoadPushSchedule 从app_state存储的JSON恢复推送计划
+// （内容为空或损坏时回退默认计划，保证推送循环始终可用）
+func LoadPushSchedule(stored string) *PushSchedule {
+	if stored == "" {
+		return DefaultPushSchedule()
+	}
+
+	var schedule PushSchedule
+	if err := json.Unmarshal([]byte(stored), &schedule); err != nil {
+		return DefaultPushSchedule()
+	}
+	if err := schedule.Validate(); err != nil {
+		return DefaultPushSchedule()
+	}
+	return &schedule
+}
+
+// Validate 校验推送计划各字段
+func (p *PushSchedule) Validate() error {
+	if _, err := parseClock(p.DailySummaryTime); err != nil {
+		return fmt.Errorf("invalid daily_summary_time %q: %v", p.DailySummaryTime, err)
+	}
+	if _, ok := weekdayNames[strings.ToLower(p.WeeklyReportDay)]; !ok {
+		return fmt.Errorf("invalid weekly_report_day %q: expected monday~sunday", p.WeeklyReportDay)
+	}
+	if p.MonthlyReportDay < 1 || p.MonthlyReportDay > 28 {
+		return fmt.Errorf("invalid monthly_report_day %d: expected 1-28", p.MonthlyReportDay)
+	}
+	return nil
+}
+
+// Encode 序列化为存储用的JSON
+func (p *PushSchedule) Encode() (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode push schedule: %v", err)
+	}
+	return string(data), nil
+}
+
+// SummaryHour 每日摘要的推送小时（0-23）
+func (p *PushSchedule) SummaryHour() int {
+	minutes, _ := parseClock(p.DailySummaryTime)
+	return minutes / 60
+}
+
+// ReportWeekday 周报的推送星期
+func (p *PushSchedule) ReportWeekday() time.Weekday {
+	return weekdayNames[strings.ToLower(p.WeeklyReportDay)]
+}
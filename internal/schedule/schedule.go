@@ -0,0 +1,124 @@
+// Package schedule PC28开奖节奏模型
+// 描述固定的开奖间隔与每日维护时段，供开奖倒计时展示、
+// 维护时段暂停轮询和标记维护期预测使用
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDrawInterval PC28默认开奖间隔（约3.5分钟一期，每天约288期）
+const DefaultDrawInterval = 210 * time.Second
+
+// Window 每日维护时段（"HH:MM"格式，Start晚于End表示跨零点）
+type Window struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// Schedule 开奖节奏模型
+type Schedule struct {
+	drawInterval time.Duration
+	windows      []Window
+}
+
+// New 创建开奖节奏模型（drawInterval非正时使用默认间隔）
+func New(drawInterval time.Duration, windows []Window) *Schedule {
+	if drawInterval <= 0 {
+		drawInterval = DefaultDrawInterval
+	}
+	return &Schedule{
+		drawInterval: drawInterval,
+		windows:      windows,
+	}
+}
+
+// DrawInterval 开奖间隔
+func (s *Schedule) DrawInterval() time.Duration {
+	return s.drawInterval
+}
+
+// InMaintenance 判断指定时刻是否处于维护时段
+func (s *Schedule) InMaintenance(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	for _, w := range s.windows {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			continue
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			continue
+		}
+
+		if start <= end {
+			if minute >= start && minute < end {
+				return true
+			}
+		} else {
+			// 跨零点时段（如23:55-01:30）
+			if minute >= start || minute < end {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MaintenanceEnd 返回t所处维护时段的结束时刻（不在维护时段时返回t本身）
+func (s *Schedule) MaintenanceEnd(t time.Time) time.Time {
+	end := t
+	// 按分钟推进直到离开维护时段（上限一天，防止全天维护配置导致死循环）
+	for i := 0; i < 24*60 && s.InMaintenance(end); i++ {
+		end = end.Add(time.Minute)
+	}
+	return end
+}
+
+// NextDrawTime 根据上一期开奖时间推算下一期开奖时刻
+// 已经漏掉的期次按间隔顺延，落在维护时段内时推迟到维护结束后的第一期
+func (s *Schedule) NextDrawTime(lastDraw, now time.Time) time.Time {
+	if lastDraw.IsZero() {
+		lastDraw = now
+	}
+
+	next := lastDraw.Add(s.drawInterval)
+	// 上限一天，避免过旧的lastDraw导致长时间循环
+	for i := 0; i < 24*60 && next.Before(now); i++ {
+		next = next.Add(s.drawInterval)
+	}
+
+	if s.InMaintenance(next) {
+		next = s.MaintenanceEnd(next).Add(s.drawInterval)
+	}
+	return next
+}
+
+// Countdown 距下一期开奖的剩余时间
+func (s *Schedule) Countdown(lastDraw, now time.Time) time.Duration {
+	remaining := s.NextDrawTime(lastDraw, now).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// parseClock 解析"HH:MM"为当日分钟数
+func parseClock(clock string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(clock), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid clock format: %s", clock)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, fmt.Errorf("invalid hour in clock: %s", clock)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid minute in clock: %s", clock)
+	}
+	return hour*60 + minute, nil
+}
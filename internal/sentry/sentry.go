@@ -0,0 +1,156 @@
+package sentry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Hook 将错误级别日志上报到Sentry兼容服务的logrus钩子
+// 只依赖Sentry的store HTTP接口，不引入官方SDK
+type Hook struct {
+	endpoint    string
+	authHeader  string
+	release     string
+	environment string
+	client      *http.Client
+	events      chan *event
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+}
+
+// event Sentry store接口的事件载荷
+type event struct {
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Message     string                 `json:"message"`
+	Platform    string                 `json:"platform"`
+	Release     string                 `json:"release,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+}
+
+// NewHook 根据DSN创建Sentry上报钩子并启动后台发送协程
+func NewHook(dsn, release, environment string) (*Hook, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sentry dsn: %v", err)
+	}
+	if u.User == nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid sentry dsn: missing key or host")
+	}
+
+	projectID := strings.Trim(u.Path, "/")
+	if projectID == "" {
+		return nil, fmt.Errorf("invalid sentry dsn: missing project id")
+	}
+
+	h := &Hook{
+		endpoint:    fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, projectID),
+		authHeader:  fmt.Sprintf("Sentry sentry_version=7, sentry_key=%s", u.User.Username()),
+		release:     release,
+		environment: environment,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		events:      make(chan *event, 32),
+		stopChan:    make(chan struct{}),
+	}
+	go h.sendLoop()
+	return h, nil
+}
+
+// Levels 钩子关注的日志级别
+func (h *Hook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+// Fire 将日志条目转换为Sentry事件（队列满时丢弃，不阻塞日志调用方）
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	evt := &event{
+		Timestamp:   entry.Time.UTC().Format("2006-01-02T15:04:05"),
+		Level:       sentryLevel(entry.Level),
+		Message:     entry.Message,
+		Platform:    "go",
+		Release:     h.release,
+		Environment: h.environment,
+	}
+
+	// 附带结构化字段（qihao、module等）作为事件上下文
+	if len(entry.Data) > 0 {
+		evt.Extra = make(map[string]interface{}, len(entry.Data))
+		for key, value := range entry.Data {
+			evt.Extra[key] = value
+		}
+	}
+
+	select {
+	case h.events <- evt:
+	default:
+		// 队列已满，丢弃事件避免阻塞
+	}
+	return nil
+}
+
+// Stop 停止后台发送协程
+func (h *Hook) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopChan)
+	})
+}
+
+// sendLoop 后台串行发送事件
+func (h *Hook) sendLoop() {
+	for {
+		select {
+		case evt := <-h.events:
+			h.send(evt)
+		case <-h.stopChan:
+			// 发送剩余已入队的事件
+			for {
+				select {
+				case evt := <-h.events:
+					h.send(evt)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send 上报单个事件（失败时静默丢弃，不能递归走logger）
+func (h *Hook) send(evt *event) {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", h.authHeader)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// sentryLevel 将logrus级别映射为Sentry级别
+func sentryLevel(level logrus.Level) string {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return "fatal"
+	default:
+		return "error"
+	}
+}
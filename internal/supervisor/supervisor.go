@@ -0,0 +1,124 @@
+package supervisor
+
+import (
+	"sync"
+	"time"
+
+	"pc28-bot/internal/logger"
+)
+
+// AlertFunc 崩溃告警回调（协程名称、累计崩溃次数、panic原因）
+type AlertFunc func(name string, crashes int, reason interface{})
+
+// Supervisor 协程监管器
+// 包装长时间运行的协程：捕获panic、记录日志、按指数退避重启，
+// 超过最大重启次数后放弃并触发告警回调
+type Supervisor struct {
+	wg        sync.WaitGroup
+	stop      chan struct{}
+	stopOnce  sync.Once
+	alertFunc AlertFunc
+
+	maxRestarts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+
+	// 告警阈值：连续崩溃达到该次数时触发告警
+	alertThreshold int
+}
+
+// NewSupervisor 创建新的协程监管器
+func NewSupervisor() *Supervisor {
+	return &Supervisor{
+		stop:           make(chan struct{}),
+		maxRestarts:    10,
+		baseBackoff:    1 * time.Second,
+		maxBackoff:     2 * time.Minute,
+		alertThreshold: 3,
+	}
+}
+
+// SetAlertFunc 设置崩溃告警回调
+func (s *Supervisor) SetAlertFunc(fn AlertFunc) {
+	s.alertFunc = fn
+}
+
+// Go 启动受监管的协程
+// fn正常返回视为协程退出；panic会被捕获并按退避策略重启
+func (s *Supervisor) Go(name string, fn func()) {
+	s.wg.Add(1)
+	go s.runSupervised(name, fn)
+}
+
+// runSupervised 监管循环：运行、捕获panic、退避重启
+func (s *Supervisor) runSupervised(name string, fn func()) {
+	defer s.wg.Done()
+
+	crashes := 0
+	for {
+		panicked, reason := s.runOnce(name, fn)
+		if !panicked {
+			// 正常返回，协程完成任务退出
+			return
+		}
+
+		crashes++
+		logger.Errorf("Goroutine %s crashed (count %d): %v", name, crashes, reason)
+
+		// 连续崩溃达到阈值时触发告警
+		if crashes >= s.alertThreshold && s.alertFunc != nil {
+			s.alertFunc(name, crashes, reason)
+		}
+
+		if crashes > s.maxRestarts {
+			logger.Errorf("Goroutine %s exceeded max restarts (%d), giving up", name, s.maxRestarts)
+			return
+		}
+
+		// 指数退避等待后重启
+		backoff := s.backoffFor(crashes)
+		logger.Warnf("Restarting goroutine %s in %v", name, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// runOnce 运行一次fn并捕获panic
+func (s *Supervisor) runOnce(name string, fn func()) (panicked bool, reason interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			reason = r
+		}
+	}()
+
+	fn()
+	return false, nil
+}
+
+// backoffFor 计算第n次崩溃后的退避时间
+func (s *Supervisor) backoffFor(crashes int) time.Duration {
+	backoff := s.baseBackoff
+	for i := 1; i < crashes; i++ {
+		backoff *= 2
+		if backoff >= s.maxBackoff {
+			return s.maxBackoff
+		}
+	}
+	return backoff
+}
+
+// Stop 停止监管器（不再重启崩溃的协程）
+func (s *Supervisor) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Wait 等待所有受监管协程结束
+func (s *Supervisor) Wait() {
+	s.wg.Wait()
+}
@@ -0,0 +1,194 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// 提醒规则引导菜单的可选阈值（连续形态与和值各自固定几档，避免自由输入）
+var (
+	alertStreakThresholds = []int{3, 5, 7}
+	alertSumThresholds    = []int{20, 22, 24, 26}
+)
+
+// handleAlertsCommand 处理提醒规则命令
+// 列出用户已有的规则并附带引导菜单，全部操作通过内联按钮完成
+func (b *Bot) handleAlertsCommand(chatID int64, corrID string) {
+	rules, err := b.db.GetAlertRules(chatID)
+	if err != nil {
+		logger.Errorf("Failed to get alert rules: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("Failed to load alert rules, please try again later. (trace: %s)", corrID))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, b.formatAlertRulesMessage(rules))
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.ReplyMarkup = alertMenuKeyboard()
+	if _, err := b.api.Send(msg); err != nil {
+		logger.Errorf("Failed to send alerts menu: %v", err)
+	}
+}
+
+// handleAlertCallback 处理提醒规则菜单回调（alert_*）
+// alert_pick_<形态>进入阈值选择，alert_add_<规则>_<阈值>保存规则，alert_clear清空
+func (b *Bot) handleAlertCallback(callback *tgbotapi.CallbackQuery, chatID int64, data string) {
+	response := ""
+
+	switch {
+	case data == "alert_clear":
+		deleted, err := b.db.DeleteAlertRules(chatID)
+		if err != nil {
+			logger.Errorf("Failed to delete alert rules: %v", err)
+			response = "Failed to clear rules"
+		} else {
+			b.sendMessage(chatID, fmt.Sprintf("🗑 Removed %d alert rule(s).", deleted))
+		}
+
+	case strings.HasPrefix(data, "alert_pick_"):
+		b.sendAlertThresholdPicker(chatID, strings.TrimPrefix(data, "alert_pick_"))
+
+	case strings.HasPrefix(data, "alert_add_"):
+		rule, ok := parseAlertRuleData(chatID, strings.TrimPrefix(data, "alert_add_"))
+		if !ok {
+			response = "Unknown rule"
+			break
+		}
+		if err := b.db.SaveAlertRule(rule); err != nil {
+			logger.Errorf("Failed to save alert rule: %v", err)
+			response = "Failed to save rule"
+			break
+		}
+		b.sendMessage(chatID, fmt.Sprintf("✅ Alert rule saved: %s.", describeAlertRule(rule)))
+	}
+
+	callbackResponse := tgbotapi.NewCallback(callback.ID, response)
+	b.api.Request(callbackResponse)
+}
+
+// sendAlertThresholdPicker 发送指定形态的阈值选择键盘
+func (b *Bot) sendAlertThresholdPicker(chatID int64, kind string) {
+	var text string
+	var row []tgbotapi.InlineKeyboardButton
+
+	if kind == "sum" {
+		text = "🎯 Alert me when the sum is at least:"
+		for _, threshold := range alertSumThresholds {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("≥ %d", threshold),
+				fmt.Sprintf("alert_add_%s_%d", database.AlertRuleSumGte, threshold)))
+		}
+	} else {
+		ruleType, ok := streakRuleType(kind)
+		if !ok {
+			return
+		}
+		text = fmt.Sprintf("🎯 Alert me after how many %s results in a row?", kind)
+		for _, threshold := range alertStreakThresholds {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				strconv.Itoa(threshold),
+				fmt.Sprintf("alert_add_%s_%d", ruleType, threshold)))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(row...))
+	if _, err := b.api.Send(msg); err != nil {
+		logger.Errorf("Failed to send alert threshold picker: %v", err)
+	}
+}
+
+// alertMenuKeyboard 构建提醒规则主菜单键盘
+func alertMenuKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔴 Odd streak", "alert_pick_odd"),
+			tgbotapi.NewInlineKeyboardButtonData("🔵 Even streak", "alert_pick_even"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔼 Big streak", "alert_pick_big"),
+			tgbotapi.NewInlineKeyboardButtonData("🔽 Small streak", "alert_pick_small"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🎯 High sum", "alert_pick_sum"),
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Clear all", "alert_clear"),
+		),
+	)
+}
+
+// formatAlertRulesMessage 格式化用户的提醒规则列表
+func (b *Bot) formatAlertRulesMessage(rules []database.AlertRule) string {
+	var message strings.Builder
+	message.WriteString("🔔 *Custom Alert Rules*\n\n")
+
+	if len(rules) == 0 {
+		message.WriteString("You have no alert rules yet.\n")
+	} else {
+		for _, rule := range rules {
+			message.WriteString(fmt.Sprintf("• %s\n", describeAlertRule(&rule)))
+		}
+	}
+
+	message.WriteString("\n💡 Use the buttons below to add a rule or clear them all")
+	return message.String()
+}
+
+// streakRuleType 将菜单形态标识映射为规则类型常量
+func streakRuleType(kind string) (string, bool) {
+	switch kind {
+	case "odd":
+		return database.AlertRuleStreakOdd, true
+	case "even":
+		return database.AlertRuleStreakEven, true
+	case "big":
+		return database.AlertRuleStreakBig, true
+	case "small":
+		return database.AlertRuleStreakSmall, true
+	default:
+		return "", false
+	}
+}
+
+// parseAlertRuleData 解析alert_add_回调数据（<规则类型>_<阈值>）
+func parseAlertRuleData(userID int64, data string) (*database.AlertRule, bool) {
+	idx := strings.LastIndex(data, "_")
+	if idx <= 0 {
+		return nil, false
+	}
+	ruleType := data[:idx]
+	threshold, err := strconv.Atoi(data[idx+1:])
+	if err != nil || threshold <= 0 {
+		return nil, false
+	}
+
+	switch ruleType {
+	case database.AlertRuleStreakOdd, database.AlertRuleStreakEven,
+		database.AlertRuleStreakBig, database.AlertRuleStreakSmall, database.AlertRuleSumGte:
+		return &database.AlertRule{UserID: userID, RuleType: ruleType, Threshold: threshold}, true
+	default:
+		return nil, false
+	}
+}
+
+// describeAlertRule 生成规则的可读描述
+func describeAlertRule(rule *database.AlertRule) string {
+	switch rule.RuleType {
+	case database.AlertRuleStreakOdd:
+		return fmt.Sprintf("%d odd results in a row", rule.Threshold)
+	case database.AlertRuleStreakEven:
+		return fmt.Sprintf("%d even results in a row", rule.Threshold)
+	case database.AlertRuleStreakBig:
+		return fmt.Sprintf("%d big results in a row", rule.Threshold)
+	case database.AlertRuleStreakSmall:
+		return fmt.Sprintf("%d small results in a row", rule.Threshold)
+	case database.AlertRuleSumGte:
+		return fmt.Sprintf("sum ≥ %d", rule.Threshold)
+	default:
+		return fmt.Sprintf("%s (%d)", rule.RuleType, rule.Threshold)
+	}
+}
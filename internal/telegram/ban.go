@@ -0,0 +1,188 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+)
+
+// 限流与自动封禁参数
+const (
+	rateLimitWindow  = time.Minute      // 滑动计数窗口
+	rateLimitMax     = 20               // 窗口内允许的最大消息数
+	rateLimitStrikes = 3                // 触发自动封禁所需的超限次数
+	autoBanDuration  = 10 * time.Minute // 自动封禁时长
+	rateStrikeDecay  = 10 * time.Minute // 超限计数的衰减周期
+	ratePruneEvery   = time.Minute      // 过期限流状态的清理间隔
+)
+
+// rateState 单个用户的限流状态
+type rateState struct {
+	windowStart time.Time // 当前计数窗口起点
+	count       int       // 窗口内的消息数
+	strikes     int       // 连续超限次数
+	lastStrike  time.Time // 最近一次超限时间
+}
+
+// checkRateLimit 检查用户是否超出消息频率限制
+// 窗口内超限返回false并累计超限次数，反复超限触发自动临时封禁
+func (b *Bot) checkRateLimit(chatID int64) bool {
+	now := time.Now()
+
+	b.rateMutex.Lock()
+	defer b.rateMutex.Unlock()
+
+	b.pruneRateStates(now)
+
+	state, ok := b.rateStates[chatID]
+	if !ok {
+		state = &rateState{windowStart: now}
+		b.rateStates[chatID] = state
+	}
+
+	// 长时间无超限时衰减历史超限计数
+	if state.strikes > 0 && now.Sub(state.lastStrike) > rateStrikeDecay {
+		state.strikes = 0
+	}
+
+	if now.Sub(state.windowStart) > rateLimitWindow {
+		state.windowStart = now
+		state.count = 0
+	}
+
+	state.count++
+	if state.count <= rateLimitMax {
+		return true
+	}
+
+	// 每个窗口只记一次超限
+	if state.count == rateLimitMax+1 {
+		state.strikes++
+		state.lastStrike = now
+		logger.Warnf("Rate limit exceeded by %d (strike %d/%d)", chatID, state.strikes, rateLimitStrikes)
+
+		if state.strikes >= rateLimitStrikes {
+			expiresAt := now.Add(autoBanDuration)
+			ban := &database.BannedUser{
+				UserID:    chatID,
+				Reason:    "repeated rate limit violations",
+				BannedBy:  0,
+				ExpiresAt: &expiresAt,
+			}
+			if err := b.db.BanUser(ban); err != nil {
+				logger.Errorf("Failed to auto-ban user %d: %v", chatID, err)
+			} else {
+				state.strikes = 0
+				logger.Warnf("Auto-banned user %d for %v", chatID, autoBanDuration)
+			}
+		}
+	}
+	return false
+}
+
+// pruneRateStates 清理已过期的限流状态（调用方需持有rateMutex）
+// 窗口已过且超限计数已衰减完的条目不再携带信息，定期删除防止
+// 每个来访chatID都永久占用一份状态
+func (b *Bot) pruneRateStates(now time.Time) {
+	if now.Sub(b.lastRatePrune) < ratePruneEvery {
+		return
+	}
+	b.lastRatePrune = now
+
+	for chatID, state := range b.rateStates {
+		windowExpired := now.Sub(state.windowStart) > rateLimitWindow
+		strikesExpired := state.strikes == 0 || now.Sub(state.lastStrike) > rateStrikeDecay
+		if windowExpired && strikesExpired {
+			delete(b.rateStates, chatID)
+		}
+	}
+}
+
+// isBanned 检查用户是否处于封禁状态
+func (b *Bot) isBanned(chatID int64) bool {
+	ban, err := b.db.GetBan(chatID)
+	if err != nil {
+		// 查询失败时放行，避免数据库抖动误伤全部用户
+		logger.Warnf("Failed to check ban for %d: %v", chatID, err)
+		return false
+	}
+	return ban != nil
+}
+
+// handleBanCommand 封禁用户（仅管理员可用）
+// 用法：/ban <用户ID> [时长] [原因]，时长如30m/24h，省略表示永久
+func (b *Bot) handleBanCommand(chatID int64, args, corrID string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.sendMessage(chatID, "Usage: /ban <user_id> [duration] [reason]\nExample: /ban 123456789 24h spamming")
+		return
+	}
+
+	userID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || userID <= 0 {
+		b.sendMessage(chatID, fmt.Sprintf("Invalid user ID `%s`.", fields[0]))
+		return
+	}
+	if b.isAdmin(userID) {
+		b.sendMessage(chatID, "❌ Cannot ban an administrator.")
+		return
+	}
+
+	var expiresAt *time.Time
+	reasonStart := 1
+	if len(fields) > 1 {
+		if duration, err := time.ParseDuration(fields[1]); err == nil && duration > 0 {
+			t := time.Now().Add(duration)
+			expiresAt = &t
+			reasonStart = 2
+		}
+	}
+	reason := strings.Join(fields[reasonStart:], " ")
+
+	ban := &database.BannedUser{UserID: userID, Reason: reason, BannedBy: chatID, ExpiresAt: expiresAt}
+	if err := b.db.BanUser(ban); err != nil {
+		logger.Errorf("Failed to ban user: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to ban user, please try again later. (trace: %s)", corrID))
+		return
+	}
+
+	scope := "permanently"
+	if expiresAt != nil {
+		scope = "until " + expiresAt.Format("2006-01-02 15:04:05")
+	}
+	b.recordAudit(chatID, "user_ban", fmt.Sprintf("%d %s", userID, scope))
+	b.sendMessage(chatID, fmt.Sprintf("✅ User `%d` banned %s.", userID, scope))
+}
+
+// handleUnbanCommand 解除用户封禁（仅管理员可用）
+func (b *Bot) handleUnbanCommand(chatID int64, args, corrID string) {
+	fields := strings.Fields(args)
+	if len(fields) != 1 {
+		b.sendMessage(chatID, "Usage: /unban <user_id>")
+		return
+	}
+
+	userID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || userID <= 0 {
+		b.sendMessage(chatID, fmt.Sprintf("Invalid user ID `%s`.", fields[0]))
+		return
+	}
+
+	removed, err := b.db.UnbanUser(userID)
+	if err != nil {
+		logger.Errorf("Failed to unban user: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to unban user, please try again later. (trace: %s)", corrID))
+		return
+	}
+	if !removed {
+		b.sendMessage(chatID, fmt.Sprintf("ℹ️ User `%d` is not banned.", userID))
+		return
+	}
+
+	b.recordAudit(chatID, "user_unban", strconv.FormatInt(userID, 10))
+	b.sendMessage(chatID, fmt.Sprintf("✅ User `%d` unbanned.", userID))
+}
@@ -2,25 +2,104 @@ package telegram
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"pc28-bot/internal/cache"
+	"pc28-bot/internal/chaos"
 	"pc28-bot/internal/config"
 	"pc28-bot/internal/database"
 	"pc28-bot/internal/logger"
+	"pc28-bot/internal/predictor"
+	"pc28-bot/internal/report"
+	"pc28-bot/internal/schedule"
+	"pc28-bot/internal/supervisor"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// Corrector 开奖数据更正接口（由pipeline的单期处理器实现）
+type Corrector interface {
+	CorrectRound(qihao, openNum string) (*predictor.ValidationResult, error)
+}
+
+// StalenessChecker 上游数据新鲜度查询接口（由pipeline的单期处理器实现）
+type StalenessChecker interface {
+	DataStale() bool
+}
+
+// ColdHistory 冷存储历史读取接口（由coldstore的Reader实现）
+// 回测窗口超出热存储范围时透明补足归档数据
+type ColdHistory interface {
+	ReadBefore(beforeQihao string, limit int) ([]database.LotteryResult, error)
+}
+
+// 通知事件类型（/notify开关与各广播路径共用，未设置的事件默认开启）
+const (
+	NotifyPredictions  = "predictions"  // 新预测推送
+	NotifyVerification = "verification" // 验证结果
+	NotifySummary      = "summary"      // 每日摘要
+	NotifyStreak       = "streak"       // 趋势/连对连错提醒
+	NotifyResults      = "results"      // 仅开奖结果推送（不含预测内容，opt-in，默认关闭）
+)
+
+// notifyEvents 全部通知事件类型（用于参数校验和开关展示）
+var notifyEvents = []string{NotifyPredictions, NotifyVerification, NotifySummary, NotifyStreak, NotifyResults}
+
 // Bot Telegram机器人
 type Bot struct {
-	api           *tgbotapi.BotAPI
-	cacheManager  *cache.CacheManager
-	updateChannel tgbotapi.UpdatesChannel
-	stopChannel   chan bool
+	api             *tgbotapi.BotAPI
+	cacheManager    *cache.CacheManager
+	db              database.DB
+	statCalculator  *predictor.StatisticsCalculator
+	reportGen       *report.Generator
+	corrector       Corrector                   // 开奖更正入口（未配置时更正命令不可用）
+	staleness       StalenessChecker            // 上游数据新鲜度（未配置时不显示延迟横幅）
+	coldHistory     ColdHistory                 // 冷存储历史读取器（未配置冷存储时为nil）
+	commandChain    commandHandler              // 命令处理链（中间件包装后的dispatchCommand）
+	predictorMgr    *predictor.PredictorManager // 预测器管理器（用于离线沙盒评估，未配置时评估命令不可用）
+	judgePolicy     predictor.CorrectnessPolicy // 命中判定策略（与线上验证同口径）
+	drawSchedule    *schedule.Schedule          // 开奖节奏（用于倒计时展示，未配置时不显示）
+	adminChatIDs    []int64
+	cmdMetrics      *CommandMetrics   // 命令调用次数与耗时统计
+	templates       *TemplateRegistry // 出站消息模板注册表
+	updateChannel   tgbotapi.UpdatesChannel
+	stopChannel     chan bool
+	supervisor      *supervisor.Supervisor
+	errorHook       *ErrorHook // 管理员错误转发钩子（未配置管理员时为nil）
+	restartFunc     func()     // 请求排空并重启（由main注入，未配置时重启命令不可用）
+	exportMutex     sync.Mutex
+	exportJobs      map[int64]*exportJob // 每个会话的分块导出断点状态
+	rateMutex       sync.Mutex
+	rateStates      map[int64]*rateState // 每个会话的消息频率限流状态
+	lastRatePrune   time.Time            // 上次清理过期限流状态的时间（rateMutex保护）
+	draftMutex      sync.Mutex
+	broadcastDrafts map[int64]*broadcastDraft // 每个管理员会话的广播草稿
+	convMutex       sync.Mutex
+	conversations   map[int64]*conversation // 每个会话进行中的多步交互状态
+
+	// 广播消息倒计时编辑（为0时关闭，见countdown.go）
+	countdownInterval time.Duration
+	countdownMutex    sync.Mutex
+	countdownStop     chan struct{} // 当前编辑协程的取消通道（新一轮广播时关闭）
+
+	// 首装管理员认领码（仅在无管理员配置时生成，认领成功后清空）
+	claimCode    string
+	oddsOddEven  float64         // 单双玩法净赔率（假想盈亏回算用，未配置时取默认值）
+	oddsBigSmall float64         // 大小玩法净赔率
+	chaos        *chaos.Injector // 故障注入器（未启用时为nil）
+}
+
+// SetFaultInjector 设置故障注入器（仅staging验证弹性能力时启用）
+func (b *Bot) SetFaultInjector(injector *chaos.Injector) {
+	b.chaos = injector
 }
 
 // NewBot 创建新的Telegram机器人
-func NewBot(cfg *config.Telegram, cacheManager *cache.CacheManager) (*Bot, error) {
+func NewBot(cfg *config.Telegram, cacheManager *cache.CacheManager, db database.DB) (*Bot, error) {
 	bot, err := tgbotapi.NewBotAPI(cfg.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create telegram bot: %v", err)
@@ -29,25 +108,73 @@ func NewBot(cfg *config.Telegram, cacheManager *cache.CacheManager) (*Bot, error
 	bot.Debug = false
 	logger.Infof("Telegram bot authorized on account: %s", bot.Self.UserName)
 
+	// 加载消息模板（启动期校验，模板损坏直接拒绝启动）
+	templates, err := NewTemplateRegistry(cfg.TemplateDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load message templates: %v", err)
+	}
+	if cfg.TemplateDir != "" {
+		logger.Infof("Loaded message templates from %s", cfg.TemplateDir)
+	}
+
 	// 配置更新
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = int(cfg.Timeout.Seconds())
 
 	updates := bot.GetUpdatesChan(u)
 
-	return &Bot{
-		api:           bot,
-		cacheManager:  cacheManager,
-		updateChannel: updates,
-		stopChannel:   make(chan bool),
-	}, nil
+	b := &Bot{
+		api:             bot,
+		cacheManager:    cacheManager,
+		db:              db,
+		statCalculator:  predictor.NewStatisticsCalculator(db),
+		reportGen:       report.NewGenerator(db),
+		adminChatIDs:    cfg.AdminChatIDs,
+		cmdMetrics:      NewCommandMetrics(),
+		templates:       templates,
+		updateChannel:   updates,
+		stopChannel:     make(chan bool),
+		supervisor:      supervisor.NewSupervisor(),
+		exportJobs:      make(map[int64]*exportJob),
+		rateStates:      make(map[int64]*rateState),
+		broadcastDrafts: make(map[int64]*broadcastDraft),
+		conversations:   make(map[int64]*conversation),
+	}
+
+	// 组装命令中间件链（鉴权、限流、指标、日志、崩溃恢复）
+	b.commandChain = b.buildCommandChain()
+
+	// 配置了管理员会话时启用错误日志转发
+	if len(cfg.AdminChatIDs) > 0 {
+		b.errorHook = NewErrorHook(bot, cfg.AdminChatIDs)
+		logger.AddHook(b.errorHook)
+		logger.Infof("Error forwarding enabled for %d admin chat(s)", len(cfg.AdminChatIDs))
+	} else {
+		// 全新安装无管理员时进入认领流程（恢复历史认领或打印一次性认领码）
+		b.initAdminClaim()
+	}
+
+	return b, nil
+}
+
+// CheckToken 验证Telegram token有效性（不启动更新轮询），返回机器人用户名
+func CheckToken(cfg *config.Telegram) (string, error) {
+	bot, err := tgbotapi.NewBotAPI(cfg.Token)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate telegram token: %v", err)
+	}
+	return bot.Self.UserName, nil
 }
 
 // Start 启动机器人
 func (b *Bot) Start() {
 	logger.Info("Starting Telegram bot...")
 
-	go b.handleUpdates()
+	// 注册命令菜单（失败不影响启动，仅损失命令自动补全）
+	b.registerCommandMenu()
+
+	// 受监管启动更新处理协程，panic后自动重启
+	b.supervisor.Go("telegram-updates", b.handleUpdates)
 	logger.Info("Telegram bot started successfully")
 }
 
@@ -55,7 +182,12 @@ func (b *Bot) Start() {
 func (b *Bot) Stop() {
 	logger.Info("Stopping Telegram bot...")
 	b.stopChannel <- true
+	b.supervisor.Stop()
 	b.api.StopReceivingUpdates()
+	b.supervisor.Wait()
+	if b.errorHook != nil {
+		b.errorHook.Stop()
+	}
 	logger.Info("Telegram bot stopped")
 }
 
@@ -88,36 +220,121 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 		return
 	}
 
+	// 文档消息单独处理（管理员上传推送计划文件）
+	if message.Document != nil {
+		b.handleDocument(message)
+		return
+	}
+
+	// 命令的封禁/限流检查由中间件链完成（见middleware.go）
 	if message.IsCommand() {
 		b.handleCommand(message)
-	} else {
-		b.handleTextMessage(message)
+		return
 	}
-}
 
-// handleCommand 处理命令
-func (b *Bot) handleCommand(message *tgbotapi.Message) {
-	// 确保只在私聊中处理命令
-	if !message.Chat.IsPrivate() {
+	// 封禁用户的文本消息直接丢弃，限流超限的消息也不处理
+	if b.isBanned(message.Chat.ID) {
+		logger.Debugf("Dropping message from banned user %d", message.Chat.ID)
+		return
+	}
+	if !b.checkRateLimit(message.Chat.ID) {
 		return
 	}
 
-	command := message.Command()
-	chatID := message.Chat.ID
+	b.handleTextMessage(message)
+}
 
-	logger.Debugf("Received private command: %s from user: %d", command, chatID)
+// handleCommand 处理命令：组装调用上下文后交给中间件链
+// （鉴权、限流、指标、日志、崩溃恢复见middleware.go）
+func (b *Bot) handleCommand(message *tgbotapi.Message) {
+	b.commandChain(&commandContext{
+		message: message,
+		chatID:  message.Chat.ID,
+		command: message.Command(),
+		args:    message.CommandArguments(),
+		// 每个更新分配关联ID，串联该流程的全部日志
+		corrID: logger.NewCorrelationID(),
+	})
+}
+
+// dispatchCommand 按命令名分发到具体处理器（中间件链的末端）
+func (b *Bot) dispatchCommand(ctx *commandContext) {
+	chatID := ctx.chatID
+	corrID := ctx.corrID
 
-	switch command {
+	switch ctx.command {
 	case "start":
 		b.handleStartCommand(chatID)
 	case "help":
 		b.handleHelpCommand(chatID)
 	case "latest":
-		b.handleLatestCommand(chatID)
+		b.handleLatestCommand(chatID, corrID)
 	case "history":
-		b.handleHistoryCommand(chatID)
+		b.handleHistoryCommand(chatID, corrID)
+	case "export":
+		b.handleExportCommand(chatID, ctx.args, corrID)
 	case "stats":
-		b.handleStatsCommand(chatID)
+		b.handleStatsCommand(chatID, corrID)
+	case "distribution":
+		b.handleDistributionCommand(chatID, ctx.args, corrID)
+	case "positions":
+		b.handlePositionsCommand(chatID, ctx.args, corrID)
+	case "ev":
+		b.handleEVCommand(chatID, ctx.args, corrID)
+	case "heatmap":
+		b.handleHeatmapCommand(chatID, corrID)
+	case "audit":
+		b.handleAuditCommand(chatID, corrID)
+	case "analytics":
+		b.handleAnalyticsCommand(chatID, corrID)
+	case "preview":
+		b.handlePreviewCommand(chatID, ctx.args, corrID)
+	case "restart":
+		b.handleRestartCommand(chatID, corrID)
+	case "evaluate":
+		b.handleEvaluateCommand(chatID, ctx.args, corrID)
+	case "leaderboard":
+		b.handleLeaderboardCommand(chatID, corrID)
+	case "special":
+		b.handleSpecialCommand(chatID, corrID)
+	case "profit":
+		b.handleProfitCommand(chatID, corrID)
+	case "correct":
+		b.handleCorrectCommand(chatID, ctx.args, corrID)
+	case "disclaimer":
+		b.handleDisclaimerCommand(chatID, ctx.args, corrID)
+	case "ban":
+		b.handleBanCommand(chatID, ctx.args, corrID)
+	case "unban":
+		b.handleUnbanCommand(chatID, ctx.args, corrID)
+	case "broadcast":
+		b.handleBroadcastCommand(chatID, ctx.args, corrID)
+	case "vip":
+		b.handleVipCommand(chatID, ctx.args, corrID)
+	case "report":
+		b.handleReportCommand(chatID, ctx.args, corrID)
+	case "trend":
+		b.handleTrendCommand(chatID)
+	case "myrecord":
+		b.handleMyRecordCommand(chatID, corrID)
+	case "notify":
+		b.handleNotifyCommand(chatID, ctx.args, corrID)
+	case "alerts":
+		b.handleAlertsCommand(chatID, corrID)
+	case "settings":
+		b.handleSettingsCommand(chatID, ctx.args, corrID)
+	case "claim":
+		b.handleClaimCommand(chatID, ctx.args, corrID)
+	case "timeline":
+		b.handleTimelineCommand(chatID, ctx.args, corrID)
+	case "policydiff":
+		b.handlePolicyDiffCommand(chatID, ctx.args, corrID)
+	case "feedback":
+		b.handleFeedbackCommand(chatID, ctx.args, corrID)
+	case "mydata":
+		b.handleMyDataCommand(chatID, corrID)
+	case "forgetme":
+		b.handleForgetMeCommand(chatID)
 	// 移除了 prediction 命令
 	default:
 		b.sendMessage(chatID, "Unknown command. Type /help to view available commands.")
@@ -125,25 +342,97 @@ func (b *Bot) handleCommand(message *tgbotapi.Message) {
 }
 
 // handleStartCommand 处理开始命令
+// 未完成引导的用户进入多步引导流程（语言选择 -> 通知开关 -> 置顶快捷菜单），
+// 已完成引导的用户直接收到欢迎消息和快捷菜单
 func (b *Bot) handleStartCommand(chatID int64) {
-	welcomeText := `🎮 Welcome to PC28 Prediction Bot!
+	language, err := b.db.GetUserLanguage(chatID)
+	if err != nil {
+		logger.Warnf("Failed to get user language: %v", err)
+	}
+	if language != "" {
+		b.finishOnboarding(chatID, language)
+		return
+	}
+
+	// 引导第一步：语言选择
+	msg := tgbotapi.NewMessage(chatID, "👋 Welcome! Please choose your language\n欢迎！请选择语言")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("English", "onboard_lang_en"),
+			tgbotapi.NewInlineKeyboardButtonData("中文", "onboard_lang_zh"),
+		),
+	)
+	if _, err := b.api.Send(msg); err != nil {
+		logger.Errorf("Failed to send onboarding message: %v", err)
+	}
+}
+
+// handleOnboardLanguage 处理引导流程的语言选择，进入通知开关步骤
+func (b *Bot) handleOnboardLanguage(chatID int64, language string) {
+	if err := b.db.SetUserLanguage(chatID, language); err != nil {
+		logger.Errorf("Failed to set user language: %v", err)
+		b.sendMessage(chatID, "❌ Something went wrong, please send /start again.")
+		return
+	}
 
-🤖 I am your intelligent prediction assistant, providing you with:
-• 📊 Latest lottery results
-• 🔮 Smart prediction results  
-• 📈 Historical prediction records
-• 📊 Accuracy statistics
+	// 引导第二步：通知开关
+	text := "🔔 Would you like to receive new prediction pushes?"
+	enableLabel, skipLabel := "🔔 Enable", "🔕 Not now"
+	if language == "zh" {
+		text = "🔔 是否接收新预测推送？"
+		enableLabel, skipLabel = "🔔 开启", "🔕 暂不"
+	}
 
-📝 Available commands:
-/latest - View latest predictions
-/history - View lottery records
-/stats - View statistics
-/help - Help information
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(enableLabel, "onboard_notify_on"),
+			tgbotapi.NewInlineKeyboardButtonData(skipLabel, "onboard_notify_off"),
+		),
+	)
+	if _, err := b.api.Send(msg); err != nil {
+		logger.Errorf("Failed to send onboarding message: %v", err)
+	}
+}
 
-⚠️ Note: This bot only provides services in private chats
-🔔 The bot will automatically push the latest prediction results!`
+// handleOnboardNotify 处理引导流程的通知开关选择，完成引导
+func (b *Bot) handleOnboardNotify(chatID int64, enabled bool) {
+	if err := b.db.SetNotifyPreference(chatID, NotifyPredictions, enabled); err != nil {
+		logger.Errorf("Failed to set notify preference: %v", err)
+	}
 
-	b.sendMessage(chatID, welcomeText)
+	language, err := b.db.GetUserLanguage(chatID)
+	if err != nil {
+		logger.Warnf("Failed to get user language: %v", err)
+	}
+	b.finishOnboarding(chatID, language)
+}
+
+// finishOnboarding 发送欢迎消息和快捷菜单，并尝试置顶快捷菜单
+func (b *Bot) finishOnboarding(chatID int64, language string) {
+	templateName := "welcome"
+	if language == "zh" {
+		templateName = "welcome_zh"
+	}
+
+	msg := tgbotapi.NewMessage(chatID, b.renderTemplate(templateName, nil))
+	msg.ReplyMarkup = tgbotapi.InlineKeyboardMarkup{InlineKeyboard: b.CreateInlineKeyboard()}
+
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		logger.Errorf("Failed to send welcome message: %v", err)
+		return
+	}
+
+	// 置顶快捷菜单（失败只记日志，不影响引导完成）
+	pin := tgbotapi.PinChatMessageConfig{
+		ChatID:              chatID,
+		MessageID:           sent.MessageID,
+		DisableNotification: true,
+	}
+	if _, err := b.api.Request(pin); err != nil {
+		logger.Warnf("Failed to pin quick menu for chat %d: %v", chatID, err)
+	}
 }
 
 // handleHelpCommand 处理帮助命令
@@ -153,7 +442,19 @@ func (b *Bot) handleHelpCommand(chatID int64) {
 /start - Start using the bot
 /latest - Get latest prediction results
 /history - View recent 10 lottery records
+/export - Export lottery history in chunks or as a file
 /stats - View prediction accuracy statistics
+/distribution - View sum value distribution
+/ev - Expected value and Kelly stake calculator
+/heatmap - Accuracy by weekday and hour
+/trend - Toggle accuracy trend notifications
+/notify - Toggle notification types (predictions/verification/summary/streak/results)
+/alerts - Manage custom alert rules (streaks, high sums)
+/settings - View preferences and set your timezone
+/myrecord - View predictions you followed
+/leaderboard - Algorithm accuracy leaderboard
+/special - Recent pair and triple rounds
+/profit - Hypothetical profit and loss tracking
 /help - Show this help information
 
 💡 Usage Tips:
@@ -167,51 +468,730 @@ func (b *Bot) handleHelpCommand(chatID int64) {
 }
 
 // handleLatestCommand 处理最新命令
-func (b *Bot) handleLatestCommand(chatID int64) {
+func (b *Bot) handleLatestCommand(chatID int64, corrID string) {
 	// 获取预测历史记录（10期历史 + 1期最新预测 = 11期）
 	predictionHistory, err := b.cacheManager.GetPredictionHistory(11)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Failed to get prediction records, please try again later.")
-		logger.Errorf("Failed to get prediction history: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get prediction records, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get prediction history: %v", err)
 		return
 	}
 
 	// 格式化消息（使用新的单双预测模板）
 	message := b.formatPredictionHistoryMessage(predictionHistory)
-	b.sendMessage(chatID, message)
+
+	// 附加距下一期开奖的倒计时
+	if b.drawSchedule != nil {
+		if latest, err := b.cacheManager.GetLatestLotteryData(); err == nil {
+			message += "\n" + b.formatCountdownLine(latest.OpenTime)
+		}
+	}
+
+	// 上游数据延迟时附加降级横幅（此间新预测已暂停生成）
+	if b.staleness != nil && b.staleness.DataStale() {
+		message += "\n⚠️ *Data Delayed*: the source has not published new rounds recently. Predictions are paused until data resumes."
+	}
+
+	b.sendMessage(chatID, b.appendDisclaimer(message))
 }
 
 // handleHistoryCommand 处理历史命令
-func (b *Bot) handleHistoryCommand(chatID int64) {
+func (b *Bot) handleHistoryCommand(chatID int64, corrID string) {
 	// 获取历史开奖记录
 	lotteryHistory, err := b.cacheManager.GetLotteryHistory(10)
 	if err != nil {
-		b.sendMessage(chatID, "❌ Failed to get history records, please try again later.")
-		logger.Errorf("Failed to get lottery history: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get history records, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get lottery history: %v", err)
 		return
 	}
 
 	// 格式化消息
-	message := b.formatLotteryHistoryMessage(lotteryHistory)
+	message := b.appendDisclaimer(b.formatLotteryHistoryMessage(b.userLocale(chatID), lotteryHistory))
 	b.sendMessage(chatID, message)
 }
 
-// handleStatsCommand 处理统计命令
-func (b *Bot) handleStatsCommand(chatID int64) {
-	// 获取统计信息
+// handleStatsCommand 处理统计命令（默认全量口径，附滚动窗口切换按钮）
+func (b *Bot) handleStatsCommand(chatID int64, corrID string) {
+	b.sendStatsMessage(chatID, 0, corrID)
+}
+
+// 移除了 handlePredictionCommand 函数
+
+// handleDistributionCommand 处理和值分布统计命令
+func (b *Bot) handleDistributionCommand(chatID int64, args, corrID string) {
+	// 可选的窗口参数，默认最近100期
+	window := 100
+	if trimmed := strings.TrimSpace(args); trimmed != "" {
+		if n, err := strconv.Atoi(trimmed); err == nil && n > 0 && n <= 1000 {
+			window = n
+		}
+	}
+
+	distribution, err := b.db.GetSumDistribution(window)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get distribution, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get sum distribution: %v", err)
+		return
+	}
+
+	b.sendMessage(chatID, b.formatDistributionMessage(distribution, window))
+}
+
+// handlePositionsCommand 处理各位置数字分布统计命令
+func (b *Bot) handlePositionsCommand(chatID int64, args, corrID string) {
+	// 可选的窗口参数，默认最近100期
+	window := 100
+	if trimmed := strings.TrimSpace(args); trimmed != "" {
+		if n, err := strconv.Atoi(trimmed); err == nil && n > 0 && n <= 1000 {
+			window = n
+		}
+	}
+
+	distribution, err := b.cacheManager.GetPositionDistribution(window)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get position statistics, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get position distribution: %v", err)
+		return
+	}
+
+	b.sendMessage(chatID, b.formatPositionsMessage(distribution, window))
+}
+
+// handleEVCommand 处理期望值与凯利仓位计算命令
+func (b *Bot) handleEVCommand(chatID int64, args, corrID string) {
+	// 可选的净赔率参数，默认0.95（押1赢0.95）
+	odds := 0.95
+	if trimmed := strings.TrimSpace(args); trimmed != "" {
+		parsed, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil || parsed <= 0 || parsed > 10 {
+			b.sendMessage(chatID, "Usage: /ev [odds]\nodds is the net payout per unit staked, e.g. /ev 0.95")
+			return
+		}
+		odds = parsed
+	}
+
 	stats, err := b.cacheManager.GetPredictionStats()
 	if err != nil {
-		b.sendMessage(chatID, "❌ Failed to get statistics, please try again later.")
-		logger.Errorf("Failed to get prediction stats: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get statistics, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get prediction stats for EV: %v", err)
+		return
+	}
+	if stats.TotalPredictions == 0 {
+		b.sendMessage(chatID, "📊 Not enough verified predictions to compute expected value yet.")
+		return
+	}
+
+	analysis := predictor.ComputeEV(stats.AccuracyRate/100, odds)
+	b.sendMessage(chatID, b.formatEVMessage(analysis, stats.TotalPredictions))
+}
+
+// handleHeatmapCommand 处理准确率热力图命令
+func (b *Bot) handleHeatmapCommand(chatID int64, corrID string) {
+	heatmap, err := b.statCalculator.ComputeAccuracyHeatmap()
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to compute heatmap, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to compute accuracy heatmap: %v", err)
 		return
 	}
 
-	// 格式化消息
-	message := b.formatStatsMessage(stats)
-	b.sendMessage(chatID, message)
+	b.sendMessage(chatID, b.formatHeatmapMessage(b.userLocale(chatID), heatmap))
 }
 
-// 移除了 handlePredictionCommand 函数
+// handleReportCommand 生成周期报表（仅管理员可用）
+func (b *Bot) handleReportCommand(chatID int64, args, corrID string) {
+	period := strings.TrimSpace(args)
+	if period != "month" {
+		period = "week"
+	}
+
+	b.recordAudit(chatID, "report_generate", period)
+	b.sendReport(chatID, period, corrID)
+}
+
+// sendReport 生成报表并发送消息与CSV附件
+func (b *Bot) sendReport(chatID int64, period, corrID string) {
+	rpt, err := b.reportGen.Generate(period)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to generate report, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to generate %s report: %v", period, err)
+		return
+	}
+
+	b.sendMessage(chatID, b.reportGen.FormatTelegram(rpt))
+
+	// CSV附件（生成失败不影响文本报表）
+	csvPath, err := b.reportGen.WriteCSV(rpt)
+	if err != nil {
+		logger.Warnf("Failed to write report CSV: %v", err)
+		return
+	}
+	defer os.Remove(csvPath)
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FilePath(csvPath))
+	if _, err := b.api.Send(doc); err != nil {
+		logger.Warnf("Failed to send report CSV to %d: %v", chatID, err)
+	}
+}
+
+// SendScheduledReport 向所有管理员推送定时报表
+func (b *Bot) SendScheduledReport(period string) {
+	corrID := logger.NewCorrelationID()
+	for _, adminID := range b.adminChatIDs {
+		b.sendReport(adminID, period, corrID)
+	}
+}
+
+// handleFollowCallback 处理跟单按钮回调
+func (b *Bot) handleFollowCallback(callback *tgbotapi.CallbackQuery, chatID int64, qihao string) {
+	responseText := "👍 Followed! Check /myrecord for your results."
+	if err := b.db.SaveUserFollow(chatID, qihao); err != nil {
+		logger.Errorf("Failed to save user follow: %v", err)
+		responseText = "❌ Failed to record, please try again."
+	}
+
+	callbackResponse := tgbotapi.NewCallback(callback.ID, responseText)
+	b.api.Request(callbackResponse)
+}
+
+// handleMyRecordCommand 处理个人跟单记录命令
+func (b *Bot) handleMyRecordCommand(chatID int64, corrID string) {
+	follows, err := b.db.GetUserFollows(chatID, 10)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get your record, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get user follows: %v", err)
+		return
+	}
+
+	// 逐期补充预测结果（跟单数量上限10，逐条查询开销可接受）
+	var records []followRecord
+	for _, follow := range follows {
+		record := followRecord{Qihao: follow.Qihao}
+		if prediction, err := b.db.GetPredictionByQihao(follow.Qihao); err == nil && prediction != nil {
+			record.Prediction = prediction
+		}
+		records = append(records, record)
+	}
+
+	b.sendMessage(chatID, b.formatMyRecordMessage(records))
+}
+func (b *Bot) handleTrendCommand(chatID int64) {
+	enabled := !b.notifyEnabled(chatID, NotifyStreak)
+	if err := b.db.SetNotifyPreference(chatID, NotifyStreak, enabled); err != nil {
+		logger.Errorf("Failed to set notify preference: %v", err)
+		b.sendMessage(chatID, "❌ Failed to update notification settings, please try again later.")
+		return
+	}
+
+	if enabled {
+		b.sendMessage(chatID, "🔔 Accuracy trend notifications enabled.")
+	} else {
+		b.sendMessage(chatID, "🔕 Accuracy trend notifications disabled. Use /trend to enable again.")
+	}
+}
+
+// handleNotifyCommand 处理通知开关命令
+// 无参数时展示当前各事件开关，带参数时切换指定事件
+func (b *Bot) handleNotifyCommand(chatID int64, args string, corrID string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		prefs, err := b.db.GetNotifyPreferences(chatID)
+		if err != nil {
+			b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get notification settings, please try again later. (trace: %s)", corrID))
+			logger.WithFields(logger.Fields{
+				"module":  "telegram",
+				"chat_id": chatID,
+				"corr_id": corrID,
+			}).Errorf("Failed to get notify preferences: %v", err)
+			return
+		}
+
+		var builder strings.Builder
+		builder.WriteString("🔔 *Notification Settings*\n\n")
+		for _, event := range notifyEvents {
+			// results为opt-in事件，未显式开启时默认关闭；其余事件默认开启
+			enabled, set := prefs[event]
+			if !set {
+				enabled = event != NotifyResults
+			}
+			state := "on ✅"
+			if !enabled {
+				state = "off 🔕"
+			}
+			builder.WriteString(fmt.Sprintf("`%s`: %s\n", event, state))
+		}
+		builder.WriteString("\nUsage: /notify <event> on|off")
+		b.sendMessage(chatID, builder.String())
+		return
+	}
+
+	if len(fields) != 2 || (fields[1] != "on" && fields[1] != "off") {
+		b.sendMessage(chatID, "Usage: /notify <event> on|off")
+		return
+	}
+
+	event := fields[0]
+	if !isNotifyEvent(event) {
+		b.sendMessage(chatID, fmt.Sprintf("Unknown event `%s`. Available: `%s`",
+			event, strings.Join(notifyEvents, "`, `")))
+		return
+	}
+
+	enabled := fields[1] == "on"
+	if err := b.db.SetNotifyPreference(chatID, event, enabled); err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to update notification settings, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to set notify preference: %v", err)
+		return
+	}
+
+	if enabled {
+		b.sendMessage(chatID, fmt.Sprintf("🔔 `%s` notifications enabled.", event))
+	} else {
+		b.sendMessage(chatID, fmt.Sprintf("🔕 `%s` notifications disabled.", event))
+	}
+}
+
+// isNotifyEvent 校验通知事件类型是否有效
+func isNotifyEvent(event string) bool {
+	for _, known := range notifyEvents {
+		if known == event {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyEnabled 查询用户对某类通知事件的开关（未设置时默认开启）
+func (b *Bot) notifyEnabled(chatID int64, event string) bool {
+	prefs, err := b.db.GetNotifyPreferences(chatID)
+	if err != nil {
+		logger.Warnf("Failed to get notify preferences: %v", err)
+		return true
+	}
+	if enabled, set := prefs[event]; set {
+		return enabled
+	}
+	return true
+}
+
+// handleAuditCommand 处理审计记录查询命令（仅管理员可用）
+func (b *Bot) handleAuditCommand(chatID int64, corrID string) {
+	entries, err := b.db.GetAuditEntries(10)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get audit log, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get audit entries: %v", err)
+		return
+	}
+
+	b.recordAudit(chatID, "audit_view", "")
+	b.sendMessage(chatID, b.formatAuditLogMessage(b.userLocale(chatID), entries))
+}
+
+// handlePreviewCommand 处理模板预览命令（仅管理员可用）
+// 用样例或最新真实数据渲染指定模板，预览只发给管理员本人
+func (b *Bot) handlePreviewCommand(chatID int64, args string, corrID string) {
+	name := strings.TrimSpace(args)
+	if name == "" {
+		b.sendMessage(chatID, fmt.Sprintf("Usage: /preview <template>\n\nAvailable templates:\n`%s`",
+			strings.Join(b.templates.Names(), "`, `")))
+		return
+	}
+
+	preview, err := b.templates.Render(name, b.previewData(name))
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to render template: %v (trace: %s)", err, corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to render template preview %s: %v", name, err)
+		return
+	}
+
+	b.recordAudit(chatID, "template_preview", name)
+	b.sendMessage(chatID, fmt.Sprintf("👁 *Preview of `%s`*\n——————————————\n", name)+preview)
+}
+
+// previewData 构造模板预览用的数据（优先使用最新真实数据，取不到时回退样例）
+func (b *Bot) previewData(name string) interface{} {
+	switch name {
+	case "new_prediction":
+		latest, _ := b.cacheManager.GetLatestLotteryData()
+		prediction, err := b.cacheManager.GetLatestPrediction()
+		if err != nil || prediction == nil {
+			prediction = &database.Prediction{
+				TargetQihao:      "3326001",
+				PredictedNum:     "1+2+3",
+				PredictedSum:     6,
+				PredictedOddEven: "双",
+			}
+		}
+		return b.predictionCardData(&database.PredictionCard{
+			Prediction: prediction,
+			Latest:     latest,
+			Streak:     2,
+			NextDrawIn: 3*time.Minute + 30*time.Second,
+		})
+	case "verification":
+		return map[string]interface{}{
+			"Qihao":        "3326001",
+			"PredictedNum": "1+2+3",
+			"ActualNum":    "1+2+3",
+			"IsCorrect":    true,
+		}
+	case "error":
+		return map[string]interface{}{
+			"ErrorType":  "sample_error",
+			"Details":    "sample details",
+			"OccurredAt": time.Now(),
+		}
+	case "maintenance":
+		return map[string]interface{}{
+			"Reason":        "sample maintenance",
+			"EstimatedTime": (30 * time.Minute).String(),
+			"StartTime":     time.Now(),
+		}
+	case "countdown":
+		return map[string]interface{}{
+			"Minutes": 3,
+			"Seconds": 30,
+		}
+	default:
+		return nil
+	}
+}
+
+// handleRestartCommand 处理排空重启命令（仅管理员可用）
+// 通知订阅用户后请求main排空在途处理并以重启专用退出码退出，
+// 由进程守护（systemd等）根据退出码拉起新实例
+func (b *Bot) handleRestartCommand(chatID int64, corrID string) {
+	if b.restartFunc == nil {
+		b.sendMessage(chatID, "❌ Restart is not available in this mode.")
+		return
+	}
+
+	b.recordAudit(chatID, "drain_restart", "")
+	logger.WithFields(logger.Fields{
+		"module":  "telegram",
+		"chat_id": chatID,
+		"corr_id": corrID,
+	}).Warn("Drain-and-restart requested by admin")
+
+	// 通知订阅用户即将短暂维护
+	notice := b.formatMaintenanceMessage("Scheduled restart", time.Minute)
+	for _, userID := range b.subscribersFor(NotifyPredictions) {
+		if userID > 0 {
+			b.sendMessage(userID, notice)
+		}
+	}
+
+	b.sendMessage(chatID, "🔁 Draining in-flight work, the bot will restart shortly.")
+	b.restartFunc()
+}
+
+// handleAnalyticsCommand 处理用户活跃度分析命令（仅管理员可用）
+func (b *Bot) handleAnalyticsCommand(chatID int64, corrID string) {
+	stats, err := b.db.GetEngagementStats(7)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get analytics, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get engagement stats: %v", err)
+		return
+	}
+
+	b.recordAudit(chatID, "analytics_view", "")
+	b.sendMessage(chatID, b.formatAnalyticsMessage(stats))
+}
+
+// SetPredictorManager 设置预测器管理器与命中判定策略（用于离线评估命令）
+func (b *Bot) SetPredictorManager(pm *predictor.PredictorManager, policy predictor.CorrectnessPolicy) {
+	b.predictorMgr = pm
+	b.judgePolicy = policy
+}
+
+// handleEvaluateCommand 处理算法离线沙盒评估命令（仅管理员可用）
+// 在最近N期存量数据上滚动评估指定算法并与线上算法对照，不写预测表
+func (b *Bot) handleEvaluateCommand(chatID int64, args, corrID string) {
+	if b.predictorMgr == nil {
+		b.sendMessage(chatID, "❌ Evaluation is not available in this mode.")
+		return
+	}
+
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		b.sendMessage(chatID, fmt.Sprintf("Usage: /evaluate <algorithm> <rounds>\nAvailable algorithms: %s",
+			strings.Join(b.predictorMgr.GetAvailablePredictors(), ", ")))
+		return
+	}
+
+	target := b.predictorMgr.GetPredictor(parts[0])
+	if target == nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Unknown algorithm: %s\nAvailable algorithms: %s",
+			parts[0], strings.Join(b.predictorMgr.GetAvailablePredictors(), ", ")))
+		return
+	}
+
+	rounds, err := strconv.Atoi(parts[1])
+	if err != nil || rounds <= 0 || rounds > 1000 {
+		b.sendMessage(chatID, "❌ Rounds must be a number between 1 and 1000.")
+		return
+	}
+
+	// 多取所需历史余量，保证最旧的目标期也有足够输入
+	need := rounds + target.GetRequiredHistorySize()
+	history, err := b.db.GetLatestLotteryResults(need)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to load history, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to load history for evaluation: %v", err)
+		return
+	}
+
+	// 热存储不足时从冷存储归档透明补足回测窗口
+	if len(history) < need && len(history) > 0 && b.coldHistory != nil {
+		cold, err := b.coldHistory.ReadBefore(history[len(history)-1].Qihao, need-len(history))
+		if err != nil {
+			logger.Warnf("Failed to read cold history for evaluation: %v", err)
+		} else {
+			history = append(history, cold...)
+		}
+	}
+
+	result, err := predictor.EvaluateOffline(target, b.judgePolicy, history, rounds)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Evaluation failed: %v (trace: %s)", err, corrID))
+		return
+	}
+
+	// 线上算法在同一窗口上评估作为对照（目标即线上算法时跳过）
+	var baseline *predictor.EvaluationResult
+	if live := b.predictorMgr.GetCurrentPredictor(); live != nil && live.GetName() != target.GetName() {
+		baseline, err = predictor.EvaluateOffline(live, b.judgePolicy, history, rounds)
+		if err != nil {
+			logger.Warnf("Failed to evaluate live algorithm for comparison: %v", err)
+			baseline = nil
+		}
+	}
+
+	b.recordAudit(chatID, "algorithm_evaluate", args)
+	b.sendMessage(chatID, b.formatEvaluationMessage(result, baseline))
+}
+
+// handleLeaderboardCommand 处理算法榜单查询命令
+func (b *Bot) handleLeaderboardCommand(chatID int64, corrID string) {
+	entries, err := b.db.GetLeaderboard()
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get leaderboard, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get leaderboard: %v", err)
+		return
+	}
+
+	if len(entries) == 0 {
+		b.sendMessage(chatID, "📊 No leaderboard data yet, cross-validation has not run.")
+		return
+	}
+
+	b.sendMessage(chatID, b.formatLeaderboardMessage(b.userLocale(chatID), entries))
+}
+
+// handleSpecialCommand 处理对子/豹子形态查询命令
+func (b *Bot) handleSpecialCommand(chatID int64, corrID string) {
+	// 留存窗口内的全部开奖（24小时约288期）
+	results, err := b.db.GetLatestLotteryResults(288)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get lottery data, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get lottery results for special patterns: %v", err)
+		return
+	}
+
+	if len(results) == 0 {
+		b.sendMessage(chatID, "😕 No lottery data available yet.")
+		return
+	}
+
+	b.sendMessage(chatID, b.formatSpecialMessage(results))
+}
+
+// handleProfitCommand 处理假想盈亏查询命令
+// 按留存窗口内的已验证预测回算：每期投注1单位，命中得净赔率，未中损失本金
+func (b *Bot) handleProfitCommand(chatID int64, corrID string) {
+	analysis, err := b.statCalculator.ComputeProfit(b.configuredOdds(), 288)
+	if err != nil {
+		b.sendMessage(chatID, "📊 Not enough verified predictions to compute profit yet.")
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Debugf("Profit analysis unavailable: %v", err)
+		return
+	}
+
+	b.sendMessage(chatID, b.formatProfitMessage(analysis))
+}
+
+// configuredOdds 返回当前判定策略对应的净赔率（未配置时取默认值）
+func (b *Bot) configuredOdds() float64 {
+	odds := b.oddsOddEven
+	if b.judgePolicy != nil && b.judgePolicy.Name() == "big_small" {
+		odds = b.oddsBigSmall
+	}
+	if odds <= 0 {
+		odds = 0.95
+	}
+	return odds
+}
+
+// SetOdds 设置各玩法的净赔率（用于假想盈亏回算）
+func (b *Bot) SetOdds(oddEven, bigSmall float64) {
+	b.oddsOddEven = oddEven
+	b.oddsBigSmall = bigSmall
+}
+
+// SetCorrector 设置开奖更正入口
+func (b *Bot) SetCorrector(c Corrector) {
+	b.corrector = c
+}
+
+// SetStalenessChecker 设置上游数据新鲜度查询入口
+func (b *Bot) SetStalenessChecker(s StalenessChecker) {
+	b.staleness = s
+}
+
+// SetColdHistory 设置冷存储历史读取入口
+func (b *Bot) SetColdHistory(c ColdHistory) {
+	b.coldHistory = c
+}
+
+// SetSchedule 设置开奖节奏模型（用于倒计时展示）
+func (b *Bot) SetSchedule(s *schedule.Schedule) {
+	b.drawSchedule = s
+}
+
+// SetRestartFunc 设置排空重启回调
+func (b *Bot) SetRestartFunc(f func()) {
+	b.restartFunc = f
+}
+
+// CommandStats 导出各命令的调用次数与耗时聚合（供健康检查展示）
+func (b *Bot) CommandStats() map[string]interface{} {
+	return b.cmdMetrics.Snapshot()
+}
+
+// handleCorrectCommand 更正开奖号码并重新验证（仅管理员可用）
+// 用法：/correct <期号> <开奖号码>，例如 /correct 3327001 1,2,3
+func (b *Bot) handleCorrectCommand(chatID int64, args, corrID string) {
+	if b.corrector == nil {
+		b.sendMessage(chatID, "❌ Correction is not available in this mode.")
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		b.sendMessage(chatID, "Usage: /correct <qihao> <opennum>\nExample: /correct 3327001 1,2,3")
+		return
+	}
+	qihao, openNum := fields[0], fields[1]
+
+	validation, err := b.corrector.CorrectRound(qihao, openNum)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Correction failed: %v (trace: %s)", err, corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to correct round %s: %v", qihao, err)
+		return
+	}
+
+	b.recordAudit(chatID, "round_correction", fmt.Sprintf("%s -> %s", qihao, openNum))
+
+	outcome := "😅 Prediction Wrong"
+	if validation.IsCorrect {
+		outcome = "🎉 Prediction Correct"
+	}
+	b.sendMessage(chatID, fmt.Sprintf("✅ Round `%s` corrected to `%s`.\nRe-verified: %s", qihao, openNum, outcome))
+
+	// 向订阅用户推送更正通知，避免此前推送的结果误导用户
+	notice := fmt.Sprintf("📢 *Result Correction*\n\nRound `%s` has been corrected by the source.\nNumbers: `%s`\nVerification: %s", qihao, openNum, outcome)
+	if err := b.BroadcastNotice(notice); err != nil {
+		logger.Warnf("Failed to broadcast correction notice: %v", err)
+	}
+}
+
+// isAdmin 检查会话是否属于配置的管理员
+func (b *Bot) isAdmin(chatID int64) bool {
+	for _, adminID := range b.adminChatIDs {
+		if adminID == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// recordAudit 记录管理员操作到审计表（失败只记日志，不中断命令）
+func (b *Bot) recordAudit(actor int64, action, params string) {
+	entry := &database.AuditEntry{
+		Actor:  actor,
+		Action: action,
+		Params: params,
+	}
+	if err := b.db.SaveAuditEntry(entry); err != nil {
+		logger.Warnf("Failed to record audit entry %s: %v", action, err)
+	}
+}
 
 // handleTextMessage 处理文本消息
 func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
@@ -222,15 +1202,21 @@ func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
 
 	chatID := message.Chat.ID
 	text := message.Text
+	corrID := logger.NewCorrelationID()
+
+	// 进行中的多步会话优先接管自由文本回复（见conversation.go）
+	if b.resumeConversation(chatID, text) {
+		return
+	}
 
 	// 简单的智能回复
 	switch text {
 	case "最新", "最新数据":
-		b.handleLatestCommand(chatID)
+		b.handleLatestCommand(chatID, corrID)
 	case "历史", "历史记录":
-		b.handleHistoryCommand(chatID)
+		b.handleHistoryCommand(chatID, corrID)
 	case "统计", "准确率":
-		b.handleStatsCommand(chatID)
+		b.handleStatsCommand(chatID, corrID)
 	// 移除了预测相关的文本命令
 	default:
 		b.sendMessage(chatID, "Please use commands or keywords, type /help for help.")
@@ -246,16 +1232,53 @@ func (b *Bot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 
 	chatID := callback.Message.Chat.ID
 	data := callback.Data
+	corrID := logger.NewCorrelationID()
+
+	logger.WithFields(logger.Fields{
+		"module":  "telegram",
+		"chat_id": chatID,
+		"corr_id": corrID,
+	}).Debugf("Received private callback: %s", data)
+
+	// 跟单按钮回调（follow_<期号>）
+	if qihao := strings.TrimPrefix(data, "follow_"); qihao != data {
+		b.handleFollowCallback(callback, chatID, qihao)
+		return
+	}
+
+	// 提醒规则菜单回调（alert_*）
+	if strings.HasPrefix(data, "alert_") {
+		b.handleAlertCallback(callback, chatID, data)
+		return
+	}
 
-	logger.Debugf("Received private callback: %s from user: %d", data, chatID)
+	// 管理员广播草稿回调（bcast_*）
+	if strings.HasPrefix(data, "bcast_") {
+		b.handleBroadcastCallback(callback, chatID, data)
+		return
+	}
+
+	// 统计窗口切换回调（stats_win_<N>）
+	if strings.HasPrefix(data, "stats_win_") {
+		b.handleStatsWindowCallback(callback, chatID, data)
+		return
+	}
 
 	switch data {
 	case "refresh_latest":
-		b.handleLatestCommand(chatID)
+		b.handleLatestCommand(chatID, corrID)
 	case "view_history":
-		b.handleHistoryCommand(chatID)
+		b.handleHistoryCommand(chatID, corrID)
 	case "view_stats":
-		b.handleStatsCommand(chatID)
+		b.handleStatsCommand(chatID, corrID)
+	case "onboard_lang_en":
+		b.handleOnboardLanguage(chatID, "en")
+	case "onboard_lang_zh":
+		b.handleOnboardLanguage(chatID, "zh")
+	case "onboard_notify_on":
+		b.handleOnboardNotify(chatID, true)
+	case "onboard_notify_off":
+		b.handleOnboardNotify(chatID, false)
 	}
 
 	// 应答回调查询
@@ -271,39 +1294,165 @@ func (b *Bot) sendMessage(chatID int64, text string) {
 		return
 	}
 
+	if err := b.chaos.Maybe("telegram.send"); err != nil {
+		logger.Debugf("Chaos: dropped message to %d", chatID)
+		return
+	}
+
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = tgbotapi.ModeMarkdown
 
 	_, err := b.api.Send(msg)
 	if err != nil {
-		logger.Errorf("Failed to send message to user %d: %v", chatID, err)
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+		}).Errorf("Failed to send message: %v", err)
 	}
 }
 
-// BroadcastNewPrediction 广播新预测结果（仅发送给私聊用户）
-func (b *Bot) BroadcastNewPrediction(prediction *database.Prediction, actualResult *database.LotteryResult) error {
-	message := b.formatNewPredictionBroadcast(prediction, actualResult)
+// BroadcastNewPrediction 广播新预测卡片（仅发送给私聊用户）
+// card.Verified不为nil时将上一期验证结果与新预测合并为一条消息，减少推送打扰；
+// 验证结果部分只发给未关闭verification通知的用户
+func (b *Bot) BroadcastNewPrediction(card *database.PredictionCard) error {
+	message := b.appendDisclaimer(b.formatNewPredictionBroadcast(card))
+
+	verificationPart := ""
+	if verified := card.Verified; verified != nil && verified.IsCorrect != nil && verified.ActualNum != nil {
+		verificationPart = b.formatVerificationMessage(verified.TargetQihao, *verified.IsCorrect,
+			*verified.ActualNum, verified.PredictedNum) + "\n"
+	}
+
+	subscribers := b.subscribersFor(NotifyPredictions)
+
+	// 预取验证结果通知的订阅集合，避免逐用户查询
+	verificationSubscribers := make(map[int64]bool)
+	if verificationPart != "" {
+		for _, userID := range b.subscribersFor(NotifyVerification) {
+			verificationSubscribers[userID] = true
+		}
+	}
+
+	// 跟单按钮（点击后记入个人跟单记录，/myrecord可查）
+	followKeyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👍 Follow", "follow_"+card.Prediction.TargetQihao),
+		),
+	)
 
-	// 获取私聊订阅用户列表
-	subscribedUsers := b.getSubscribedUsers()
+	// 倒计时编辑开启时跟踪前若干条已发送消息（见countdown.go）
+	var tracked []countdownMessage
+	trackCountdown := b.countdownInterval > 0 && card.NextDrawIn > 0 &&
+		strings.Contains(message, countdownMarker)
 
-	for _, userID := range subscribedUsers {
-		// 确保只向私聊用户发送
-		if userID > 0 { // 正数ID表示用户，负数ID表示群组
-			b.sendMessage(userID, message)
+	for _, userID := range subscribers {
+		// 确保只向私聊用户发送（正数ID表示用户，负数ID表示群组）
+		if userID <= 0 {
+			continue
+		}
+		text := message
+		if verificationPart != "" && verificationSubscribers[userID] {
+			text = verificationPart + message
+		}
+		msg := tgbotapi.NewMessage(userID, text)
+		msg.ParseMode = tgbotapi.ModeMarkdown
+		msg.ReplyMarkup = followKeyboard
+		sent, err := b.api.Send(msg)
+		if err != nil {
+			logger.WithFields(logger.Fields{
+				"module":  "telegram",
+				"chat_id": userID,
+			}).Errorf("Failed to send prediction push: %v", err)
+			continue
+		}
+		if trackCountdown && len(tracked) < countdownMaxMessages {
+			tracked = append(tracked, countdownMessage{
+				chatID:    userID,
+				messageID: sent.MessageID,
+				text:      text,
+				countdown: formatCountdownValue(card.NextDrawIn),
+			})
 		}
 	}
 
-	logger.Infof("Broadcasted new prediction to %d private users", len(subscribedUsers))
+	b.startCountdownEdits(tracked, time.Now().Add(card.NextDrawIn), followKeyboard)
+
+	logger.Infof("Broadcasted new prediction to %d private users", len(subscribers))
 	return nil
 }
 
-// getSubscribedUsers 获取订阅的私聊用户列表
-func (b *Bot) getSubscribedUsers() []int64 {
-	// 这里应该从数据库获取已订阅的私聊用户ID列表
-	// 目前返回空列表，实际使用时需要实现用户订阅功能
-	// 注意：只返回正数的用户ID，不包含群组ID（负数）
-	return []int64{}
+// BroadcastNotice 广播文本通知（跳过关闭streak通知的用户）
+func (b *Bot) BroadcastNotice(text string) error {
+	subscribers := b.subscribersFor(NotifyStreak)
+
+	sent := 0
+	for _, userID := range subscribers {
+		if userID <= 0 {
+			continue
+		}
+		b.sendMessage(userID, text)
+		sent++
+	}
+
+	logger.Infof("Broadcasted notice to %d private users", sent)
+	return nil
+}
+
+// BroadcastRawResult 向开启results订阅的用户推送纯开奖结果（不含预测内容）
+// results为opt-in事件，只推送给显式开启的用户
+func (b *Bot) BroadcastRawResult(result *database.LotteryResult) error {
+	subscribers, err := b.db.GetOptInSubscribersFor(NotifyResults)
+	if err != nil {
+		return fmt.Errorf("failed to get results subscribers: %v", err)
+	}
+
+	message := b.formatRawResultMessage(result)
+	sent := 0
+	for _, userID := range subscribers {
+		if userID <= 0 {
+			continue
+		}
+		b.sendMessage(userID, message)
+		sent++
+	}
+
+	if sent > 0 {
+		logger.Infof("Broadcasted raw result to %d private users", sent)
+	}
+	return nil
+}
+
+// SendNotice 向单个私聊用户发送文本通知（供pipeline推送提醒规则命中）
+func (b *Bot) SendNotice(chatID int64, text string) error {
+	return b.sendMessageErr(chatID, text)
+}
+
+// BroadcastDailySummary 广播每日摘要（只发给未关闭summary通知的用户）
+func (b *Bot) BroadcastDailySummary(stats *database.DailyStats) error {
+	subscribers := b.subscribersFor(NotifySummary)
+
+	sent := 0
+	for _, userID := range subscribers {
+		if userID <= 0 {
+			continue
+		}
+		// 按接收者的时区与语言逐个渲染日期
+		b.sendMessage(userID, b.formatDailySummaryMessage(b.userLocale(userID), stats))
+		sent++
+	}
+
+	logger.Infof("Broadcasted daily summary to %d private users", sent)
+	return nil
+}
+
+// subscribersFor 获取未关闭指定通知事件的私聊用户列表
+func (b *Bot) subscribersFor(event string) []int64 {
+	users, err := b.db.GetSubscribersFor(event)
+	if err != nil {
+		logger.Errorf("Failed to get subscribers for %s: %v", event, err)
+		return nil
+	}
+	return users
 }
 
 // GetBotInfo 获取机器人信息
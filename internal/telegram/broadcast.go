@@ -0,0 +1,229 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// broadcastDraft 管理员广播草稿
+// compose -> preview -> confirm流程的中间状态，按管理员会话各自保存，
+// 确认发送或取消后清除
+type broadcastDraft struct {
+	text      string
+	audience  string        // 受众选择器（见database.GetBroadcastTargets）
+	delay     time.Duration // 推迟发送时长（0为立即发送）
+	createdAt time.Time
+}
+
+// broadcastAudienceLabels 受众选择器的展示名称
+var broadcastAudienceLabels = map[string]string{
+	"all":                "All users",
+	database.UserTierVIP: "VIP users",
+	"lang:en":            "English users",
+	"lang:zh":            "Chinese users",
+	"inactive:7":         "Inactive 7+ days",
+	"accuracy_lt:50":     "Follow accuracy below 50%",
+}
+
+// handleBroadcastCommand 处理管理员广播命令（仅管理员可用）
+// `/broadcast <文案>`创建草稿并展示预览，受众、发送时间和确认都通过
+// 内联按钮完成，不会直接发送
+func (b *Bot) handleBroadcastCommand(chatID int64, args, corrID string) {
+	text := strings.TrimSpace(args)
+	if text == "" {
+		b.sendMessage(chatID, "Usage: /broadcast <message>\n\nCompose a broadcast draft, then pick the audience and send time from the preview.")
+		return
+	}
+
+	b.draftMutex.Lock()
+	b.broadcastDrafts[chatID] = &broadcastDraft{
+		text:      text,
+		audience:  "all",
+		createdAt: time.Now(),
+	}
+	b.draftMutex.Unlock()
+
+	b.sendBroadcastPreview(chatID)
+}
+
+// sendBroadcastPreview 发送广播草稿预览（附受众/时间/确认按钮）
+func (b *Bot) sendBroadcastPreview(chatID int64) {
+	b.draftMutex.Lock()
+	draft := b.broadcastDrafts[chatID]
+	b.draftMutex.Unlock()
+	if draft == nil {
+		return
+	}
+
+	when := "immediately"
+	if draft.delay > 0 {
+		when = fmt.Sprintf("in %v", draft.delay)
+	}
+	preview := fmt.Sprintf("📢 *Broadcast Draft*\n\n%s\n\n👥 Audience: %s\n⏰ Send: %s",
+		draft.text, broadcastAudienceLabels[draft.audience], when)
+
+	msg := tgbotapi.NewMessage(chatID, preview)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👥 All", "bcast_aud_all"),
+			tgbotapi.NewInlineKeyboardButtonData("⭐ VIP", "bcast_aud_vip"),
+			tgbotapi.NewInlineKeyboardButtonData("🇬🇧 EN", "bcast_aud_en"),
+			tgbotapi.NewInlineKeyboardButtonData("🇨🇳 中文", "bcast_aud_zh"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("😴 Inactive 7d", "bcast_aud_inactive7"),
+			tgbotapi.NewInlineKeyboardButtonData("📉 Accuracy <50%", "bcast_aud_lowacc"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🚀 Now", "bcast_when_now"),
+			tgbotapi.NewInlineKeyboardButtonData("⏰ +5m", "bcast_when_5m"),
+			tgbotapi.NewInlineKeyboardButtonData("⏰ +30m", "bcast_when_30m"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Confirm", "bcast_confirm"),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "bcast_cancel"),
+		),
+	)
+	if _, err := b.api.Send(msg); err != nil {
+		logger.Errorf("Failed to send broadcast preview: %v", err)
+	}
+}
+
+// handleBroadcastCallback 处理广播草稿回调（bcast_*）
+// 受众和发送时间的选择刷新预览，确认后立即或定时发送，取消清除草稿
+func (b *Bot) handleBroadcastCallback(callback *tgbotapi.CallbackQuery, chatID int64, data string) {
+	response := ""
+
+	if !b.isAdmin(chatID) {
+		response = "Not authorized"
+	} else {
+		b.draftMutex.Lock()
+		draft := b.broadcastDrafts[chatID]
+		b.draftMutex.Unlock()
+
+		if draft == nil {
+			response = "No broadcast draft in progress"
+		} else {
+			switch data {
+			case "bcast_aud_all", "bcast_aud_vip", "bcast_aud_en", "bcast_aud_zh",
+				"bcast_aud_inactive7", "bcast_aud_lowacc":
+				audience := map[string]string{
+					"bcast_aud_all":       "all",
+					"bcast_aud_vip":       database.UserTierVIP,
+					"bcast_aud_en":        "lang:en",
+					"bcast_aud_zh":        "lang:zh",
+					"bcast_aud_inactive7": "inactive:7",
+					"bcast_aud_lowacc":    "accuracy_lt:50",
+				}[data]
+				draft.audience = audience
+				response = "Audience: " + broadcastAudienceLabels[audience]
+				b.sendBroadcastPreview(chatID)
+			case "bcast_when_now", "bcast_when_5m", "bcast_when_30m":
+				draft.delay = map[string]time.Duration{
+					"bcast_when_now": 0,
+					"bcast_when_5m":  5 * time.Minute,
+					"bcast_when_30m": 30 * time.Minute,
+				}[data]
+				response = "Send time updated"
+				b.sendBroadcastPreview(chatID)
+			case "bcast_confirm":
+				b.draftMutex.Lock()
+				delete(b.broadcastDrafts, chatID)
+				b.draftMutex.Unlock()
+				b.confirmBroadcast(chatID, draft)
+				response = "Broadcast confirmed"
+			case "bcast_cancel":
+				b.draftMutex.Lock()
+				delete(b.broadcastDrafts, chatID)
+				b.draftMutex.Unlock()
+				b.sendMessage(chatID, "🗑 Broadcast draft discarded.")
+				response = "Cancelled"
+			}
+		}
+	}
+
+	callbackResp := tgbotapi.NewCallback(callback.ID, response)
+	if _, err := b.api.Request(callbackResp); err != nil {
+		logger.Errorf("Failed to answer broadcast callback: %v", err)
+	}
+}
+
+// confirmBroadcast 确认发送广播草稿（按草稿设置立即或定时发送）
+func (b *Bot) confirmBroadcast(chatID int64, draft *broadcastDraft) {
+	b.recordAudit(chatID, "broadcast_send",
+		fmt.Sprintf("audience=%s delay=%v len=%d", draft.audience, draft.delay, len(draft.text)))
+
+	if draft.delay > 0 {
+		sendAt := time.Now().Add(draft.delay)
+		b.sendMessage(chatID, fmt.Sprintf("⏰ Broadcast scheduled, sending in %v.", draft.delay))
+		time.AfterFunc(draft.delay, func() {
+			b.deliverBroadcast(chatID, draft)
+		})
+		logger.Infof("Admin broadcast scheduled for %s (audience %s)",
+			sendAt.Format("15:04:05"), draft.audience)
+		return
+	}
+	b.deliverBroadcast(chatID, draft)
+}
+
+// deliverBroadcast 向目标受众逐个投递广播并回报发送量
+func (b *Bot) deliverBroadcast(adminID int64, draft *broadcastDraft) {
+	targets, err := b.db.GetBroadcastTargets(draft.audience)
+	if err != nil {
+		logger.Errorf("Failed to resolve broadcast audience %s: %v", draft.audience, err)
+		b.sendMessage(adminID, "❌ Failed to resolve broadcast audience, broadcast not sent.")
+		return
+	}
+
+	sent := 0
+	for _, userID := range targets {
+		if userID <= 0 {
+			continue
+		}
+		b.sendMessage(userID, "📢 "+draft.text)
+		sent++
+	}
+
+	logger.Infof("Admin broadcast delivered to %d user(s) (audience %s)", sent, draft.audience)
+	b.sendMessage(adminID, fmt.Sprintf("✅ Broadcast delivered to %d user(s).", sent))
+}
+
+// handleVipCommand 设置用户VIP层级（仅管理员可用）
+// `/vip <user_id>`标记为VIP，`/vip <user_id> off`恢复普通用户
+func (b *Bot) handleVipCommand(chatID int64, args, corrID string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		b.sendMessage(chatID, "Usage: /vip <user_id> [off]")
+		return
+	}
+	userID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		b.sendMessage(chatID, "❌ Invalid user ID.")
+		return
+	}
+
+	tier := database.UserTierVIP
+	if len(fields) > 1 && fields[1] == "off" {
+		tier = ""
+	}
+	if err := b.db.SetUserTier(userID, tier); err != nil {
+		logger.Errorf("Failed to set user tier: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to update user tier. (trace: %s)", corrID))
+		return
+	}
+
+	b.recordAudit(chatID, "user_tier", fmt.Sprintf("user=%d tier=%s", userID, tier))
+	if tier == "" {
+		b.sendMessage(chatID, fmt.Sprintf("✅ User `%d` is now a regular user.", userID))
+	} else {
+		b.sendMessage(chatID, fmt.Sprintf("⭐ User `%d` is now a VIP.", userID))
+	}
+}
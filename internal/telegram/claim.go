@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pc28-bot/internal/logger"
+)
+
+// stateClaimedAdmin 通过认领流程获得管理员身份的chat ID（持久化在app_state表）
+const stateClaimedAdmin = "claimed_admin_id"
+
+// initAdminClaim 无管理员配置时的首装认领流程
+// 优先恢复历史认领记录；全新安装时生成一次性认领码打印到控制台，
+// 第一个发送 /claim <code> 的用户成为管理员，后续配置即可全部在机器人内完成
+func (b *Bot) initAdminClaim() {
+	if stored, err := b.db.GetAppState(stateClaimedAdmin); err == nil && stored != "" {
+		adminID, err := strconv.ParseInt(stored, 10, 64)
+		if err != nil {
+			logger.Warnf("Ignoring malformed claimed admin id %q: %v", stored, err)
+		} else {
+			b.adminChatIDs = append(b.adminChatIDs, adminID)
+			logger.Infof("Restored claimed admin %d from app state", adminID)
+			return
+		}
+	}
+
+	code, err := generateClaimCode()
+	if err != nil {
+		logger.Warnf("Failed to generate admin claim code: %v", err)
+		return
+	}
+	b.claimCode = code
+	logger.Status("🔑 未配置管理员：向机器人发送 /claim %s 认领管理员身份（仅首个用户生效）", code)
+}
+
+// generateClaimCode 生成一次性认领码（8位十六进制）
+func generateClaimCode() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// handleClaimCommand 处理管理员认领命令
+// 仅在认领模式下有效（已配置或已认领过管理员时表现为未知命令，不暴露流程）
+func (b *Bot) handleClaimCommand(chatID int64, args, corrID string) {
+	if b.claimCode == "" {
+		b.sendMessage(chatID, "Unknown command. Type /help to view available commands.")
+		return
+	}
+
+	if strings.TrimSpace(args) != b.claimCode {
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Warn("Rejected admin claim with wrong code")
+		b.sendMessage(chatID, "❌ Invalid claim code.")
+		return
+	}
+
+	if err := b.db.SaveAppState(stateClaimedAdmin, strconv.FormatInt(chatID, 10)); err != nil {
+		logger.Errorf("Failed to persist claimed admin: %v", err)
+		b.sendMessage(chatID, "❌ Failed to save admin assignment, please try again.")
+		return
+	}
+
+	b.adminChatIDs = append(b.adminChatIDs, chatID)
+	b.claimCode = "" // 一次性：认领成功后作废
+	b.recordAudit(chatID, "admin_claim", "")
+
+	logger.Status("👑 用户 %d 已通过认领码成为管理员", chatID)
+	b.sendMessage(chatID, "✅ *You are now the bot admin.*\n\nAdmin commands are available in this chat. Type /help to see them.")
+}
@@ -0,0 +1,69 @@
+package telegram
+
+import (
+	"strings"
+	"time"
+)
+
+// 多步会话框架：命令启动会话后接管该会话的自由文本回复，
+// 每步处理函数返回下一步，直到返回nil结束。会话状态按chat
+// 保存在内存中（与导出断点、广播草稿同样的单实例语义），
+// 闲置超时后自由文本回复恢复默认处理
+
+// conversationTTL 会话闲置超时
+const conversationTTL = 5 * time.Minute
+
+// conversationStep 会话单步处理函数：处理一条自由文本回复，
+// 返回下一步（返回nil表示会话结束，返回自身表示重试当前步骤）
+type conversationStep func(chatID int64, text string) conversationStep
+
+// conversation 进行中的多步会话
+type conversation struct {
+	step      conversationStep
+	updatedAt time.Time
+}
+
+// startConversation 开始一个多步会话（同会话重复开始为覆盖）
+func (b *Bot) startConversation(chatID int64, step conversationStep) {
+	b.convMutex.Lock()
+	defer b.convMutex.Unlock()
+	b.conversations[chatID] = &conversation{step: step, updatedAt: time.Now()}
+}
+
+// endConversation 中止进行中的会话（无会话时为空操作）
+func (b *Bot) endConversation(chatID int64) {
+	b.convMutex.Lock()
+	defer b.convMutex.Unlock()
+	delete(b.conversations, chatID)
+}
+
+// resumeConversation 将自由文本回复交给进行中的会话处理，
+// 返回是否已被会话消费；回复cancel在任何步骤都会中止会话
+func (b *Bot) resumeConversation(chatID int64, text string) bool {
+	b.convMutex.Lock()
+	conv, ok := b.conversations[chatID]
+	if ok && time.Since(conv.updatedAt) > conversationTTL {
+		delete(b.conversations, chatID)
+		ok = false
+	}
+	b.convMutex.Unlock()
+	if !ok {
+		return false
+	}
+
+	if strings.EqualFold(strings.TrimSpace(text), "cancel") {
+		b.endConversation(chatID)
+		b.sendMessage(chatID, "✅ Cancelled.")
+		return true
+	}
+
+	next := conv.step(chatID, text)
+	b.convMutex.Lock()
+	if next == nil {
+		delete(b.conversations, chatID)
+	} else {
+		b.conversations[chatID] = &conversation{step: next, updatedAt: time.Now()}
+	}
+	b.convMutex.Unlock()
+	return true
+}
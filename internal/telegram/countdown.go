@@ -0,0 +1,123 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"pc28-bot/internal/logger"
+)
+
+// countdownMarker 倒计时行的定位标记（编辑时按此替换倒计时值，
+// 自定义模板改写了该行措辞时倒计时编辑自动不生效）
+const countdownMarker = "⏳ Next draw in "
+
+// countdownMaxMessages 单轮倒计时编辑跟踪的消息数上限
+// （编辑与发送共享Telegram限流配额，只对前若干订阅者做软实时更新）
+const countdownMaxMessages = 30
+
+// countdownEditDelay 相邻两次编辑请求的间隔（避免触发限流）
+const countdownEditDelay = 100 * time.Millisecond
+
+// 倒计时编辑间隔的允许范围（过于频繁会耗尽限流配额，过慢失去意义）
+const (
+	countdownMinInterval = 30 * time.Second
+	countdownMaxInterval = 60 * time.Second
+)
+
+// countdownMessage 倒计时编辑跟踪的已发送消息
+type countdownMessage struct {
+	chatID    int64
+	messageID int
+	text      string // 当前消息全文（每次编辑后更新）
+	countdown string // 当前显示的倒计时值（下次编辑时被替换）
+}
+
+// SetCountdownInterval 设置广播消息的倒计时编辑间隔
+// 为0时关闭倒计时编辑，非0时钳制到允许范围内
+func (b *Bot) SetCountdownInterval(interval time.Duration) {
+	if interval <= 0 {
+		b.countdownInterval = 0
+		return
+	}
+	if interval < countdownMinInterval {
+		interval = countdownMinInterval
+	}
+	if interval > countdownMaxInterval {
+		interval = countdownMaxInterval
+	}
+	b.countdownInterval = interval
+}
+
+// formatCountdownValue 格式化倒计时值（与广播模板的初始渲染一致）
+func formatCountdownValue(remaining time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(remaining.Minutes()), int(remaining.Seconds())%60)
+}
+
+// startCountdownEdits 启动本轮广播的倒计时编辑协程
+// 每隔配置的间隔将各消息的倒计时行刷新为最新剩余时间，到达预计
+// 开奖时间即停止；新一轮广播启动时上一轮的编辑协程被取消
+func (b *Bot) startCountdownEdits(messages []countdownMessage, drawAt time.Time, markup tgbotapi.InlineKeyboardMarkup) {
+	if b.countdownInterval <= 0 || len(messages) == 0 {
+		return
+	}
+
+	b.countdownMutex.Lock()
+	if b.countdownStop != nil {
+		close(b.countdownStop)
+	}
+	stop := make(chan struct{})
+	b.countdownStop = stop
+	b.countdownMutex.Unlock()
+
+	go b.runCountdownEdits(messages, drawAt, markup, stop)
+}
+
+// runCountdownEdits 倒计时编辑工作协程（生命周期截止到预计开奖时间）
+func (b *Bot) runCountdownEdits(messages []countdownMessage, drawAt time.Time,
+	markup tgbotapi.InlineKeyboardMarkup, stop chan struct{}) {
+	ticker := time.NewTicker(b.countdownInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		remaining := time.Until(drawAt)
+		if remaining <= 0 {
+			return
+		}
+
+		value := formatCountdownValue(remaining)
+		for i := range messages {
+			msg := &messages[i]
+			if msg.messageID == 0 || value == msg.countdown {
+				continue
+			}
+
+			newText := strings.Replace(msg.text,
+				countdownMarker+msg.countdown, countdownMarker+value, 1)
+			if newText == msg.text {
+				msg.messageID = 0 // 倒计时行已不可定位，放弃该消息
+				continue
+			}
+
+			edit := tgbotapi.NewEditMessageTextAndMarkup(msg.chatID, msg.messageID, newText, markup)
+			edit.ParseMode = tgbotapi.ModeMarkdown
+			if _, err := b.api.Request(edit); err != nil {
+				// 编辑失败（消息被删除、限流等）即放弃该消息，不重试
+				logger.Debugf("Countdown edit failed for chat %d: %v", msg.chatID, err)
+				msg.messageID = 0
+				continue
+			}
+			msg.text = newText
+			msg.countdown = value
+			time.Sleep(countdownEditDelay)
+		}
+	}
+}
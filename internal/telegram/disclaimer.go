@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"pc28-bot/internal/logger"
+)
+
+// 免责声明在app_state表中的存储键（运行期可改，重启后保留）
+const disclaimerStateKey = "disclaimer_footer"
+
+// disclaimerFooter 读取当前配置的免责声明文案（未配置时返回空字符串）
+func (b *Bot) disclaimerFooter() string {
+	footer, err := b.db.GetAppState(disclaimerStateKey)
+	if err != nil {
+		logger.Warnf("Failed to load disclaimer footer: %v", err)
+		return ""
+	}
+	return footer
+}
+
+// appendDisclaimer 在出站消息末尾附加免责声明（未配置时原样返回）
+func (b *Bot) appendDisclaimer(message string) string {
+	footer := b.disclaimerFooter()
+	if footer == "" {
+		return message
+	}
+	return message + "\n\n⚖️ " + footer
+}
+
+// handleDisclaimerCommand 管理免责声明文案（仅管理员可用）
+// 无参数时展示当前文案，`set <文案>`更新，`clear`移除
+func (b *Bot) handleDisclaimerCommand(chatID int64, args, corrID string) {
+	fields := strings.SplitN(strings.TrimSpace(args), " ", 2)
+	switch fields[0] {
+	case "":
+		footer := b.disclaimerFooter()
+		if footer == "" {
+			b.sendMessage(chatID, "ℹ️ No disclaimer footer configured.\n\nUsage: /disclaimer set <text> | /disclaimer clear")
+			return
+		}
+		b.sendMessage(chatID, fmt.Sprintf("⚖️ *Current Disclaimer*\n\n%s\n\nUsage: /disclaimer set <text> | /disclaimer clear", footer))
+
+	case "set":
+		if len(fields) < 2 || strings.TrimSpace(fields[1]) == "" {
+			b.sendMessage(chatID, "Usage: /disclaimer set <text>")
+			return
+		}
+		text := strings.TrimSpace(fields[1])
+		if err := b.db.SaveAppState(disclaimerStateKey, text); err != nil {
+			logger.Errorf("Failed to save disclaimer footer: %v", err)
+			b.sendMessage(chatID, fmt.Sprintf("❌ Failed to save disclaimer, please try again later. (trace: %s)", corrID))
+			return
+		}
+		b.recordAudit(chatID, "disclaimer_set", text)
+		b.sendMessage(chatID, "✅ Disclaimer footer updated. It will be appended to prediction and history messages.")
+
+	case "clear":
+		if err := b.db.SaveAppState(disclaimerStateKey, ""); err != nil {
+			logger.Errorf("Failed to clear disclaimer footer: %v", err)
+			b.sendMessage(chatID, fmt.Sprintf("❌ Failed to clear disclaimer, please try again later. (trace: %s)", corrID))
+			return
+		}
+		b.recordAudit(chatID, "disclaimer_clear", "")
+		b.sendMessage(chatID, "✅ Disclaimer footer removed.")
+
+	default:
+		b.sendMessage(chatID, "Usage: /disclaimer set <text> | /disclaimer clear")
+	}
+}
@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	errorHookFlushInterval = 1 * time.Minute  // 批量发送间隔（限流）
+	errorHookDedupWindow   = 10 * time.Minute // 相同错误在窗口期内只转发一次
+	errorHookMaxBatch      = 10               // 单批最多携带的错误条数
+)
+
+// ErrorHook 将Error及以上级别日志批量转发给管理员的logrus钩子
+// 批量发送和去重避免错误风暴时刷爆管理员会话
+type ErrorHook struct {
+	api      *tgbotapi.BotAPI
+	chatIDs  []int64
+	mutex    sync.Mutex
+	pending  []string
+	dropped  int // 超出批量上限被丢弃的条数
+	seen     map[string]time.Time
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewErrorHook 创建错误转发钩子并启动后台发送协程
+func NewErrorHook(api *tgbotapi.BotAPI, chatIDs []int64) *ErrorHook {
+	h := &ErrorHook{
+		api:      api,
+		chatIDs:  chatIDs,
+		seen:     make(map[string]time.Time),
+		stopChan: make(chan struct{}),
+	}
+	go h.flushLoop()
+	return h
+}
+
+// Levels 钩子关注的日志级别
+func (h *ErrorHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+// Fire 收集错误日志条目（由logrus在每条日志时调用）
+func (h *ErrorHook) Fire(entry *logrus.Entry) error {
+	h.mutex.Lock()
+
+	// 去重：窗口期内相同消息只转发一次
+	if lastSeen, ok := h.seen[entry.Message]; ok && time.Since(lastSeen) < errorHookDedupWindow {
+		h.mutex.Unlock()
+		return nil
+	}
+	h.seen[entry.Message] = time.Now()
+
+	if len(h.pending) < errorHookMaxBatch {
+		h.pending = append(h.pending,
+			fmt.Sprintf("[%s] %s", strings.ToUpper(entry.Level.String()), entry.Message))
+	} else {
+		h.dropped++
+	}
+	h.mutex.Unlock()
+
+	// Fatal/Panic后进程即将退出，立即发送而不等批量周期
+	if entry.Level <= logrus.FatalLevel {
+		h.flush()
+	}
+	return nil
+}
+
+// Stop 停止后台发送协程并发送剩余批量
+func (h *ErrorHook) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stopChan)
+	})
+}
+
+// flushLoop 周期性批量发送收集到的错误
+func (h *ErrorHook) flushLoop() {
+	ticker := time.NewTicker(errorHookFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.flush()
+		case <-h.stopChan:
+			h.flush()
+			return
+		}
+	}
+}
+
+// flush 将当前批量发送给所有管理员会话
+func (h *ErrorHook) flush() {
+	h.mutex.Lock()
+	if len(h.pending) == 0 {
+		h.mutex.Unlock()
+		return
+	}
+	batch := h.pending
+	dropped := h.dropped
+	h.pending = nil
+	h.dropped = 0
+
+	// 清理过期的去重记录
+	for msg, seenAt := range h.seen {
+		if time.Since(seenAt) > errorHookDedupWindow {
+			delete(h.seen, msg)
+		}
+	}
+	h.mutex.Unlock()
+
+	text := fmt.Sprintf("🚨 错误日志 (%d条):\n%s", len(batch), strings.Join(batch, "\n"))
+	if dropped > 0 {
+		text += fmt.Sprintf("\n... 另有%d条已省略", dropped)
+	}
+
+	for _, chatID := range h.chatIDs {
+		msg := tgbotapi.NewMessage(chatID, text)
+		// 发送失败时不能再走logger.Error，否则会递归触发本钩子
+		if _, err := h.api.Send(msg); err != nil {
+			fmt.Printf("⚠️ 错误日志转发失败 (chat %d): %v\n", chatID, err)
+		}
+	}
+}
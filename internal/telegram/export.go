@@ -0,0 +1,218 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// 大体量历史导出参数
+const (
+	exportDefaultRounds = 288  // 默认导出约1天的开奖记录
+	exportMaxRounds     = 2016 // 最多导出约7天的开奖记录
+	exportChunkLimit    = 3500 // 单条消息的内容上限（留出分页头的余量，Telegram硬限制4096）
+	exportFileThreshold = 8    // 分块数超过阈值时改为发送文件，避免刷屏
+	exportSendInterval  = 300 * time.Millisecond
+)
+
+// exportJob 分块导出任务的进度状态
+// 发送中途失败时保留剩余分块，用户可通过/export resume从断点继续
+type exportJob struct {
+	chunks    []string  // 全部分块内容
+	nextChunk int       // 下一个待发送的分块下标
+	startedAt time.Time // 任务创建时间
+}
+
+// handleExportCommand 处理历史导出命令
+// 用法：/export [期数] 导出开奖历史，/export resume 从上次失败处继续发送
+func (b *Bot) handleExportCommand(chatID int64, args, corrID string) {
+	args = strings.TrimSpace(args)
+
+	// 断点续传：继续发送上次中断的任务
+	if args == "resume" {
+		b.resumeExportJob(chatID, corrID)
+		return
+	}
+
+	rounds := exportDefaultRounds
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n < 1 || n > exportMaxRounds {
+			b.sendMessage(chatID, fmt.Sprintf("Usage: /export [rounds]\nrounds must be between 1 and %d, or /export resume to continue an interrupted export.", exportMaxRounds))
+			return
+		}
+		rounds = n
+	}
+
+	history, err := b.db.GetLotteryHistory(rounds)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get history records, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get lottery history for export: %v", err)
+		return
+	}
+	if len(history) == 0 {
+		b.sendMessage(chatID, "📭 No lottery records to export yet.")
+		return
+	}
+
+	lines := formatExportLines(history)
+	chunks := splitIntoChunks(lines, exportChunkLimit)
+
+	// 分块过多时改为发送单个文本文件，避免大量消息刷屏
+	if len(chunks) > exportFileThreshold {
+		b.sendExportFile(chatID, lines, len(history), corrID)
+		return
+	}
+
+	b.exportMutex.Lock()
+	b.exportJobs[chatID] = &exportJob{chunks: chunks, startedAt: time.Now()}
+	b.exportMutex.Unlock()
+
+	b.runExportJob(chatID, corrID)
+}
+
+// resumeExportJob 从断点继续发送导出任务
+func (b *Bot) resumeExportJob(chatID int64, corrID string) {
+	b.exportMutex.Lock()
+	job := b.exportJobs[chatID]
+	b.exportMutex.Unlock()
+
+	if job == nil || job.nextChunk >= len(job.chunks) {
+		b.sendMessage(chatID, "📭 No interrupted export to resume. Start one with /export.")
+		return
+	}
+	b.runExportJob(chatID, corrID)
+}
+
+// runExportJob 按序发送剩余分块（限速，失败即停并保留断点）
+func (b *Bot) runExportJob(chatID int64, corrID string) {
+	b.exportMutex.Lock()
+	job := b.exportJobs[chatID]
+	b.exportMutex.Unlock()
+	if job == nil {
+		return
+	}
+
+	total := len(job.chunks)
+	for job.nextChunk < total {
+		i := job.nextChunk
+		text := fmt.Sprintf("📦 *Export* (part %d/%d)\n\n%s", i+1, total, job.chunks[i])
+		if err := b.sendMessageErr(chatID, text); err != nil {
+			logger.WithFields(logger.Fields{
+				"module":  "telegram",
+				"chat_id": chatID,
+				"corr_id": corrID,
+			}).Errorf("Export send failed at part %d/%d: %v", i+1, total, err)
+			b.sendMessage(chatID, fmt.Sprintf("⚠️ Export interrupted at part %d/%d. Send /export resume to continue. (trace: %s)", i+1, total, corrID))
+			return
+		}
+
+		b.exportMutex.Lock()
+		job.nextChunk++
+		b.exportMutex.Unlock()
+
+		// 限速发送，避免触发Telegram频率限制
+		if job.nextChunk < total {
+			time.Sleep(exportSendInterval)
+		}
+	}
+
+	// 全部发送完成，清理任务状态
+	b.exportMutex.Lock()
+	delete(b.exportJobs, chatID)
+	b.exportMutex.Unlock()
+
+	b.sendMessage(chatID, fmt.Sprintf("✅ Export complete: %d part(s) sent.", total))
+}
+
+// sendExportFile 以文本文件形式发送导出内容
+func (b *Bot) sendExportFile(chatID int64, lines []string, rounds int, corrID string) {
+	content := strings.Join(lines, "\n")
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("lottery_export_%s.txt", time.Now().Format("20060102_150405")),
+		Bytes: []byte(content),
+	})
+	doc.Caption = fmt.Sprintf("📦 Lottery history export: %d rounds", rounds)
+
+	if _, err := b.api.Send(doc); err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to send export file, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to send export file: %v", err)
+	}
+}
+
+// formatExportLines 将开奖历史格式化为逐行文本（最老的在最前面）
+func formatExportLines(history []database.LotteryResult) []string {
+	lines := make([]string, 0, len(history)+1)
+	lines = append(lines, "qihao | open_num | sum | odd_even | big_small | open_time")
+
+	for i := len(history) - 1; i >= 0; i-- {
+		result := history[i]
+
+		oddEvenPattern := "Even"
+		if result.SumValue%2 == 1 {
+			oddEvenPattern = "Odd"
+		}
+		sizePattern := "Small"
+		if result.SumValue >= 14 {
+			sizePattern = "Big"
+		}
+
+		lines = append(lines, fmt.Sprintf("%s | %s | %d | %s | %s | %s",
+			result.Qihao, result.OpenNum, result.SumValue, oddEvenPattern, sizePattern, result.OpenTime))
+	}
+	return lines
+}
+
+// splitIntoChunks 将多行内容按消息长度上限切分为若干分块
+func splitIntoChunks(lines []string, limit int) []string {
+	var chunks []string
+	var builder strings.Builder
+
+	for _, line := range lines {
+		// 当前分块放不下时另起新块（单行超限的情况按行整体放入新块）
+		if builder.Len() > 0 && builder.Len()+len(line)+1 > limit {
+			chunks = append(chunks, builder.String())
+			builder.Reset()
+		}
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(line)
+	}
+	if builder.Len() > 0 {
+		chunks = append(chunks, builder.String())
+	}
+	return chunks
+}
+
+// sendMessageErr 发送消息并返回错误（供需要感知失败的调用方使用）
+func (b *Bot) sendMessageErr(chatID int64, text string) error {
+	if chatID < 0 {
+		return nil
+	}
+
+	if err := b.chaos.Maybe("telegram.send"); err != nil {
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	if _, err := b.api.Send(msg); err != nil {
+		return fmt.Errorf("failed to send message: %v", err)
+	}
+	return nil
+}
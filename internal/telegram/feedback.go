@@ -0,0 +1,87 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+)
+
+// feedbackMaxLength 单条反馈内容的长度上限
+const feedbackMaxLength = 1000
+
+// handleFeedbackCommand 处理意见反馈命令
+// 无参数时进入多步会话：先收集反馈内容，确认后保存；
+// list参数供管理员查看最近的反馈
+func (b *Bot) handleFeedbackCommand(chatID int64, args, corrID string) {
+	if strings.TrimSpace(args) == "list" && b.isAdmin(chatID) {
+		entries, err := b.db.GetFeedbackEntries(10)
+		if err != nil {
+			b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get feedback, please try again later. (trace: %s)", corrID))
+			logger.WithFields(logger.Fields{
+				"module":  "telegram",
+				"chat_id": chatID,
+				"corr_id": corrID,
+			}).Errorf("Failed to get feedback entries: %v", err)
+			return
+		}
+		b.sendMessage(chatID, b.formatFeedbackList(b.userLocale(chatID), entries))
+		return
+	}
+
+	b.startConversation(chatID, b.feedbackContentStep)
+	b.sendMessage(chatID, "📝 Please reply with your feedback or suggestion.\nReply \"cancel\" at any time to abort.")
+}
+
+// feedbackContentStep 反馈会话第一步：收集反馈内容
+func (b *Bot) feedbackContentStep(chatID int64, text string) conversationStep {
+	content := strings.TrimSpace(text)
+	if content == "" {
+		b.sendMessage(chatID, "Feedback cannot be empty, please try again.")
+		return b.feedbackContentStep
+	}
+	if len(content) > feedbackMaxLength {
+		b.sendMessage(chatID, fmt.Sprintf("Feedback is too long (max %d characters), please shorten it.", feedbackMaxLength))
+		return b.feedbackContentStep
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("Submit this feedback? (yes/no)\n\n%s", content))
+
+	// 第二步：确认提交（闭包携带第一步收集的内容）
+	var confirmStep conversationStep
+	confirmStep = func(chatID int64, reply string) conversationStep {
+		switch strings.ToLower(strings.TrimSpace(reply)) {
+		case "yes", "y":
+			if err := b.db.SaveFeedback(&database.Feedback{UserID: chatID, Content: content}); err != nil {
+				logger.Errorf("Failed to save feedback from %d: %v", chatID, err)
+				b.sendMessage(chatID, "❌ Failed to save feedback, please try again later.")
+				return nil
+			}
+			b.sendMessage(chatID, "🙏 Thanks! Your feedback has been recorded.")
+			return nil
+		case "no", "n":
+			b.sendMessage(chatID, "✅ Discarded.")
+			return nil
+		default:
+			b.sendMessage(chatID, "Please reply \"yes\" or \"no\" (or \"cancel\" to abort).")
+			return confirmStep
+		}
+	}
+	return confirmStep
+}
+
+// formatFeedbackList 格式化最近的用户反馈（管理员查看）
+func (b *Bot) formatFeedbackList(locale *userLocale, entries []database.Feedback) string {
+	if len(entries) == 0 {
+		return "📝 No feedback collected yet."
+	}
+
+	var builder strings.Builder
+	builder.WriteString("📝 *Recent Feedback*\n\n")
+	for _, entry := range entries {
+		builder.WriteString(fmt.Sprintf("👤 `%d` · %s\n%s\n\n",
+			entry.UserID, locale.MinuteTime(entry.CreatedAt), entry.Content))
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"pc28-bot/internal/logger"
+)
+
+// localeLayouts 单个语言的日期时间布局集合
+type localeLayouts struct {
+	short  string // 月日+时分秒（单条记录的紧凑展示）
+	minute string // 完整日期+时分
+	full   string // 完整日期+时分秒
+	date   string // 仅日期
+}
+
+// 各语言的日期布局（未知语言回退英文数字格式）
+var layoutsByLanguage = map[string]localeLayouts{
+	"en": {
+		short:  "01-02 15:04:05",
+		minute: "2006-01-02 15:04",
+		full:   "2006-01-02 15:04:05",
+		date:   "2006-01-02",
+	},
+	"zh": {
+		short:  "01月02日 15:04:05",
+		minute: "2006年01月02日 15:04",
+		full:   "2006年01月02日 15:04:05",
+		date:   "2006年01月02日",
+	},
+}
+
+// userLocale 用户的时区与日期格式上下文
+// 每次命令处理开始时解析一次，避免模板内逐个时间字段查库
+type userLocale struct {
+	location *time.Location
+	layouts  localeLayouts
+}
+
+// userLocale 解析用户的时区和语言设置
+// 未设置时区或时区名无效时回退服务器本地时区，未设置语言时回退英文格式
+func (b *Bot) userLocale(chatID int64) *userLocale {
+	loc := time.Local
+	if tz, err := b.db.GetUserTimezone(chatID); err != nil {
+		logger.Warnf("Failed to get user timezone: %v", err)
+	} else if tz != "" {
+		if parsed, err := time.LoadLocation(tz); err != nil {
+			logger.Warnf("Invalid user timezone %s: %v", tz, err)
+		} else {
+			loc = parsed
+		}
+	}
+
+	language := ""
+	if lang, err := b.db.GetUserLanguage(chatID); err != nil {
+		logger.Warnf("Failed to get user language: %v", err)
+	} else {
+		language = lang
+	}
+	layouts, ok := layoutsByLanguage[language]
+	if !ok {
+		layouts = layoutsByLanguage["en"]
+	}
+
+	return &userLocale{location: loc, layouts: layouts}
+}
+
+// ShortTime 按用户时区格式化为月日+时分秒
+func (ul *userLocale) ShortTime(t time.Time) string {
+	return t.In(ul.location).Format(ul.layouts.short)
+}
+
+// MinuteTime 按用户时区格式化为完整日期+时分
+func (ul *userLocale) MinuteTime(t time.Time) string {
+	return t.In(ul.location).Format(ul.layouts.minute)
+}
+
+// FullTime 按用户时区格式化为完整日期+时分秒
+func (ul *userLocale) FullTime(t time.Time) string {
+	return t.In(ul.location).Format(ul.layouts.full)
+}
+
+// Date 按用户时区格式化为仅日期
+func (ul *userLocale) Date(t time.Time) string {
+	return t.In(ul.location).Format(ul.layouts.date)
+}
+
+// handleSettingsCommand 处理个人设置命令
+// 无参数时展示当前时区与时间预览，`timezone <IANA名称>`设置时区
+func (b *Bot) handleSettingsCommand(chatID int64, args string, corrID string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		tz, err := b.db.GetUserTimezone(chatID)
+		if err != nil {
+			logger.Errorf("Failed to get user timezone: %v", err)
+			b.sendMessage(chatID, fmt.Sprintf("Failed to load settings, please try again later. (trace: %s)", corrID))
+			return
+		}
+		display := tz
+		if display == "" {
+			display = "server default"
+		}
+
+		locale := b.userLocale(chatID)
+		var builder strings.Builder
+		builder.WriteString("⚙️ *Settings*\n\n")
+		builder.WriteString(fmt.Sprintf("🌍 Timezone: `%s`\n", display))
+		builder.WriteString(fmt.Sprintf("🕐 Current time: `%s`\n\n", locale.FullTime(time.Now())))
+		builder.WriteString("Usage: /settings timezone <IANA name>\n")
+		builder.WriteString("Examples: `Asia/Shanghai`, `America/New_York`, `UTC`")
+		b.sendMessage(chatID, builder.String())
+		return
+	}
+
+	if len(fields) != 2 || fields[0] != "timezone" {
+		b.sendMessage(chatID, "Usage: /settings timezone <IANA name>")
+		return
+	}
+
+	tz := fields[1]
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("Unknown timezone `%s`. Use an IANA name like `Asia/Shanghai` or `UTC`.", tz))
+		return
+	}
+
+	if err := b.db.SetUserTimezone(chatID, tz); err != nil {
+		logger.Errorf("Failed to set user timezone: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("Failed to save settings, please try again later. (trace: %s)", corrID))
+		return
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("✅ Timezone set to `%s`. Current time there: `%s`.",
+		tz, time.Now().In(loc).Format("2006-01-02 15:04:05")))
+}
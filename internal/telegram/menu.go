@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"pc28-bot/internal/logger"
+)
+
+// menuCommand 命令菜单条目（双语描述，注册到Telegram命令自动补全）
+// admin为true的命令只注册到管理员会话的scope，普通用户的菜单中不出现
+type menuCommand struct {
+	command string
+	descEN  string
+	descZH  string
+	admin   bool
+}
+
+// menuCommands 命令菜单（顺序与/help一致，管理命令排在末尾）
+var menuCommands = []menuCommand{
+	{command: "start", descEN: "Start using the bot", descZH: "开始使用机器人"},
+	{command: "latest", descEN: "Get latest prediction results", descZH: "获取最新预测结果"},
+	{command: "history", descEN: "View recent 10 lottery records", descZH: "查看最近10期开奖记录"},
+	{command: "export", descEN: "Export lottery history", descZH: "导出开奖历史"},
+	{command: "stats", descEN: "View prediction accuracy statistics", descZH: "查看预测准确率统计"},
+	{command: "distribution", descEN: "View sum value distribution", descZH: "查看和值分布"},
+	{command: "positions", descEN: "Per-position digit frequency", descZH: "查看各位置数字分布"},
+	{command: "ev", descEN: "Expected value and Kelly stake calculator", descZH: "期望值与凯利仓位计算"},
+	{command: "heatmap", descEN: "Accuracy by weekday and hour", descZH: "按星期和小时的准确率分布"},
+	{command: "trend", descEN: "Toggle accuracy trend notifications", descZH: "开关准确率趋势通知"},
+	{command: "notify", descEN: "Toggle notification types", descZH: "设置通知类型开关"},
+	{command: "alerts", descEN: "Manage custom alert rules", descZH: "管理自定义提醒规则"},
+	{command: "settings", descEN: "View preferences and set your timezone", descZH: "查看偏好并设置时区"},
+	{command: "myrecord", descEN: "View predictions you followed", descZH: "查看跟单记录"},
+	{command: "leaderboard", descEN: "Algorithm accuracy leaderboard", descZH: "算法准确率榜单"},
+	{command: "special", descEN: "Recent pair and triple rounds", descZH: "最近的对子和豹子"},
+	{command: "profit", descEN: "Hypothetical profit and loss tracking", descZH: "假想盈亏跟踪"},
+	{command: "feedback", descEN: "Send feedback to the operators", descZH: "发送意见反馈"},
+	{command: "mydata", descEN: "Export your personal data", descZH: "导出个人数据"},
+	{command: "forgetme", descEN: "Delete your personal data", descZH: "删除个人数据"},
+	{command: "help", descEN: "Show help information", descZH: "显示帮助信息"},
+	{command: "report", descEN: "Generate operations report", descZH: "生成运营报表", admin: true},
+	{command: "audit", descEN: "View admin audit log", descZH: "查看管理操作审计", admin: true},
+	{command: "analytics", descEN: "User engagement analytics", descZH: "用户活跃度分析", admin: true},
+	{command: "timeline", descEN: "Per-round processing timeline", descZH: "单期处理时间线", admin: true},
+	{command: "policydiff", descEN: "Recheck history under a policy", descZH: "策略复判差异报告", admin: true},
+	{command: "preview", descEN: "Preview message templates", descZH: "预览消息模板", admin: true},
+	{command: "evaluate", descEN: "Backtest prediction algorithms", descZH: "回测预测算法", admin: true},
+	{command: "correct", descEN: "Correct a drawn round", descZH: "更正开奖号码", admin: true},
+	{command: "disclaimer", descEN: "Manage broadcast disclaimer", descZH: "管理广播免责声明", admin: true},
+	{command: "broadcast", descEN: "Compose a broadcast draft", descZH: "编写广播草稿", admin: true},
+	{command: "vip", descEN: "Set a user's VIP tier", descZH: "设置用户VIP层级", admin: true},
+	{command: "ban", descEN: "Ban a user", descZH: "封禁用户", admin: true},
+	{command: "unban", descEN: "Unban a user", descZH: "解除用户封禁", admin: true},
+	{command: "restart", descEN: "Restart the bot", descZH: "重启机器人", admin: true},
+}
+
+// isAdminCommand 命令是否为管理员专属（按菜单注册表判定，
+// 权限中间件据此做统一的访问控制）
+func isAdminCommand(command string) bool {
+	for _, cmd := range menuCommands {
+		if cmd.command == command {
+			return cmd.admin
+		}
+	}
+	return false
+}
+
+// registerCommandMenu 通过setMyCommands注册命令菜单（用户端获得命令自动补全）
+// 默认scope注册英文描述，zh语言scope注册中文描述；管理员会话按chat scope
+// 额外注册管理命令。命令集变化时可重复调用，Telegram端按最新注册覆盖
+func (b *Bot) registerCommandMenu() {
+	userEN := make([]tgbotapi.BotCommand, 0, len(menuCommands))
+	userZH := make([]tgbotapi.BotCommand, 0, len(menuCommands))
+	allEN := make([]tgbotapi.BotCommand, 0, len(menuCommands))
+	for _, cmd := range menuCommands {
+		allEN = append(allEN, tgbotapi.BotCommand{Command: cmd.command, Description: cmd.descEN})
+		if cmd.admin {
+			continue
+		}
+		userEN = append(userEN, tgbotapi.BotCommand{Command: cmd.command, Description: cmd.descEN})
+		userZH = append(userZH, tgbotapi.BotCommand{Command: cmd.command, Description: cmd.descZH})
+	}
+
+	if _, err := b.api.Request(tgbotapi.NewSetMyCommands(userEN...)); err != nil {
+		logger.Warnf("Failed to register command menu: %v", err)
+		return
+	}
+	if _, err := b.api.Request(tgbotapi.NewSetMyCommandsWithScopeAndLanguage(
+		tgbotapi.NewBotCommandScopeDefault(), "zh", userZH...)); err != nil {
+		logger.Warnf("Failed to register zh command menu: %v", err)
+	}
+
+	// 管理员会话额外可见管理命令
+	for _, adminID := range b.adminChatIDs {
+		if _, err := b.api.Request(tgbotapi.NewSetMyCommandsWithScope(
+			tgbotapi.NewBotCommandScopeChat(adminID), allEN...)); err != nil {
+			logger.Warnf("Failed to register admin command menu for %d: %v", adminID, err)
+		}
+	}
+
+	logger.Infof("Registered command menu: %d user commands, %d admin chats", len(userEN), len(b.adminChatIDs))
+}
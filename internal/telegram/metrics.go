@@ -0,0 +1,73 @@
+package telegram
+
+import (
+	"sync"
+	"time"
+)
+
+// slowHandlerThreshold 命令处理耗时超过该值视为慢处理
+const slowHandlerThreshold = time.Second
+
+// commandStats 单个命令的调用聚合（次数、总耗时、最大耗时、慢处理次数）
+type commandStats struct {
+	count     int64
+	sum       time.Duration
+	max       time.Duration
+	slowCount int64
+}
+
+// CommandMetrics 命令调用统计
+// 记录每个命令的调用次数与处理耗时，慢处理（如/history命中未缓存查询）
+// 会在指标中单独计数，便于从状态视图定位
+type CommandMetrics struct {
+	mutex sync.Mutex
+	stats map[string]*commandStats
+}
+
+// NewCommandMetrics 创建命令调用统计器
+func NewCommandMetrics() *CommandMetrics {
+	return &CommandMetrics{
+		stats: make(map[string]*commandStats),
+	}
+}
+
+// Observe 记录一次命令调用耗时，返回是否为慢处理
+func (cm *CommandMetrics) Observe(command string, elapsed time.Duration) bool {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	stats, ok := cm.stats[command]
+	if !ok {
+		stats = &commandStats{}
+		cm.stats[command] = stats
+	}
+
+	stats.count++
+	stats.sum += elapsed
+	if elapsed > stats.max {
+		stats.max = elapsed
+	}
+
+	slow := elapsed > slowHandlerThreshold
+	if slow {
+		stats.slowCount++
+	}
+	return slow
+}
+
+// Snapshot 导出各命令的调用聚合
+func (cm *CommandMetrics) Snapshot() map[string]interface{} {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	snapshot := make(map[string]interface{}, len(cm.stats))
+	for command, stats := range cm.stats {
+		snapshot[command] = map[string]interface{}{
+			"count": stats.count,
+			"avg":   (stats.sum / time.Duration(stats.count)).String(),
+			"max":   stats.max.String(),
+			"slow":  stats.slowCount,
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,143 @@
+package telegram
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"pc28-bot/internal/logger"
+)
+
+// commandContext 单次命令调用的上下文（沿中间件链传递到处理器）
+type commandContext struct {
+	message *tgbotapi.Message
+	chatID  int64
+	command string
+	args    string
+	corrID  string
+}
+
+// commandHandler 命令处理函数（中间件链的末端为dispatchCommand）
+type commandHandler func(ctx *commandContext)
+
+// commandMiddleware 命令中间件：包装下一级处理函数，可在调用前后
+// 拦截或附加行为。新命令接入dispatchCommand后自动获得整条链的
+// 访问控制与观测能力，无需在处理器内重复检查
+type commandMiddleware func(b *Bot, next commandHandler) commandHandler
+
+// commandMiddlewares 命令中间件链（按声明顺序从外到内执行）：
+// 崩溃恢复 → 私聊校验 → 封禁 → 限流 → 权限 → 活跃度记录 → 耗时统计
+var commandMiddlewares = []commandMiddleware{
+	recoveryMiddleware,
+	privateOnlyMiddleware,
+	banMiddleware,
+	rateLimitMiddleware,
+	authMiddleware,
+	activityMiddleware,
+	metricsMiddleware,
+}
+
+// buildCommandChain 组装命令处理链（NewBot时调用一次）
+func (b *Bot) buildCommandChain() commandHandler {
+	handler := b.dispatchCommand
+	for i := len(commandMiddlewares) - 1; i >= 0; i-- {
+		handler = commandMiddlewares[i](b, handler)
+	}
+	return handler
+}
+
+// recoveryMiddleware 捕获处理器崩溃，避免单条消息拖垮更新循环
+func recoveryMiddleware(b *Bot, next commandHandler) commandHandler {
+	return func(ctx *commandContext) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.WithFields(logger.Fields{
+					"module":  "telegram",
+					"chat_id": ctx.chatID,
+					"corr_id": ctx.corrID,
+				}).Errorf("Command handler panicked: /%s: %v", ctx.command, r)
+				b.sendMessage(ctx.chatID, fmt.Sprintf("❌ Something went wrong, please try again later. (trace: %s)", ctx.corrID))
+			}
+		}()
+		next(ctx)
+	}
+}
+
+// privateOnlyMiddleware 只在私聊中处理命令
+func privateOnlyMiddleware(b *Bot, next commandHandler) commandHandler {
+	return func(ctx *commandContext) {
+		if !ctx.message.Chat.IsPrivate() {
+			return
+		}
+		next(ctx)
+	}
+}
+
+// banMiddleware 封禁用户的命令直接丢弃
+func banMiddleware(b *Bot, next commandHandler) commandHandler {
+	return func(ctx *commandContext) {
+		if b.isBanned(ctx.chatID) {
+			logger.Debugf("Dropping command from banned user %d", ctx.chatID)
+			return
+		}
+		next(ctx)
+	}
+}
+
+// rateLimitMiddleware 超出消息频率限制的命令不处理
+func rateLimitMiddleware(b *Bot, next commandHandler) commandHandler {
+	return func(ctx *commandContext) {
+		if !b.checkRateLimit(ctx.chatID) {
+			return
+		}
+		next(ctx)
+	}
+}
+
+// authMiddleware 管理命令的统一权限检查（按菜单注册表判定）
+// 对非管理员伪装成未知命令，不暴露管理命令的存在
+func authMiddleware(b *Bot, next commandHandler) commandHandler {
+	return func(ctx *commandContext) {
+		if isAdminCommand(ctx.command) && !b.isAdmin(ctx.chatID) {
+			b.sendMessage(ctx.chatID, "Unknown command. Type /help to view available commands.")
+			return
+		}
+		next(ctx)
+	}
+}
+
+// activityMiddleware 记录命令日志与用户活跃度（记录失败不影响命令处理）
+func activityMiddleware(b *Bot, next commandHandler) commandHandler {
+	return func(ctx *commandContext) {
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": ctx.chatID,
+			"corr_id": ctx.corrID,
+		}).Debugf("Received private command: %s", ctx.command)
+
+		if err := b.db.RecordUserActivity(ctx.chatID, ctx.command); err != nil {
+			logger.Warnf("Failed to record user activity: %v", err)
+		}
+		next(ctx)
+	}
+}
+
+// metricsMiddleware 记录命令调用耗时，慢处理单独告警日志
+func metricsMiddleware(b *Bot, next commandHandler) commandHandler {
+	return func(ctx *commandContext) {
+		startTime := time.Now()
+		defer func() {
+			elapsed := time.Since(startTime)
+			if b.cmdMetrics.Observe(ctx.command, elapsed) {
+				logger.WithFields(logger.Fields{
+					"module":  "telegram",
+					"chat_id": ctx.chatID,
+					"corr_id": ctx.corrID,
+					"elapsed": elapsed.String(),
+				}).Warnf("Slow command handler: /%s", ctx.command)
+			}
+		}()
+		next(ctx)
+	}
+}
@@ -0,0 +1,75 @@
+package telegram
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/predictor"
+)
+
+// handlePolicyDiffCommand 处理判定策略复判命令（管理员专用）
+// 用指定策略复判留存的已验证预测并回传新旧口径差异，历史is_correct不改写，
+// 复判结果落在predictions表的recheck_*平行列供事后核查
+func (b *Bot) handlePolicyDiffCommand(chatID int64, args, corrID string) {
+	policyName := strings.TrimSpace(args)
+	if policyName == "" {
+		b.sendMessage(chatID, "Usage: /policydiff <odd_even|big_small|exact|positions>")
+		return
+	}
+
+	policy, err := predictor.NewCorrectnessPolicy(policyName)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Unknown policy `%s`. Available: odd_even, big_small, exact, positions", policyName))
+		return
+	}
+
+	validator := predictor.NewValidator(b.db, nil)
+	diff, err := validator.RecomputeUnderPolicy(policy)
+	if err != nil {
+		logger.Errorf("Failed to recompute under policy %s: %v", policyName, err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ Policy recheck failed. (trace: %s)", corrID))
+		return
+	}
+
+	b.recordAudit(chatID, "policy_diff", policyName)
+	b.sendMessage(chatID, formatPolicyDiff(diff))
+}
+
+// formatPolicyDiff 渲染策略复判差异报告
+func formatPolicyDiff(diff *predictor.PolicyDiff) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧮 *Policy Recheck Report* (`%s`)\n\n", diff.NewPolicy))
+
+	if diff.Total == 0 {
+		sb.WriteString("No verified predictions available for recheck.")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("Rechecked Predictions: `%d`\n", diff.Total))
+	sb.WriteString(fmt.Sprintf("Stored Accuracy: `%.2f%%` (%d correct)\n", diff.StoredAccuracy, diff.StoredCorrect))
+	sb.WriteString(fmt.Sprintf("Recheck Accuracy: `%.2f%%` (%d correct)\n", diff.RecheckAccuracy, diff.RecheckCorrect))
+	sb.WriteString(fmt.Sprintf("Flipped Verdicts: `%d`\n", diff.Flipped))
+
+	if len(diff.FlippedSample) > 0 {
+		sb.WriteString(fmt.Sprintf("Sample Flipped Rounds: `%s`\n", strings.Join(diff.FlippedSample, "`, `")))
+	}
+
+	// 原判定策略分布（固定排序保证报告可复现比对）
+	if len(diff.StoredPolicies) > 0 {
+		var names []string
+		for name := range diff.StoredPolicies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		sb.WriteString("\n📋 *Stored Policy Mix*\n")
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("`%s`: %d\n", name, diff.StoredPolicies[name]))
+		}
+	}
+
+	sb.WriteString("\n💡 *Note*: Stored results are unchanged; recheck verdicts live in a parallel column")
+	return sb.String()
+}
@@ -0,0 +1,82 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"pc28-bot/internal/logger"
+)
+
+// handleMyDataCommand 处理个人数据导出命令
+// 将该用户存储的全部个人数据（登记信息、通知偏好、提醒规则、
+// 跟单记录、反馈）汇总为JSON文件发送给本人
+func (b *Bot) handleMyDataCommand(chatID int64, corrID string) {
+	export, err := b.db.GetUserDataExport(chatID)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to export your data, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to export user data: %v", err)
+		return
+	}
+
+	content, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to export your data, please try again later. (trace: %s)", corrID))
+		logger.Errorf("Failed to encode user data export for %d: %v", chatID, err)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("my_data_%s.json", time.Now().Format("20060102_150405")),
+		Bytes: content,
+	})
+	doc.Caption = "📦 Everything this bot stores about you. Use /forgetme to delete it."
+
+	if _, err := b.api.Send(doc); err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to send export file, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to send user data export: %v", err)
+	}
+}
+
+// handleForgetMeCommand 处理个人数据删除命令
+// 二次确认后删除该用户的全部个人数据（封禁记录除外）；
+// 删除后再次使用命令会重新登记为新用户
+func (b *Bot) handleForgetMeCommand(chatID int64) {
+	b.startConversation(chatID, b.forgetMeConfirmStep)
+	b.sendMessage(chatID, "⚠️ This will permanently delete your subscription, preferences, "+
+		"alert rules, follow records and feedback.\n\n"+
+		"Reply \"delete\" to confirm, or \"cancel\" to abort.\n"+
+		"Tip: use /mydata first if you want a copy.")
+}
+
+// forgetMeConfirmStep 删除会话确认步骤
+func (b *Bot) forgetMeConfirmStep(chatID int64, text string) conversationStep {
+	if strings.ToLower(strings.TrimSpace(text)) != "delete" {
+		b.sendMessage(chatID, "Please reply \"delete\" to confirm, or \"cancel\" to abort.")
+		return b.forgetMeConfirmStep
+	}
+
+	deleted, err := b.db.DeleteUserData(chatID)
+	if err != nil {
+		logger.Errorf("Failed to delete user data for %d: %v", chatID, err)
+		b.sendMessage(chatID, "❌ Failed to delete your data, please try again later.")
+		return nil
+	}
+
+	logger.Infof("Deleted %d personal data record(s) for user %d on request", deleted, chatID)
+	b.sendMessage(chatID, fmt.Sprintf(
+		"🗑 Done. %d record(s) about you have been deleted.\n"+
+			"Using the bot again will register you as a new user.", deleted))
+	return nil
+}
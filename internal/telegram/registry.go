@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultTemplates 内置出站消息模板
+// 运维可在模板目录放置同名的.tmpl文件覆盖措辞、emoji和品牌文案，无需重新编译
+var defaultTemplates = map[string]string{
+	"welcome": `🎮 Welcome to PC28 Prediction Bot!
+
+🤖 I am your intelligent prediction assistant, providing you with:
+• 📊 Latest lottery results
+• 🔮 Smart prediction results
+• 📈 Historical prediction records
+• 📊 Accuracy statistics
+
+📝 Available commands:
+/latest - View latest predictions
+/history - View lottery records
+/stats - View statistics
+/help - Help information
+
+⚠️ Note: This bot only provides services in private chats
+🔔 The bot will automatically push the latest prediction results!`,
+
+	"welcome_zh": `🎮 欢迎使用PC28预测机器人！
+
+🤖 我是您的智能预测助手，为您提供：
+• 📊 最新开奖结果
+• 🔮 智能预测结果
+• 📈 历史预测记录
+• 📊 准确率统计
+
+📝 可用命令：
+/latest - 查看最新预测
+/history - 查看开奖记录
+/stats - 查看统计信息
+/help - 帮助信息
+
+⚠️ 注意：机器人仅在私聊中提供服务
+🔔 机器人会自动推送最新预测结果！`,
+
+	"new_prediction": `🚨 *New Round Prediction Push*
+
+{{if .Latest}}📊 *Latest Result*
+Round: ` + "`{{.Latest.Qihao}}`" + `
+Numbers: ` + "`{{.Latest.OpenNum}}`" + `
+Sum: ` + "`{{.Latest.SumValue}}`" + `
+
+{{end}}🔮 *Next Round Prediction*
+Round: ` + "`{{.Prediction.TargetQihao}}`" + `
+Numbers: ` + "`{{.Prediction.PredictedNum}}`" + `
+Pick: ` + "`{{.OddEven}}` / `{{.BigSmall}}`" + `
+{{if .History}}
+📜 *Recent Rounds*
+{{range .History}}` + "`{{.Qihao}}`" + ` {{.Mark}}
+{{end}}{{end}}{{if .StreakLine}}{{.StreakLine}}
+{{end}}{{if .LowConsensus}}⚠️ Low consensus: algorithms disagree this round
+{{end}}{{if .Countdown}}⏳ Next draw in {{.Countdown}}
+{{end}}
+💡 Send /latest for details`,
+
+	"verification": `✅ *Prediction Verification Result*
+
+Round: ` + "`{{.Qihao}}`" + `
+Predicted Numbers: ` + "`{{.PredictedNum}}`" + `
+Actual Numbers: ` + "`{{.ActualNum}}`" + `
+{{if .IsCorrect}}🎉 *Prediction Correct!*{{else}}😅 *Prediction Wrong*{{end}}
+`,
+
+	"error": `❌ *System Error*
+
+Error Type: ` + "`{{.ErrorType}}`" + `
+{{if .Details}}Details: ` + "`{{.Details}}`" + `
+{{end}}Occurred Time: ` + "`{{formatTime .OccurredAt \"2006-01-02 15:04:05\"}}`" + `
+
+Please try again later or contact the administrator`,
+
+	"maintenance": `🔧 *System Maintenance Notice*
+
+Maintenance Reason: {{.Reason}}
+Estimated Duration: {{.EstimatedTime}}
+Start Time: {{formatTime .StartTime "2006-01-02 15:04:05"}}
+
+Service may be temporarily unavailable during maintenance, please wait patiently`,
+
+	"countdown": `⏳ Next draw in {{printf "%02d:%02d" .Minutes .Seconds}}`,
+
+	"maintenance_pause": `⏸ Draws paused for daily maintenance`,
+}
+
+// templateFuncs 模板内可用的辅助函数
+var templateFuncs = template.FuncMap{
+	"formatTime": func(t time.Time, layout string) string {
+		return t.Format(layout)
+	},
+}
+
+// TemplateRegistry 出站消息模板注册表
+type TemplateRegistry struct {
+	templates map[string]*template.Template
+}
+
+// NewTemplateRegistry 创建模板注册表
+// 先解析全部内置模板，再用dir下的同名.tmpl文件覆盖；
+// 任一模板解析失败都会返回错误，保证问题在启动期暴露而非发消息时
+func NewTemplateRegistry(dir string) (*TemplateRegistry, error) {
+	registry := &TemplateRegistry{
+		templates: make(map[string]*template.Template),
+	}
+
+	for name, text := range defaultTemplates {
+		if err := registry.parse(name, text); err != nil {
+			return nil, fmt.Errorf("invalid built-in template %s: %v", name, err)
+		}
+	}
+
+	if dir == "" {
+		return registry, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template dir %s: %v", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tmpl") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file %s: %v", entry.Name(), err)
+		}
+		if err := registry.parse(name, string(data)); err != nil {
+			return nil, fmt.Errorf("invalid template file %s: %v", entry.Name(), err)
+		}
+	}
+
+	return registry, nil
+}
+
+// parse 解析并注册单个模板（同名覆盖）
+func (tr *TemplateRegistry) parse(name, text string) error {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+	tr.templates[name] = tmpl
+	return nil
+}
+
+// Render 渲染指定名称的模板
+func (tr *TemplateRegistry) Render(name string, data interface{}) (string, error) {
+	tmpl, ok := tr.templates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown template: %s", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+// Names 返回全部已注册的模板名称（按字典序）
+func (tr *TemplateRegistry) Names() []string {
+	names := make([]string, 0, len(tr.templates))
+	for name := range tr.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
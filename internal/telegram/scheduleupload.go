@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/schedule"
+)
+
+// scheduleFileMaxSize 计划文件大小上限（计划内容很小，过大文件直接拒绝）
+const scheduleFileMaxSize = 64 * 1024
+
+// handleDocument 处理文档消息
+// 目前仅支持管理员上传推送计划文件（YAML/JSON），校验通过后存入DB，
+// 推送循环在下次检查时生效；非管理员的文档消息直接忽略
+func (b *Bot) handleDocument(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if !b.isAdmin(chatID) {
+		logger.Debugf("Ignoring document from non-admin user %d", chatID)
+		return
+	}
+
+	doc := message.Document
+	name := strings.ToLower(doc.FileName)
+	if !strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml") && !strings.HasSuffix(name, ".json") {
+		b.sendMessage(chatID, "❌ Unsupported file type. Upload a .yaml or .json push schedule file.")
+		return
+	}
+	if doc.FileSize > scheduleFileMaxSize {
+		b.sendMessage(chatID, "❌ Schedule file is too large (max 64 KB).")
+		return
+	}
+
+	data, err := b.downloadDocument(doc.FileID)
+	if err != nil {
+		b.sendMessage(chatID, "❌ Failed to download the file, please try again.")
+		logger.Errorf("Failed to download schedule file from %d: %v", chatID, err)
+		return
+	}
+
+	pushSchedule, err := schedule.ParsePushSchedule(data)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Invalid schedule file: %v", err))
+		return
+	}
+
+	encoded, err := pushSchedule.Encode()
+	if err != nil {
+		b.sendMessage(chatID, "❌ Failed to store the schedule, please try again.")
+		logger.Errorf("Failed to encode push schedule: %v", err)
+		return
+	}
+	if err := b.db.SaveAppState(schedule.PushScheduleStateKey, encoded); err != nil {
+		b.sendMessage(chatID, "❌ Failed to store the schedule, please try again.")
+		logger.Errorf("Failed to save push schedule: %v", err)
+		return
+	}
+
+	b.recordAudit(chatID, "push_schedule_update", doc.FileName)
+	b.sendMessage(chatID, fmt.Sprintf(
+		"✅ *Push schedule updated*\n\n"+
+			"🌅 Daily summary: %s\n"+
+			"📅 Weekly report: %s\n"+
+			"🗓 Monthly report: day %d",
+		pushSchedule.DailySummaryTime, pushSchedule.ReportWeekday(),
+		pushSchedule.MonthlyReportDay))
+}
+
+// downloadDocument 下载Telegram文档内容（带大小上限）
+func (b *Bot) downloadDocument(fileID string) ([]byte, error) {
+	url, err := b.api.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve file url: %v", err)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch file: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected file download status: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, scheduleFileMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	if len(data) > scheduleFileMaxSize {
+		return nil, fmt.Errorf("file exceeds %d bytes", scheduleFileMaxSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,81 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"pc28-bot/internal/logger"
+)
+
+// statsWindows /stats支持的滚动窗口档位（0表示全量口径）
+var statsWindows = []int{50, 100, 500, 0}
+
+// statsWindowKeyboard 构建统计窗口选择键盘（回调数据为stats_win_<N>）
+func statsWindowKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+	for _, window := range statsWindows {
+		label := "All"
+		if window > 0 {
+			label = fmt.Sprintf("Last %d", window)
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("stats_win_%d", window)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(buttons...))
+}
+
+// sendStatsMessage 发送指定窗口的统计消息（附窗口选择按钮）
+func (b *Bot) sendStatsMessage(chatID int64, window int, corrID string) {
+	stats, err := b.cacheManager.GetPredictionStatsWindow(window)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to get statistics, please try again later. (trace: %s)", corrID))
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to get prediction stats: %v", err)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, b.formatStatsMessage(b.userLocale(chatID), stats, window))
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.ReplyMarkup = statsWindowKeyboard()
+	if _, err := b.api.Send(msg); err != nil {
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+			"corr_id": corrID,
+		}).Errorf("Failed to send stats message: %v", err)
+	}
+}
+
+// handleStatsWindowCallback 处理统计窗口切换回调（原地编辑统计消息）
+func (b *Bot) handleStatsWindowCallback(callback *tgbotapi.CallbackQuery, chatID int64, data string) {
+	window, err := strconv.Atoi(strings.TrimPrefix(data, "stats_win_"))
+	if err != nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	stats, statsErr := b.cacheManager.GetPredictionStatsWindow(window)
+	if statsErr != nil {
+		logger.WithFields(logger.Fields{
+			"module":  "telegram",
+			"chat_id": chatID,
+		}).Errorf("Failed to get windowed prediction stats: %v", statsErr)
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "Failed to load statistics"))
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, callback.Message.MessageID,
+		b.formatStatsMessage(b.userLocale(chatID), stats, window), statsWindowKeyboard())
+	edit.ParseMode = tgbotapi.ModeMarkdown
+	if _, err := b.api.Send(edit); err != nil {
+		// 窗口内容未变化时Telegram会返回message is not modified，仅记录调试日志
+		logger.Debugf("Failed to edit stats message for %d: %v", chatID, err)
+	}
+
+	b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+}
@@ -2,16 +2,19 @@ package telegram
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+	"pc28-bot/internal/predictor"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 // formatLatestDataMessage 格式化最新数据消息
-func (b *Bot) formatLatestDataMessage(latest *database.LotteryResult, prediction *database.Prediction) string {
+func (b *Bot) formatLatestDataMessage(locale *userLocale, latest *database.LotteryResult, prediction *database.Prediction) string {
 	var builder strings.Builder
 
 	// 标题
@@ -22,7 +25,7 @@ func (b *Bot) formatLatestDataMessage(latest *database.LotteryResult, prediction
 	builder.WriteString(fmt.Sprintf("Round: `%s`\n", latest.Qihao))
 	builder.WriteString(fmt.Sprintf("Numbers: `%s`\n", latest.OpenNum))
 	builder.WriteString(fmt.Sprintf("Sum: `%d`\n", latest.SumValue))
-	builder.WriteString(fmt.Sprintf("Time: `%s`\n\n", latest.OpenTime.Format("01-02 15:04:05")))
+	builder.WriteString(fmt.Sprintf("Time: `%s`\n\n", locale.ShortTime(latest.OpenTime)))
 
 	// 最新预测信息
 	if prediction != nil {
@@ -32,7 +35,7 @@ func (b *Bot) formatLatestDataMessage(latest *database.LotteryResult, prediction
 
 		// 移除置信度显示
 
-		builder.WriteString(fmt.Sprintf("Prediction Time: `%s`\n", prediction.PredictedAt.Format("01-02 15:04:05")))
+		builder.WriteString(fmt.Sprintf("Prediction Time: `%s`\n", locale.ShortTime(prediction.PredictedAt)))
 
 		// 如果已验证，显示结果
 		if prediction.IsCorrect != nil {
@@ -51,6 +54,195 @@ func (b *Bot) formatLatestDataMessage(latest *database.LotteryResult, prediction
 	return builder.String()
 }
 
+// formatHeatmapMessage 格式化准确率热力图消息（用灰度块字符渲染）
+func (b *Bot) formatHeatmapMessage(locale *userLocale, heatmap *predictor.HeatmapData) string {
+	var builder strings.Builder
+
+	builder.WriteString("🗓 *Accuracy Heatmap (weekday × hour)*\n\n")
+
+	weekdays := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+	hasData := false
+
+	builder.WriteString("```\n")
+	builder.WriteString("    0     6     12    18    23\n")
+	for weekday := 0; weekday < 7; weekday++ {
+		builder.WriteString(weekdays[weekday] + " ")
+		for hour := 0; hour < 24; hour++ {
+			if heatmap.Samples[weekday][hour] == 0 {
+				builder.WriteString("·")
+				continue
+			}
+			hasData = true
+			// 准确率映射到四级灰度：<45% ░，45-55% ▒，55-65% ▓，>65% █
+			accuracy := heatmap.Accuracy[weekday][hour]
+			switch {
+			case accuracy < 45:
+				builder.WriteString("░")
+			case accuracy < 55:
+				builder.WriteString("▒")
+			case accuracy < 65:
+				builder.WriteString("▓")
+			default:
+				builder.WriteString("█")
+			}
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString("```\n")
+
+	if !hasData {
+		return "🗓 No verified predictions available for the heatmap yet."
+	}
+
+	builder.WriteString("`░`<45% `▒`45-55% `▓`55-65% `█`>65% `·`no data\n")
+	builder.WriteString(fmt.Sprintf("Generated: `%s`", locale.ShortTime(heatmap.GeneratedAt)))
+	return builder.String()
+}
+
+// formatEVMessage 格式化期望值分析消息
+func (b *Bot) formatEVMessage(analysis *predictor.EVAnalysis, sampleSize int) string {
+	var builder strings.Builder
+
+	builder.WriteString("🧮 *Expected Value Analysis*\n\n")
+	builder.WriteString(fmt.Sprintf("Measured Accuracy: `%.2f%%` (%d rounds)\n", analysis.Accuracy*100, sampleSize))
+	builder.WriteString(fmt.Sprintf("Odds (net payout): `%.2f`\n", analysis.Odds))
+	builder.WriteString(fmt.Sprintf("Break-even Accuracy: `%.2f%%`\n\n", analysis.BreakEvenAccuracy*100))
+
+	builder.WriteString(fmt.Sprintf("Expected Value per unit: `%+.4f`\n", analysis.ExpectedValue))
+	if analysis.KellyFraction > 0 {
+		builder.WriteString(fmt.Sprintf("Kelly Stake Fraction: `%.2f%%`\n", analysis.KellyFraction*100))
+	} else {
+		builder.WriteString("Kelly Stake Fraction: `0%` (negative edge)\n")
+	}
+
+	builder.WriteString("\n💡 *Tips*: Predictions are for reference only, please be rational")
+	return builder.String()
+}
+
+// formatDistributionMessage 格式化和值分布消息
+func (b *Bot) formatDistributionMessage(distribution map[int]int, window int) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("📊 *Sum Value Distribution (last %d rounds)*\n\n", window))
+
+	total := 0
+	maxCount := 0
+	for _, count := range distribution {
+		total += count
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+	if total == 0 {
+		builder.WriteString("No lottery data available yet.")
+		return builder.String()
+	}
+
+	// 0-27直方图（按最大值缩放到10格）
+	for sum := 0; sum <= 27; sum++ {
+		count, ok := distribution[sum]
+		if !ok {
+			continue
+		}
+		barLength := count * 10 / maxCount
+		if barLength == 0 {
+			barLength = 1
+		}
+		builder.WriteString(fmt.Sprintf("`%2d` %s %d\n", sum, strings.Repeat("▇", barLength), count))
+	}
+
+	// 大小与单双比例（和值>=14为大）
+	big, odd := 0, 0
+	for sum, count := range distribution {
+		if sum >= 14 {
+			big += count
+		}
+		if sum%2 == 1 {
+			odd += count
+		}
+	}
+	builder.WriteString(fmt.Sprintf("\n🔺 Big/Small: `%.1f%%` / `%.1f%%`\n",
+		float64(big)/float64(total)*100, float64(total-big)/float64(total)*100))
+	builder.WriteString(fmt.Sprintf("🔸 Odd/Even: `%.1f%%` / `%.1f%%`\n",
+		float64(odd)/float64(total)*100, float64(total-odd)/float64(total)*100))
+
+	// 边界和值出现频率（13/14为大小分界）
+	builder.WriteString(fmt.Sprintf("⚖️ Edge values: `13`×%d `14`×%d\n",
+		distribution[13], distribution[14]))
+
+	return builder.String()
+}
+
+// formatPositionsMessage 格式化各位置数字分布消息
+func (b *Bot) formatPositionsMessage(distribution database.PositionDistribution, window int) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("🎰 *Position Digit Frequency (last %d rounds)*\n\n", window))
+
+	total := 0
+	for _, count := range distribution[0] {
+		total += count
+	}
+	if total == 0 {
+		builder.WriteString("No lottery data available yet.")
+		return builder.String()
+	}
+
+	for position, counts := range distribution {
+		builder.WriteString(fmt.Sprintf("🔢 *Position %d*\n", position+1))
+
+		maxCount := 0
+		for _, count := range counts {
+			if count > maxCount {
+				maxCount = count
+			}
+		}
+
+		// 0-9直方图（按最大值缩放到8格）
+		for digit, count := range counts {
+			if count == 0 {
+				continue
+			}
+			barLength := count * 8 / maxCount
+			if barLength == 0 {
+				barLength = 1
+			}
+			builder.WriteString(fmt.Sprintf("`%d` %s %d\n", digit, strings.Repeat("▇", barLength), count))
+		}
+		builder.WriteString("\n")
+	}
+
+	builder.WriteString("💡 *Note*: Raw draw statistics for your own analysis")
+	return builder.String()
+}
+
+// formatAuditLogMessage 格式化审计记录消息
+func (b *Bot) formatAuditLogMessage(locale *userLocale, entries []database.AuditEntry) string {
+	var builder strings.Builder
+
+	builder.WriteString("📋 *Recent Admin Actions*\n\n")
+
+	if len(entries) == 0 {
+		builder.WriteString("No audit records yet.")
+		return builder.String()
+	}
+
+	for _, entry := range entries {
+		actor := fmt.Sprintf("%d", entry.Actor)
+		if entry.Actor == 0 {
+			actor = "system"
+		}
+		builder.WriteString(fmt.Sprintf("`%s` %s by `%s`",
+			locale.ShortTime(entry.CreatedAt), entry.Action, actor))
+		if entry.Params != "" {
+			builder.WriteString(fmt.Sprintf(" (%s)", entry.Params))
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
 // formatHistoryMessage 格式化历史记录消息
 func (b *Bot) formatHistoryMessage(predictions []database.Prediction) string {
 	var builder strings.Builder
@@ -185,7 +377,7 @@ func (b *Bot) formatPredictionHistoryMessage(predictions []database.Prediction)
 }
 
 // formatLotteryHistoryMessage 格式化历史开奖消息
-func (b *Bot) formatLotteryHistoryMessage(lotteryHistory []database.LotteryResult) string {
+func (b *Bot) formatLotteryHistoryMessage(locale *userLocale, lotteryHistory []database.LotteryResult) string {
 	var builder strings.Builder
 
 	builder.WriteString("📊 *Recent 10 Lottery Records*\n\n")
@@ -222,7 +414,12 @@ func (b *Bot) formatLotteryHistoryMessage(lotteryHistory []database.LotteryResul
 		//          Time: 08-23 10:15
 		builder.WriteString(fmt.Sprintf("Round `%s`\n", result.Qihao))
 		builder.WriteString(fmt.Sprintf("   Numbers: `%s=%d` (%s %s)\n", result.OpenNum, result.SumValue, sizePattern, oddEvenPattern))
-		builder.WriteString(fmt.Sprintf("   Time: `%s`\n", result.OpenTimeString))
+		// 开奖时间按用户时区展示（解析失败的旧数据回退API原始字符串）
+		openTime := result.OpenTimeString
+		if !result.OpenTime.IsZero() {
+			openTime = locale.ShortTime(result.OpenTime)
+		}
+		builder.WriteString(fmt.Sprintf("   Time: `%s`\n", openTime))
 		builder.WriteString("\n")
 	}
 
@@ -243,24 +440,35 @@ func (b *Bot) formatLotteryHistoryMessage(lotteryHistory []database.LotteryResul
 	return builder.String()
 }
 
-// formatStatsMessage 格式化统计信息消息
-func (b *Bot) formatStatsMessage(stats *database.PredictionStats) string {
+// formatStatsMessage 格式化统计信息消息（window>0时标题标注滚动窗口口径）
+func (b *Bot) formatStatsMessage(locale *userLocale, stats *database.PredictionStats, window int) string {
 	var builder strings.Builder
 
-	builder.WriteString("📊 *Prediction Statistics*\n\n")
+	if window > 0 {
+		builder.WriteString(fmt.Sprintf("📊 *Prediction Statistics* (last %d)\n\n", window))
+	} else {
+		builder.WriteString("📊 *Prediction Statistics*\n\n")
+	}
 
 	// 基本统计
 	builder.WriteString("🎯 *Overall Performance*\n")
 	builder.WriteString(fmt.Sprintf("Total Predictions: `%d`\n", stats.TotalPredictions))
 	builder.WriteString(fmt.Sprintf("Correct Predictions: `%d`\n", stats.CorrectPredictions))
 	builder.WriteString(fmt.Sprintf("Wrong Predictions: `%d`\n", stats.TotalPredictions-stats.CorrectPredictions))
-	builder.WriteString(fmt.Sprintf("Overall Accuracy: `%.2f%%`\n\n", stats.AccuracyRate))
+	builder.WriteString(fmt.Sprintf("Overall Accuracy: `%.2f%%`\n", stats.AccuracyRate))
+
+	// Wilson置信区间：小样本下区间自动变宽，提示准确率的统计意义有限
+	if stats.TotalPredictions > 0 {
+		low, high := predictor.WilsonInterval(stats.CorrectPredictions, stats.TotalPredictions)
+		builder.WriteString(fmt.Sprintf("95%% Confidence: `%.1f%% – %.1f%%`\n", low, high))
+	}
+	builder.WriteString("\n")
 
 	// 时间信息
 	if !stats.FirstPrediction.IsZero() {
 		builder.WriteString("⏰ *Time Span*\n")
-		builder.WriteString(fmt.Sprintf("First Prediction: `%s`\n", stats.FirstPrediction.Format("2006-01-02 15:04")))
-		builder.WriteString(fmt.Sprintf("Latest Prediction: `%s`\n", stats.LastPrediction.Format("2006-01-02 15:04")))
+		builder.WriteString(fmt.Sprintf("First Prediction: `%s`\n", locale.MinuteTime(stats.FirstPrediction)))
+		builder.WriteString(fmt.Sprintf("Latest Prediction: `%s`\n", locale.MinuteTime(stats.LastPrediction)))
 
 		duration := stats.LastPrediction.Sub(stats.FirstPrediction)
 		days := int(duration.Hours() / 24)
@@ -271,6 +479,16 @@ func (b *Bot) formatStatsMessage(stats *database.PredictionStats) string {
 	rating := b.calculatePerformanceRating(stats.AccuracyRate)
 	builder.WriteString(fmt.Sprintf("🏆 *Performance Rating*: %s\n\n", rating))
 
+	// 影子算法共识度（注册多个算法时才有数据）
+	if stats.AvgDisagreement != nil {
+		builder.WriteString(fmt.Sprintf("🤝 *Algorithm Consensus*: avg disagreement `%.1f%%`\n\n", *stats.AvgDisagreement))
+	}
+
+	// 覆盖缺口（漏预测的轮次，不计入准确率分母）
+	if stats.CoverageGaps > 0 {
+		builder.WriteString(fmt.Sprintf("⚠️ *Coverage Gaps*: `%d` rounds drawn without a prediction\n\n", stats.CoverageGaps))
+	}
+
 	// 提示信息
 	builder.WriteString("💡 *Note*: Statistics are based on verified prediction results")
 
@@ -279,50 +497,79 @@ func (b *Bot) formatStatsMessage(stats *database.PredictionStats) string {
 
 // 移除了 formatPredictionMessage 函数
 
-// formatNewPredictionBroadcast 格式化新预测广播消息
-func (b *Bot) formatNewPredictionBroadcast(prediction *database.Prediction, latestResult *database.LotteryResult) string {
-	var builder strings.Builder
+// renderTemplate 渲染注册表中的模板
+// 模板在启动期已校验，这里的失败只可能来自数据问题，记录日志并返回兜底文案
+func (b *Bot) renderTemplate(name string, data interface{}) string {
+	text, err := b.templates.Render(name, data)
+	if err != nil {
+		logger.Errorf("Failed to render template %s: %v", name, err)
+		return fmt.Sprintf("⚠️ Message unavailable (template: %s)", name)
+	}
+	return text
+}
+
+// formatNewPredictionBroadcast 格式化新预测卡片消息
+func (b *Bot) formatNewPredictionBroadcast(card *database.PredictionCard) string {
+	return b.renderTemplate("new_prediction", b.predictionCardData(card))
+}
 
-	builder.WriteString("🚨 *New Round Prediction Push*\n\n")
+// predictionCardData 将预测卡片转换为模板数据
+func (b *Bot) predictionCardData(card *database.PredictionCard) map[string]interface{} {
+	prediction := card.Prediction
 
-	// 最新开奖信息
-	if latestResult != nil {
-		builder.WriteString("📊 *Latest Result*\n")
-		builder.WriteString(fmt.Sprintf("Round: `%s`\n", latestResult.Qihao))
-		builder.WriteString(fmt.Sprintf("Numbers: `%s`\n", latestResult.OpenNum))
-		builder.WriteString(fmt.Sprintf("Sum: `%d`\n\n", latestResult.SumValue))
+	bigSmall := "Small"
+	if prediction.PredictedSum >= 14 {
+		bigSmall = "Big"
 	}
 
-	// 新预测信息
-	builder.WriteString("🔮 *Next Round Prediction*\n")
-	builder.WriteString(fmt.Sprintf("Round: `%s`\n", prediction.TargetQihao))
-	builder.WriteString(fmt.Sprintf("Numbers: `%s`\n", prediction.PredictedNum))
+	// 近期战绩（新到旧）
+	var history []map[string]interface{}
+	for _, pred := range card.MiniHistory {
+		mark := "✅"
+		if pred.IsCorrect != nil && !*pred.IsCorrect {
+			mark = "❌"
+		}
+		history = append(history, map[string]interface{}{
+			"Qihao": pred.TargetQihao,
+			"Mark":  mark,
+		})
+	}
 
-	// 移除置信度显示
+	streakLine := ""
+	if card.Streak > 1 {
+		streakLine = fmt.Sprintf("🔥 Win streak: %d rounds", card.Streak)
+	} else if card.Streak < -1 {
+		streakLine = fmt.Sprintf("🧊 Losing streak: %d rounds", -card.Streak)
+	}
 
-	// 添加快捷按钮提示
-	builder.WriteString("\n💡 Send /latest for details")
+	countdown := ""
+	if card.NextDrawIn > 0 {
+		countdown = fmt.Sprintf("%02d:%02d", int(card.NextDrawIn.Minutes()), int(card.NextDrawIn.Seconds())%60)
+	}
 
-	return builder.String()
+	// 影子算法分歧度过半时标记低共识（历史上与较低准确率相关）
+	lowConsensus := prediction.Disagreement != nil && *prediction.Disagreement >= 50
+
+	return map[string]interface{}{
+		"Latest":       card.Latest,
+		"Prediction":   prediction,
+		"OddEven":      b.translateOddEven(prediction.PredictedOddEven),
+		"BigSmall":     bigSmall,
+		"History":      history,
+		"StreakLine":   streakLine,
+		"LowConsensus": lowConsensus,
+		"Countdown":    countdown,
+	}
 }
 
 // formatVerificationMessage 格式化验证结果消息
 func (b *Bot) formatVerificationMessage(qihao string, isCorrect bool, actualNum string, predictedNum string) string {
-	var builder strings.Builder
-
-	builder.WriteString("✅ *Prediction Verification Result*\n\n")
-
-	builder.WriteString(fmt.Sprintf("Round: `%s`\n", qihao))
-	builder.WriteString(fmt.Sprintf("Predicted Numbers: `%s`\n", predictedNum))
-	builder.WriteString(fmt.Sprintf("Actual Numbers: `%s`\n", actualNum))
-
-	if isCorrect {
-		builder.WriteString("🎉 *Prediction Correct!*\n")
-	} else {
-		builder.WriteString("😅 *Prediction Wrong*\n")
-	}
-
-	return builder.String()
+	return b.renderTemplate("verification", map[string]interface{}{
+		"Qihao":        qihao,
+		"PredictedNum": predictedNum,
+		"ActualNum":    actualNum,
+		"IsCorrect":    isCorrect,
+	})
 }
 
 // calculatePerformanceRating 计算性能评级
@@ -376,34 +623,291 @@ func (b *Bot) translateOddEven(oddEven string) string {
 
 // formatErrorMessage 格式化错误消息
 func (b *Bot) formatErrorMessage(errorType string, details string) string {
+	return b.renderTemplate("error", map[string]interface{}{
+		"ErrorType":  errorType,
+		"Details":    details,
+		"OccurredAt": time.Now(),
+	})
+}
+
+// formatMaintenanceMessage 格式化维护消息
+func (b *Bot) formatMaintenanceMessage(reason string, estimatedTime time.Duration) string {
+	return b.renderTemplate("maintenance", map[string]interface{}{
+		"Reason":        reason,
+		"EstimatedTime": estimatedTime.String(),
+		"StartTime":     time.Now(),
+	})
+}
+
+// formatAnalyticsMessage 格式化用户活跃度分析消息
+func (b *Bot) formatAnalyticsMessage(stats *database.EngagementStats) string {
 	var builder strings.Builder
 
-	builder.WriteString("❌ *System Error*\n\n")
-	builder.WriteString(fmt.Sprintf("Error Type: `%s`\n", errorType))
+	builder.WriteString("📈 *Subscriber Analytics*\n\n")
+	builder.WriteString(fmt.Sprintf("Total Users: `%d`\n", stats.TotalUsers))
+	builder.WriteString(fmt.Sprintf("Active Today: `%d`\n", stats.ActiveToday))
+	builder.WriteString(fmt.Sprintf("New Today: `%d`\n", stats.NewToday))
+	builder.WriteString(fmt.Sprintf("Churned (>%dd inactive): `%d`\n\n", stats.WindowDays, stats.ChurnedUsers))
 
-	if details != "" {
-		builder.WriteString(fmt.Sprintf("Details: `%s`\n", details))
+	builder.WriteString(fmt.Sprintf("📊 *Command Usage (last %d days)*\n", stats.WindowDays))
+	if len(stats.CommandCounts) == 0 {
+		builder.WriteString("No command usage recorded yet.")
+		return builder.String()
 	}
 
-	builder.WriteString(fmt.Sprintf("Occurred Time: `%s`\n\n", time.Now().Format("2006-01-02 15:04:05")))
-	builder.WriteString("Please try again later or contact the administrator")
+	// 按使用次数降序排列
+	commands := make([]string, 0, len(stats.CommandCounts))
+	for command := range stats.CommandCounts {
+		commands = append(commands, command)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		if stats.CommandCounts[commands[i]] != stats.CommandCounts[commands[j]] {
+			return stats.CommandCounts[commands[i]] > stats.CommandCounts[commands[j]]
+		}
+		return commands[i] < commands[j]
+	})
+
+	for _, command := range commands {
+		builder.WriteString(fmt.Sprintf("/%s: `%d`\n", command, stats.CommandCounts[command]))
+	}
 
 	return builder.String()
 }
 
-// formatMaintenanceMessage 格式化维护消息
-func (b *Bot) formatMaintenanceMessage(reason string, estimatedTime time.Duration) string {
+// followRecord 跟单记录展示数据（预测可能已被清理，此时只展示期号）
+type followRecord struct {
+	Qihao      string
+	Prediction *database.Prediction
+}
+
+// formatMyRecordMessage 格式化个人跟单记录消息
+func (b *Bot) formatMyRecordMessage(records []followRecord) string {
+	var builder strings.Builder
+
+	builder.WriteString("🙋 *My Followed Predictions*\n\n")
+
+	if len(records) == 0 {
+		builder.WriteString("You haven't followed any predictions yet.\nTap 👍 Follow on a prediction push to start tracking.")
+		return builder.String()
+	}
+
+	correctCount := 0
+	verifiedCount := 0
+	for _, record := range records {
+		builder.WriteString(fmt.Sprintf("Round `%s`", record.Qihao))
+		if record.Prediction == nil {
+			builder.WriteString(" | expired\n")
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf(" %s", b.translateOddEven(record.Prediction.PredictedOddEven)))
+		if record.Prediction.IsCorrect != nil {
+			verifiedCount++
+			if *record.Prediction.IsCorrect {
+				builder.WriteString(" ✅")
+				correctCount++
+			} else {
+				builder.WriteString(" ❌")
+			}
+		} else {
+			builder.WriteString(" ⏳")
+		}
+		builder.WriteString("\n")
+	}
+
+	builder.WriteString("\n")
+	if verifiedCount > 0 {
+		accuracy := float64(correctCount) / float64(verifiedCount) * 100
+		builder.WriteString(fmt.Sprintf("📈 Followed Accuracy: %.1f%% (%d/%d)", accuracy, correctCount, verifiedCount))
+	} else {
+		builder.WriteString("📈 No verified follows yet")
+	}
+
+	return builder.String()
+}
+
+// formatDailySummaryMessage 格式化每日摘要消息
+func (b *Bot) formatDailySummaryMessage(locale *userLocale, stats *database.DailyStats) string {
+	var builder strings.Builder
+
+	builder.WriteString("🗞 *Daily Prediction Summary*\n\n")
+	builder.WriteString(fmt.Sprintf("Date: `%s`\n", locale.Date(stats.StatDate)))
+	builder.WriteString(fmt.Sprintf("Predictions: `%d`\n", stats.TotalPredictions))
+	builder.WriteString(fmt.Sprintf("Correct: `%d`\n", stats.CorrectPredictions))
+	builder.WriteString(fmt.Sprintf("Accuracy: `%.1f%%`\n", stats.AccuracyRate))
+	builder.WriteString(fmt.Sprintf("Best Streak: `%d` | Worst Streak: `%d`\n", stats.BestStreak, stats.WorstStreak))
+
+	// 假想盈亏：每期投注1单位，命中得净赔率，未中损失本金
+	odds := b.configuredOdds()
+	profit := float64(stats.CorrectPredictions)*odds - float64(stats.TotalPredictions-stats.CorrectPredictions)
+	builder.WriteString(fmt.Sprintf("Hypothetical P&L: `%+.2f` units (odds %.2f)\n\n", profit, odds))
+
+	builder.WriteString("💡 Use /notify summary off to stop daily summaries")
+
+	return builder.String()
+}
+
+// formatProfitMessage 格式化假想盈亏分析消息
+func (b *Bot) formatProfitMessage(analysis *predictor.ProfitAnalysis) string {
+	var builder strings.Builder
+
+	builder.WriteString("💰 *Hypothetical Profit & Loss*\n")
+	builder.WriteString("——————————————\n")
+	builder.WriteString(fmt.Sprintf("Rounds: `%d` (W %d / L %d)\n", analysis.Rounds, analysis.Wins, analysis.Losses))
+	builder.WriteString(fmt.Sprintf("Odds (net payout): `%.2f`\n", analysis.Odds))
+	builder.WriteString(fmt.Sprintf("Net P&L: `%+.2f` units\n", analysis.NetProfit))
+	builder.WriteString(fmt.Sprintf("Max Drawdown: `%.2f` units\n", analysis.MaxDrawdown))
+
+	if len(analysis.Recent) > 0 {
+		builder.WriteString("\n📜 *Recent Rounds*\n")
+		for _, round := range analysis.Recent {
+			icon := "❌"
+			if round.Win {
+				icon = "✅"
+			}
+			builder.WriteString(fmt.Sprintf("%s `%s` %+.2f (cum %+.2f)\n",
+				icon, round.Qihao, round.Profit, round.Cumulative))
+		}
+	}
+
+	builder.WriteString("\n💡 Flat 1-unit stake per round, for reference only")
+	return builder.String()
+}
+
+// formatRawResultMessage 格式化纯开奖结果推送（results订阅模式，不含任何预测内容）
+func (b *Bot) formatRawResultMessage(result *database.LotteryResult) string {
 	var builder strings.Builder
+	builder.WriteString("🎲 *New Draw*\n\n")
+	builder.WriteString(fmt.Sprintf("🎯 Round: `%s`\n", result.Qihao))
+	builder.WriteString(fmt.Sprintf("🔢 Numbers: `%s` = `%d`\n", result.OpenNum, result.SumValue))
 
-	builder.WriteString("🔧 *System Maintenance Notice*\n\n")
-	builder.WriteString(fmt.Sprintf("Maintenance Reason: %s\n", reason))
-	builder.WriteString(fmt.Sprintf("Estimated Duration: %s\n", estimatedTime.String()))
-	builder.WriteString(fmt.Sprintf("Start Time: %s\n\n", time.Now().Format("2006-01-02 15:04:05")))
-	builder.WriteString("Service may be temporarily unavailable during maintenance, please wait patiently")
+	bigSmall := "Small"
+	if result.SumValue >= 14 {
+		bigSmall = "Big"
+	}
+	builder.WriteString(fmt.Sprintf("📊 Attributes: %s / %s", b.translateOddEven(result.OddEven), bigSmall))
 
+	if result.IsTriple {
+		builder.WriteString(" / Triple 💎")
+	} else if result.IsPair {
+		builder.WriteString(" / Pair 👯")
+	}
 	return builder.String()
 }
 
+// formatEvaluationMessage 格式化离线评估结果（baseline为线上算法对照，可能为nil）
+func (b *Bot) formatEvaluationMessage(result, baseline *predictor.EvaluationResult) string {
+	var builder strings.Builder
+
+	builder.WriteString("🧪 *Offline Evaluation*\n")
+	builder.WriteString("——————————————\n")
+	builder.WriteString(fmt.Sprintf("Algorithm: `%s` (v%s)\n", result.Algorithm, result.Version))
+	builder.WriteString(fmt.Sprintf("Rounds: `%d`", result.Rounds))
+	if result.Skipped > 0 {
+		builder.WriteString(fmt.Sprintf(" (skipped %d)", result.Skipped))
+	}
+	builder.WriteString("\n")
+	builder.WriteString(fmt.Sprintf("Accuracy: `%.1f%%` (%d/%d)\n", result.AccuracyRate, result.Correct, result.Rounds))
+	builder.WriteString(fmt.Sprintf("Best Streak: `%d` | Worst Streak: `%d`\n", result.BestStreak, result.WorstStreak))
+
+	if baseline != nil {
+		builder.WriteString("\n📊 *vs Live Algorithm*\n")
+		builder.WriteString(fmt.Sprintf("Live: `%s` Accuracy: `%.1f%%` (%d/%d)\n",
+			baseline.Algorithm, baseline.AccuracyRate, baseline.Correct, baseline.Rounds))
+		diff := result.AccuracyRate - baseline.AccuracyRate
+		if diff >= 0 {
+			builder.WriteString(fmt.Sprintf("Difference: `+%.1f%%` 📈\n", diff))
+		} else {
+			builder.WriteString(fmt.Sprintf("Difference: `%.1f%%` 📉\n", diff))
+		}
+	}
+
+	builder.WriteString("\n💡 Sandbox run, nothing was written to predictions")
+	return builder.String()
+}
+
+// formatSpecialMessage 格式化对子/豹子形态统计（results按时间倒序）
+func (b *Bot) formatSpecialMessage(results []database.LotteryResult) string {
+	var builder strings.Builder
+
+	builder.WriteString("💎 *Pair / Triple Patterns*\n")
+	builder.WriteString("——————————————\n")
+
+	pairs, triples := 0, 0
+	listed := 0
+	var recent strings.Builder
+	for _, result := range results {
+		if result.IsTriple {
+			triples++
+		} else if result.IsPair {
+			pairs++
+		} else {
+			continue
+		}
+
+		if listed < 10 {
+			mark := "🔗 Pair"
+			if result.IsTriple {
+				mark = "💎 Triple"
+			}
+			recent.WriteString(fmt.Sprintf("`%s` `%s` %s\n", result.Qihao, result.OpenNum, mark))
+			listed++
+		}
+	}
+
+	total := len(results)
+	builder.WriteString(fmt.Sprintf("Rounds Analyzed: `%d`\n", total))
+	builder.WriteString(fmt.Sprintf("Pairs: `%d` (`%.1f%%`)\n", pairs, float64(pairs)/float64(total)*100))
+	builder.WriteString(fmt.Sprintf("Triples: `%d` (`%.1f%%`)\n", triples, float64(triples)/float64(total)*100))
+
+	if listed > 0 {
+		builder.WriteString("\n📜 *Recent Special Rounds*\n")
+		builder.WriteString(recent.String())
+	} else {
+		builder.WriteString("\n😕 No pairs or triples in the recent window")
+	}
+
+	return builder.String()
+}
+
+// formatLeaderboardMessage 格式化算法交叉验证榜单
+func (b *Bot) formatLeaderboardMessage(locale *userLocale, entries []database.LeaderboardEntry) string {
+	var builder strings.Builder
+
+	builder.WriteString("🏆 *Algorithm Leaderboard*\n")
+	builder.WriteString("——————————————\n")
+
+	medals := []string{"🥇", "🥈", "🥉"}
+	for i, entry := range entries {
+		rank := fmt.Sprintf("%d.", i+1)
+		if i < len(medals) {
+			rank = medals[i]
+		}
+		builder.WriteString(fmt.Sprintf("%s `%s` — `%.1f%%` (%d/%d)\n",
+			rank, entry.Algorithm, entry.AccuracyRate, entry.Correct, entry.Rounds))
+		builder.WriteString(fmt.Sprintf("    Best Streak: `%d` | Worst Streak: `%d`\n",
+			entry.BestStreak, entry.WorstStreak))
+	}
+
+	builder.WriteString(fmt.Sprintf("\n🕐 Evaluated: %s (trailing %d rounds)",
+		locale.MinuteTime(entries[0].EvaluatedAt), entries[0].Rounds))
+	return builder.String()
+}
+
+// formatCountdownLine 格式化距下一期开奖的倒计时行
+func (b *Bot) formatCountdownLine(lastOpenTime time.Time) string {
+	now := time.Now()
+	if b.drawSchedule.InMaintenance(now) {
+		return b.renderTemplate("maintenance_pause", nil)
+	}
+
+	remaining := b.drawSchedule.Countdown(lastOpenTime, now)
+	return b.renderTemplate("countdown", map[string]interface{}{
+		"Minutes": int(remaining.Minutes()),
+		"Seconds": int(remaining.Seconds()) % 60,
+	})
+}
+
 // CreateInlineKeyboard 创建内联键盘
 func (b *Bot) CreateInlineKeyboard() [][]tgbotapi.InlineKeyboardButton {
 	return [][]tgbotapi.InlineKeyboardButton{
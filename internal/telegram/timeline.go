@@ -0,0 +1,77 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"pc28-bot/internal/database"
+	"pc28-bot/internal/logger"
+)
+
+// handleTimelineCommand 处理单期处理时间线查询命令（管理员专用）
+// 展示该期各处理阶段的落点时间与阶段间耗时，排查"为什么这期推送慢"
+func (b *Bot) handleTimelineCommand(chatID int64, args, corrID string) {
+	qihao := strings.TrimSpace(args)
+	if qihao == "" {
+		b.sendMessage(chatID, "Usage: /timeline <qihao>")
+		return
+	}
+
+	timeline, err := b.db.GetRoundTimeline(qihao)
+	if err != nil {
+		logger.Errorf("Failed to get round timeline: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ Failed to load timeline. (trace: %s)", corrID))
+		return
+	}
+	if timeline == nil {
+		b.sendMessage(chatID, fmt.Sprintf("No timeline recorded for round `%s`.", qihao))
+		return
+	}
+
+	b.sendMessage(chatID, formatTimeline(timeline))
+}
+
+// timelineStage 时间线展示用的阶段标签与时间戳
+type timelineStage struct {
+	label string
+	at    *time.Time
+}
+
+// formatTimeline 渲染时间线消息（各阶段绝对时间+相对上一阶段的耗时）
+func formatTimeline(timeline *database.RoundTimeline) string {
+	stages := []timelineStage{
+		{"Fetched", timeline.FetchedAt},
+		{"Saved", timeline.SavedAt},
+		{"Verified", timeline.VerifiedAt},
+		{"Predicted", timeline.PredictedAt},
+		{"Broadcast", timeline.BroadcastAt},
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("⏱ *Round Timeline* `%s`\n\n", timeline.Qihao))
+
+	var prev *time.Time
+	var first, last *time.Time
+	for _, stage := range stages {
+		if stage.at == nil {
+			sb.WriteString(fmt.Sprintf("`%-9s` —\n", stage.label))
+			continue
+		}
+		line := fmt.Sprintf("`%-9s` %s", stage.label, stage.at.Format("15:04:05.000"))
+		if prev != nil {
+			line += fmt.Sprintf("  (+%v)", stage.at.Sub(*prev).Round(time.Millisecond))
+		}
+		sb.WriteString(line + "\n")
+		if first == nil {
+			first = stage.at
+		}
+		last = stage.at
+		prev = stage.at
+	}
+
+	if first != nil && last != nil && last.After(*first) {
+		sb.WriteString(fmt.Sprintf("\nTotal: %v", last.Sub(*first).Round(time.Millisecond)))
+	}
+	return sb.String()
+}
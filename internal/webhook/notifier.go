@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pc28-bot/internal/config"
+	"pc28-bot/internal/logger"
+)
+
+// 投递参数
+const (
+	queueSize      = 64              // 待投递事件缓冲（满时丢弃并告警，不阻塞开奖流程）
+	maxAttempts    = 3               // 单端点单事件的最大投递尝试次数
+	initialBackoff = 1 * time.Second // 首次重试退避，之后指数递增
+)
+
+// Event 推送给第三方的领域事件
+type Event struct {
+	Type      string          `json:"type"`
+	Qihao     string          `json:"qihao,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// Notifier 对外webhook通知器
+// 将领域事件以JSON POST到配置的第三方端点，请求体带HMAC-SHA256签名
+// （X-PC28-Signature头），失败时指数退避重试；投递在后台协程串行进行，
+// 不阻塞开奖处理流程
+type Notifier struct {
+	endpoints []config.WebhookEndpoint
+	client    *http.Client
+
+	queue       chan Event
+	stopChannel chan struct{}
+	doneChannel chan struct{}
+}
+
+// NewNotifier 创建webhook通知器并启动投递循环
+func NewNotifier(endpoints []config.WebhookEndpoint) *Notifier {
+	n := &Notifier{
+		endpoints: endpoints,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		queue:       make(chan Event, queueSize),
+		stopChannel: make(chan struct{}),
+		doneChannel: make(chan struct{}),
+	}
+
+	go n.deliverLoop()
+	logger.Infof("Webhook notifier initialized with %d endpoints", len(endpoints))
+	return n
+}
+
+// Publish 异步投递领域事件（实现pipeline.EventPublisher）
+// 队列已满时丢弃事件并告警，保证调用方不被慢端点拖住
+func (n *Notifier) Publish(eventType, qihao, payload string) {
+	event := Event{
+		Type:      eventType,
+		Qihao:     qihao,
+		Timestamp: time.Now(),
+	}
+	if payload != "" {
+		event.Payload = json.RawMessage(payload)
+	}
+
+	select {
+	case n.queue <- event:
+	default:
+		logger.Warnf("Webhook queue full, dropping event %s for %s", eventType, qihao)
+	}
+}
+
+// Stop 停止投递循环（队列中未投递的事件丢弃）
+func (n *Notifier) Stop() {
+	close(n.stopChannel)
+	<-n.doneChannel
+}
+
+// deliverLoop 投递循环（单协程串行投递，保持事件顺序）
+func (n *Notifier) deliverLoop() {
+	defer close(n.doneChannel)
+
+	for {
+		select {
+		case event := <-n.queue:
+			n.deliver(event)
+		case <-n.stopChannel:
+			return
+		}
+	}
+}
+
+// deliver 将事件投递到所有订阅了该事件类型的端点
+func (n *Notifier) deliver(event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Warnf("Failed to marshal webhook event: %v", err)
+		return
+	}
+
+	for i := range n.endpoints {
+		endpoint := &n.endpoints[i]
+		if !subscribes(endpoint, event.Type) {
+			continue
+		}
+		if err := n.postWithRetry(endpoint, event.Type, body); err != nil {
+			logger.Warnf("Webhook delivery to %s failed after %d attempts: %v",
+				endpoint.URL, maxAttempts, err)
+		}
+	}
+}
+
+// subscribes 判断端点是否订阅了指定事件类型（未配置过滤时订阅全部）
+func subscribes(endpoint *config.WebhookEndpoint, eventType string) bool {
+	if len(endpoint.Events) == 0 {
+		return true
+	}
+	for _, e := range endpoint.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// postWithRetry 带指数退避重试的投递（重试间隔可被停机打断）
+func (n *Notifier) postWithRetry(endpoint *config.WebhookEndpoint, eventType string, body []byte) error {
+	backoff := initialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if lastErr = n.post(endpoint, eventType, body); lastErr == nil {
+			return nil
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-n.stopChannel:
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// post 单次投递（2xx视为成功）
+func (n *Notifier) post(endpoint *config.WebhookEndpoint, eventType string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-PC28-Event", eventType)
+	if endpoint.Secret != "" {
+		req.Header.Set("X-PC28-Signature", "sha256="+sign(endpoint.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign 计算请求体的HMAC-SHA256签名（十六进制编码）
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}